@@ -49,7 +49,8 @@ func UpdateOperatingState(name string, state string, lc logger.LoggingClient, dc
 	}
 }
 
-func SendEvent(event *dtos.Event, correlationID string, dic *di.Container) {
+// SendEvent publishes the Event to the MessageBus and returns the publish error, if any
+func SendEvent(event *dtos.Event, correlationID string, dic *di.Container) error {
 	lc := bootstrapContainer.LoggingClientFrom(dic.Get)
 	configuration := container.ConfigurationFrom(dic.Get)
 	ctx := context.WithValue(context.Background(), common.CorrelationHeader, correlationID) // nolint: staticcheck
@@ -67,7 +68,7 @@ func SendEvent(event *dtos.Event, correlationID string, dic *di.Container) {
 	err := mc.PublishWithSizeLimit(envelope, publishTopic, configuration.MaxEventSize)
 	if err != nil {
 		lc.Errorf("Failed to publish event to MessageBus: %s", err)
-		return
+		return err
 	}
 	lc.Debugf("Event(profileName: %s, deviceName: %s, sourceName: %s, id: %s) published to MessageBus on topic: %s",
 		event.ProfileName, event.DeviceName, event.SourceName, event.Id, publishTopic)
@@ -77,6 +78,7 @@ func SendEvent(event *dtos.Event, correlationID string, dic *di.Container) {
 		eventsSent.Inc(1)
 		readingsSent.Inc(int64(len(event.Readings)))
 	}
+	return nil
 }
 
 func InitializeSentMetrics(lc logger.LoggingClient, dic *di.Container) {
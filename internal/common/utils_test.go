@@ -190,12 +190,14 @@ func TestSendEvent(t *testing.T) {
 
 			InitializeSentMetrics(logger.NewMockClient(), dic)
 
-			SendEvent(tt.event, testUUIDString, dic)
+			err := SendEvent(tt.event, testUUIDString, dic)
 			mcMock.AssertNumberOfCalls(t, "PublishWithSizeLimit", 1)
 			if tt.eventTooLarge {
+				assert.Error(t, err)
 				assert.Equal(t, int64(0), eventsSent.Count())
 				assert.Equal(t, int64(0), readingsSent.Count())
 			} else {
+				assert.NoError(t, err)
 				assert.Equal(t, int64(1), eventsSent.Count())
 				assert.Equal(t, int64(1), readingsSent.Count())
 			}
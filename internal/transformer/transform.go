@@ -43,7 +43,7 @@ func CommandValuesToEventDTO(cvs []*models.CommandValue, deviceName string, sour
 	}
 
 	var transformsOK = true
-	origin := getUniqueOrigin()
+	origin := getEventOrigin(cvs)
 	tags := make(map[string]interface{})
 	lc := bootstrapContainer.LoggingClientFrom(dic.Get)
 	readings := make([]dtos.BaseReading, 0, len(cvs))
@@ -175,6 +175,22 @@ func commandValueToReading(cv *models.CommandValue, deviceName, profileName, med
 	return reading, nil
 }
 
+// getEventOrigin returns the latest Origin set by the ProtocolDriver implementation,
+// e.g. the measurement time reported by the device, and falls back to a unique
+// host clock Origin when none of the CommandValues carries one
+func getEventOrigin(cvs []*models.CommandValue) int64 {
+	var origin int64
+	for _, cv := range cvs {
+		if cv != nil && cv.Origin > origin {
+			origin = cv.Origin
+		}
+	}
+	if origin != 0 {
+		return origin
+	}
+	return getUniqueOrigin()
+}
+
 func getUniqueOrigin() int64 {
 	originMutex.Lock()
 	defer originMutex.Unlock()
@@ -151,6 +151,51 @@ func Test_getUniqueOrigin(t *testing.T) {
 	}
 }
 
+func TestCommandValuesToEventDTO_Origin(t *testing.T) {
+	dic := NewMockDIC()
+	err := cache.InitCache(TestDeviceService, TestDeviceService, dic)
+	require.NoError(t, err)
+
+	const deviceOrigin = int64(1749513600000000000)
+	newCommandValue := func(origin int64) *sdkModels.CommandValue {
+		cv, e := sdkModels.NewCommandValueWithOrigin(TestDeviceResource, common.ValueTypeString, TestValue, origin)
+		require.NoError(t, e)
+		return cv
+	}
+
+	tests := []struct {
+		Name           string
+		CommandValues  []*sdkModels.CommandValue
+		ExpectedOrigin int64
+	}{
+		{"Origin set by driver", []*sdkModels.CommandValue{newCommandValue(deviceOrigin)}, deviceOrigin},
+		{"latest Origin set by driver", []*sdkModels.CommandValue{newCommandValue(deviceOrigin - 1000), newCommandValue(deviceOrigin)}, deviceOrigin},
+		{"Origin partially set by driver", []*sdkModels.CommandValue{newCommandValue(0), newCommandValue(deviceOrigin)}, deviceOrigin},
+		{"no Origin set by driver", []*sdkModels.CommandValue{newCommandValue(0)}, 0},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.Name, func(t *testing.T) {
+			event, err := CommandValuesToEventDTO(testCase.CommandValues, TestDevice, TestDeviceCommand, false, dic)
+			require.NoError(t, err)
+
+			if testCase.ExpectedOrigin == 0 {
+				assert.Greater(t, event.Origin, deviceOrigin, "host clock Origin expected")
+			} else {
+				assert.Equal(t, testCase.ExpectedOrigin, event.Origin)
+			}
+			// readings without their own Origin inherit the Event Origin
+			for i, reading := range event.Readings {
+				if cv := testCase.CommandValues[i]; cv.Origin != 0 {
+					assert.Equal(t, cv.Origin, reading.Origin)
+				} else {
+					assert.Equal(t, event.Origin, reading.Origin)
+				}
+			}
+		})
+	}
+}
+
 func TestCommandValuesToEventDTO_ReadingUnits(t *testing.T) {
 	dic := NewMockDIC()
 	err := cache.InitCache(TestDeviceService, TestDeviceService, dic)
@@ -6,9 +6,21 @@
 
 package models
 
+import "errors"
+
+// ErrAsyncValuesRejected is wrapped by the error reported on AsyncValues.Published when
+// the values can never be published as they are, e.g. there are no CommandValues, the
+// Event cannot be built from them, or it exceeds MaxEventSize. Other errors (MessageBus
+// not connected, publish timeout) may succeed when retried.
+var ErrAsyncValuesRejected = errors.New("async values rejected")
+
 // AsyncValues is the struct for sending Device readings asynchronously via ProtocolDrivers
 type AsyncValues struct {
 	DeviceName    string
 	SourceName    string
 	CommandValues []*CommandValue
+	// Published is optional. When set, the SDK reports the outcome of sending these
+	// values to it: nil once the Event is on the MessageBus, otherwise the error.
+	// The SDK never blocks on it, so the channel should be buffered.
+	Published chan<- error
 }
@@ -58,10 +58,12 @@ func (s *deviceService) sendAsyncValues(acv *sdkModels.AsyncValues, working chan
 
 	if len(acv.CommandValues) == 0 {
 		s.lc.Error("Skip sending AsyncValues because the CommandValues is empty.")
+		notifyPublished(acv, fmt.Errorf("%w: the CommandValues is empty", sdkModels.ErrAsyncValuesRejected))
 		return
 	}
 	if len(acv.CommandValues) > 1 && acv.SourceName == "" {
 		s.lc.Error("Skip sending AsyncValues because the SourceName is empty.")
+		notifyPublished(acv, fmt.Errorf("%w: the SourceName is empty", sdkModels.ErrAsyncValuesRejected))
 		return
 	}
 	// We can use the first reading's DeviceResourceName as the SourceName
@@ -74,10 +76,50 @@ func (s *deviceService) sendAsyncValues(acv *sdkModels.AsyncValues, working chan
 	event, err := transformer.CommandValuesToEventDTO(acv.CommandValues, acv.DeviceName, acv.SourceName, configuration.Device.DataTransform, dic)
 	if err != nil {
 		s.lc.Errorf("failed to transform CommandValues to Event: %v", err)
+		notifyPublished(acv, fmt.Errorf("%w: %w", sdkModels.ErrAsyncValuesRejected, err))
 		return
 	}
+	if event == nil {
+		notifyPublished(acv, fmt.Errorf("%w: no readings to send", sdkModels.ErrAsyncValuesRejected))
+		return
+	}
+
+	// an oversized event would be refused by the MessageBus client on every retry
+	if err := checkEventSize(event, configuration.MaxEventSize); err != nil {
+		s.lc.Errorf("Skip sending AsyncValues: %v", err)
+		notifyPublished(acv, err)
+		return
+	}
+
+	notifyPublished(acv, common.SendEvent(event, "", dic))
+}
+
+// checkEventSize returns an error wrapping ErrAsyncValuesRejected when the encoded event
+// exceeds maxEventSize (in KB). A maxEventSize of 0 means unlimited.
+func checkEventSize(event *dtos.Event, maxEventSize int64) error {
+	if maxEventSize <= 0 {
+		return nil
+	}
+	req := requests.NewAddEventRequest(*event)
+	encoded, _, err := req.Encode()
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkModels.ErrAsyncValuesRejected, err)
+	}
+	if int64(len(encoded)) > maxEventSize*1024 {
+		return fmt.Errorf("%w: event size %d bytes exceeds MaxEventSize %d KB", sdkModels.ErrAsyncValuesRejected, len(encoded), maxEventSize)
+	}
+	return nil
+}
 
-	common.SendEvent(event, "", dic)
+// notifyPublished reports the publish outcome to the ProtocolDriver if it asked for it
+func notifyPublished(acv *sdkModels.AsyncValues, err error) {
+	if acv.Published == nil {
+		return
+	}
+	select {
+	case acv.Published <- err:
+	default:
+	}
 }
 
 // processAsyncFilterAndAdd filter and add devices discovered by
@@ -6,12 +6,28 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 
+	bootstrapContainer "github.com/edgexfoundry/go-mod-bootstrap/v4/bootstrap/container"
+	bootstrapMocks "github.com/edgexfoundry/go-mod-bootstrap/v4/bootstrap/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-bootstrap/v4/di"
+	clientMocks "github.com/edgexfoundry/go-mod-core-contracts/v4/clients/interfaces/mocks"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/dtos/responses"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	msgMocks "github.com/edgexfoundry/go-mod-messaging/v4/messaging/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
+	"github.com/edgexfoundry/device-sdk-go/v4/internal/cache"
+	"github.com/edgexfoundry/device-sdk-go/v4/internal/config"
+	"github.com/edgexfoundry/device-sdk-go/v4/internal/container"
 	sdkModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
 )
 
@@ -153,3 +169,126 @@ func Test_checkBlockList(t *testing.T) {
 		})
 	}
 }
+
+func Test_notifyPublished(t *testing.T) {
+	published := make(chan error, 1)
+	acv := &sdkModels.AsyncValues{Published: published}
+
+	notifyPublished(acv, errors.New("publish failed"))
+	// the channel is full, the second outcome is dropped instead of blocking
+	notifyPublished(acv, nil)
+	assert.EqualError(t, <-published, "publish failed")
+	assert.Empty(t, published)
+
+	// drivers that do not ask for the outcome are unaffected
+	notifyPublished(&sdkModels.AsyncValues{}, nil)
+}
+
+const (
+	testAsyncService  = "test-service"
+	testAsyncDevice   = "test-device"
+	testAsyncProfile  = "test-profile"
+	testAsyncResource = "test-resource"
+)
+
+// newAsyncTestDIC returns a DIC with one device in the cache and the given MessageBus client
+func newAsyncTestDIC(t *testing.T, maxEventSize int64, mc *msgMocks.MessageClient) *di.Container {
+	devices := responses.MultiDevicesResponse{
+		Devices: []dtos.Device{{Name: testAsyncDevice, ProfileName: testAsyncProfile}},
+	}
+	dcMock := &clientMocks.DeviceClient{}
+	dcMock.On("DevicesByServiceName", context.Background(), testAsyncService, 0, -1).Return(devices, nil)
+
+	profile := responses.DeviceProfileResponse{
+		Profile: dtos.DeviceProfile{
+			DeviceProfileBasicInfo: dtos.DeviceProfileBasicInfo{Name: testAsyncProfile},
+			DeviceResources: []dtos.DeviceResource{
+				{Name: testAsyncResource, Properties: dtos.ResourceProperties{ValueType: common.ValueTypeString}},
+			},
+		},
+	}
+	dpcMock := &clientMocks.DeviceProfileClient{}
+	dpcMock.On("DeviceProfileByName", context.Background(), testAsyncProfile).Return(profile, nil)
+
+	pwcMock := &clientMocks.ProvisionWatcherClient{}
+	pwcMock.On("ProvisionWatchersByServiceName", context.Background(), testAsyncService, 0, -1).Return(responses.MultiProvisionWatchersResponse{}, nil)
+
+	mockMetricsManager := &bootstrapMocks.MetricsManager{}
+	mockMetricsManager.On("Register", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	dic := di.NewContainer(di.ServiceConstructorMap{
+		container.ConfigurationName: func(get di.Get) interface{} {
+			return &config.ConfigurationStruct{MaxEventSize: maxEventSize}
+		},
+		container.DeviceServiceName: func(get di.Get) any {
+			return &models.DeviceService{Name: testAsyncService}
+		},
+		bootstrapContainer.LoggingClientInterfaceName: func(get di.Get) interface{} {
+			return logger.NewMockClient()
+		},
+		bootstrapContainer.DeviceClientName: func(get di.Get) interface{} {
+			return dcMock
+		},
+		bootstrapContainer.DeviceProfileClientName: func(get di.Get) interface{} {
+			return dpcMock
+		},
+		bootstrapContainer.ProvisionWatcherClientName: func(get di.Get) interface{} {
+			return pwcMock
+		},
+		bootstrapContainer.MessagingClientName: func(get di.Get) interface{} {
+			return mc
+		},
+		bootstrapContainer.MetricsManagerInterfaceName: func(get di.Get) interface{} {
+			return mockMetricsManager
+		},
+	})
+	require.NoError(t, cache.InitCache(testAsyncService, testAsyncService, dic))
+	return dic
+}
+
+func Test_sendAsyncValues(t *testing.T) {
+	reading := func(size int) []*sdkModels.CommandValue {
+		cv, err := sdkModels.NewCommandValue(testAsyncResource, common.ValueTypeString, strings.Repeat("x", size))
+		require.NoError(t, err)
+		return []*sdkModels.CommandValue{cv}
+	}
+
+	tests := []struct {
+		name          string
+		commandValues []*sdkModels.CommandValue
+		publishErr    error
+		published     bool
+		rejected      bool
+	}{
+		{"Valid, published", reading(10), nil, true, false},
+		{"Valid, MessageBus unavailable", reading(10), errors.New("not connected"), true, false},
+		{"Invalid, empty CommandValues", nil, nil, false, true},
+		{"Invalid, over max event size", reading(2048), nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mcMock := &msgMocks.MessageClient{}
+			mcMock.On("PublishWithSizeLimit", mock.Anything, mock.Anything, int64(1)).Return(tt.publishErr)
+			dic := newAsyncTestDIC(t, 1, mcMock)
+			ds := newDeviceService()
+
+			published := make(chan error, 1)
+			acv := &sdkModels.AsyncValues{DeviceName: testAsyncDevice, CommandValues: tt.commandValues, Published: published}
+			ds.sendAsyncValues(acv, make(chan bool, 1), dic)
+
+			require.Len(t, published, 1)
+			err := <-published
+			if tt.published {
+				mcMock.AssertNumberOfCalls(t, "PublishWithSizeLimit", 1)
+			} else {
+				mcMock.AssertNotCalled(t, "PublishWithSizeLimit", mock.Anything, mock.Anything, mock.Anything)
+			}
+			assert.Equal(t, tt.rejected, errors.Is(err, sdkModels.ErrAsyncValuesRejected), "rejected: %v", err)
+			if tt.publishErr == nil && !tt.rejected {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
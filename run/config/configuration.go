@@ -29,6 +29,7 @@ import (
 // 'SimpleCustom' in this example.
 type ServiceConfig struct {
 	SimpleCustom SimpleCustomConfig
+	GPSCustom    GPSCustomConfig
 }
 
 // SimpleCustomConfig is example of service's custom structured configuration that is specified in the service's
@@ -44,6 +45,23 @@ type SimpleWritable struct {
 	DiscoverSleepDurationSecs int64
 }
 
+// GPSCustomConfig GPS驱动的自定义配置，对应configuration.yaml中的GPSCustom段
+type GPSCustomConfig struct {
+	Writable GPSWritable
+}
+
+// GPSWritable GPS驱动可在运行时修改的配置
+type GPSWritable struct {
+	Format FormatConfig
+}
+
+// FormatConfig 显示格式配置，留空的字段沿用Driver段中的设置
+type FormatConfig struct {
+	Locale      string // zh-CN/en-US
+	Units       string // metric/imperial/nautical
+	Coordinates string // decimal/dms/ddm/utm/mgrs
+}
+
 // UpdateFromRaw updates the service's full configuration from raw data received from
 // the Service Provider.
 func (sw *ServiceConfig) UpdateFromRaw(rawConfig interface{}) bool {
@@ -0,0 +1,696 @@
+package driver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/spf13/cast"
+)
+
+// 基准站模式，对应PQTMCFGSVIN的Mode字段0、1、2
+const (
+	BaseModeDisabled = "disabled"
+	BaseModeSurveyIn = "survey-in"
+	BaseModeFixed    = "fixed"
+)
+
+// 勘测状态，对应PQTMSVINSTATUS的Valid字段0、1、2
+const (
+	SurveyInInvalid    = "invalid"
+	SurveyInInProgress = "in-progress"
+	SurveyInValid      = "valid"
+)
+
+// 基准站坐标的来源
+const (
+	BasePositionRTCM       = "rtcm"       // 接收机输出的1005/1006电文
+	BasePositionSurveyIn   = "survey-in"  // 勘测完成的平均位置
+	BasePositionConfigured = "configured" // base_fixed设置的坐标
+)
+
+const (
+	baseClientQueue    = 64               // 每个客户端待发送的帧数，积压超过后断开该客户端
+	baseRequestTimeout = 10 * time.Second // 等待客户端请求的超时时间
+	baseWriteTimeout   = 5 * time.Second  // 向客户端写入一帧的超时时间
+)
+
+var baseModeCodes = []string{BaseModeDisabled, BaseModeSurveyIn, BaseModeFixed}
+
+var surveyInStates = []string{SurveyInInvalid, SurveyInInProgress, SurveyInValid}
+
+// BaseModeConfig 接收机的基准站配置
+type BaseModeConfig struct {
+	Mode          string  `json:"mode"`
+	MinDuration   int     `json:"minDuration,omitempty"`   // 勘测最短时长（秒）
+	AccuracyLimit float64 `json:"accuracyLimit,omitempty"` // 勘测三维精度门限（米），0为不限
+	X             float64 `json:"x,omitempty"`             // 固定坐标，地心地固（米）
+	Y             float64 `json:"y,omitempty"`
+	Z             float64 `json:"z,omitempty"`
+}
+
+// SurveyInStatus 接收机输出的勘测状态
+type SurveyInStatus struct {
+	State        string  `json:"state"`
+	Observations int     `json:"observations"` // 已参与平均的历元数
+	MinDuration  int     `json:"minDuration"`
+	X            float64 `json:"x"` // 平均位置，地心地固（米）
+	Y            float64 `json:"y"`
+	Z            float64 `json:"z"`
+	Accuracy     float64 `json:"accuracy"` // 平均位置的三维精度（米）
+}
+
+// BasePosition 基准站坐标
+type BasePosition struct {
+	Source    string  `json:"source"`
+	StationID int     `json:"stationId,omitempty"` // 仅来自RTCM电文时有效
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Height    float64 `json:"height"` // 椭球高（米）
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Z         float64 `json:"z"`
+}
+
+// BaseClientStatus 一个已连接的NTRIP客户端
+type BaseClientStatus struct {
+	Address   string  `json:"address"`
+	Agent     string  `json:"agent,omitempty"`
+	Connected float64 `json:"connected"` // 已连接时长（秒）
+	Bytes     int64   `json:"bytes"`
+}
+
+// BaseStatus 基准站状态，作为base_status资源的值
+type BaseStatus struct {
+	Mode          *BaseModeConfig    `json:"mode,omitempty"` // 最近一次查询或设置的接收机配置
+	SurveyIn      *SurveyInStatus    `json:"surveyIn,omitempty"`
+	Position      *BasePosition      `json:"position,omitempty"`
+	Address       string             `json:"address"`
+	Mountpoint    string             `json:"mountpoint"`
+	Uptime        float64            `json:"uptime"` // 播发服务运行时长（秒）
+	Clients       []BaseClientStatus `json:"clients"`
+	ClientsServed int64              `json:"clientsServed"`
+	Output        CorrectionsStatus  `json:"output"` // 接收机输出的RTCM3统计
+}
+
+// ParsPQTMSVINSTATUS 解析勘测状态语句，格式错误时返回nil
+func ParsPQTMSVINSTATUS(sentence string) *SurveyInStatus {
+	body, _, _ := strings.Cut(sentence, "*")
+	fields := strings.Split(body, ",")
+	if len(fields) < 11 || fields[0] != "$PQTMSVINSTATUS" {
+		return nil
+	}
+	valid, err := strconv.Atoi(fields[2])
+	if err != nil || valid < 0 || valid >= len(surveyInStates) {
+		return nil
+	}
+	status := &SurveyInStatus{State: surveyInStates[valid]}
+	status.Observations, _ = strconv.Atoi(fields[5])
+	status.MinDuration, _ = strconv.Atoi(fields[6])
+	status.X, _ = strconv.ParseFloat(fields[7], 64)
+	status.Y, _ = strconv.ParseFloat(fields[8], 64)
+	status.Z, _ = strconv.ParseFloat(fields[9], 64)
+	status.Accuracy, _ = strconv.ParseFloat(fields[10], 64)
+	return status
+}
+
+// parseProprietary 处理Quectel专有语句：勘测状态单独保存，其余交给命令事务
+func (lcx6xz *LCX6XZ) parseProprietary(sentence string) {
+	if !ValidateNMEAChecksum(sentence, len(sentence)) {
+		fmt.Printf("专有语句校验和错误: %s\n", sentence)
+		return
+	}
+	if strings.HasPrefix(sentence, "$PQTMSVINSTATUS,") {
+		if status := ParsPQTMSVINSTATUS(sentence); status != nil {
+			lcx6xz.mutex.Lock()
+			lcx6xz.surveyIn = status
+			lcx6xz.mutex.Unlock()
+		}
+		return
+	}
+	lcx6xz.deliver([]byte(sentence))
+}
+
+// SurveyIn 返回最近一条勘测状态，尚未收到时返回nil
+func (lcx6xz *LCX6XZ) SurveyIn() *SurveyInStatus {
+	lcx6xz.mutex.Lock()
+	defer lcx6xz.mutex.Unlock()
+	if lcx6xz.surveyIn == nil {
+		return nil
+	}
+	status := *lcx6xz.surveyIn
+	return &status
+}
+
+// pqtmReply 匹配专有命令name的应答，OK时返回其余字段，ERROR时返回错误
+func pqtmReply(name string, fields *[]string) func([]byte) (bool, error) {
+	return func(frame []byte) (bool, error) {
+		if !strings.HasPrefix(string(frame), "$"+name+",") {
+			return false, nil
+		}
+		body, _, _ := strings.Cut(string(frame[1:]), "*")
+		parts := strings.Split(body, ",")
+		if parts[1] != "OK" {
+			return true, fmt.Errorf("设备拒绝命令%s: %s", name, strings.Join(parts[1:], ","))
+		}
+		if fields != nil {
+			*fields = parts[2:]
+		}
+		return true, nil
+	}
+}
+
+// SetBaseMode 设置接收机的基准站模式并保存到接收机
+func SetBaseMode(lcx6xz *LCX6XZ, cfg BaseModeConfig) error {
+	code := -1
+	for i, mode := range baseModeCodes {
+		if mode == cfg.Mode {
+			code = i
+		}
+	}
+	if code < 0 {
+		return fmt.Errorf("无效的基准站模式: %s", cfg.Mode)
+	}
+
+	cmd := BuildNMEASentence(fmt.Sprintf("PQTMCFGSVIN,W,%d,%d,%.4f,%.4f,%.4f,%.4f",
+		code, cfg.MinDuration, cfg.AccuracyLimit, cfg.X, cfg.Y, cfg.Z))
+	if err := lcx6xz.transact([]byte(cmd), pqtmReply("PQTMCFGSVIN", nil)); err != nil {
+		return err
+	}
+	return lcx6xz.transact([]byte(BuildNMEASentence("PQTMSAVEPAR")), pqtmReply("PQTMSAVEPAR", nil))
+}
+
+// GetBaseMode 查询接收机的基准站模式
+func GetBaseMode(lcx6xz *LCX6XZ) (BaseModeConfig, error) {
+	var fields []string
+	if err := lcx6xz.transact([]byte(BuildNMEASentence("PQTMCFGSVIN,R")), pqtmReply("PQTMCFGSVIN", &fields)); err != nil {
+		return BaseModeConfig{}, err
+	}
+	if len(fields) < 6 {
+		return BaseModeConfig{}, fmt.Errorf("无效的PQTMCFGSVIN应答: %v", fields)
+	}
+	code, err := strconv.Atoi(fields[0])
+	if err != nil || code < 0 || code >= len(baseModeCodes) {
+		return BaseModeConfig{}, fmt.Errorf("无效的基准站模式: %s", fields[0])
+	}
+	cfg := BaseModeConfig{Mode: baseModeCodes[code]}
+	cfg.MinDuration, _ = strconv.Atoi(fields[1])
+	cfg.AccuracyLimit, _ = strconv.ParseFloat(fields[2], 64)
+	cfg.X, _ = strconv.ParseFloat(fields[3], 64)
+	cfg.Y, _ = strconv.ParseFloat(fields[4], 64)
+	cfg.Z, _ = strconv.ParseFloat(fields[5], 64)
+	return cfg, nil
+}
+
+// BaseConfig 基准站的本地NTRIP播发服务配置
+type BaseConfig struct {
+	Enabled    bool
+	Listen     string // 监听地址，如":2102"
+	Mountpoint string
+	SecretName string // 客户端用户名和密码所在的密钥，为空时不认证
+	MaxClients int
+}
+
+// BaseCaster 基准站的本地NTRIP播发服务，把接收机输出的RTCM3帧转发给所有已连接的客户端。可并发使用。
+type BaseCaster struct {
+	cfg         BaseConfig
+	listener    net.Listener
+	credentials func() (string, string, error)
+	monitor     *RTCMMonitor
+	started     time.Time
+
+	mutex   sync.Mutex
+	stream  *RTCMStream
+	clients map[*casterClient]bool
+	served  int64
+	mode    *BaseModeConfig
+	closed  bool
+}
+
+// casterClient 一个已连接的客户端
+type casterClient struct {
+	conn   net.Conn
+	agent  string
+	since  time.Time
+	frames chan []byte
+	bytes  int64 // 只在持有BaseCaster.mutex时访问
+}
+
+// NewBaseCaster 开始监听并接受客户端连接，credentials返回空用户名时不认证
+func NewBaseCaster(cfg BaseConfig, credentials func() (string, string, error)) (*BaseCaster, error) {
+	if cfg.Mountpoint == "" {
+		return nil, errors.New("基准站挂载点不能为空")
+	}
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("基准站播发服务监听%s失败: %w", cfg.Listen, err)
+	}
+	c := &BaseCaster{
+		cfg:         cfg,
+		listener:    listener,
+		credentials: credentials,
+		monitor:     NewRTCMMonitor(),
+		started:     time.Now(),
+		clients:     make(map[*casterClient]bool),
+	}
+	c.stream = c.monitor.Stream(RTCMSourceReceiver)
+	go c.serve()
+	return c, nil
+}
+
+// Addr 返回实际监听的地址
+func (c *BaseCaster) Addr() net.Addr {
+	return c.listener.Addr()
+}
+
+// SetMode 记录接收机当前的基准站配置
+func (c *BaseCaster) SetMode(mode BaseModeConfig) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.mode = &mode
+}
+
+// Write 统计一帧RTCM3电文并转发给所有客户端，总是返回len(p)
+func (c *BaseCaster) Write(p []byte) (int, error) {
+	frame := append([]byte(nil), p...)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, _ = c.stream.Write(frame)
+	for client := range c.clients {
+		select {
+		case client.frames <- frame:
+			client.bytes += int64(len(frame))
+		default:
+			// 客户端接收太慢，断开以免阻塞其他客户端
+			c.removeLocked(client)
+		}
+	}
+	return len(p), nil
+}
+
+// Close 停止监听并断开所有客户端
+func (c *BaseCaster) Close() error {
+	c.mutex.Lock()
+	c.closed = true
+	for client := range c.clients {
+		c.removeLocked(client)
+	}
+	c.mutex.Unlock()
+	return c.listener.Close()
+}
+
+// serve 接受客户端连接，监听关闭后退出
+func (c *BaseCaster) serve() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		go c.handle(conn)
+	}
+}
+
+// handle 处理一个客户端请求，挂载点正确时持续发送数据直到断开
+func (c *BaseCaster) handle(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(baseRequestTimeout))
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	v2 := req.Header.Get("Ntrip-Version") == "Ntrip/2.0"
+	reply := func(status int, header string) {
+		if v2 {
+			fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nNtrip-Version: Ntrip/2.0\r\n%sConnection: close\r\n\r\n", status, http.StatusText(status), header)
+		} else {
+			fmt.Fprintf(conn, "HTTP/1.0 %d %s\r\n%s\r\n", status, http.StatusText(status), header)
+		}
+	}
+
+	mountpoint := strings.TrimPrefix(req.URL.Path, "/")
+	if mountpoint != c.cfg.Mountpoint {
+		// 请求源列表或挂载点不存在：v1返回源列表，v2对错误的挂载点返回404
+		if mountpoint != "" && v2 {
+			reply(http.StatusNotFound, "")
+		} else {
+			c.writeSourceTable(conn, v2)
+		}
+		_ = conn.Close()
+		return
+	}
+
+	if username, password, err := c.credentials(); err != nil {
+		reply(http.StatusInternalServerError, "")
+		_ = conn.Close()
+		return
+	} else if username != "" {
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != username || pass != password {
+			reply(http.StatusUnauthorized, fmt.Sprintf("WWW-Authenticate: Basic realm=%q\r\n", c.cfg.Mountpoint))
+			_ = conn.Close()
+			return
+		}
+	}
+
+	client := &casterClient{conn: conn, agent: req.UserAgent(), since: time.Now(), frames: make(chan []byte, baseClientQueue)}
+	if err := c.add(client); err != nil {
+		reply(http.StatusServiceUnavailable, "")
+		_ = conn.Close()
+		return
+	}
+	if v2 {
+		reply(http.StatusOK, "Content-Type: gnss/data\r\nCache-Control: no-store, no-cache, max-age=0\r\n")
+	} else {
+		fmt.Fprint(conn, "ICY 200 OK\r\n")
+	}
+
+	// 客户端上传的GGA等数据不使用，读取失败即视为断开
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			if _, err := br.Read(buf); err != nil {
+				c.remove(client)
+				return
+			}
+		}
+	}()
+	for frame := range client.frames {
+		_ = conn.SetWriteDeadline(time.Now().Add(baseWriteTimeout))
+		if _, err := conn.Write(frame); err != nil {
+			c.remove(client)
+		}
+	}
+}
+
+// add 登记客户端，超过最大客户端数时返回错误
+func (c *BaseCaster) add(client *casterClient) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return errors.New("基准站播发服务已关闭")
+	}
+	if c.cfg.MaxClients > 0 && len(c.clients) >= c.cfg.MaxClients {
+		return fmt.Errorf("客户端数已达上限%d", c.cfg.MaxClients)
+	}
+	c.clients[client] = true
+	c.served++
+	return nil
+}
+
+// remove 断开并移除客户端，可重复调用
+func (c *BaseCaster) remove(client *casterClient) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.removeLocked(client)
+}
+
+// removeLocked 断开并移除客户端，调用方需持有mutex
+func (c *BaseCaster) removeLocked(client *casterClient) {
+	if !c.clients[client] {
+		return
+	}
+	delete(c.clients, client)
+	close(client.frames)
+	_ = client.conn.Close()
+}
+
+// writeSourceTable 发送只含本挂载点的源列表
+func (c *BaseCaster) writeSourceTable(conn net.Conn, v2 bool) {
+	status := c.Status(time.Now(), nil)
+	var formats, systems []string
+	seen := make(map[string]bool)
+	for _, message := range status.Output.Messages {
+		formats = append(formats, fmt.Sprintf("%d(%d)", message.Type, int(math.Max(1, math.Round(1/math.Max(message.Rate, 1e-3))))))
+		if system := rtcmNavSystem(message.Type); system != "" && !seen[system] {
+			seen[system] = true
+			systems = append(systems, system)
+		}
+	}
+	var lat, lon float64
+	if status.Position != nil {
+		lat, lon = status.Position.Latitude, status.Position.Longitude
+	}
+	auth := "N"
+	if username, _, err := c.credentials(); err != nil || username != "" {
+		auth = "B"
+	}
+	table := fmt.Sprintf("STR;%s;%s;RTCM 3.3;%s;2;%s;EdgeX;;%.2f;%.2f;0;0;device-gps;none;%s;N;0;\r\nENDSOURCETABLE\r\n",
+		c.cfg.Mountpoint, c.cfg.Mountpoint, strings.Join(formats, ","), strings.Join(systems, "+"), lat, lon, auth)
+
+	if v2 {
+		fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nNtrip-Version: Ntrip/2.0\r\nContent-Type: gnss/sourcetable\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s", len(table), table)
+	} else {
+		fmt.Fprintf(conn, "SOURCETABLE 200 OK\r\nContent-Type: text/plain\r\nContent-Length: %d\r\n\r\n%s", len(table), table)
+	}
+}
+
+// rtcmNavSystem MSM电文对应的卫星系统，其他电文返回空字符串
+func rtcmNavSystem(msgType int) string {
+	switch msgType / 10 {
+	case 107:
+		return "GPS"
+	case 108:
+		return "GLO"
+	case 109:
+		return "GAL"
+	case 111:
+		return "QZS"
+	case 112:
+		return "BDS"
+	}
+	return ""
+}
+
+// Status 返回now时刻的基准站状态，survey为接收机最近的勘测状态
+func (c *BaseCaster) Status(now time.Time, survey *SurveyInStatus) BaseStatus {
+	c.mutex.Lock()
+	status := BaseStatus{
+		SurveyIn:      survey,
+		Address:       c.listener.Addr().String(),
+		Mountpoint:    c.cfg.Mountpoint,
+		Uptime:        now.Sub(c.started).Seconds(),
+		Clients:       make([]BaseClientStatus, 0, len(c.clients)),
+		ClientsServed: c.served,
+	}
+	if c.mode != nil {
+		mode := *c.mode
+		status.Mode = &mode
+	}
+	for client := range c.clients {
+		status.Clients = append(status.Clients, BaseClientStatus{
+			Address:   client.conn.RemoteAddr().String(),
+			Agent:     client.agent,
+			Connected: now.Sub(client.since).Seconds(),
+			Bytes:     client.bytes,
+		})
+	}
+	c.mutex.Unlock()
+
+	sort.Slice(status.Clients, func(i, j int) bool { return status.Clients[i].Address < status.Clients[j].Address })
+	status.Output = c.monitor.Status(now)
+	status.Position = basePosition(status.Output.Base, survey, status.Mode)
+	return status
+}
+
+// basePosition 按RTCM电文、勘测结果、设置的固定坐标的顺序确定基准站坐标，都没有时返回nil
+func basePosition(arp *BaseStation, survey *SurveyInStatus, mode *BaseModeConfig) *BasePosition {
+	var pos BasePosition
+	switch {
+	case arp != nil:
+		pos = BasePosition{Source: BasePositionRTCM, StationID: arp.StationID, X: arp.X, Y: arp.Y, Z: arp.Z}
+	case survey != nil && survey.State == SurveyInValid:
+		pos = BasePosition{Source: BasePositionSurveyIn, X: survey.X, Y: survey.Y, Z: survey.Z}
+	case mode != nil && mode.Mode == BaseModeFixed:
+		pos = BasePosition{Source: BasePositionConfigured, X: mode.X, Y: mode.Y, Z: mode.Z}
+	default:
+		return nil
+	}
+	latLon, height := ecefToGeodetic(pos.X, pos.Y, pos.Z)
+	pos.Latitude, pos.Longitude = math.Round(latLon.Lat*1e9)/1e9, math.Round(latLon.Lon*1e9)/1e9
+	pos.Height = math.Round(height*1e4) / 1e4
+	return &pos
+}
+
+// newBaseCaster 按驱动配置和设备协议属性创建基准站播发服务，未开启时返回nil。
+// 配置不变时沿用old，使重新打开接收机时客户端保持连接，否则先关闭old。
+func (s *Driver) newBaseCaster(deviceName string, gps *LCX6XZ, old *BaseCaster, protocols map[string]models.ProtocolProperties) *BaseCaster {
+	cfg := s.config.Base
+	for _, protocol := range protocols {
+		if value, ok := protocol["base"]; ok && value != "" {
+			enabled, err := cast.ToBoolE(value)
+			if err != nil {
+				s.lc.Errorf("设备%s的base属性无效: %v，使用默认设置", deviceName, value)
+			} else {
+				cfg.Enabled = enabled
+			}
+		}
+		if listen := cast.ToString(protocol["baseListen"]); listen != "" {
+			cfg.Listen = listen
+		}
+		if mountpoint := cast.ToString(protocol["baseMountpoint"]); mountpoint != "" {
+			cfg.Mountpoint = mountpoint
+		}
+	}
+
+	if old != nil {
+		if cfg.Enabled && cfg == old.cfg {
+			gps.SetRTCMOutput(old)
+			return old
+		}
+		s.closeBaseCaster(deviceName, old)
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+
+	caster, err := NewBaseCaster(cfg, s.secretCredentials(cfg.SecretName))
+	if err != nil {
+		s.lc.Errorf("设备%s开启基准站模式失败: %v", deviceName, err)
+		return nil
+	}
+	gps.SetRTCMOutput(caster)
+	s.lc.Infof("设备%s作为基准站在%s/%s播发差分数据", deviceName, caster.Addr(), cfg.Mountpoint)
+	return caster
+}
+
+// closeBaseCaster 关闭基准站播发服务
+func (s *Driver) closeBaseCaster(deviceName string, caster *BaseCaster) {
+	if caster == nil {
+		return
+	}
+	if err := caster.Close(); err != nil {
+		s.lc.Errorf("关闭设备%s的基准站播发服务失败: %v", deviceName, err)
+	}
+}
+
+// setupBase 查询接收机的基准站配置，回放设备不支持命令，跳过
+func (s *Driver) setupBase(deviceName string, rcv *receiver) {
+	if rcv.base == nil || rcv.replay != nil {
+		return
+	}
+	mode, err := GetBaseMode(rcv.gps)
+	if err != nil {
+		s.lc.Warnf("设备%s查询基准站模式失败: %v", deviceName, err)
+		return
+	}
+	rcv.base.SetMode(mode)
+	s.lc.Infof("设备%s当前基准站模式为%s", deviceName, mode.Mode)
+}
+
+// setBaseMode 处理base_survey_in、base_fixed和base_disable写入命令
+func (s *Driver) setBaseMode(deviceName string, rcv *receiver, req dsModels.CommandRequest, param *dsModels.CommandValue) error {
+	if param == nil {
+		return fmt.Errorf("参数值为空")
+	}
+	if rcv.replay != nil {
+		return errors.New("回放设备不支持基准站命令")
+	}
+
+	var mode BaseModeConfig
+	switch req.DeviceResourceName {
+	case "base_survey_in":
+		// 参数格式: "最短时长秒数[,三维精度门限米]"
+		value, ok := param.Value.(string)
+		if !ok {
+			return fmt.Errorf("参数值必须是字符串格式")
+		}
+		durationStr, accuracyStr, hasAccuracy := strings.Cut(value, ",")
+		duration, err := strconv.Atoi(strings.TrimSpace(durationStr))
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("无效的勘测时长: %s", durationStr)
+		}
+		mode = BaseModeConfig{Mode: BaseModeSurveyIn, MinDuration: duration}
+		if hasAccuracy {
+			accuracy, err := strconv.ParseFloat(strings.TrimSpace(accuracyStr), 64)
+			if err != nil || accuracy < 0 {
+				return fmt.Errorf("无效的勘测精度门限: %s", accuracyStr)
+			}
+			mode.AccuracyLimit = accuracy
+		}
+	case "base_fixed":
+		// 参数格式: "纬度,经度,椭球高"
+		value, ok := param.Value.(string)
+		if !ok {
+			return fmt.Errorf("参数值必须是字符串格式")
+		}
+		parts := strings.Split(value, ",")
+		if len(parts) != 3 {
+			return fmt.Errorf("参数格式应为\"纬度,经度,椭球高\": %s", value)
+		}
+		var coords [3]float64
+		for i, part := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return fmt.Errorf("无效的坐标: %s", part)
+			}
+			coords[i] = v
+		}
+		if math.Abs(coords[0]) > 90 || math.Abs(coords[1]) > 180 {
+			return fmt.Errorf("坐标超出范围: %s", value)
+		}
+		mode = BaseModeConfig{Mode: BaseModeFixed}
+		mode.X, mode.Y, mode.Z = geodeticToECEF(LatLon{Lat: coords[0], Lon: coords[1]}, coords[2])
+	case "base_disable":
+		disable, err := param.BoolValue()
+		if err != nil {
+			return fmt.Errorf("参数值必须是Bool: %v", err)
+		}
+		if !disable {
+			return nil
+		}
+		mode = BaseModeConfig{Mode: BaseModeDisabled}
+	}
+
+	if err := SetBaseMode(rcv.gps, mode); err != nil {
+		return err
+	}
+	if rcv.base != nil {
+		rcv.base.SetMode(mode)
+	}
+	s.lc.Infof("设备%s基准站模式已设置为%s", deviceName, mode.Mode)
+	return nil
+}
+
+// getBaseValue 获取base_status、base_position、base_uptime_s或base_clients资源，设备未开启基准站模式时返回nil
+func (s *Driver) getBaseValue(rcv *receiver, req dsModels.CommandRequest) *dsModels.CommandValue {
+	if rcv.base == nil {
+		s.lc.Warnf("设备未开启基准站模式，无法读取%s", req.DeviceResourceName)
+		return nil
+	}
+	status := rcv.base.Status(time.Now(), rcv.gps.SurveyIn())
+
+	var cv *dsModels.CommandValue
+	switch req.DeviceResourceName {
+	case "base_status":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeObject, status)
+	case "base_position":
+		if status.Position == nil {
+			return nil
+		}
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeObject, *status.Position)
+	case "base_uptime_s":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, status.Uptime)
+	case "base_clients":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt32, int32(len(status.Clients)))
+	}
+	return cv
+}
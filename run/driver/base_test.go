@@ -0,0 +1,412 @@
+package driver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+// frameRecorder 按写入次数记录接收机输出的RTCM3帧
+type frameRecorder struct {
+	mutex  sync.Mutex
+	frames [][]byte
+}
+
+func (r *frameRecorder) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.frames = append(r.frames, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestParsRTCM(t *testing.T) {
+	gps := &LCX6XZ{}
+	recorder := &frameRecorder{}
+	gps.SetRTCMOutput(recorder)
+
+	sample, _ := hex.DecodeString(rtcm1005Sample)
+	corrupt := rtcmTestFrame(1077, 40)
+	corrupt[20] ^= 0xFF
+	survey := BuildNMEASentence("PQTMSVINSTATUS,100000,1,,,12,60,-1334711.0000,5326982.0000,3169016.0000,2.5000")
+
+	var stream []byte
+	stream = append(stream, sample...)
+	stream = append(stream, survey...)
+	stream = append(stream, corrupt...)
+	stream = append(stream, rtcmTestFrame(1127, 300)...)
+
+	// 分两次到达，第二帧跨越两次读取
+	split := len(stream) - 100
+	rest := processNMEAData(append([]byte(nil), stream[:split]...), gps)
+	rest = processNMEAData(append(rest, stream[split:]...), gps)
+	if len(rest) != 0 {
+		t.Errorf("unprocessed = %d bytes", len(rest))
+	}
+
+	if len(recorder.frames) != 2 || !bytes.Equal(recorder.frames[0], sample) || len(recorder.frames[1]) != 306 {
+		t.Errorf("frames = %d", len(recorder.frames))
+	}
+	status := gps.SurveyIn()
+	if status == nil || status.State != SurveyInInProgress || status.Observations != 12 || status.MinDuration != 60 ||
+		status.X != -1334711 || status.Accuracy != 2.5 {
+		t.Errorf("SurveyIn() = %+v", status)
+	}
+
+	// 未设置输出时0xD3不按RTCM3帧解析，帧数据当作噪声跳过
+	gps.SetRTCMOutput(nil)
+	stream = append(append([]byte(nil), sample...), survey...)
+	if rest = processNMEAData(stream, gps); len(rest) != 0 || len(recorder.frames) != 2 {
+		t.Errorf("after SetRTCMOutput(nil): rest = %d, frames = %d", len(rest), len(recorder.frames))
+	}
+}
+
+func TestStrayBinaryHeader(t *testing.T) {
+	rmc := []byte(BuildNMEASentence("GPRMC,055525.000,A,3044.368753,N,10357.548051,E,0.00,000.00,100625,,,A"))
+	tests := []struct {
+		name   string
+		header []byte
+		rtcm   bool
+	}{
+		{"RTCM3 without output", []byte{0xD3, 0x03, 0xFF}, false},
+		{"RTCM3 with output", []byte{0xD3, 0x03, 0xFF}, true},
+		{"binary message", []byte{0xF1, 0xD9, 0x06}, false},
+	}
+	for _, test := range tests {
+		gps := &LCX6XZ{}
+		if test.rtcm {
+			gps.SetRTCMOutput(&frameRecorder{})
+		}
+		// 误判的帧头声明的长度远超后续数据，随后的完整语句不被扣留
+		stream := append(append([]byte(nil), test.header...), rmc...)
+		if rest := processNMEAData(stream, gps); len(rest) != 0 || gps.NMEA_RMC == nil {
+			t.Errorf("%s: rest = %d bytes, RMC parsed = %v", test.name, len(rest), gps.NMEA_RMC != nil)
+		}
+		// 只有不完整的语句时继续等待
+		if rest := processNMEAData(stream[:len(stream)-3], gps); len(rest) != len(stream)-3 {
+			t.Errorf("%s: partial sentence rest = %d bytes", test.name, len(rest))
+		}
+	}
+}
+
+func TestParsPQTMSVINSTATUS(t *testing.T) {
+	tests := []struct {
+		sentence string
+		state    string
+	}{
+		{"$PQTMSVINSTATUS,100000,0,,,0,60,0,0,0,0", SurveyInInvalid},
+		{"$PQTMSVINSTATUS,100000,2,,,60,60,1,2,3,1.2", SurveyInValid},
+		{"$PQTMSVINSTATUS,100000,3,,,60,60,1,2,3,1.2", ""},
+		{"$PQTMSVINSTATUS,100000,2,,,60", ""},
+	}
+	for _, tt := range tests {
+		status := ParsPQTMSVINSTATUS(tt.sentence)
+		if (status == nil) != (tt.state == "") || (status != nil && status.State != tt.state) {
+			t.Errorf("ParsPQTMSVINSTATUS(%s) = %+v", tt.sentence, status)
+		}
+	}
+}
+
+func TestGeodeticToECEF(t *testing.T) {
+	for _, p := range []struct {
+		pos    LatLon
+		height float64
+	}{
+		{LatLon{38.80475943, -77.06477360}, 114.5611},
+		{LatLon{30.739479, 103.959134}, 500},
+		{LatLon{-89.9, 10}, -20},
+	} {
+		x, y, z := geodeticToECEF(p.pos, p.height)
+		pos, height := ecefToGeodetic(x, y, z)
+		if math.Abs(pos.Lat-p.pos.Lat) > 1e-9 || math.Abs(pos.Lon-p.pos.Lon) > 1e-9 || math.Abs(height-p.height) > 1e-4 {
+			t.Errorf("round trip of %v, %v = %v, %v", p.pos, p.height, pos, height)
+		}
+	}
+
+	// 与RTCM标准1005示例中基准站2003的坐标一致
+	x, y, z := geodeticToECEF(LatLon{38.80475943, -77.06477360}, 114.5611)
+	if math.Abs(x-1114104.5999) > 0.01 || math.Abs(y+4850729.7108) > 0.01 || math.Abs(z-3975521.4643) > 0.01 {
+		t.Errorf("geodeticToECEF = %v, %v, %v", x, y, z)
+	}
+	frame, _ := hex.DecodeString(rtcm1005Sample)
+	if payload := encodeStationARP(2003, 1114104.5999, -4850729.7108, 3975521.4643); !bytes.Equal(payload, frame[3:len(frame)-3]) {
+		t.Errorf("encodeStationARP = % X", payload)
+	}
+}
+
+// casterRequest 向播发服务发送一个请求并返回应答的第一行
+func casterRequest(t *testing.T, addr, request string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, request)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+func TestBaseCaster(t *testing.T) {
+	cfg := BaseConfig{Enabled: true, Listen: "127.0.0.1:0", Mountpoint: "BASE", MaxClients: 2}
+	credentials := func() (string, string, error) { return "gps", "secret", nil }
+	caster, err := NewBaseCaster(cfg, credentials)
+	if err != nil {
+		t.Fatalf("NewBaseCaster failed: %v", err)
+	}
+	defer caster.Close()
+	addr := caster.Addr().String()
+
+	// v1和v2客户端使用本仓库的NTRIP客户端连接
+	sinks := make([]*bufferPort, 2)
+	done := make(chan struct{})
+	defer close(done)
+	for i, version := range []int{1, 2} {
+		sinks[i] = &bufferPort{}
+		client := NewNTRIPClient(NTRIPConfig{Caster: addr, Mountpoint: "BASE", Version: version, ReconnectDelay: 50 * time.Millisecond, Timeout: 5 * time.Second},
+			sinks[i], credentials, func() (Fix, bool) { return Fix{}, false })
+		go client.Run(done, nil)
+	}
+	waitFor(t, 2*time.Second, func() bool { return len(caster.Status(time.Now(), nil).Clients) == 2 }, "clients")
+
+	sample, _ := hex.DecodeString(rtcm1005Sample)
+	msm := rtcmTestFrame(1077, 60)
+	for i := 0; i < 3; i++ {
+		caster.Write(sample)
+		caster.Write(msm)
+	}
+	expected := bytes.Repeat(append(append([]byte(nil), sample...), msm...), 3)
+	for i, sink := range sinks {
+		waitFor(t, 2*time.Second, func() bool {
+			sink.mutex.Lock()
+			defer sink.mutex.Unlock()
+			return sink.tx.Len() >= len(expected)
+		}, fmt.Sprintf("client %d data", i))
+		sink.mutex.Lock()
+		if !bytes.Equal(sink.tx.Bytes(), expected) {
+			t.Errorf("client %d received % X", i, sink.tx.Bytes())
+		}
+		sink.mutex.Unlock()
+	}
+
+	status := caster.Status(time.Now(), nil)
+	if status.ClientsServed != 2 || status.Mountpoint != "BASE" || status.Output.Frames != 6 || status.Uptime <= 0 {
+		t.Errorf("Status() = %+v", status)
+	}
+	if status.Position == nil || status.Position.Source != BasePositionRTCM || status.Position.StationID != 2003 ||
+		math.Abs(status.Position.Latitude-38.80475943) > 1e-8 {
+		t.Errorf("position = %+v", status.Position)
+	}
+	if status.Clients[0].Bytes != int64(len(expected)) {
+		t.Errorf("client bytes = %d", status.Clients[0].Bytes)
+	}
+
+	// 客户端数已满、账号错误、挂载点错误和源列表
+	if line := casterRequest(t, addr, "GET /BASE HTTP/1.1\r\nNtrip-Version: Ntrip/2.0\r\nAuthorization: Basic Z3BzOnNlY3JldA==\r\n\r\n"); !strings.Contains(line, "503") {
+		t.Errorf("third client: %s", line)
+	}
+	if line := casterRequest(t, addr, "GET /BASE HTTP/1.0\r\nAuthorization: Basic Z3BzOndyb25n\r\n\r\n"); !strings.Contains(line, "401") {
+		t.Errorf("wrong password: %s", line)
+	}
+	if line := casterRequest(t, addr, "GET /OTHER HTTP/1.1\r\nNtrip-Version: Ntrip/2.0\r\n\r\n"); !strings.Contains(line, "404") {
+		t.Errorf("wrong mountpoint: %s", line)
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	fmt.Fprint(conn, "GET / HTTP/1.0\r\n\r\n")
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var table bytes.Buffer
+	_, _ = table.ReadFrom(conn)
+	conn.Close()
+	if !strings.HasPrefix(table.String(), "SOURCETABLE 200 OK") ||
+		!strings.Contains(table.String(), "STR;BASE;BASE;RTCM 3.3;1005(1),1077(1);2;GPS;") || !strings.Contains(table.String(), ";B;N;") {
+		t.Errorf("source table = %q", table.String())
+	}
+
+	// 关闭后断开所有客户端
+	caster.Close()
+	if clients := caster.Status(time.Now(), nil).Clients; len(clients) != 0 {
+		t.Errorf("clients after Close = %+v", clients)
+	}
+}
+
+func TestBasePosition(t *testing.T) {
+	x, y, z := geodeticToECEF(LatLon{30, 104}, 500)
+	survey := &SurveyInStatus{State: SurveyInInProgress, X: x, Y: y, Z: z}
+	fixed := &BaseModeConfig{Mode: BaseModeFixed, X: x, Y: y, Z: z}
+
+	if pos := basePosition(nil, survey, nil); pos != nil {
+		t.Errorf("survey in progress = %+v", pos)
+	}
+	survey.State = SurveyInValid
+	if pos := basePosition(nil, survey, fixed); pos == nil || pos.Source != BasePositionSurveyIn || pos.Latitude != 30 || pos.Longitude != 104 || pos.Height != 500 {
+		t.Errorf("valid survey = %+v", pos)
+	}
+	if pos := basePosition(nil, nil, fixed); pos == nil || pos.Source != BasePositionConfigured {
+		t.Errorf("fixed = %+v", pos)
+	}
+	if pos := basePosition(&BaseStation{StationID: 7, X: x, Y: y, Z: z}, survey, fixed); pos == nil || pos.Source != BasePositionRTCM || pos.StationID != 7 {
+		t.Errorf("rtcm = %+v", pos)
+	}
+}
+
+// writeBase 向设备写入一个基准站命令
+func writeBase(t *testing.T, driver *Driver, resource string, valueType string, value any) error {
+	t.Helper()
+	cv, err := dsModels.NewCommandValue(resource, valueType, value)
+	if err != nil {
+		t.Fatalf("NewCommandValue failed: %v", err)
+	}
+	return driver.HandleWriteCommands("GPS-A", nil, []dsModels.CommandRequest{{DeviceResourceName: resource}}, []*dsModels.CommandValue{cv})
+}
+
+// baseStatus 读取base_status资源
+func baseStatus(t *testing.T, driver *Driver) BaseStatus {
+	t.Helper()
+	status, ok := readResource(t, driver, "GPS-A", "base_status").Value.(BaseStatus)
+	if !ok {
+		t.Fatal("base_status is not a BaseStatus")
+	}
+	return status
+}
+
+func TestDriverBase(t *testing.T) {
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["base"] = "true"
+	protocols[ProtocolSIM]["baseListen"] = "127.0.0.1:0"
+	driver, _ := newTestDriverWithConfig(t, map[string]string{configBaseMountpoint: "SITE"}, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// 启动时查询到接收机未开启基准站模式，没有坐标和电文
+	waitFor(t, 2*time.Second, func() bool { return baseStatus(t, driver).Mode != nil }, "base mode query")
+	status := baseStatus(t, driver)
+	if status.Mode.Mode != BaseModeDisabled || status.Mountpoint != "SITE" || status.Position != nil || status.Output.Frames != 0 {
+		t.Errorf("initial status = %+v", status)
+	}
+
+	if err := writeBase(t, driver, "base_survey_in", common.ValueTypeString, "0"); err == nil {
+		t.Error("base_survey_in accepted a zero duration")
+	}
+	if err := writeBase(t, driver, "base_survey_in", common.ValueTypeString, "1,5"); err != nil {
+		t.Fatalf("base_survey_in failed: %v", err)
+	}
+
+	// 勘测完成后接收机输出1005和MSM电文，客户端通过本地播发服务收到；模拟器勘测的是路线起点
+	sink := &bufferPort{}
+	done := make(chan struct{})
+	defer close(done)
+	client := NewNTRIPClient(NTRIPConfig{Caster: status.Address, Mountpoint: "SITE", Version: 2, ReconnectDelay: 50 * time.Millisecond, Timeout: 5 * time.Second},
+		sink, func() (string, string, error) { return "", "", nil }, func() (Fix, bool) { return Fix{}, false })
+	go client.Run(done, nil)
+
+	waitFor(t, 5*time.Second, func() bool {
+		status := baseStatus(t, driver)
+		return status.Position != nil && status.Position.Source == BasePositionRTCM && len(status.Clients) == 1 && status.Output.Frames > 3
+	}, "survey-in")
+	status = baseStatus(t, driver)
+	if status.Mode.Mode != BaseModeSurveyIn || status.SurveyIn == nil || status.SurveyIn.State != SurveyInValid || status.SurveyIn.Observations < 1 {
+		t.Errorf("survey status = %+v, %+v", status.Mode, status.SurveyIn)
+	}
+	if math.Abs(status.Position.Latitude-30) > 1e-3 || status.Position.StationID != simStationID {
+		t.Errorf("position = %+v", status.Position)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		sink.mutex.Lock()
+		defer sink.mutex.Unlock()
+		var decoder RTCMDecoder
+		frames, _ := decoder.Feed(sink.tx.Bytes())
+		return len(frames) >= 3
+	}, "client data")
+
+	if clients := readResource(t, driver, "GPS-A", "base_clients").Value; clients != int32(1) {
+		t.Errorf("base_clients = %v", clients)
+	}
+	if uptime, ok := readResource(t, driver, "GPS-A", "base_uptime_s").Value.(float64); !ok || uptime <= 0 {
+		t.Errorf("base_uptime_s = %v", uptime)
+	}
+
+	// 固定坐标立即生效
+	if err := writeBase(t, driver, "base_fixed", common.ValueTypeString, "30.5,104.5,600"); err != nil {
+		t.Fatalf("base_fixed failed: %v", err)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		pos, ok := readResource(t, driver, "GPS-A", "base_position").Value.(BasePosition)
+		return ok && math.Abs(pos.Latitude-30.5) < 1e-6 && math.Abs(pos.Height-600) < 1e-3
+	}, "fixed position")
+
+	if err := writeBase(t, driver, "base_disable", common.ValueTypeBool, true); err != nil {
+		t.Fatalf("base_disable failed: %v", err)
+	}
+	if mode := baseStatus(t, driver).Mode; mode == nil || mode.Mode != BaseModeDisabled {
+		t.Errorf("mode after base_disable = %+v", mode)
+	}
+}
+
+func TestDriverBaseReopen(t *testing.T) {
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["base"] = "true"
+	protocols[ProtocolSIM]["baseListen"] = "127.0.0.1:0"
+	driver, _ := newTestDriverWithConfig(t, map[string]string{configBaseMountpoint: "SITE"}, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	addr := baseStatus(t, driver).Address
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /SITE HTTP/1.0\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if line, _ := reader.ReadString('\n'); strings.TrimSpace(line) != "ICY 200 OK" {
+		t.Fatalf("status line = %q", line)
+	}
+	waitFor(t, 2*time.Second, func() bool { return len(baseStatus(t, driver).Clients) == 1 }, "caster client")
+
+	// 只改变与基准站无关的属性时播发服务沿用，客户端不断开
+	updated := simProtocols("30.0")
+	for key, value := range protocols[ProtocolSIM] {
+		updated[ProtocolSIM][key] = value
+	}
+	updated[ProtocolSIM]["traceSize"] = "16"
+	if err := driver.UpdateDevice("GPS-A", updated, models.Unlocked); err != nil {
+		t.Fatalf("UpdateDevice failed: %v", err)
+	}
+	status := baseStatus(t, driver)
+	if status.Address != addr || len(status.Clients) != 1 {
+		t.Fatalf("status after UpdateDevice = %+v", status)
+	}
+
+	// 重新打开的接收机输出的电文发给原来的客户端
+	if err := writeBase(t, driver, "base_survey_in", common.ValueTypeString, "1,5"); err != nil {
+		t.Fatalf("base_survey_in failed: %v", err)
+	}
+	var decoder RTCMDecoder
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for frames := 0; frames < 3; {
+		n, err := reader.Read(buf)
+		if err != nil {
+			t.Fatalf("client read after UpdateDevice failed: %v", err)
+		}
+		decoded, _ := decoder.Feed(buf[:n])
+		frames += len(decoded)
+	}
+}
@@ -99,6 +99,16 @@ func QlCheckQuectel(data []byte) uint16 {
 	return uint16(chk1) | (uint16(chk2) << 8)
 }
 
+// BuildBinaryFrame 按Quectel二进制协议组帧：帧头 + 组ID + 子ID + 长度 + 载荷 + 校验码
+func BuildBinaryFrame(gid GroupID, sid uint8, payload []byte) []byte {
+	frame := make([]byte, 0, 8+len(payload))
+	frame = append(frame, 0xF1, 0xD9, uint8(gid), sid)
+	frame = binary.LittleEndian.AppendUint16(frame, uint16(len(payload)))
+	frame = append(frame, payload...)
+	checksum := QlCheckQuectel(frame)
+	return binary.LittleEndian.AppendUint16(frame, checksum)
+}
+
 // CfgMsgSetOutRate 设置消息输出速率
 func CfgMsgSetOutRate(gid GroupID, sid NMEA_SUB_ID, outRate uint8) *CFG_MSG {
 	msg := &CFG_MSG{
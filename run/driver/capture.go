@@ -0,0 +1,316 @@
+package driver
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 抓包文件格式（文本，每行一条记录）：
+//
+//	# device-gps capture v1
+//	<接收时间，Unix纳秒> <原始字节的十六进制>
+//
+// 以#开头的行和空行会被忽略。记录的是串口收到的原始字节流，
+// NMEA语句和二进制响应帧都按原样保存，回放时交给同一套解析流程。
+const captureHeader = "# device-gps capture v1"
+
+// 回放速度的特殊取值：每条记录需要通过Step显式放行
+const ReplaySpeedStep = "step"
+
+// captureRecord 抓包文件中的一条记录
+type captureRecord struct {
+	Time int64 // Unix纳秒
+	Data []byte
+}
+
+// parseCaptureLine 解析一行抓包记录，注释和空行返回 ok=false
+func parseCaptureLine(line string) (captureRecord, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return captureRecord{}, false, nil
+	}
+
+	timeStr, hexStr, found := strings.Cut(line, " ")
+	if !found {
+		return captureRecord{}, false, fmt.Errorf("无效的抓包记录: %s", line)
+	}
+	ts, err := strconv.ParseInt(timeStr, 10, 64)
+	if err != nil {
+		return captureRecord{}, false, fmt.Errorf("无效的记录时间: %s", timeStr)
+	}
+	data, err := hex.DecodeString(strings.TrimSpace(hexStr))
+	if err != nil {
+		return captureRecord{}, false, fmt.Errorf("无效的记录数据: %v", err)
+	}
+	return captureRecord{Time: ts, Data: data}, true, nil
+}
+
+// Recorder 将接收机的原始字节流连同接收时间写入抓包文件
+type Recorder struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewRecorder 以追加方式打开抓包文件，新文件会写入格式头
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("打开抓包文件失败: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取抓包文件信息失败: %w", err)
+	}
+	if info.Size() == 0 {
+		if _, err := fmt.Fprintln(file, captureHeader); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("写入抓包文件头失败: %w", err)
+		}
+	}
+
+	return &Recorder{file: file}, nil
+}
+
+// Record 写入一条记录
+func (r *Recorder) Record(t time.Time, data []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	_, err := fmt.Fprintf(r.file, "%d %s\n", t.UnixNano(), hex.EncodeToString(data))
+	return err
+}
+
+// Close 关闭抓包文件
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// ReplayConfig 回放设备配置，对应设备的FILE协议属性
+type ReplayConfig struct {
+	Path  string  // 抓包文件路径
+	Speed float64 // 回放倍速，1为实时
+	Step  bool    // 单步模式，忽略Speed
+	Loop  bool    // 到达文件末尾后从头开始
+}
+
+// ParseReplaySpeed 解析速度属性：正数表示倍速，step表示单步
+func ParseReplaySpeed(speed string) (float64, bool, error) {
+	speed = strings.TrimSpace(speed)
+	if speed == "" {
+		return 1, false, nil
+	}
+	if strings.EqualFold(speed, ReplaySpeedStep) {
+		return 0, true, nil
+	}
+	value, err := strconv.ParseFloat(speed, 64)
+	if err != nil || value <= 0 {
+		return 0, false, fmt.Errorf("无效的回放速度: %s", speed)
+	}
+	return value, false, nil
+}
+
+// chunkSource 通过通道向读取方提供数据块，回放和模拟等虚拟接收机共用。
+// Read 只能由一个协程调用（即接收任务）。
+type chunkSource struct {
+	data     chan []byte
+	leftover []byte
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newChunkSource() chunkSource {
+	return chunkSource{
+		data: make(chan []byte),
+		done: make(chan struct{}),
+	}
+}
+
+// Read 读取数据，没有数据时阻塞直到有新数据块或数据源关闭
+func (c *chunkSource) Read(p []byte) (int, error) {
+	if len(c.leftover) == 0 {
+		select {
+		case chunk := <-c.data:
+			c.leftover = chunk
+		case <-c.done:
+			return 0, os.ErrClosed
+		}
+	}
+	n := copy(p, c.leftover)
+	c.leftover = c.leftover[n:]
+	return n, nil
+}
+
+// Close 关闭数据源
+func (c *chunkSource) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
+
+// isClosed 判断数据源是否已关闭
+func (c *chunkSource) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// push 发送一个数据块，数据源关闭时返回os.ErrClosed
+func (c *chunkSource) push(chunk []byte) error {
+	select {
+	case c.data <- chunk:
+		return nil
+	case <-c.done:
+		return os.ErrClosed
+	}
+}
+
+// sleep 等待一段时间，数据源关闭时提前返回os.ErrClosed
+func (c *chunkSource) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.done:
+		return os.ErrClosed
+	}
+}
+
+// ReplayPort 把抓包文件当作接收机串口回放，供SerialPort包装后交给LCX6XZ使用
+type ReplayPort struct {
+	chunkSource
+	cfg   ReplayConfig
+	file  *os.File
+	steps chan struct{}
+}
+
+// NewReplayPort 打开抓包文件并开始回放
+func NewReplayPort(cfg ReplayConfig) (*ReplayPort, error) {
+	if !cfg.Step && cfg.Speed <= 0 {
+		return nil, fmt.Errorf("无效的回放速度: %v", cfg.Speed)
+	}
+	file, err := os.Open(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("打开回放文件失败: %w", err)
+	}
+
+	r := &ReplayPort{
+		chunkSource: newChunkSource(),
+		cfg:         cfg,
+		file:        file,
+		steps:       make(chan struct{}, 1024),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Step 单步模式下放行n条记录
+func (r *ReplayPort) Step(n int) error {
+	if !r.cfg.Step {
+		return errors.New("回放设备不是单步模式")
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case r.steps <- struct{}{}:
+		default:
+			return fmt.Errorf("待放行的记录过多，已放行%d条", i)
+		}
+	}
+	return nil
+}
+
+// Write 回放设备不能接收配置命令
+func (r *ReplayPort) Write(p []byte) (int, error) {
+	return 0, errors.New("回放设备不支持写入")
+}
+
+func (r *ReplayPort) run() {
+	defer r.file.Close()
+
+	for {
+		err := r.playOnce()
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				fmt.Printf("回放文件%s出错: %v\n", r.cfg.Path, err)
+			}
+			return
+		}
+		if !r.cfg.Loop {
+			fmt.Printf("回放文件%s已结束\n", r.cfg.Path)
+			return
+		}
+		if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+			fmt.Printf("回放文件%s重新开始失败: %v\n", r.cfg.Path, err)
+			return
+		}
+	}
+}
+
+// playOnce 从头到尾回放一遍文件，回放设备关闭时返回os.ErrClosed
+func (r *ReplayPort) playOnce() error {
+	scanner := bufio.NewScanner(r.file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var prev int64
+	for scanner.Scan() {
+		record, ok, err := parseCaptureLine(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := r.wait(prev, record.Time); err != nil {
+			return err
+		}
+		prev = record.Time
+
+		if err := r.push(record.Data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// wait 按记录间隔和倍速等待，单步模式下等待放行
+func (r *ReplayPort) wait(prev, next int64) error {
+	if r.cfg.Step {
+		select {
+		case <-r.steps:
+			return nil
+		case <-r.done:
+			return os.ErrClosed
+		}
+	}
+
+	if prev == 0 || next <= prev {
+		return nil
+	}
+	return r.sleep(time.Duration(float64(next-prev) / r.cfg.Speed))
+}
@@ -0,0 +1,181 @@
+package driver
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCapture 生成一个抓包文件，相邻记录间隔interval
+func writeCapture(t *testing.T, chunks []string, interval time.Duration) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.cap")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	start := time.Unix(1720590925, 0)
+	for i, chunk := range chunks {
+		if err := recorder.Record(start.Add(time.Duration(i)*interval), []byte(chunk)); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return path
+}
+
+// startReader 在单独的协程中持续读取，读到的数据块发送到返回的通道
+func startReader(r io.Reader) <-chan []byte {
+	chunks := make(chan []byte, 16)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, 64)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				return
+			}
+			chunks <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+	return chunks
+}
+
+// readWithin 在超时时间内从通道凑齐n个字节
+func readWithin(chunks <-chan []byte, n int, timeout time.Duration) (string, bool) {
+	var data []byte
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for len(data) < n {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				return string(data), false
+			}
+			data = append(data, chunk...)
+		case <-timer.C:
+			return string(data), false
+		}
+	}
+	return string(data), true
+}
+
+func TestParseReplaySpeed(t *testing.T) {
+	tests := []struct {
+		speed     string
+		expected  float64
+		step      bool
+		expectErr bool
+	}{
+		{"", 1, false, false},
+		{"1", 1, false, false},
+		{"10", 10, false, false},
+		{"0.5", 0.5, false, false},
+		{"step", 0, true, false},
+		{"STEP", 0, true, false},
+		{"0", 0, false, true},
+		{"-2", 0, false, true},
+		{"fast", 0, false, true},
+	}
+
+	for _, test := range tests {
+		speed, step, err := ParseReplaySpeed(test.speed)
+		if (err != nil) != test.expectErr {
+			t.Errorf("ParseReplaySpeed(%s) error = %v, expectErr %v", test.speed, err, test.expectErr)
+			continue
+		}
+		if speed != test.expected || step != test.step {
+			t.Errorf("ParseReplaySpeed(%s) = %v, %v, expected %v, %v", test.speed, speed, step, test.expected, test.step)
+		}
+	}
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	path := writeCapture(t, []string{"abc", "def", "ghi"}, time.Second)
+
+	// 100倍速下两条记录间隔10ms
+	replay, err := NewReplayPort(ReplayConfig{Path: path, Speed: 100})
+	if err != nil {
+		t.Fatalf("NewReplayPort failed: %v", err)
+	}
+	defer replay.Close()
+
+	start := time.Now()
+	data, ok := readWithin(startReader(replay), 9, time.Second)
+	if !ok {
+		t.Fatal("replay timed out")
+	}
+	if data != "abcdefghi" {
+		t.Errorf("replayed %q, expected %q", data, "abcdefghi")
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("replay took %v, expected at least 20ms", elapsed)
+	}
+}
+
+func TestReplayStep(t *testing.T) {
+	path := writeCapture(t, []string{"one", "two"}, time.Second)
+
+	replay, err := NewReplayPort(ReplayConfig{Path: path, Step: true})
+	if err != nil {
+		t.Fatalf("NewReplayPort failed: %v", err)
+	}
+	defer replay.Close()
+
+	chunks := startReader(replay)
+	if _, ok := readWithin(chunks, 3, 50*time.Millisecond); ok {
+		t.Fatal("record released without Step")
+	}
+	if err := replay.Step(1); err != nil {
+		t.Fatalf("Step failed: %v", err)
+	}
+	if data, ok := readWithin(chunks, 3, time.Second); !ok || data != "one" {
+		t.Errorf("replayed %q, expected %q", data, "one")
+	}
+	if _, ok := readWithin(chunks, 3, 50*time.Millisecond); ok {
+		t.Fatal("second record released without Step")
+	}
+	if err := replay.Step(1); err != nil {
+		t.Fatalf("Step failed: %v", err)
+	}
+	if data, ok := readWithin(chunks, 3, time.Second); !ok || data != "two" {
+		t.Errorf("replayed %q, expected %q", data, "two")
+	}
+}
+
+func TestReplayLoop(t *testing.T) {
+	path := writeCapture(t, []string{"ab"}, time.Second)
+
+	replay, err := NewReplayPort(ReplayConfig{Path: path, Speed: 1, Loop: true})
+	if err != nil {
+		t.Fatalf("NewReplayPort failed: %v", err)
+	}
+	defer replay.Close()
+
+	if data, ok := readWithin(startReader(replay), 6, time.Second); !ok || data != "ababab" {
+		t.Errorf("replayed %q, expected %q", data, "ababab")
+	}
+}
+
+func TestReplayDrivesParser(t *testing.T) {
+	rmc := BuildNMEASentence("GBRMC,055525.000,A,3044.368753,N,10357.548051,E,0.00,000.00,100625,,,A,C")
+	// 语句被拆成两条记录，验证解析器能拼接
+	path := writeCapture(t, []string{rmc[:20], rmc[20:]}, 10*time.Millisecond)
+
+	replay, err := NewReplayPort(ReplayConfig{Path: path, Speed: 10})
+	if err != nil {
+		t.Fatalf("NewReplayPort failed: %v", err)
+	}
+	lcx6xz := NewLCX6XZ(NewSerialPortFrom(replay))
+	defer lcx6xz.Close()
+
+	waitFor(t, time.Second, func() bool { return rmcLat(lcx6xz) == "3044.368753" }, "回放RMC解析")
+
+	if err := SetNMEAOutputRate(lcx6xz, NMEA_GGA_SID, 1); err == nil {
+		t.Error("SetNMEAOutputRate on replay device succeeded, expected error")
+	}
+}
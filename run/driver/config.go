@@ -0,0 +1,333 @@
+package driver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DriverConfigs()中的驱动配置项，格式相关的配置项见formatter.go
+const (
+	configTrackBufferSize        = "TrackBufferSize"
+	configTrackStoreDir          = "TrackStoreDir"
+	configTrackSegmentSize       = "TrackSegmentSize"
+	configTrackSegmentAge        = "TrackSegmentAge"
+	configTrackRetentionSize     = "TrackRetentionSize"
+	configTrackRetentionAge      = "TrackRetentionAge"
+	configTrackRetryInterval     = "TrackRetryInterval"
+	configTrackMaxRetries        = "TrackMaxRetries"
+	configGeofenceFile           = "GeofenceFile"
+	configGeofenceHysteresis     = "GeofenceHysteresis"
+	configGeofenceMinDwell       = "GeofenceMinDwell"
+	configGeofenceDwellTime      = "GeofenceDwellTime"
+	configOdometerFile           = "OdometerFile"
+	configOdometerMethod         = "OdometerMethod"
+	configOdometerMinSpeed       = "OdometerMinSpeed"
+	configOdometerMaxHDOP        = "OdometerMaxHDOP"
+	configTripStopDelay          = "TripStopDelay"
+	configMotionWindow           = "MotionWindow"
+	configMotionStationarySpeed  = "MotionStationarySpeed"
+	configMotionMovingSpeed      = "MotionMovingSpeed"
+	configMotionStationaryRadius = "MotionStationaryRadius"
+	configMotionCourseJitter     = "MotionCourseJitter"
+	configMotionHold             = "MotionHold"
+	configMotionReceiverHold     = "MotionReceiverHold"
+	configFixMaxAge              = "FixMaxAge"
+	configFixMinType             = "FixMinType"
+	configFixMaxHDOP             = "FixMaxHDOP"
+	configFixMaxPDOP             = "FixMaxPDOP"
+	configFixMinSatellites       = "FixMinSatellites"
+	configFixRequireActive       = "FixRequireActive"
+	configFixOnInvalid           = "FixOnInvalid"
+	configKalmanEnabled          = "KalmanEnabled"
+	configKalmanProcessNoise     = "KalmanProcessNoise"
+	configKalmanUERE             = "KalmanUERE"
+	configKalmanVelocityNoise    = "KalmanVelocityNoise"
+	configKalmanResetGap         = "KalmanResetGap"
+	configLeapSeconds            = "LeapSeconds"
+	configIntegrityCN0Drop       = "IntegrityCN0Drop"
+	configIntegrityCN0MinSpread  = "IntegrityCN0MinSpread"
+	configIntegritySatLoss       = "IntegritySatelliteLoss"
+	configIntegrityJumpDistance  = "IntegrityJumpDistance"
+	configIntegrityClockJump     = "IntegrityClockJump"
+	configIntegrityHold          = "IntegrityAlarmHold"
+	configNtripEnabled           = "NtripEnabled"
+	configNtripCaster            = "NtripCaster"
+	configNtripMountpoint        = "NtripMountpoint"
+	configNtripVersion           = "NtripVersion"
+	configNtripSecretName        = "NtripSecretName"
+	configNtripGGAInterval       = "NtripGGAInterval"
+	configNtripReconnectDelay    = "NtripReconnectDelay"
+	configNtripTimeout           = "NtripTimeout"
+	configBaseEnabled            = "BaseEnabled"
+	configBaseListen             = "BaseListen"
+	configBaseMountpoint         = "BaseMountpoint"
+	configBaseSecretName         = "BaseSecretName"
+	configBaseMaxClients         = "BaseMaxClients"
+	configElevationMask          = "ElevationMask"
+	configCRS                    = "CRS"
+)
+
+// driverConfig 从configuration.yaml的Driver段解析出的驱动配置
+type driverConfig struct {
+	TrackBufferSize    int              // 每台设备在内存中保留的轨迹点数
+	TrackStore         TrackStoreConfig // 磁盘轨迹日志，Dir为空表示关闭
+	TrackRetryInterval time.Duration    // 发布失败后重试的间隔
+	TrackMaxRetries    int              // 读取轨迹分段连续失败的最多次数，超过后跳过该分段
+	GeofenceFile       string           // 围栏GeoJSON文件，通过REST接口修改的围栏也保存到该文件
+	Geofence           geofenceDefaults // 围栏未单独设置时的迟滞距离和停留时间
+	OdometerFile       string           // 各设备总里程的保存文件，为空时重启后从0开始
+	Odometer           OdometerConfig
+	Motion             MotionConfig
+	Validity           ValidityPolicy
+	Kalman             KalmanConfig
+	LeapSeconds        int // 固定的GPS−UTC秒数，0表示按内置闰秒表并跟踪接收机输出的闰秒
+	Integrity          IntegrityConfig
+	NTRIP              NTRIPConfig
+	Base               BaseConfig
+	ElevationMask      float64 // 按星座统计时的仰角掩码（度），应与接收机的设置一致
+	CRS                string  // position_crs默认的坐标参考系，可被设备属性crs或请求参数crs覆盖
+}
+
+// parseDriverConfig 解析驱动配置，未配置的项使用默认值
+func parseDriverConfig(raw map[string]string) (driverConfig, error) {
+	cfg := driverConfig{
+		TrackBufferSize: defaultTrackSize,
+		TrackStore: TrackStoreConfig{
+			Dir:           strings.TrimSpace(raw[configTrackStoreDir]),
+			SegmentSize:   4 << 20,
+			SegmentAge:    time.Hour,
+			RetentionSize: 256 << 20,
+			RetentionAge:  7 * 24 * time.Hour,
+		},
+		TrackRetryInterval: 5 * time.Second,
+		TrackMaxRetries:    720,
+		ElevationMask:      5,
+		CRS:                CRSWGS84,
+		GeofenceFile:       strings.TrimSpace(raw[configGeofenceFile]),
+		Geofence: geofenceDefaults{
+			Hysteresis: 10,
+			MinDwell:   3 * time.Second,
+		},
+		OdometerFile: strings.TrimSpace(raw[configOdometerFile]),
+		Odometer: OdometerConfig{
+			Method:    DistanceHaversine,
+			MinSpeed:  0.5,
+			MaxHDOP:   5,
+			StopDelay: 2 * time.Minute,
+		},
+		Motion: MotionConfig{
+			Window:           5 * time.Second,
+			StationarySpeed:  0.3,
+			MovingSpeed:      1,
+			StationaryRadius: 5,
+			CourseJitter:     60,
+			Hold:             true,
+		},
+		Validity: ValidityPolicy{
+			MaxAge:        5 * time.Second,
+			MinFixType:    "2d",
+			RequireActive: true,
+			OnInvalid:     InvalidFixError,
+		},
+		Kalman: KalmanConfig{
+			ProcessNoise:  1,
+			UERE:          3,
+			VelocityNoise: 0.3,
+			ResetGap:      5 * time.Second,
+		},
+		Integrity: IntegrityConfig{
+			CN0Drop:       8,
+			CN0MinSpread:  1,
+			SatelliteLoss: 6,
+			JumpDistance:  100,
+			ClockJump:     time.Second,
+			Hold:          10 * time.Second,
+		},
+		NTRIP: NTRIPConfig{
+			Caster:         strings.TrimSpace(raw[configNtripCaster]),
+			Mountpoint:     strings.TrimSpace(raw[configNtripMountpoint]),
+			Version:        2,
+			SecretName:     "ntrip",
+			GGAInterval:    10 * time.Second,
+			ReconnectDelay: 5 * time.Second,
+			Timeout:        30 * time.Second,
+		},
+		Base: BaseConfig{
+			Listen:     ":2102",
+			Mountpoint: "BASE",
+			SecretName: strings.TrimSpace(raw[configBaseSecretName]),
+			MaxClients: 16,
+		},
+	}
+
+	var err error
+	positive := func(key string, def int) int {
+		value := strings.TrimSpace(raw[key])
+		if value == "" || err != nil {
+			return def
+		}
+		n, convErr := strconv.Atoi(value)
+		if convErr != nil || n <= 0 {
+			err = fmt.Errorf("无效的%s: %s", key, value)
+		}
+		return n
+	}
+	// allowZero为true时接受0，用于表示关闭的时长
+	duration := func(key string, def time.Duration, allowZero bool) time.Duration {
+		value := strings.TrimSpace(raw[key])
+		if value == "" || err != nil {
+			return def
+		}
+		d, convErr := time.ParseDuration(value)
+		if convErr != nil || d < 0 || (d == 0 && !allowZero) {
+			err = fmt.Errorf("无效的%s: %s", key, value)
+		}
+		return d
+	}
+	// count 解析非负整数
+	count := func(key string, def int) int {
+		value := strings.TrimSpace(raw[key])
+		if value == "" || err != nil {
+			return def
+		}
+		n, convErr := strconv.Atoi(value)
+		if convErr != nil || n < 0 {
+			err = fmt.Errorf("无效的%s: %s", key, value)
+		}
+		return n
+	}
+	// number 解析非负实数
+	number := func(key string, def float64) float64 {
+		value := strings.TrimSpace(raw[key])
+		if value == "" || err != nil {
+			return def
+		}
+		m, convErr := strconv.ParseFloat(value, 64)
+		if convErr != nil || m < 0 {
+			err = fmt.Errorf("无效的%s: %s", key, value)
+		}
+		return m
+	}
+	boolean := func(key string, def bool) bool {
+		value := strings.TrimSpace(raw[key])
+		if value == "" || err != nil {
+			return def
+		}
+		b, convErr := strconv.ParseBool(value)
+		if convErr != nil {
+			err = fmt.Errorf("无效的%s: %s", key, value)
+		}
+		return b
+	}
+
+	cfg.TrackBufferSize = positive(configTrackBufferSize, cfg.TrackBufferSize)
+	// 两个大小配置项的单位为MB
+	cfg.TrackStore.SegmentSize = int64(positive(configTrackSegmentSize, int(cfg.TrackStore.SegmentSize>>20))) << 20
+	cfg.TrackStore.RetentionSize = int64(positive(configTrackRetentionSize, int(cfg.TrackStore.RetentionSize>>20))) << 20
+	cfg.TrackStore.SegmentAge = duration(configTrackSegmentAge, cfg.TrackStore.SegmentAge, false)
+	cfg.TrackStore.RetentionAge = duration(configTrackRetentionAge, cfg.TrackStore.RetentionAge, false)
+	cfg.TrackRetryInterval = duration(configTrackRetryInterval, cfg.TrackRetryInterval, false)
+	cfg.TrackMaxRetries = positive(configTrackMaxRetries, cfg.TrackMaxRetries)
+	cfg.Geofence.Hysteresis = number(configGeofenceHysteresis, cfg.Geofence.Hysteresis)
+	cfg.Geofence.MinDwell = duration(configGeofenceMinDwell, cfg.Geofence.MinDwell, true)
+	cfg.Geofence.DwellTime = duration(configGeofenceDwellTime, cfg.Geofence.DwellTime, true)
+	cfg.Odometer.MinSpeed = number(configOdometerMinSpeed, cfg.Odometer.MinSpeed)
+	cfg.Odometer.MaxHDOP = number(configOdometerMaxHDOP, cfg.Odometer.MaxHDOP)
+	cfg.Odometer.StopDelay = duration(configTripStopDelay, cfg.Odometer.StopDelay, true)
+	cfg.Motion.Window = duration(configMotionWindow, cfg.Motion.Window, false)
+	cfg.Motion.StationarySpeed = number(configMotionStationarySpeed, cfg.Motion.StationarySpeed)
+	cfg.Motion.MovingSpeed = number(configMotionMovingSpeed, cfg.Motion.MovingSpeed)
+	cfg.Motion.StationaryRadius = number(configMotionStationaryRadius, cfg.Motion.StationaryRadius)
+	cfg.Motion.CourseJitter = number(configMotionCourseJitter, cfg.Motion.CourseJitter)
+	cfg.Motion.Hold = boolean(configMotionHold, cfg.Motion.Hold)
+	cfg.Motion.ReceiverHold = number(configMotionReceiverHold, cfg.Motion.ReceiverHold)
+	cfg.Validity.MaxAge = duration(configFixMaxAge, cfg.Validity.MaxAge, true)
+	cfg.Validity.MaxHDOP = number(configFixMaxHDOP, cfg.Validity.MaxHDOP)
+	cfg.Validity.MaxPDOP = number(configFixMaxPDOP, cfg.Validity.MaxPDOP)
+	cfg.Validity.MinSatellites = count(configFixMinSatellites, cfg.Validity.MinSatellites)
+	cfg.Validity.RequireActive = boolean(configFixRequireActive, cfg.Validity.RequireActive)
+	cfg.Kalman.Enabled = boolean(configKalmanEnabled, cfg.Kalman.Enabled)
+	cfg.Kalman.ProcessNoise = number(configKalmanProcessNoise, cfg.Kalman.ProcessNoise)
+	cfg.Kalman.UERE = number(configKalmanUERE, cfg.Kalman.UERE)
+	cfg.Kalman.VelocityNoise = number(configKalmanVelocityNoise, cfg.Kalman.VelocityNoise)
+	cfg.Kalman.ResetGap = duration(configKalmanResetGap, cfg.Kalman.ResetGap, false)
+	cfg.LeapSeconds = count(configLeapSeconds, cfg.LeapSeconds)
+	cfg.Integrity.CN0Drop = number(configIntegrityCN0Drop, cfg.Integrity.CN0Drop)
+	cfg.Integrity.CN0MinSpread = number(configIntegrityCN0MinSpread, cfg.Integrity.CN0MinSpread)
+	cfg.Integrity.SatelliteLoss = count(configIntegritySatLoss, cfg.Integrity.SatelliteLoss)
+	cfg.Integrity.JumpDistance = number(configIntegrityJumpDistance, cfg.Integrity.JumpDistance)
+	cfg.Integrity.ClockJump = duration(configIntegrityClockJump, cfg.Integrity.ClockJump, true)
+	cfg.Integrity.Hold = duration(configIntegrityHold, cfg.Integrity.Hold, true)
+	cfg.NTRIP.Enabled = boolean(configNtripEnabled, cfg.NTRIP.Enabled)
+	cfg.NTRIP.Version = positive(configNtripVersion, cfg.NTRIP.Version)
+	cfg.NTRIP.GGAInterval = duration(configNtripGGAInterval, cfg.NTRIP.GGAInterval, true)
+	cfg.NTRIP.ReconnectDelay = duration(configNtripReconnectDelay, cfg.NTRIP.ReconnectDelay, false)
+	cfg.NTRIP.Timeout = duration(configNtripTimeout, cfg.NTRIP.Timeout, false)
+	if _, ok := raw[configNtripSecretName]; ok {
+		cfg.NTRIP.SecretName = strings.TrimSpace(raw[configNtripSecretName])
+	}
+	cfg.ElevationMask = number(configElevationMask, cfg.ElevationMask)
+	cfg.Base.Enabled = boolean(configBaseEnabled, cfg.Base.Enabled)
+	cfg.Base.MaxClients = positive(configBaseMaxClients, cfg.Base.MaxClients)
+	if value := strings.TrimSpace(raw[configBaseListen]); value != "" {
+		cfg.Base.Listen = value
+	}
+	if value := strings.TrimSpace(raw[configBaseMountpoint]); value != "" {
+		cfg.Base.Mountpoint = value
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	switch method := strings.ToLower(strings.TrimSpace(raw[configOdometerMethod])); method {
+	case "":
+	case DistanceHaversine, DistanceVincenty:
+		cfg.Odometer.Method = method
+	default:
+		return cfg, fmt.Errorf("无效的%s: %s", configOdometerMethod, method)
+	}
+
+	if value := strings.ToLower(strings.TrimSpace(raw[configFixMinType])); value != "" {
+		if _, ok := fixTypeRank[value]; !ok {
+			return cfg, fmt.Errorf("无效的%s: %s", configFixMinType, value)
+		}
+		cfg.Validity.MinFixType = value
+	}
+	switch value := strings.ToLower(strings.TrimSpace(raw[configFixOnInvalid])); value {
+	case "":
+	case InvalidFixError, InvalidFixTag:
+		cfg.Validity.OnInvalid = value
+	default:
+		return cfg, fmt.Errorf("无效的%s: %s", configFixOnInvalid, value)
+	}
+
+	for key, value := range map[string]float64{
+		configKalmanProcessNoise:  cfg.Kalman.ProcessNoise,
+		configKalmanUERE:          cfg.Kalman.UERE,
+		configKalmanVelocityNoise: cfg.Kalman.VelocityNoise,
+	} {
+		if value <= 0 {
+			return cfg, fmt.Errorf("%s必须大于0", key)
+		}
+	}
+	if cfg.NTRIP.Version != 1 && cfg.NTRIP.Version != 2 {
+		return cfg, fmt.Errorf("%s只能为1或2", configNtripVersion)
+	}
+	if cfg.ElevationMask > 90 {
+		return cfg, fmt.Errorf("%s不能大于90", configElevationMask)
+	}
+	if crs, err := parseCRS(raw[configCRS]); err != nil {
+		return cfg, fmt.Errorf("无效的%s: %w", configCRS, err)
+	} else if crs != "" {
+		cfg.CRS = crs
+	}
+	if cfg.Motion.MovingSpeed < cfg.Motion.StationarySpeed {
+		return cfg, fmt.Errorf("%s不能小于%s", configMotionMovingSpeed, configMotionStationarySpeed)
+	}
+	if cfg.TrackStore.RetentionSize < cfg.TrackStore.SegmentSize {
+		return cfg, fmt.Errorf("%s不能小于%s", configTrackRetentionSize, configTrackSegmentSize)
+	}
+	return cfg, nil
+}
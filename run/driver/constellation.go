@@ -0,0 +1,271 @@
+package driver
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+// 卫星系统名称，用于按星座统计的输出
+const (
+	ConstellationGPS     = "GPS"
+	ConstellationGLONASS = "GLONASS"
+	ConstellationGalileo = "Galileo"
+	ConstellationBeiDou  = "BeiDou"
+	ConstellationQZSS    = "QZSS"
+)
+
+// constellationFromSystemID 根据NMEA 4.10的GNSS系统标识符返回卫星系统名称
+func constellationFromSystemID(systemID string) string {
+	switch systemID {
+	case "1":
+		return ConstellationGPS
+	case "2":
+		return ConstellationGLONASS
+	case "3":
+		return ConstellationGalileo
+	case "4":
+		return ConstellationBeiDou
+	case "5":
+		return ConstellationQZSS
+	}
+	return ""
+}
+
+// constellationFromTalker 根据TalkerID返回卫星系统名称，组合星系GN返回空
+func constellationFromTalker(talker string) string {
+	switch talker {
+	case "GP":
+		return ConstellationGPS
+	case "GL":
+		return ConstellationGLONASS
+	case "GA":
+		return ConstellationGalileo
+	case "GB", "BD":
+		return ConstellationBeiDou
+	case "GQ":
+		return ConstellationQZSS
+	}
+	return ""
+}
+
+// constellationFromSatID 旧版组合星系语句没有系统标识符时，按卫星号范围推断卫星系统
+func constellationFromSatID(satID string) string {
+	id, err := strconv.Atoi(satID)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case id >= 1 && id <= 32:
+		return ConstellationGPS
+	case id >= 65 && id <= 96:
+		return ConstellationGLONASS
+	}
+	return ""
+}
+
+// gsaConstellation 返回GSA语句所属的卫星系统，无法确定时返回空
+func gsaConstellation(gsa *NMEA_GSA) string {
+	if name := constellationFromSystemID(trimNullBytes(gsa.SystemID[:])); name != "" {
+		return name
+	}
+	return constellationFromTalker(trimNullBytes(gsa.Nmea.TalkerID[:]))
+}
+
+// constellations 按星座统计输出的卫星系统及其资源名前缀，顺序即输出顺序
+var constellations = []struct {
+	name   string
+	prefix string
+}{
+	{ConstellationGPS, "gps"},
+	{ConstellationGLONASS, "glonass"},
+	{ConstellationGalileo, "galileo"},
+	{ConstellationBeiDou, "beidou"},
+	{ConstellationQZSS, "qzss"},
+}
+
+// signalNames NMEA 4.11各卫星系统的信号标识符
+var signalNames = map[string]map[string]string{
+	ConstellationGPS:     {"1": "L1 C/A", "2": "L1 P(Y)", "3": "L1 M", "4": "L2 P(Y)", "5": "L2C-M", "6": "L2C-L", "7": "L5-I", "8": "L5-Q"},
+	ConstellationGLONASS: {"1": "G1 C/A", "2": "G1 P", "3": "G2 C/A", "4": "G2 P"},
+	ConstellationGalileo: {"1": "E5a", "2": "E5b", "3": "E5 AltBOC", "4": "E6-A", "5": "E6-BC", "6": "E1-A", "7": "E1-BC"},
+	ConstellationBeiDou: {"1": "B1I", "2": "B1Q", "3": "B1C", "4": "B1A", "5": "B2a", "6": "B2b", "7": "B2 a+b",
+		"8": "B3I", "9": "B3Q", "A": "B3A", "B": "B2I", "C": "B2Q"},
+	ConstellationQZSS: {"1": "L1 C/A", "2": "L1C(D)", "3": "L1C(P)", "4": "LIS", "5": "L2C-M", "6": "L2C-L", "7": "L5-I",
+		"8": "L5-Q", "9": "L6D", "A": "L6E"},
+}
+
+// SignalStats 一个卫星系统中一种信号的跟踪统计
+type SignalStats struct {
+	Signal  string  `json:"signal"` // NMEA 4.11信号标识符
+	Name    string  `json:"name,omitempty"`
+	Tracked int     `json:"tracked"`
+	MeanCN0 float64 `json:"meanCn0"` // 跟踪卫星的平均载噪比（dB-Hz）
+	MaxCN0  float64 `json:"maxCn0"`
+}
+
+// ConstellationStats 一个卫星系统的跟踪统计，作为constellation_stats资源的元素
+type ConstellationStats struct {
+	System    string        `json:"system"`
+	InView    int           `json:"inView"`
+	Tracked   int           `json:"tracked"`
+	Used      int           `json:"used"`
+	MeanCN0   float64       `json:"meanCn0"`   // 跟踪卫星的平均载噪比（dB-Hz），取每颗卫星最强的信号
+	MaxCN0    float64       `json:"maxCn0"`    // 跟踪卫星中最高的载噪比（dB-Hz）
+	AboveMask int           `json:"aboveMask"` // 仰角不低于仰角掩码的卫星数
+	Signals   []SignalStats `json:"signals,omitempty"`
+}
+
+// SkyPlot 天空图，作为sky_plot资源的值
+type SkyPlot struct {
+	Time          time.Time       `json:"time"`
+	ElevationMask float64         `json:"elevationMask"`
+	Satellites    []SatelliteInfo `json:"satellites"` // 仰角和方位角有效的卫星
+}
+
+// constellationStats 按卫星系统统计sats，signals为每个信号的状态，可为nil。
+// 固定输出constellations中的全部卫星系统，其他卫星系统不计入。
+func constellationStats(sats, signals []SatelliteInfo, mask float64) []ConstellationStats {
+	stats := make([]ConstellationStats, len(constellations))
+	index := make(map[string]int, len(constellations))
+	for i, c := range constellations {
+		stats[i].System = c.name
+		index[c.name] = i
+	}
+
+	cn0Sums := make([]float64, len(stats))
+	for _, sat := range sats {
+		i, ok := index[sat.System]
+		if !ok {
+			continue
+		}
+		stat := &stats[i]
+		stat.InView++
+		if sat.Used {
+			stat.Used++
+		}
+		if sat.HasAngles && sat.Elevation >= mask {
+			stat.AboveMask++
+		}
+		if sat.Tracked {
+			stat.Tracked++
+			cn0Sums[i] += sat.CN0
+			stat.MaxCN0 = math.Max(stat.MaxCN0, sat.CN0)
+		}
+	}
+	for i := range stats {
+		if stats[i].Tracked > 0 {
+			stats[i].MeanCN0 = math.Round(cn0Sums[i]/float64(stats[i].Tracked)*10) / 10
+		}
+	}
+
+	type signalKey struct{ system, signal string }
+	bySignal := make(map[signalKey]*SignalStats)
+	for _, sat := range signals {
+		if _, ok := index[sat.System]; !ok || !sat.Tracked || sat.Signal == "" {
+			continue
+		}
+		key := signalKey{sat.System, sat.Signal}
+		signal := bySignal[key]
+		if signal == nil {
+			signal = &SignalStats{Signal: sat.Signal, Name: signalNames[sat.System][strings.ToUpper(sat.Signal)]}
+			bySignal[key] = signal
+		}
+		// 先累加载噪比，最后求平均
+		signal.Tracked++
+		signal.MeanCN0 += sat.CN0
+		signal.MaxCN0 = math.Max(signal.MaxCN0, sat.CN0)
+	}
+	for key, signal := range bySignal {
+		signal.MeanCN0 = math.Round(signal.MeanCN0/float64(signal.Tracked)*10) / 10
+		i := index[key.system]
+		stats[i].Signals = append(stats[i].Signals, *signal)
+	}
+	for i := range stats {
+		sort.Slice(stats[i].Signals, func(a, b int) bool { return stats[i].Signals[a].Signal < stats[i].Signals[b].Signal })
+	}
+	return stats
+}
+
+// constellationResource 按星座统计的数值资源
+type constellationResource struct {
+	system string
+	stat   string // tracked、used、cn0_mean、cn0_max或above_mask
+}
+
+// constellationResources 按星座统计的数值资源，名称为卫星系统前缀加统计项，如gps_sats_tracked、beidou_cn0_mean
+var constellationResources = func() map[string]constellationResource {
+	resources := make(map[string]constellationResource)
+	for _, c := range constellations {
+		for suffix, stat := range map[string]string{
+			"_sats_tracked":    "tracked",
+			"_sats_used":       "used",
+			"_cn0_mean":        "cn0_mean",
+			"_cn0_max":         "cn0_max",
+			"_sats_above_mask": "above_mask",
+		} {
+			resources[c.prefix+suffix] = constellationResource{system: c.name, stat: stat}
+		}
+	}
+	return resources
+}()
+
+// getConstellationValue 获取按星座统计的数值资源、constellation_stats或sky_plot。
+// 尚未收到GSV时返回nil；卫星系统没有跟踪卫星时载噪比资源返回nil。
+func (s *Driver) getConstellationValue(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	signals := gps.SatelliteSignals()
+	if len(signals) == 0 {
+		s.lc.Debugf("尚未收到GSV，无法读取%s", req.DeviceResourceName)
+		return nil
+	}
+	sats := gps.Satellites()
+	mask := s.config.ElevationMask
+
+	var cv *dsModels.CommandValue
+	switch req.DeviceResourceName {
+	case "constellation_stats":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeObject, constellationStats(sats, signals, mask))
+	case "sky_plot":
+		plot := SkyPlot{Time: time.Now().UTC(), ElevationMask: mask, Satellites: make([]SatelliteInfo, 0, len(sats))}
+		for _, sat := range sats {
+			if sat.HasAngles {
+				plot.Satellites = append(plot.Satellites, sat)
+			}
+		}
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeObject, plot)
+	default:
+		resource, ok := constellationResources[req.DeviceResourceName]
+		if !ok {
+			return nil
+		}
+		var stat ConstellationStats
+		for _, st := range constellationStats(sats, nil, mask) {
+			if st.System == resource.system {
+				stat = st
+			}
+		}
+		switch resource.stat {
+		case "tracked":
+			cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt32, int32(stat.Tracked))
+		case "used":
+			cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt32, int32(stat.Used))
+		case "above_mask":
+			cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt32, int32(stat.AboveMask))
+		case "cn0_mean", "cn0_max":
+			if stat.Tracked == 0 {
+				return nil
+			}
+			value := stat.MeanCN0
+			if resource.stat == "cn0_max" {
+				value = stat.MaxCN0
+			}
+			cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, value)
+		}
+	}
+	return cv
+}
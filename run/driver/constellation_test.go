@@ -0,0 +1,128 @@
+package driver
+
+import (
+	"testing"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+func TestConstellationStats(t *testing.T) {
+	gps := &LCX6XZ{}
+	now := time.Now()
+	gps.mutex.Lock()
+	for _, body := range []string{
+		"GPGSV,2,1,05,01,40,083,46,02,17,308,41,03,07,344,,04,03,228,30,1",
+		"GPGSV,2,2,05,05,75,050,39,1",
+		"GPGSV,1,1,02,01,40,083,48,02,17,308,35,8",
+		"GBGSV,1,1,02,07,60,120,44,09,20,300,38,1",
+		"GLGSV,1,1,01,70,20,100,,1",
+	} {
+		sentence := BuildNMEASentence(body)
+		gps.storeGSV(ParsNMEAGSV(sentence[:len(sentence)-2], len(sentence)-2), now)
+	}
+	gps.mutex.Unlock()
+
+	signals := gps.SatelliteSignals()
+	if len(signals) != 10 || signals[3].ID != 1 || signals[3].Signal != "1" || signals[4].Signal != "8" {
+		t.Fatalf("SatelliteSignals() = %+v", signals)
+	}
+	sats := gps.Satellites()
+	for i := range sats {
+		sats[i].Used = sats[i].System == ConstellationGPS && sats[i].ID <= 2
+	}
+
+	stats := constellationStats(sats, signals, 5)
+	if len(stats) != 5 || stats[0].System != ConstellationGPS || stats[4].System != ConstellationQZSS {
+		t.Fatalf("constellationStats() = %+v", stats)
+	}
+	// GPS：1号取L5的48，2号取L1的41；3号未跟踪，4号在掩码以下
+	if gpsStats := stats[0]; gpsStats.InView != 5 || gpsStats.Tracked != 4 || gpsStats.Used != 2 || gpsStats.AboveMask != 4 ||
+		gpsStats.MeanCN0 != 39.5 || gpsStats.MaxCN0 != 48 {
+		t.Errorf("GPS = %+v", gpsStats)
+	}
+	if signals := stats[0].Signals; len(signals) != 2 || signals[0].Name != "L1 C/A" || signals[0].Tracked != 4 || signals[0].MeanCN0 != 39 ||
+		signals[1].Name != "L5-Q" || signals[1].MeanCN0 != 41.5 || signals[1].MaxCN0 != 48 {
+		t.Errorf("GPS signals = %+v", signals)
+	}
+	if glonass := stats[1]; glonass.InView != 1 || glonass.Tracked != 0 || glonass.MeanCN0 != 0 || len(glonass.Signals) != 0 {
+		t.Errorf("GLONASS = %+v", glonass)
+	}
+	if beidou := stats[3]; beidou.Tracked != 2 || beidou.MeanCN0 != 41 || beidou.AboveMask != 2 || beidou.Signals[0].Name != "B1I" {
+		t.Errorf("BeiDou = %+v", beidou)
+	}
+	if stats = constellationStats(sats, nil, 30); stats[0].AboveMask != 2 || stats[0].Signals != nil {
+		t.Errorf("mask 30: GPS = %+v", stats[0])
+	}
+}
+
+func TestSatellitesUsedBareID(t *testing.T) {
+	gps := &LCX6XZ{}
+	now := time.Now()
+	parse := func(body string) string { s := BuildNMEASentence(body); return s[:len(s)-2] }
+	gps.mutex.Lock()
+	for _, body := range []string{
+		"GBGSV,1,1,01,40,60,120,44,1",
+		"GAGSV,1,1,01,40,30,200,40,7",
+		"GNGSV,1,1,01,40,20,100,35",
+	} {
+		sentence := parse(body)
+		gps.storeGSV(ParsNMEAGSV(sentence, len(sentence)), now)
+	}
+	// 没有系统标识符的GN语句，40号卫星无法确定卫星系统
+	gsa := parse("GNGSA,A,3,40,,,,,,,,,,,,2.10,1.30,1.60")
+	gps.storeGSA(ParsNMEAGSA(gsa, len(gsa)), now)
+	gps.mutex.Unlock()
+
+	sats := gps.Satellites()
+	if len(sats) != 3 {
+		t.Fatalf("Satellites() = %+v", sats)
+	}
+	for _, sat := range sats {
+		if sat.Used != (sat.System == "") {
+			t.Errorf("satellite %s %d used = %v", sat.System, sat.ID, sat.Used)
+		}
+	}
+}
+
+func TestDriverConstellation(t *testing.T) {
+	driver, _ := newTestDriverWithConfig(t, map[string]string{configElevationMask: "30"}, models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// 模拟器的卫星一半是GPS一半是北斗
+	tracked := readResource(t, driver, "GPS-A", "gps_sats_tracked").Value.(int32)
+	used := readResource(t, driver, "GPS-A", "beidou_sats_used").Value.(int32)
+	above := readResource(t, driver, "GPS-A", "gps_sats_above_mask").Value.(int32)
+	if tracked == 0 || used == 0 || above > 6 {
+		t.Errorf("gps_sats_tracked = %d, beidou_sats_used = %d, gps_sats_above_mask = %d", tracked, used, above)
+	}
+	if cn0 := readResource(t, driver, "GPS-A", "beidou_cn0_max").Value.(float64); cn0 < simTrackCN0 {
+		t.Errorf("beidou_cn0_max = %v", cn0)
+	}
+	if cv := readResource(t, driver, "GPS-A", "galileo_sats_tracked"); cv.Value.(int32) != 0 {
+		t.Errorf("galileo_sats_tracked = %v", cv.Value)
+	}
+
+	// 没有跟踪卫星的系统没有载噪比读数
+	res, err := driver.HandleReadCommands("GPS-A", nil, []dsModels.CommandRequest{{DeviceResourceName: "galileo_cn0_mean"}})
+	if err != nil || len(res) != 0 {
+		t.Errorf("galileo_cn0_mean = %v, %v", res, err)
+	}
+
+	stats, ok := readResource(t, driver, "GPS-A", "constellation_stats").Value.([]ConstellationStats)
+	if !ok || len(stats) != 5 || stats[0].InView != 6 || stats[3].InView != 6 {
+		t.Errorf("constellation_stats = %+v", stats)
+	}
+	plot, ok := readResource(t, driver, "GPS-A", "sky_plot").Value.(SkyPlot)
+	if !ok || plot.ElevationMask != 30 || len(plot.Satellites) != 12 {
+		t.Fatalf("sky_plot = %+v", plot)
+	}
+	for _, sat := range plot.Satellites {
+		if sat.Elevation <= 0 || sat.Azimuth < 0 || sat.Azimuth >= 360 || (sat.Used && !sat.Tracked) {
+			t.Errorf("sky_plot satellite = %+v", sat)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package driver
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/spf13/cast"
+)
+
+// 坐标参考系名称
+const (
+	CRSWGS84    = "wgs84"
+	CRSGCJ02    = "gcj02"    // 国测局坐标，高德、腾讯等国内地图使用
+	CRSBD09     = "bd09"     // 百度坐标，在GCJ-02基础上再次加偏
+	CRSCGCS2000 = "cgcs2000" // 2000国家大地坐标系
+)
+
+// urlRawQuery SDK将命令URL的查询参数原样放在请求属性的这个键中
+const urlRawQuery = "urlRawQuery"
+
+// crsAliases 可接受的坐标参考系写法，不区分大小写
+var crsAliases = map[string]string{
+	"wgs84":    CRSWGS84,
+	"wgs-84":   CRSWGS84,
+	"gcj02":    CRSGCJ02,
+	"gcj-02":   CRSGCJ02,
+	"bd09":     CRSBD09,
+	"bd-09":    CRSBD09,
+	"cgcs2000": CRSCGCS2000,
+}
+
+// GCJ-02加偏使用的克拉索夫斯基椭球参数
+const (
+	gcjA  = 6378245.0
+	gcjEE = 0.00669342162296594323
+	bdXPi = math.Pi * 3000 / 180
+)
+
+// CRSPosition 转换到指定坐标参考系的位置
+type CRSPosition struct {
+	CRS               string   `json:"crs"`
+	Latitude          float64  `json:"lat"`
+	Longitude         float64  `json:"lon"`
+	Altitude          *float64 `json:"alt,omitempty"`               // 平均海平面以上海拔（米）
+	EllipsoidalHeight *float64 `json:"ellipsoidalHeight,omitempty"` // 椭球高（米）
+}
+
+// parseCRS 解析坐标参考系名称，空字符串返回空
+func parseCRS(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", nil
+	}
+	crs, ok := crsAliases[value]
+	if !ok {
+		return "", fmt.Errorf("不支持的坐标参考系: %s", value)
+	}
+	return crs, nil
+}
+
+// convertCRS 将WGS84经纬度转换到指定坐标参考系。
+// CGCS2000与WGS84的差异在厘米级（主要是板块运动），不做历元转换时按相同坐标处理。
+func convertCRS(pos LatLon, crs string) LatLon {
+	switch crs {
+	case CRSGCJ02:
+		return wgs84ToGCJ02(pos)
+	case CRSBD09:
+		return gcj02ToBD09(wgs84ToGCJ02(pos))
+	default:
+		return pos
+	}
+}
+
+// outOfChina 判断是否在中国范围以外，范围以外GCJ-02不加偏
+func outOfChina(pos LatLon) bool {
+	return pos.Lon < 72.004 || pos.Lon > 137.8347 || pos.Lat < 0.8293 || pos.Lat > 55.8271
+}
+
+// wgs84ToGCJ02 WGS84转GCJ-02（公开的加偏算法，误差约1米）
+func wgs84ToGCJ02(pos LatLon) LatLon {
+	if outOfChina(pos) {
+		return pos
+	}
+	x, y := pos.Lon-105, pos.Lat-35
+	dLat := gcjTransformLat(x, y)
+	dLon := gcjTransformLon(x, y)
+
+	sinLat := math.Sin(toRadians(pos.Lat))
+	magic := 1 - gcjEE*sinLat*sinLat
+	sqrtMagic := math.Sqrt(magic)
+	dLat = dLat * 180 / (gcjA * (1 - gcjEE) / (magic * sqrtMagic) * math.Pi)
+	dLon = dLon * 180 / (gcjA / sqrtMagic * math.Cos(toRadians(pos.Lat)) * math.Pi)
+	return LatLon{Lat: pos.Lat + dLat, Lon: pos.Lon + dLon}
+}
+
+// gcjTransformLat GCJ-02纬度偏移（相对东经105°、北纬35°）
+func gcjTransformLat(x, y float64) float64 {
+	ret := -100 + 2*x + 3*y + 0.2*y*y + 0.1*x*y + 0.2*math.Sqrt(math.Abs(x))
+	ret += (20*math.Sin(6*x*math.Pi) + 20*math.Sin(2*x*math.Pi)) * 2 / 3
+	ret += (20*math.Sin(y*math.Pi) + 40*math.Sin(y/3*math.Pi)) * 2 / 3
+	ret += (160*math.Sin(y/12*math.Pi) + 320*math.Sin(y*math.Pi/30)) * 2 / 3
+	return ret
+}
+
+// gcjTransformLon GCJ-02经度偏移（相对东经105°、北纬35°）
+func gcjTransformLon(x, y float64) float64 {
+	ret := 300 + x + 2*y + 0.1*x*x + 0.1*x*y + 0.1*math.Sqrt(math.Abs(x))
+	ret += (20*math.Sin(6*x*math.Pi) + 20*math.Sin(2*x*math.Pi)) * 2 / 3
+	ret += (20*math.Sin(x*math.Pi) + 40*math.Sin(x/3*math.Pi)) * 2 / 3
+	ret += (150*math.Sin(x/12*math.Pi) + 300*math.Sin(x/30*math.Pi)) * 2 / 3
+	return ret
+}
+
+// gcj02ToBD09 GCJ-02转BD-09
+func gcj02ToBD09(pos LatLon) LatLon {
+	x, y := pos.Lon, pos.Lat
+	z := math.Sqrt(x*x+y*y) + 0.00002*math.Sin(y*bdXPi)
+	theta := math.Atan2(y, x) + 0.000003*math.Cos(x*bdXPi)
+	return LatLon{Lat: z*math.Sin(theta) + 0.006, Lon: z*math.Cos(theta) + 0.0065}
+}
+
+// ellipsoidalHeight 由GGA海拔和大地水准面差距得到WGS84椭球高
+func ellipsoidalHeight(fix Fix) (float64, bool) {
+	if !fix.HasAltitude || !fix.HasGeoidSeparation {
+		return 0, false
+	}
+	return fix.Altitude + fix.GeoidSeparation, true
+}
+
+// crsResources 坐标转换资源
+var crsResources = map[string]bool{
+	"position_crs":         true,
+	"position_gcj02":       true,
+	"position_bd09":        true,
+	"ellipsoidal_height_m": true,
+	"utm_easting":          true,
+	"utm_northing":         true,
+	"utm_zone":             true,
+}
+
+// requestOption 读取请求URL中的查询参数，如 ?crs=gcj02
+func requestOption(req dsModels.CommandRequest, key string) string {
+	raw, _ := req.Attributes[urlRawQuery].(string)
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return ""
+	}
+	return values.Get(key)
+}
+
+// deviceOption 读取设备协议属性
+func deviceOption(protocols map[string]models.ProtocolProperties, key string) string {
+	for _, protocol := range protocols {
+		if value := cast.ToString(protocol[key]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// requestCRS 按请求参数crs、设备属性crs、驱动配置CRS的顺序确定坐标参考系
+func (s *Driver) requestCRS(rcv *receiver, req dsModels.CommandRequest) (string, error) {
+	for _, value := range []string{requestOption(req, "crs"), deviceOption(rcv.protocols, "crs")} {
+		if crs, err := parseCRS(value); err != nil || crs != "" {
+			return crs, err
+		}
+	}
+	return s.config.CRS, nil
+}
+
+// requestUTM 投影到UTM，请求参数或设备属性utmZone可指定投影带，否则按经度取所在带
+func requestUTM(rcv *receiver, req dsModels.CommandRequest, pos LatLon) (UTM, error) {
+	value := requestOption(req, "utmZone")
+	if value == "" {
+		value = deviceOption(rcv.protocols, "utmZone")
+	}
+	if value == "" {
+		return toUTM(pos)
+	}
+	zone, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return UTM{}, fmt.Errorf("无效的UTM投影带: %s", value)
+	}
+	return toUTMZone(pos, zone)
+}
+
+// getCRSValue 获取坐标转换资源，位置无效时返回nil，坐标参考系或投影带参数无效时返回错误
+func (s *Driver) getCRSValue(rcv *receiver, req dsModels.CommandRequest) (*dsModels.CommandValue, error) {
+	fix, _ := rcv.motion.Apply(rcv.gps.Fix())
+
+	var valueType string
+	var value any
+	switch name := req.DeviceResourceName; name {
+	case "ellipsoidal_height_m":
+		height, ok := ellipsoidalHeight(fix)
+		if !ok {
+			return nil, nil
+		}
+		valueType, value = common.ValueTypeFloat64, height
+	case "position_crs", "position_gcj02", "position_bd09":
+		if !fix.HasPosition {
+			return nil, nil
+		}
+		crs := strings.TrimPrefix(name, "position_")
+		if name == "position_crs" {
+			var err error
+			if crs, err = s.requestCRS(rcv, req); err != nil {
+				return nil, err
+			}
+		}
+		pos := convertCRS(LatLon{Lat: fix.Latitude, Lon: fix.Longitude}, crs)
+		height, hasHeight := ellipsoidalHeight(fix)
+		valueType, value = common.ValueTypeObject, CRSPosition{
+			CRS:               crs,
+			Latitude:          pos.Lat,
+			Longitude:         pos.Lon,
+			Altitude:          optional(fix.Altitude, fix.HasAltitude),
+			EllipsoidalHeight: optional(height, hasHeight),
+		}
+	default:
+		if !fix.HasPosition {
+			return nil, nil
+		}
+		u, err := requestUTM(rcv, req, LatLon{Lat: fix.Latitude, Lon: fix.Longitude})
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "utm_easting":
+			valueType, value = common.ValueTypeFloat64, u.Easting
+		case "utm_northing":
+			valueType, value = common.ValueTypeFloat64, u.Northing
+		default:
+			valueType, value = common.ValueTypeString, fmt.Sprintf("%d%c", u.Zone, u.Band)
+		}
+	}
+
+	cv, err := dsModels.NewCommandValue(req.DeviceResourceName, valueType, value)
+	if err != nil {
+		s.lc.Errorf("创建%s读数失败: %v", req.DeviceResourceName, err)
+		return nil, nil
+	}
+	return cv, nil
+}
@@ -0,0 +1,87 @@
+package driver
+
+import (
+	"math"
+	"testing"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+func TestConvertCRS(t *testing.T) {
+	beijing := LatLon{Lat: 39.915, Lon: 116.404}
+	tests := []struct {
+		crs      string
+		pos      LatLon
+		expected LatLon
+	}{
+		{CRSGCJ02, beijing, LatLon{Lat: 39.91640428150164, Lon: 116.41024449916938}},
+		{CRSBD09, beijing, gcj02ToBD09(LatLon{Lat: 39.91640428150164, Lon: 116.41024449916938})},
+		{CRSWGS84, beijing, beijing},
+		{CRSCGCS2000, beijing, beijing},
+		// 中国范围以外不加偏
+		{CRSGCJ02, LatLon{Lat: 48.8583, Lon: 2.2945}, LatLon{Lat: 48.8583, Lon: 2.2945}},
+	}
+	for _, test := range tests {
+		got := convertCRS(test.pos, test.crs)
+		if math.Abs(got.Lat-test.expected.Lat) > 1e-9 || math.Abs(got.Lon-test.expected.Lon) > 1e-9 {
+			t.Errorf("convertCRS(%v, %s) = %v, expected %v", test.pos, test.crs, got, test.expected)
+		}
+	}
+
+	if bd := gcj02ToBD09(beijing); math.Abs(bd.Lat-39.92133699351022) > 1e-9 || math.Abs(bd.Lon-116.41036949371029) > 1e-9 {
+		t.Errorf("gcj02ToBD09(%v) = %v", beijing, bd)
+	}
+
+	for value, expected := range map[string]string{"GCJ-02": CRSGCJ02, " bd09 ": CRSBD09, "": ""} {
+		if crs, err := parseCRS(value); err != nil || crs != expected {
+			t.Errorf("parseCRS(%q) = %q, %v, expected %q", value, crs, err, expected)
+		}
+	}
+	if _, err := parseCRS("tokyo"); err == nil {
+		t.Error("parseCRS(tokyo) succeeded, expected error")
+	}
+}
+
+func TestDriverCRS(t *testing.T) {
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["crs"] = "gcj02"
+	driver := newTestDriver(t, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// 设备属性crs选择GCJ-02，请求参数可以覆盖
+	position := readResource(t, driver, "GPS-A", "position_crs").Value.(CRSPosition)
+	if position.CRS != CRSGCJ02 || math.Abs(position.Latitude-30) > 0.01 || position.EllipsoidalHeight == nil {
+		t.Errorf("position_crs = %+v", position)
+	}
+	read := func(resource, query string) ([]*dsModels.CommandValue, error) {
+		req := dsModels.CommandRequest{DeviceResourceName: resource, Attributes: map[string]any{urlRawQuery: query}}
+		return driver.HandleReadCommands("GPS-A", nil, []dsModels.CommandRequest{req})
+	}
+	res, err := read("position_crs", "crs=bd09")
+	if err != nil || len(res) != 1 || res[0].Value.(CRSPosition).CRS != CRSBD09 {
+		t.Errorf("position_crs?crs=bd09 = %v, %v", res, err)
+	}
+	if _, err := read("position_crs", "crs=tokyo"); err == nil {
+		t.Error("position_crs?crs=tokyo succeeded, expected error")
+	}
+
+	if gcj := readResource(t, driver, "GPS-A", "position_gcj02").Value.(CRSPosition); gcj.Longitude-104 < 0.001 {
+		t.Errorf("position_gcj02 = %+v, expected an offset", gcj)
+	}
+	if zone := readResource(t, driver, "GPS-A", "utm_zone").Value; zone != "48R" {
+		t.Errorf("utm_zone = %v, expected 48R", zone)
+	}
+	if easting := readResource(t, driver, "GPS-A", "utm_easting").Value.(float64); math.Abs(easting-403600) > 200 {
+		t.Errorf("utm_easting = %v", easting)
+	}
+	res, err = read("utm_easting", "utmZone=47")
+	if err != nil || len(res) != 1 || res[0].Value.(float64) < 900000 {
+		t.Errorf("utm_easting?utmZone=47 = %v, %v", res, err)
+	}
+	if height := readResource(t, driver, "GPS-A", "ellipsoidal_height_m").Value.(float64); height == 0 {
+		t.Errorf("ellipsoidal_height_m = %v", height)
+	}
+}
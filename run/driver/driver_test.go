@@ -0,0 +1,302 @@
+package driver
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/device-sdk-go/v4/pkg/interfaces/mocks"
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	bootstrapMocks "github.com/edgexfoundry/go-mod-bootstrap/v4/bootstrap/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestDriver 使用模拟SDK初始化驱动，devices为服务启动时已存在的设备
+func newTestDriver(t *testing.T, devices ...models.Device) *Driver {
+	t.Helper()
+	driver, _ := newTestDriverWithConfig(t, map[string]string{}, devices...)
+	return driver
+}
+
+// newTestDriverWithConfig 使用指定的驱动配置初始化驱动，同时返回异步上报通道
+func newTestDriverWithConfig(t *testing.T, driverConfigs map[string]string, devices ...models.Device) (*Driver, chan *dsModels.AsyncValues) {
+	t.Helper()
+
+	asyncCh := make(chan *dsModels.AsyncValues, 16)
+	sdk := &mocks.DeviceServiceSDK{}
+	sdk.On("LoggingClient").Return(logger.NewMockClient())
+	sdk.On("AsyncValuesChannel").Return(asyncCh)
+	sdk.On("DiscoveredDeviceChannel").Return(make(chan []dsModels.DiscoveredDevice, 1))
+	sdk.On("MetricsManager").Return(nil)
+	sdk.On("Devices").Return(devices)
+	sdk.On("DriverConfigs").Return(driverConfigs).Maybe()
+	sdk.On("LoadCustomConfig", mock.Anything, gpsCustomSection).Return(nil).Maybe()
+	sdk.On("ListenForCustomConfigChanges", mock.Anything, gpsCustomWritableSection, mock.Anything).Return(nil).Maybe()
+	sdk.On("AddCustomRoute", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	sdk.On("PublishGenericSystemEvent", mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+	secrets := &bootstrapMocks.SecretProvider{}
+	secrets.On("GetSecret", "ntrip", "username", "password").Return(map[string]string{"username": "gps", "password": "secret"}, nil).Maybe()
+	sdk.On("SecretProvider").Return(secrets).Maybe()
+
+	driver := &Driver{}
+	if err := driver.Initialize(sdk); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Stop(false) })
+	return driver, asyncCh
+}
+
+// simProtocols 以lat为圆心纬度的模拟接收机协议属性
+func simProtocols(lat string) map[string]models.ProtocolProperties {
+	return map[string]models.ProtocolProperties{
+		ProtocolSIM: {
+			"centerLat":  lat,
+			"centerLon":  "104.0",
+			"radius":     "10",
+			"speed":      "0",
+			"noise":      "0",
+			"updateRate": "10",
+		},
+	}
+}
+
+// readResource 读取设备的单个资源，在超时时间内等待资源有值
+func readResource(t *testing.T, driver *Driver, deviceName, resource string) *dsModels.CommandValue {
+	t.Helper()
+
+	var cv *dsModels.CommandValue
+	waitFor(t, 2*time.Second, func() bool {
+		res, err := driver.HandleReadCommands(deviceName, nil, []dsModels.CommandRequest{{DeviceResourceName: resource}})
+		if errors.Is(err, ErrNoValidFix) {
+			return false
+		}
+		if err != nil {
+			t.Fatalf("HandleReadCommands(%s, %s) failed: %v", deviceName, resource, err)
+		}
+		if len(res) == 0 {
+			return false
+		}
+		cv = res[0]
+		return true
+	}, deviceName+"的"+resource)
+	return cv
+}
+
+func TestDriverMultipleReceivers(t *testing.T) {
+	driver := newTestDriver(t,
+		models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")},
+		models.Device{Name: "GPS-B", Protocols: simProtocols("40.0")},
+	)
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	latA := readResource(t, driver, "GPS-A", "latitude").ValueToString()
+	latB := readResource(t, driver, "GPS-B", "latitude").ValueToString()
+	if latA == latB {
+		t.Errorf("GPS-A and GPS-B report the same latitude %s", latA)
+	}
+
+	if _, err := driver.HandleReadCommands("GPS-C", nil, []dsModels.CommandRequest{{DeviceResourceName: "latitude"}}); err == nil {
+		t.Error("read from unknown device succeeded, expected error")
+	}
+}
+
+func TestDriverDeviceLifecycle(t *testing.T) {
+	driver := newTestDriver(t)
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if err := driver.AddDevice("GPS-A", simProtocols("30.0"), models.Unlocked); err != nil {
+		t.Fatalf("AddDevice failed: %v", err)
+	}
+	before := readResource(t, driver, "GPS-A", "latitude").ValueToString()
+	first, _ := driver.receiverFor("GPS-A")
+
+	// 协议属性不变时保留原连接
+	if err := driver.UpdateDevice("GPS-A", simProtocols("30.0"), models.Locked); err != nil {
+		t.Fatalf("UpdateDevice failed: %v", err)
+	}
+	if rcv, _ := driver.receiverFor("GPS-A"); rcv != first {
+		t.Error("UpdateDevice with unchanged protocols reopened the receiver")
+	}
+
+	// 协议属性变化时重新打开
+	if err := driver.UpdateDevice("GPS-A", simProtocols("50.0"), models.Unlocked); err != nil {
+		t.Fatalf("UpdateDevice failed: %v", err)
+	}
+	if rcv, _ := driver.receiverFor("GPS-A"); rcv == first {
+		t.Error("UpdateDevice with new protocols kept the old receiver")
+	}
+	if after := readResource(t, driver, "GPS-A", "latitude").ValueToString(); after == before {
+		t.Errorf("latitude after reconfiguration = %s, expected a new position", after)
+	}
+
+	if err := driver.RemoveDevice("GPS-A", nil); err != nil {
+		t.Fatalf("RemoveDevice failed: %v", err)
+	}
+	if _, err := driver.HandleReadCommands("GPS-A", nil, []dsModels.CommandRequest{{DeviceResourceName: "latitude"}}); err == nil {
+		t.Error("read after RemoveDevice succeeded, expected error")
+	}
+	if err := driver.AddDevice("GPS-B", map[string]models.ProtocolProperties{}, models.Unlocked); err == nil {
+		t.Error("AddDevice without protocols succeeded, expected error")
+	}
+}
+
+func TestDriverUpdateDeviceKeepsState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "geofences.json")
+	depot := `{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"id": "depot", "radius": 100}, "geometry": {"type": "Point", "coordinates": [104.0, 30.0]}}]}`
+	if err := os.WriteFile(file, []byte(depot), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	configs := map[string]string{configGeofenceFile: file, configGeofenceMinDwell: "0"}
+	driver, asyncCh := newTestDriverWithConfig(t, configs, models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// nextFenceEvent 等待下一个围栏事件，超时返回false
+	nextFenceEvent := func(timeout time.Duration) (GeofenceEvent, bool) {
+		deadline := time.After(timeout)
+		for {
+			select {
+			case values := <-asyncCh:
+				if event, ok := values.CommandValues[0].Value.(GeofenceEvent); ok {
+					return event, true
+				}
+			case <-deadline:
+				return GeofenceEvent{}, false
+			}
+		}
+	}
+	if event, ok := nextFenceEvent(3 * time.Second); !ok || event.Transition != GeofenceEnter {
+		t.Fatalf("first geofence event = %+v, %v", event, ok)
+	}
+	first, _ := driver.receiverFor("GPS-A")
+	waitFor(t, 2*time.Second, func() bool { return first.track.Len() >= 5 }, "轨迹点")
+	points := first.track.Len()
+
+	// 协议属性变化时重新打开接收机，轨迹和围栏状态保留，不会再次上报进入
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["traceSize"] = "16"
+	if err := driver.UpdateDevice("GPS-A", protocols, models.Unlocked); err != nil {
+		t.Fatalf("UpdateDevice failed: %v", err)
+	}
+	rcv, err := driver.receiverFor("GPS-A")
+	if err != nil || rcv == first || rcv.track != first.track || rcv.fences != first.fences || rcv.odometer != first.odometer {
+		t.Fatalf("receiver after UpdateDevice = %+v, %v", rcv, err)
+	}
+	if rcv.track.Len() < points {
+		t.Errorf("track has %d points after UpdateDevice, expected at least %d", rcv.track.Len(), points)
+	}
+	membership, ok := readResource(t, driver, "GPS-A", "geofence_membership").Value.(GeofenceMembership)
+	if !ok || len(membership.Fences) != 1 || membership.Fences[0].FenceID != "depot" {
+		t.Errorf("membership after UpdateDevice = %+v", membership)
+	}
+	if event, ok := nextFenceEvent(time.Second); ok {
+		t.Errorf("geofence event after UpdateDevice = %+v", event)
+	}
+}
+
+func TestDriverNumericResources(t *testing.T) {
+	driver := newTestDriver(t, models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	lat, err := readResource(t, driver, "GPS-A", "latitude_deg").Float64Value()
+	if err != nil || math.Abs(lat-30.0) > 0.001 {
+		t.Errorf("latitude_deg = %v, %v, expected about 30.0", lat, err)
+	}
+	sats, err := readResource(t, driver, "GPS-A", "satellites_used").Int32Value()
+	if err != nil || sats < 4 {
+		t.Errorf("satellites_used = %v, %v, expected at least 4", sats, err)
+	}
+	ts, err := readResource(t, driver, "GPS-A", "utc_timestamp").Int64Value()
+	if err != nil || math.Abs(float64(time.Now().UnixMilli()-ts)) > 5000 {
+		t.Errorf("utc_timestamp = %v, %v, expected about now", ts, err)
+	}
+	if text := readResource(t, driver, "GPS-A", "hdop_text"); text.Type != common.ValueTypeString {
+		t.Errorf("hdop_text type = %s, expected String", text.Type)
+	}
+	// gps_status不随显示语言变化
+	if status := readResource(t, driver, "GPS-A", "gps_status").Value; status != GPSStatusActive {
+		t.Errorf("gps_status = %v, expected %s", status, GPSStatusActive)
+	}
+	if status := readResource(t, driver, "GPS-A", "gps_status_text").Value; status != "定位有效" {
+		t.Errorf("gps_status_text = %v", status)
+	}
+
+	// 读数时间为GNSS历元时间，模拟接收机输出整毫秒的UTC
+	for _, resource := range []string{"latitude_deg", "hdop_text", "odometer_m"} {
+		origin := readResource(t, driver, "GPS-A", resource).Origin
+		if origin == 0 || origin%int64(time.Millisecond) != 0 || math.Abs(float64(time.Now().UnixNano()-origin)) > float64(5*time.Second) {
+			t.Errorf("%s origin = %d, expected the epoch time", resource, origin)
+		}
+	}
+}
+
+func TestDriverPrecisionResources(t *testing.T) {
+	driver := newTestDriver(t, models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	for _, resource := range []string{"pdop", "vdop", "estimated_horizontal_accuracy_m"} {
+		if value, err := readResource(t, driver, "GPS-A", resource).Float64Value(); err != nil || value <= 0 {
+			t.Errorf("%s = %v, %v", resource, value, err)
+		}
+	}
+	// 模拟接收机默认不输出GST
+	res, err := driver.HandleReadCommands("GPS-A", nil, []dsModels.CommandRequest{{DeviceResourceName: "sigma_lat_m"}, {DeviceResourceName: "error_ellipse"}})
+	if err != nil || len(res) != 0 {
+		t.Errorf("GST resources without GST = %v, %v", res, err)
+	}
+
+	cv, _ := dsModels.NewCommandValue("set_output_rate", common.ValueTypeString, "GST:1")
+	if err := driver.HandleWriteCommands("GPS-A", nil, []dsModels.CommandRequest{{DeviceResourceName: "set_output_rate"}}, []*dsModels.CommandValue{cv}); err != nil {
+		t.Fatalf("set_output_rate failed: %v", err)
+	}
+	for _, resource := range []string{"sigma_lat_m", "sigma_lon_m", "sigma_alt_m"} {
+		if value, err := readResource(t, driver, "GPS-A", resource).Float64Value(); err != nil || value <= 0 {
+			t.Errorf("%s = %v, %v", resource, value, err)
+		}
+	}
+	ellipse, ok := readResource(t, driver, "GPS-A", "error_ellipse").Value.(FixErrorEllipse)
+	if !ok || ellipse.SemiMajor == nil || ellipse.SigmaAlt == nil {
+		t.Errorf("error_ellipse = %+v", ellipse)
+	}
+}
+
+func TestDriverFixObject(t *testing.T) {
+	driver := newTestDriver(t, models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// 等待同一历元的GSA都到达后再检查各系统的卫星数
+	var doc FixDocument
+	waitFor(t, 2*time.Second, func() bool {
+		value, err := readResource(t, driver, "GPS-A", "fix").ObjectValue()
+		if err != nil {
+			t.Fatalf("fix ObjectValue failed: %v", err)
+		}
+		doc = value.(FixDocument)
+		return len(doc.Satellites.ByConstellation) == 2
+	}, "fix中各卫星系统的卫星数")
+
+	if !doc.Valid || doc.FixType != "3d" || doc.Position == nil || doc.DOP == nil || doc.DOP.PDOP == nil {
+		t.Errorf("fix = %+v", doc)
+	}
+	used := doc.Satellites.ByConstellation[ConstellationGPS] + doc.Satellites.ByConstellation[ConstellationBeiDou]
+	if used != doc.Satellites.Used {
+		t.Errorf("satellites by constellation %v do not add up to %d", doc.Satellites.ByConstellation, doc.Satellites.Used)
+	}
+}
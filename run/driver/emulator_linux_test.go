@@ -0,0 +1,250 @@
+package driver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty 创建一对伪终端，返回主端文件和从端设备路径
+func openPty() (*os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rawConn, err := master.SyscallConn()
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+
+	var ptyNum uint32
+	var ctrlErr error
+	err = rawConn.Control(func(fd uintptr) {
+		// unlockpt + ptsname
+		if ctrlErr = unix.IoctlSetPointerInt(int(fd), unix.TIOCSPTLCK, 0); ctrlErr != nil {
+			return
+		}
+		ptyNum, ctrlErr = unix.IoctlGetUint32(int(fd), unix.TIOCGPTN)
+	})
+	if err == nil {
+		err = ctrlErr
+	}
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+
+	return master, fmt.Sprintf("/dev/pts/%d", ptyNum), nil
+}
+
+// receiverEmulator 在伪终端主端模拟一台Quectel接收机：
+// 按脚本回放NMEA语句，并应答二进制CFG-MSG设置/查询帧。
+// 查询只回复速率帧，设置回复ACK或NAK。
+type receiverEmulator struct {
+	t      *testing.T
+	master *os.File
+	link   string // 指向当前从端的符号链接，驱动通过它打开串口
+
+	mutex    sync.Mutex
+	rates    map[NMEA_SUB_ID]uint8
+	nak      map[NMEA_SUB_ID]bool
+	mute     bool
+	received [][]byte
+
+	wg sync.WaitGroup
+}
+
+// newReceiverEmulator 创建伪终端并启动模拟接收机
+func newReceiverEmulator(t *testing.T) *receiverEmulator {
+	t.Helper()
+
+	e := &receiverEmulator{
+		t:     t,
+		link:  filepath.Join(t.TempDir(), "ttyGPS"),
+		rates: map[NMEA_SUB_ID]uint8{NMEA_GGA_SID: 1, NMEA_RMC_SID: 1},
+		nak:   make(map[NMEA_SUB_ID]bool),
+	}
+	if err := e.plug(); err != nil {
+		t.Skipf("伪终端不可用: %v", err)
+	}
+	t.Cleanup(e.unplug)
+	return e
+}
+
+// plug 创建新的伪终端并把符号链接指向它，相当于接收机重新上电枚举
+func (e *receiverEmulator) plug() error {
+	master, slave, err := openPty()
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(e.link)
+	if err := os.Symlink(slave, e.link); err != nil {
+		master.Close()
+		return err
+	}
+
+	e.master = master
+	e.wg.Add(1)
+	go e.serve(master)
+	return nil
+}
+
+// unplug 关闭主端，驱动侧读取将返回错误，相当于拔出接收机
+func (e *receiverEmulator) unplug() {
+	if e.master == nil {
+		return
+	}
+	e.master.Close()
+	e.wg.Wait()
+	e.master = nil
+}
+
+// hangup 挂断从端：驱动已打开的串口此后每次读取都立即返回0字节，
+// 与USB接收机被拔出时tty的表现一致；主端保持打开，重新打开从端即可恢复通信
+func (e *receiverEmulator) hangup() error {
+	return hangupPty(e.link)
+}
+
+// hangupPty 挂断伪终端从端，已打开的从端此后读取立即返回EOF
+func hangupPty(path string) error {
+	slave, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return err
+	}
+	defer slave.Close()
+
+	rawConn, err := slave.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ioctlErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		ioctlErr = unix.IoctlSetInt(int(fd), unix.TIOCVHANGUP, 0)
+	}); err != nil {
+		return err
+	}
+	return ioctlErr
+}
+
+// sendNMEA 以正确的校验和发送一条NMEA语句
+func (e *receiverEmulator) sendNMEA(body string) {
+	e.t.Helper()
+	if _, err := e.master.Write([]byte(BuildNMEASentence(body))); err != nil {
+		e.t.Fatalf("写入NMEA失败: %v", err)
+	}
+}
+
+// replay 按间隔回放一组NMEA语句
+func (e *receiverEmulator) replay(bodies []string, interval time.Duration) {
+	for _, body := range bodies {
+		e.sendNMEA(body)
+		time.Sleep(interval)
+	}
+}
+
+func (e *receiverEmulator) setNAK(sid NMEA_SUB_ID) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.nak[sid] = true
+}
+
+func (e *receiverEmulator) setMute(mute bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.mute = mute
+}
+
+func (e *receiverEmulator) rate(sid NMEA_SUB_ID) (uint8, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	rate, ok := e.rates[sid]
+	return rate, ok
+}
+
+func (e *receiverEmulator) frames() [][]byte {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([][]byte(nil), e.received...)
+}
+
+func (e *receiverEmulator) serve(master *os.File) {
+	defer e.wg.Done()
+
+	var pending []byte
+	buf := make([]byte, 256)
+	for {
+		n, err := master.Read(buf)
+		if err != nil {
+			// 主端被关闭或驱动侧已断开
+			return
+		}
+		pending = append(pending, buf[:n]...)
+		pending = e.handleFrames(master, pending)
+	}
+}
+
+func (e *receiverEmulator) handleFrames(master *os.File, data []byte) []byte {
+	for {
+		start := -1
+		for i := 0; i+1 < len(data); i++ {
+			if data[i] == 0xF1 && data[i+1] == 0xD9 {
+				start = i
+				break
+			}
+		}
+		if start < 0 || len(data)-start < 8 {
+			return data
+		}
+		data = data[start:]
+		total := 8 + int(binary.LittleEndian.Uint16(data[4:6]))
+		if len(data) < total {
+			return data
+		}
+		frame := append([]byte(nil), data[:total]...)
+		data = data[total:]
+
+		if QlCheckQuectel(frame[:total-2]) != binary.LittleEndian.Uint16(frame[total-2:]) {
+			e.t.Errorf("驱动发送的帧校验和错误: %X", frame)
+			continue
+		}
+		for _, reply := range e.respond(frame) {
+			_, _ = master.Write(reply)
+		}
+	}
+}
+
+// respond 生成对一帧配置命令的应答
+func (e *receiverEmulator) respond(frame []byte) [][]byte {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.received = append(e.received, frame)
+	if e.mute || GroupID(frame[2]) != BIN_CFG_GID || BIN_CFG_SID(frame[3]) != BM_MSG_SID {
+		return nil
+	}
+
+	payload := frame[6 : len(frame)-2]
+	sid := NMEA_SUB_ID(payload[1])
+	ackPayload := []byte{frame[2], frame[3]}
+	if e.nak[sid] {
+		return [][]byte{BuildBinaryFrame(BIN_RES_GID, uint8(BM_NAK_SID), ackPayload)}
+	}
+
+	switch len(payload) {
+	case 2: // 查询
+		return [][]byte{BuildBinaryFrame(BIN_CFG_GID, uint8(BM_MSG_SID), []byte{payload[0], payload[1], e.rates[sid]})}
+	case 3: // 设置
+		e.rates[sid] = payload[2]
+		return [][]byte{BuildBinaryFrame(BIN_RES_GID, uint8(BM_ACK_SID), ackPayload)}
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package driver
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mpsPerKnot = 0.514444 // 1节 = 0.514444 米/秒
+	mpsPerKmh  = 1 / 3.6
+)
+
+// Fix 从最近一次解析的NMEA语句中提取的数值化定位结果，Has*标记对应字段是否有效
+type Fix struct {
+	Time       time.Time // RMC日期+UTC时间
+	Latitude   float64   // 十进制度，南纬为负
+	Longitude  float64   // 十进制度，西经为负
+	Altitude   float64   // 平均海平面以上海拔（米）
+	Speed      float64   // 对地速度（米/秒）
+	Course     float64   // 对地真航向（度）
+	HDOP       float64   // 水平精度因子
+	Satellites int       // 参与解算的卫星数
+	Quality    int       // GGA定位质量，0为无效
+	Status     string    // RMC状态，A有效 V无效
+	Received   time.Time // 历元结束时的本机时间，用于计算数据时效，尚未收到完整历元时为零值
+
+	LeapSecond    bool // UTC时间为闰秒23:59:60，Time取23:59:59.999
+	RolloverWeeks int  // 接收机日期因GPS周数翻转错误而补加的周数，0表示日期正常
+
+	GeoidSeparation float64        // 大地水准面差距（米），WGS84椭球高 = Altitude + GeoidSeparation
+	PDOP            float64        // 位置精度因子
+	VDOP            float64        // 垂直精度因子
+	FixMode         int            // GSA定位模式：1无定位 2二维 3三维，0表示未知
+	SatellitesBySys map[string]int // 各卫星系统参与解算的卫星数，来自GSA
+	Errors          ErrorEllipse   // GST误差统计
+
+	HasTime            bool
+	HasPosition        bool
+	HasAltitude        bool
+	HasSpeed           bool
+	HasCourse          bool
+	HasHDOP            bool
+	HasSatellites      bool
+	HasGeoidSeparation bool
+	HasPDOP            bool
+	HasVDOP            bool
+	HasErrorEllipse    bool // 半长轴、半短轴和方向均有效
+	HasSigmas          bool // 纬度和经度标准差有效
+	HasSigmaAlt        bool
+}
+
+// ErrorEllipse GST语句给出的定位误差统计，长度单位为米
+type ErrorEllipse struct {
+	RMS         float64 // 伪距残差的RMS值
+	SemiMajor   float64 // 误差椭圆半长轴标准差
+	SemiMinor   float64 // 误差椭圆半短轴标准差
+	Orientation float64 // 半长轴方向，相对真北的角度
+	SigmaLat    float64 // 纬度误差标准差
+	SigmaLon    float64 // 经度误差标准差
+	SigmaAlt    float64 // 高度误差标准差
+}
+
+// fixQueueSize 历元定位结果通道的缓冲大小，处理跟不上时丢弃新结果
+const fixQueueSize = 16
+
+// Fix 返回当前的数值化定位结果
+func (lcx6xz *LCX6XZ) Fix() Fix {
+	lcx6xz.mutex.Lock()
+	defer lcx6xz.mutex.Unlock()
+
+	return lcx6xz.fixLocked()
+}
+
+// fixLocked 合并当前各语句得到定位结果，调用方需持有mutex
+func (lcx6xz *LCX6XZ) fixLocked() Fix {
+	fix := fixFromNMEA(lcx6xz.NMEA_RMC, lcx6xz.NMEA_GGA, lcx6xz.NMEA_VTG, lcx6xz.NMEA_GSA)
+	fix.applyGSA(lcx6xz.currentGSAs())
+	fix.applyGST(lcx6xz.NMEA_GST, lcx6xz.NMEA_RMC, lcx6xz.NMEA_GGA)
+	fix.applyZDA(lcx6xz.NMEA_ZDA, lcx6xz.NMEA_GGA)
+	// 回放的录制数据保留录制时的日期
+	if !lcx6xz.replay {
+		fix.checkRollover(minReceiverDate(time.Now()))
+	}
+	fix.Received = lcx6xz.epochAt
+	return fix
+}
+
+// checkEpoch 在收到RMC或GGA后判断当前历元是否结束，结束时推送定位结果。调用方需持有mutex。
+//
+// 同一历元的RMC和GGA具有相同的UTC时间，两者都到达即视为历元结束。只输出其中一种语句时，
+// 收到即结束；连续两条RMC都没有等到同历元的GGA时，认为GGA已关闭，此后RMC到达即结束，
+// 直到GGA重新与RMC对齐。
+func (lcx6xz *LCX6XZ) checkEpoch(nmeaType NMEA_TYPE) {
+	var rmcUTC, ggaUTC string
+	if lcx6xz.NMEA_RMC != nil {
+		rmcUTC = trimNullBytes(lcx6xz.NMEA_RMC.UTC[:])
+	}
+	if lcx6xz.NMEA_GGA != nil {
+		ggaUTC = trimNullBytes(lcx6xz.NMEA_GGA.UTC[:])
+	}
+
+	var epoch string
+	switch nmeaType {
+	case NMEA_RMC_TYPE:
+		switch {
+		case rmcUTC == ggaUTC || lcx6xz.NMEA_GGA == nil:
+			epoch = rmcUTC
+		case lcx6xz.pendingRMC != "":
+			epoch = rmcUTC
+			lcx6xz.pendingRMC = rmcUTC
+		default:
+			lcx6xz.pendingRMC = rmcUTC
+		}
+	case NMEA_GGA_TYPE:
+		if ggaUTC == rmcUTC || lcx6xz.NMEA_RMC == nil {
+			epoch = ggaUTC
+			lcx6xz.pendingRMC = ""
+		}
+	}
+
+	if epoch == "" || epoch == lcx6xz.lastEpoch {
+		return
+	}
+	lcx6xz.lastEpoch = epoch
+	lcx6xz.epochAt = time.Now()
+
+	select {
+	case lcx6xz.fixes <- lcx6xz.fixLocked():
+	default:
+	}
+}
+
+// fixFromNMEA 合并各语句中的字段。位置、时间、速度和航向优先取RMC，
+// 海拔、卫星数和定位质量取GGA，缺失时依次回退到VTG和GSA。
+func fixFromNMEA(rmc *NMEA_RMC, gga *NMEA_GGA, vtg *NMEA_VTG, gsa *NMEA_GSA) Fix {
+	var fix Fix
+
+	if rmc != nil {
+		fix.Status = trimNullBytes(rmc.Status[:])
+		fix.Time, fix.HasTime = parseNMEATime(trimNullBytes(rmc.Date[:]), trimNullBytes(rmc.UTC[:]))
+		fix.LeapSecond = fix.HasTime && isLeapSecond(trimNullBytes(rmc.UTC[:]))
+
+		lat, latOK := parseNMEACoordinate(trimNullBytes(rmc.Lat[:]), trimNullBytes(rmc.N_S[:]))
+		lon, lonOK := parseNMEACoordinate(trimNullBytes(rmc.Lon[:]), trimNullBytes(rmc.E_W[:]))
+		if latOK && lonOK {
+			fix.Latitude, fix.Longitude, fix.HasPosition = lat, lon, true
+		}
+
+		if sog, ok := parseNMEAFloat(rmc.SOG[:]); ok {
+			fix.Speed, fix.HasSpeed = sog*mpsPerKnot, true
+		}
+		fix.Course, fix.HasCourse = parseNMEAFloat(rmc.COG[:])
+	}
+
+	if gga != nil {
+		if !fix.HasPosition {
+			lat, latOK := parseNMEACoordinate(trimNullBytes(gga.Lat[:]), trimNullBytes(gga.N_S[:]))
+			lon, lonOK := parseNMEACoordinate(trimNullBytes(gga.Lon[:]), trimNullBytes(gga.E_W[:]))
+			if latOK && lonOK {
+				fix.Latitude, fix.Longitude, fix.HasPosition = lat, lon, true
+			}
+		}
+
+		fix.Altitude, fix.HasAltitude = parseNMEAFloat(gga.Alt[:])
+		fix.GeoidSeparation, fix.HasGeoidSeparation = parseNMEAFloat(gga.Sep[:])
+		fix.HDOP, fix.HasHDOP = parseNMEAFloat(gga.HDOP[:])
+		if quality, ok := parseNMEAFloat(gga.Quality[:]); ok {
+			fix.Quality = int(quality)
+		}
+		if sats, ok := parseNMEAFloat(gga.NumSatUsed[:]); ok {
+			fix.Satellites, fix.HasSatellites = int(sats), true
+		}
+	}
+
+	if vtg != nil {
+		if !fix.HasSpeed {
+			if sogk, ok := parseNMEAFloat(vtg.SOGK[:]); ok {
+				fix.Speed, fix.HasSpeed = sogk*mpsPerKmh, true
+			}
+		}
+		if !fix.HasCourse {
+			fix.Course, fix.HasCourse = parseNMEAFloat(vtg.COGT[:])
+		}
+	}
+
+	if gsa != nil && !fix.HasHDOP {
+		fix.HDOP, fix.HasHDOP = parseNMEAFloat(gsa.HDOP[:])
+	}
+
+	// RMC有效但GGA尚未到达时，定位质量至少为1
+	if fix.Quality == 0 && fix.Status == "A" {
+		fix.Quality = 1
+	}
+
+	return fix
+}
+
+// applyGSA 合并同一历元内各卫星系统的GSA：定位模式、PDOP/VDOP和各系统参与解算的卫星数
+func (fix *Fix) applyGSA(gsas []*NMEA_GSA) {
+	for _, gsa := range gsas {
+		if mode, ok := parseNMEAFloat(gsa.FixMode[:]); ok && int(mode) > fix.FixMode {
+			fix.FixMode = int(mode)
+		}
+		// 组合解算时各系统的GSA给出相同的DOP，99.99表示无效
+		if pdop, ok := parseNMEAFloat(gsa.PDOP[:]); ok && !fix.HasPDOP && pdop < 99.99 {
+			fix.PDOP, fix.HasPDOP = pdop, true
+		}
+		if vdop, ok := parseNMEAFloat(gsa.VDOP[:]); ok && !fix.HasVDOP && vdop < 99.99 {
+			fix.VDOP, fix.HasVDOP = vdop, true
+		}
+
+		system := gsaConstellation(gsa)
+		for _, id := range gsa.SatID {
+			satID := trimNullBytes(id[:])
+			if satID == "" {
+				continue
+			}
+			name := system
+			if name == "" {
+				name = constellationFromSatID(satID)
+			}
+			if name == "" {
+				name = "unknown"
+			}
+			if fix.SatellitesBySys == nil {
+				fix.SatellitesBySys = make(map[string]int)
+			}
+			fix.SatellitesBySys[name]++
+		}
+	}
+}
+
+// applyGST 合并GST语句中的误差椭圆和各方向标准差。GST通常在历元末尾输出，UTC时间与定位所用的
+// RMC（没有RMC时为GGA）不同时是上一历元的统计，不合并，精度回退到HDOP × UERE。
+func (fix *Fix) applyGST(gst *NMEA_GST, rmc *NMEA_RMC, gga *NMEA_GGA) {
+	if gst == nil {
+		return
+	}
+	var epoch []byte
+	switch {
+	case rmc != nil:
+		epoch = rmc.UTC[:]
+	case gga != nil:
+		epoch = gga.UTC[:]
+	}
+	gstUTC, gstOK := parseNMEAFloat(gst.UTC[:])
+	epochUTC, epochOK := parseNMEAFloat(epoch)
+	if !gstOK || !epochOK || gstUTC != epochUTC {
+		return
+	}
+
+	fix.Errors.RMS, _ = parseNMEAFloat(gst.RMS_D[:])
+
+	major, majorOK := parseNMEAFloat(gst.MajorD[:])
+	minor, minorOK := parseNMEAFloat(gst.MinorD[:])
+	orient, orientOK := parseNMEAFloat(gst.Orient[:])
+	if majorOK && minorOK && orientOK {
+		fix.Errors.SemiMajor, fix.Errors.SemiMinor, fix.Errors.Orientation = major, minor, orient
+		fix.HasErrorEllipse = true
+	}
+
+	lat, latOK := parseNMEAFloat(gst.LatD[:])
+	lon, lonOK := parseNMEAFloat(gst.LonD[:])
+	if latOK && lonOK {
+		fix.Errors.SigmaLat, fix.Errors.SigmaLon, fix.HasSigmas = lat, lon, true
+	}
+	fix.Errors.SigmaAlt, fix.HasSigmaAlt = parseNMEAFloat(gst.AltD[:])
+}
+
+// HorizontalAccuracy 估算水平定位误差（米，1倍标准差的DRMS）。
+// 优先使用GST的纬度、经度标准差，其次是误差椭圆的半长轴和半短轴，没有GST时用HDOP × UERE。
+func (fix Fix) HorizontalAccuracy(uere float64) (float64, bool) {
+	switch {
+	case fix.HasSigmas:
+		return math.Hypot(fix.Errors.SigmaLat, fix.Errors.SigmaLon), true
+	case fix.HasErrorEllipse:
+		return math.Hypot(fix.Errors.SemiMajor, fix.Errors.SemiMinor), true
+	case fix.HasHDOP && uere > 0:
+		return fix.HDOP * uere, true
+	}
+	return 0, false
+}
+
+// applyZDA RMC没有给出日期时间时（如只输出GGA和ZDA），用ZDA的日期和四位年份得到定位时间。
+// ZDA通常在历元末尾输出，有GGA时取GGA的UTC时间，跨过午夜时日期加一天。
+func (fix *Fix) applyZDA(zda *NMEA_ZDA, gga *NMEA_GGA) {
+	if zda == nil || fix.HasTime {
+		return
+	}
+	zdaTime, ok := parseZDATime(zda)
+	if !ok {
+		return
+	}
+	utc := trimNullBytes(zda.UTC[:])
+
+	if gga != nil {
+		if ggaUTC := trimNullBytes(gga.UTC[:]); len(ggaUTC) >= 6 {
+			date := zdaTime.Format("02012006")
+			if t, ok := parseUTCDateTime("02012006", date, ggaUTC); ok {
+				if zdaTime.Sub(t) > 12*time.Hour {
+					t = t.AddDate(0, 0, 1)
+				}
+				zdaTime, utc = t, ggaUTC
+			}
+		}
+	}
+	fix.Time, fix.HasTime, fix.LeapSecond = zdaTime, true, isLeapSecond(utc)
+}
+
+// checkRollover 检查接收机日期。固件未处理GPS周数翻转时，日期会比实际早1024周的整数倍，
+// 早于floor的日期按1024周补齐。晚于floor的日期照原样使用。
+func (fix *Fix) checkRollover(floor time.Time) {
+	if !fix.HasTime {
+		return
+	}
+	for fix.Time.Before(floor) {
+		fix.Time = fix.Time.AddDate(0, 0, 7*gpsRolloverWeeks)
+		fix.RolloverWeeks += gpsRolloverWeeks
+	}
+}
+
+// parseNMEAFloat 解析NMEA字段中的数值，字段为空或无效时返回false
+func parseNMEAFloat(field []byte) (float64, bool) {
+	str := strings.TrimSpace(trimNullBytes(field))
+	if str == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// parseNMEACoordinate 将度分格式（ddmm.mmmm 或 dddmm.mmmm）转换为十进制度数，并返回是否有效
+func parseNMEACoordinate(dmsStr, direction string) (float64, bool) {
+	if len(dmsStr) < 4 || direction == "" {
+		return 0.0, false
+	}
+
+	// 小数点前两位是分，再往前是度
+	dotIndex := strings.Index(dmsStr, ".")
+	if dotIndex < 3 {
+		return 0.0, false
+	}
+	degrees, err := strconv.ParseFloat(dmsStr[:dotIndex-2], 64)
+	if err != nil {
+		return 0.0, false
+	}
+	minutes, err := strconv.ParseFloat(dmsStr[dotIndex-2:], 64)
+	if err != nil {
+		return 0.0, false
+	}
+
+	decimal := degrees + minutes/60.0
+
+	// 根据方向调整符号
+	if direction == "S" || direction == "W" {
+		decimal = -decimal
+	}
+
+	return decimal, true
+}
+
+// parseNMEATime 合并RMC日期（ddmmyy）和UTC时间（hhmmss.sss）
+func parseNMEATime(date, utc string) (time.Time, bool) {
+	if len(date) != 6 {
+		return time.Time{}, false
+	}
+	return parseUTCDateTime("020106", date, utc)
+}
+
+// parseZDATime 合并ZDA的日、月、四位年份和UTC时间
+func parseZDATime(zda *NMEA_ZDA) (time.Time, bool) {
+	day, month, year := trimNullBytes(zda.Day[:]), trimNullBytes(zda.Month[:]), trimNullBytes(zda.Year[:])
+	if len(day) != 2 || len(month) != 2 || len(year) != 4 {
+		return time.Time{}, false
+	}
+	return parseUTCDateTime("02012006", day+month+year, trimNullBytes(zda.UTC[:]))
+}
+
+// isLeapSecond 判断UTC时间（hhmmss.sss）是否为闰秒23:59:60
+func isLeapSecond(utc string) bool {
+	return len(utc) >= 6 && utc[4:6] == "60"
+}
+
+// parseUTCDateTime 按dateLayout解析日期并合并UTC时间。time.Time无法表示闰秒23:59:60，
+// 取23:59:59.999，保证时间不倒退。
+func parseUTCDateTime(dateLayout, date, utc string) (time.Time, bool) {
+	if len(utc) < 6 {
+		return time.Time{}, false
+	}
+
+	clock, leap := utc[:6], isLeapSecond(utc)
+	if leap {
+		clock = utc[:4] + "59"
+	}
+	t, err := time.Parse(dateLayout+"150405", date+clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if leap {
+		return t.Add(time.Second - time.Millisecond), true
+	}
+	if len(utc) > 7 && utc[6] == '.' {
+		frac, err := strconv.ParseFloat("0"+utc[6:], 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		t = t.Add(time.Duration(frac * float64(time.Second)).Round(time.Millisecond))
+	}
+	return t, true
+}
@@ -0,0 +1,244 @@
+package driver
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseNMEATime(t *testing.T) {
+	tests := []struct {
+		date, utc string
+		expected  time.Time
+		ok        bool
+	}{
+		{"100625", "055525.000", time.Date(2025, 6, 10, 5, 55, 25, 0, time.UTC), true},
+		{"311299", "235959.250", time.Date(1999, 12, 31, 23, 59, 59, 250e6, time.UTC), true},
+		{"010124", "000000", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"", "055525.000", time.Time{}, false},
+		{"100625", "", time.Time{}, false},
+		{"321325", "055525.000", time.Time{}, false},
+		{"100625", "0555xx.000", time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseNMEATime(test.date, test.utc)
+		if ok != test.ok || !got.Equal(test.expected) {
+			t.Errorf("parseNMEATime(%s, %s) = %v, %v, expected %v, %v", test.date, test.utc, got, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestFixFromNMEA(t *testing.T) {
+	parse := func(body string) string { s := BuildNMEASentence(body); return s[:len(s)-2] }
+	rmcStr := parse("GNRMC,055525.000,A,3044.368753,N,10357.548051,E,10.00,090.50,100625,,,A,V")
+	ggaStr := parse("GNGGA,055525.000,3044.368753,N,10357.548051,E,1,08,1.20,129.3,M,-32.3,M,,")
+	vtgStr := parse("GNVTG,091.00,T,,M,9.0,N,16.7,K,A")
+	gsaStr := parse("GNGSA,A,3,01,02,03,04,,,,,,,,,2.10,1.30,1.60,1")
+
+	rmc := ParsNMEARMC(rmcStr, len(rmcStr))
+	gga := ParsNMEAGGA(ggaStr, len(ggaStr))
+	vtg := ParsNMEAVTG(vtgStr, len(vtgStr))
+	gsa := ParsNMEAGSA(gsaStr, len(gsaStr))
+	if rmc == nil || gga == nil || vtg == nil || gsa == nil {
+		t.Fatal("failed to parse test sentences")
+	}
+
+	fix := fixFromNMEA(rmc, gga, vtg, gsa)
+	if !fix.HasPosition || math.Abs(fix.Latitude-30.739479) > 1e-6 || math.Abs(fix.Longitude-103.959134) > 1e-6 {
+		t.Errorf("position = %v, %v, expected 30.739479, 103.959134", fix.Latitude, fix.Longitude)
+	}
+	if !fix.HasTime || !fix.Time.Equal(time.Date(2025, 6, 10, 5, 55, 25, 0, time.UTC)) {
+		t.Errorf("time = %v", fix.Time)
+	}
+	if !fix.HasAltitude || fix.Altitude != 129.3 {
+		t.Errorf("altitude = %v, expected 129.3", fix.Altitude)
+	}
+	// 速度和航向优先取RMC
+	if !fix.HasSpeed || math.Abs(fix.Speed-5.14444) > 1e-6 {
+		t.Errorf("speed = %v m/s, expected 5.14444", fix.Speed)
+	}
+	if !fix.HasCourse || fix.Course != 90.5 {
+		t.Errorf("course = %v, expected 90.5", fix.Course)
+	}
+	if !fix.HasHDOP || fix.HDOP != 1.2 {
+		t.Errorf("hdop = %v, expected 1.2 from GGA", fix.HDOP)
+	}
+	if !fix.HasSatellites || fix.Satellites != 8 || fix.Quality != 1 {
+		t.Errorf("satellites = %d, quality = %d", fix.Satellites, fix.Quality)
+	}
+
+	// 缺少RMC和GGA时回退到VTG和GSA
+	fallback := fixFromNMEA(nil, nil, vtg, gsa)
+	if fallback.HasPosition || fallback.HasTime || fallback.HasAltitude || fallback.HasSatellites {
+		t.Errorf("fallback fix has fields without RMC/GGA: %+v", fallback)
+	}
+	if !fallback.HasSpeed || math.Abs(fallback.Speed-16.7/3.6) > 1e-9 {
+		t.Errorf("fallback speed = %v, expected VTG km/h", fallback.Speed)
+	}
+	if !fallback.HasCourse || fallback.Course != 91 {
+		t.Errorf("fallback course = %v, expected 91", fallback.Course)
+	}
+	if !fallback.HasHDOP || fallback.HDOP != 1.3 {
+		t.Errorf("fallback hdop = %v, expected 1.3 from GSA", fallback.HDOP)
+	}
+
+	if empty := fixFromNMEA(nil, nil, nil, nil); !reflect.DeepEqual(empty, Fix{}) {
+		t.Errorf("empty fix = %+v", empty)
+	}
+}
+
+func TestFixGSAAndGST(t *testing.T) {
+	parse := func(body string) string { s := BuildNMEASentence(body); return s[:len(s)-2] }
+	sentences := []string{
+		parse("GNGSA,A,3,01,02,03,04,,,,,,,,,2.10,1.30,1.60,1"),
+		parse("GNGSA,A,3,11,12,,,,,,,,,,,2.10,1.30,1.60,4"),
+		parse("GPGSA,A,2,05,,,,,,,,,,,,99.99,99.99,99.99"),
+		parse("GNGSA,A,3,70,09,,,,,,,,,,,2.10,1.30,1.60"),
+	}
+	var gsas []*NMEA_GSA
+	for _, str := range sentences {
+		gsa := ParsNMEAGSA(str, len(str))
+		if gsa == nil {
+			t.Fatalf("ParsNMEAGSA(%s) failed", str)
+		}
+		gsas = append(gsas, gsa)
+	}
+
+	var fix Fix
+	fix.applyGSA(gsas)
+	if fix.FixMode != 3 {
+		t.Errorf("FixMode = %d, expected 3", fix.FixMode)
+	}
+	if !fix.HasPDOP || fix.PDOP != 2.1 || !fix.HasVDOP || fix.VDOP != 1.6 {
+		t.Errorf("PDOP = %v, VDOP = %v", fix.PDOP, fix.VDOP)
+	}
+	// 系统标识符优先，其次TalkerID，旧版GN语句按卫星号推断
+	expected := map[string]int{ConstellationGPS: 4 + 1 + 1, ConstellationBeiDou: 2, ConstellationGLONASS: 1}
+	for name, count := range expected {
+		if fix.SatellitesBySys[name] != count {
+			t.Errorf("SatellitesBySys[%s] = %d, expected %d (%v)", name, fix.SatellitesBySys[name], count, fix.SatellitesBySys)
+		}
+	}
+
+	gstStr := parse("GNGST,055525.000,2.0,1.5,0.8,35.5,1.2,1.0,2.4")
+	gst := ParsNMEAGST(gstStr, len(gstStr))
+	if gst == nil {
+		t.Fatal("ParsNMEAGST failed")
+	}
+	rmcStr := parse("GNRMC,055525.000,A,3044.368753,N,10357.548051,E,0.00,0.00,100625,,,A,V")
+	ggaStr := parse("GNGGA,055526.000,3044.368753,N,10357.548051,E,1,12,0.93,500.0,M,-32.0,M,,")
+	rmc, gga := ParsNMEARMC(rmcStr, len(rmcStr)), ParsNMEAGGA(ggaStr, len(ggaStr))
+	if rmc == nil || gga == nil {
+		t.Fatal("ParsNMEARMC or ParsNMEAGGA failed")
+	}
+
+	// 上一历元的GST不合并：定位取RMC时与RMC比较，只有GGA时与GGA比较
+	for name, stale := range map[string]*NMEA_GGA{"GGA 055526": gga, "no epoch": nil} {
+		var fix Fix
+		if fix.applyGST(gst, nil, stale); fix.HasErrorEllipse || fix.HasSigmas || fix.HasSigmaAlt || fix.Errors.RMS != 0 {
+			t.Errorf("GST 055525 applied to %s: %+v", name, fix.Errors)
+		}
+	}
+
+	fix.applyGST(gst, rmc, gga)
+	if !fix.HasErrorEllipse || fix.Errors.SemiMajor != 1.5 || fix.Errors.SemiMinor != 0.8 || fix.Errors.Orientation != 35.5 {
+		t.Errorf("error ellipse = %+v", fix.Errors)
+	}
+	if !fix.HasSigmas || fix.Errors.SigmaLat != 1.2 || fix.Errors.SigmaLon != 1.0 || !fix.HasSigmaAlt || fix.Errors.SigmaAlt != 2.4 {
+		t.Errorf("sigmas = %+v", fix.Errors)
+	}
+
+	// 无效的GST字段为空
+	emptyStr := parse("GNGST,055525.000,,,,,,,")
+	var empty Fix
+	empty.applyGST(ParsNMEAGST(emptyStr, len(emptyStr)), rmc, nil)
+	if empty.HasErrorEllipse || empty.HasSigmas || empty.HasSigmaAlt {
+		t.Errorf("empty GST produced values: %+v", empty)
+	}
+}
+
+func TestCheckEpoch(t *testing.T) {
+	sentence := func(body string) []byte { return []byte(BuildNMEASentence(body)) }
+	rmc := func(utc string) []byte {
+		return sentence("GNRMC," + utc + ",A,3044.368753,N,10357.548051,E,0.00,0.00,100625,,,A,V")
+	}
+	gga := func(utc string) []byte {
+		return sentence("GNGGA," + utc + ",3044.368753,N,10357.548051,E,1,08,1.20,129.3,M,-32.3,M,,")
+	}
+
+	lcx6xz := &LCX6XZ{fixes: make(chan Fix, fixQueueSize)}
+	feed := func(s []byte) {
+		if err := parseNMEASentence(s, lcx6xz); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(utc string) {
+		t.Helper()
+		select {
+		case fix := <-lcx6xz.fixes:
+			if got := fix.Time.Format("150405"); got != utc {
+				t.Errorf("epoch fix time = %s, expected %s", got, utc)
+			}
+		default:
+			if utc != "" {
+				t.Errorf("no epoch fix, expected %s", utc)
+			}
+			return
+		}
+		if utc == "" {
+			t.Error("unexpected epoch fix")
+		}
+	}
+
+	// 只有RMC时收到即结束
+	feed(rmc("000001.000"))
+	expect("000001")
+
+	// RMC先到，等待同历元的GGA
+	feed(gga("000001.000"))
+	expect("")
+	feed(rmc("000002.000"))
+	expect("")
+	feed(gga("000002.000"))
+	expect("000002")
+
+	// GGA先到，RMC到达时结束
+	feed(gga("000003.000"))
+	expect("")
+	feed(rmc("000003.000"))
+	expect("000003")
+
+	// GGA关闭后，连续两条RMC未对齐即改为收到RMC就结束
+	feed(rmc("000004.000"))
+	expect("")
+	feed(rmc("000005.000"))
+	expect("000005")
+	feed(rmc("000006.000"))
+	expect("000006")
+}
+
+func TestHorizontalAccuracy(t *testing.T) {
+	var fix Fix
+	if _, ok := fix.HorizontalAccuracy(3); ok {
+		t.Error("empty fix produced an accuracy")
+	}
+	fix.HDOP, fix.HasHDOP = 1.5, true
+	if accuracy, ok := fix.HorizontalAccuracy(2); !ok || accuracy != 3 {
+		t.Errorf("HDOP accuracy = %v, %v, expected 3", accuracy, ok)
+	}
+	if _, ok := fix.HorizontalAccuracy(0); ok {
+		t.Error("zero UERE produced an accuracy")
+	}
+
+	// GST优先于HDOP，纬度经度标准差优先于误差椭圆
+	fix.Errors.SemiMajor, fix.Errors.SemiMinor, fix.HasErrorEllipse = 1.2, 0.5, true
+	if accuracy, ok := fix.HorizontalAccuracy(2); !ok || math.Abs(accuracy-1.3) > 1e-9 {
+		t.Errorf("ellipse accuracy = %v, %v, expected 1.3", accuracy, ok)
+	}
+	fix.Errors.SigmaLat, fix.Errors.SigmaLon, fix.HasSigmas = 0.3, 0.4, true
+	if accuracy, ok := fix.HorizontalAccuracy(2); !ok || math.Abs(accuracy-0.5) > 1e-9 {
+		t.Errorf("sigma accuracy = %v, %v, expected 0.5", accuracy, ok)
+	}
+}
@@ -0,0 +1,162 @@
+package driver
+
+import "time"
+
+// fix资源的JSON文档模式。结构变化不兼容时递增版本号，
+// 完整定义见 res/schemas/fix.v1.schema.json。
+const (
+	FixSchemaName    = "device-gps/fix"
+	FixSchemaVersion = 1
+)
+
+// FixDocument fix资源（Object类型）的内容，一条读数给出同一时刻完整的定位结果。
+// 可选字段在数据无效时省略，长度单位为米，角度单位为度。
+type FixDocument struct {
+	Schema      string           `json:"schema"`
+	Version     int              `json:"version"`
+	Epoch       string           `json:"epoch,omitempty"`   // RMC日期+UTC时间，RFC3339
+	EpochMillis int64            `json:"epochMs,omitempty"` // Unix毫秒
+	Valid       bool             `json:"valid"`
+	FixType     string           `json:"fixType"` // none/2d/3d
+	Quality     int              `json:"quality"` // GGA定位质量
+	QualityName string           `json:"qualityName"`
+	Position    *FixPosition     `json:"position,omitempty"`
+	Velocity    *FixVelocity     `json:"velocity,omitempty"`
+	DOP         *FixDOP          `json:"dop,omitempty"`
+	Error       *FixErrorEllipse `json:"errorEllipse,omitempty"`
+	Satellites  FixSatellites    `json:"satellites"`
+}
+
+// FixPosition WGS84位置，altitude为海拔，ellipsoidalHeight = altitude + geoidSeparation
+type FixPosition struct {
+	Latitude          float64  `json:"latitude"`
+	Longitude         float64  `json:"longitude"`
+	Altitude          *float64 `json:"altitude,omitempty"`
+	GeoidSeparation   *float64 `json:"geoidSeparation,omitempty"`
+	EllipsoidalHeight *float64 `json:"ellipsoidalHeight,omitempty"`
+}
+
+// FixVelocity 对地速度（米/秒）和真航向
+type FixVelocity struct {
+	Speed  *float64 `json:"speed,omitempty"`
+	Course *float64 `json:"course,omitempty"`
+}
+
+// FixDOP 精度因子
+type FixDOP struct {
+	PDOP *float64 `json:"pdop,omitempty"`
+	HDOP *float64 `json:"hdop,omitempty"`
+	VDOP *float64 `json:"vdop,omitempty"`
+}
+
+// FixErrorEllipse GST误差椭圆和各方向标准差
+type FixErrorEllipse struct {
+	RMS         *float64 `json:"rms,omitempty"`
+	SemiMajor   *float64 `json:"semiMajor,omitempty"`
+	SemiMinor   *float64 `json:"semiMinor,omitempty"`
+	Orientation *float64 `json:"orientation,omitempty"`
+	SigmaLat    *float64 `json:"sigmaLat,omitempty"`
+	SigmaLon    *float64 `json:"sigmaLon,omitempty"`
+	SigmaAlt    *float64 `json:"sigmaAlt,omitempty"`
+}
+
+// FixSatellites 参与解算的卫星数，byConstellation的键为GPS/GLONASS/Galileo/BeiDou/QZSS
+type FixSatellites struct {
+	Used            int            `json:"used"`
+	ByConstellation map[string]int `json:"byConstellation,omitempty"`
+}
+
+// qualityNames GGA定位质量对应的机器可读名称
+var qualityNames = [...]string{"none", "gps", "dgps", "pps", "rtk_fixed", "rtk_float", "estimated", "manual", "simulation"}
+
+// NewFixDocument 将数值化定位结果转换为fix文档
+func NewFixDocument(fix Fix) FixDocument {
+	doc := FixDocument{
+		Schema:      FixSchemaName,
+		Version:     FixSchemaVersion,
+		Valid:       fix.Status == "A" || fix.Quality > 0,
+		Quality:     fix.Quality,
+		QualityName: "unknown",
+		FixType:     fixType(fix),
+		Satellites:  FixSatellites{Used: fix.Satellites, ByConstellation: fix.SatellitesBySys},
+	}
+	if fix.Quality >= 0 && fix.Quality < len(qualityNames) {
+		doc.QualityName = qualityNames[fix.Quality]
+	}
+
+	if fix.HasTime {
+		doc.Epoch = fix.Time.UTC().Format(time.RFC3339Nano)
+		doc.EpochMillis = fix.Time.UnixMilli()
+	}
+
+	if fix.HasPosition {
+		doc.Position = &FixPosition{
+			Latitude:        fix.Latitude,
+			Longitude:       fix.Longitude,
+			Altitude:        optional(fix.Altitude, fix.HasAltitude),
+			GeoidSeparation: optional(fix.GeoidSeparation, fix.HasGeoidSeparation),
+		}
+		doc.Position.EllipsoidalHeight = optional(fix.Altitude+fix.GeoidSeparation, fix.HasAltitude && fix.HasGeoidSeparation)
+	}
+
+	if fix.HasSpeed || fix.HasCourse {
+		doc.Velocity = &FixVelocity{
+			Speed:  optional(fix.Speed, fix.HasSpeed),
+			Course: optional(fix.Course, fix.HasCourse),
+		}
+	}
+
+	if fix.HasPDOP || fix.HasHDOP || fix.HasVDOP {
+		doc.DOP = &FixDOP{
+			PDOP: optional(fix.PDOP, fix.HasPDOP),
+			HDOP: optional(fix.HDOP, fix.HasHDOP),
+			VDOP: optional(fix.VDOP, fix.HasVDOP),
+		}
+	}
+
+	doc.Error = newFixErrorEllipse(fix)
+
+	return doc
+}
+
+// newFixErrorEllipse 将GST误差统计转换为文档格式，没有GST数据时返回nil
+func newFixErrorEllipse(fix Fix) *FixErrorEllipse {
+	if !fix.HasErrorEllipse && !fix.HasSigmas && !fix.HasSigmaAlt {
+		return nil
+	}
+	return &FixErrorEllipse{
+		RMS:         optional(fix.Errors.RMS, fix.Errors.RMS > 0),
+		SemiMajor:   optional(fix.Errors.SemiMajor, fix.HasErrorEllipse),
+		SemiMinor:   optional(fix.Errors.SemiMinor, fix.HasErrorEllipse),
+		Orientation: optional(fix.Errors.Orientation, fix.HasErrorEllipse),
+		SigmaLat:    optional(fix.Errors.SigmaLat, fix.HasSigmas),
+		SigmaLon:    optional(fix.Errors.SigmaLon, fix.HasSigmas),
+		SigmaAlt:    optional(fix.Errors.SigmaAlt, fix.HasSigmaAlt),
+	}
+}
+
+// fixType 优先使用GSA定位模式，没有GSA时按定位有效性和海拔推断
+func fixType(fix Fix) string {
+	switch {
+	case fix.FixMode == 3:
+		return "3d"
+	case fix.FixMode == 2:
+		return "2d"
+	case fix.FixMode == 1:
+		return "none"
+	case fix.Quality == 0 && fix.Status != "A":
+		return "none"
+	case fix.HasAltitude:
+		return "3d"
+	default:
+		return "2d"
+	}
+}
+
+// optional 有效时返回值的指针，用于JSON中省略无效字段
+func optional(value float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,81 @@
+package driver
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewFixDocument(t *testing.T) {
+	fix := Fix{
+		Time:               time.Date(2025, 6, 10, 5, 55, 25, 500e6, time.UTC),
+		Latitude:           30.5,
+		Longitude:          104.25,
+		Altitude:           129.3,
+		GeoidSeparation:    -32.3,
+		Speed:              5,
+		HDOP:               1.3,
+		PDOP:               2.1,
+		Satellites:         8,
+		Quality:            4,
+		Status:             "A",
+		FixMode:            3,
+		SatellitesBySys:    map[string]int{ConstellationGPS: 5, ConstellationBeiDou: 3},
+		Errors:             ErrorEllipse{SemiMajor: 0.02, SemiMinor: 0.01, Orientation: 10},
+		HasTime:            true,
+		HasPosition:        true,
+		HasAltitude:        true,
+		HasGeoidSeparation: true,
+		HasSpeed:           true,
+		HasHDOP:            true,
+		HasPDOP:            true,
+		HasSatellites:      true,
+		HasErrorEllipse:    true,
+	}
+
+	data, err := json.Marshal(NewFixDocument(fix))
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc["schema"] != FixSchemaName || doc["version"] != float64(FixSchemaVersion) {
+		t.Errorf("schema = %v v%v", doc["schema"], doc["version"])
+	}
+	if doc["epoch"] != "2025-06-10T05:55:25.5Z" || doc["epochMs"] != float64(fix.Time.UnixMilli()) {
+		t.Errorf("epoch = %v (%v)", doc["epoch"], doc["epochMs"])
+	}
+	if doc["valid"] != true || doc["fixType"] != "3d" || doc["qualityName"] != "rtk_fixed" {
+		t.Errorf("valid = %v, fixType = %v, qualityName = %v", doc["valid"], doc["fixType"], doc["qualityName"])
+	}
+
+	position := doc["position"].(map[string]any)
+	if h, ok := position["ellipsoidalHeight"].(float64); !ok || h < 96.99 || h > 97.01 {
+		t.Errorf("ellipsoidalHeight = %v, expected 97.0", position["ellipsoidalHeight"])
+	}
+	velocity := doc["velocity"].(map[string]any)
+	if _, ok := velocity["course"]; ok {
+		t.Error("velocity.course present without a valid course")
+	}
+	dop := doc["dop"].(map[string]any)
+	if _, ok := dop["vdop"]; ok || dop["pdop"] != 2.1 {
+		t.Errorf("dop = %v", dop)
+	}
+	ellipse := doc["errorEllipse"].(map[string]any)
+	if ellipse["semiMajor"] != 0.02 || ellipse["sigmaLat"] != nil {
+		t.Errorf("errorEllipse = %v", ellipse)
+	}
+	satellites := doc["satellites"].(map[string]any)
+	if satellites["used"] != float64(8) || satellites["byConstellation"].(map[string]any)[ConstellationBeiDou] != float64(3) {
+		t.Errorf("satellites = %v", satellites)
+	}
+
+	// 没有任何数据时只有必选字段
+	empty := NewFixDocument(Fix{})
+	if empty.Valid || empty.FixType != "none" || empty.Position != nil || empty.DOP != nil || empty.Error != nil {
+		t.Errorf("empty document = %+v", empty)
+	}
+}
@@ -0,0 +1,327 @@
+package driver
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Locale 显示文本的语言
+type Locale string
+
+const (
+	LocaleZhCN Locale = "zh-CN"
+	LocaleEnUS Locale = "en-US"
+)
+
+// UnitSystem 速度和高度使用的单位制
+type UnitSystem string
+
+const (
+	UnitsMetric   UnitSystem = "metric"   // km/h，米
+	UnitsImperial UnitSystem = "imperial" // mph，英尺
+	UnitsNautical UnitSystem = "nautical" // 节，米
+)
+
+// CoordinateStyle 坐标的显示格式
+type CoordinateStyle string
+
+const (
+	CoordinatesDecimal CoordinateStyle = "decimal" // 30.739479°N
+	CoordinatesDMS     CoordinateStyle = "dms"     // 30°44'22.1"N
+	CoordinatesDDM     CoordinateStyle = "ddm"     // 30°44.3687'N
+	CoordinatesUTM     CoordinateStyle = "utm"     // 48R 400360 3401192
+	CoordinatesMGRS    CoordinateStyle = "mgrs"    // 48R VV 00360 01192
+)
+
+const (
+	feetPerMeter = 3.28084
+	mphPerMps    = 2.236936
+)
+
+// DriverConfigs()中的格式配置项
+const (
+	configFormatLocale      = "FormatLocale"
+	configFormatUnits       = "FormatUnits"
+	configFormatCoordinates = "FormatCoordinates"
+)
+
+// configuration.yaml中的自定义配置段，Writable部分支持运行时修改
+const (
+	gpsCustomSection         = "GPSCustom"
+	gpsCustomWritableSection = "GPSCustom/Writable"
+)
+
+// FormatOptions 格式化配置，空字段表示使用默认值
+type FormatOptions struct {
+	Locale      string
+	Units       string
+	Coordinates string
+}
+
+// formatOptionsFromConfig 从驱动配置中读取格式化配置
+func formatOptionsFromConfig(driverConfigs map[string]string) FormatOptions {
+	return FormatOptions{
+		Locale:      strings.TrimSpace(driverConfigs[configFormatLocale]),
+		Units:       strings.TrimSpace(driverConfigs[configFormatUnits]),
+		Coordinates: strings.TrimSpace(driverConfigs[configFormatCoordinates]),
+	}
+}
+
+// Merge 用override中的非空字段覆盖当前配置
+func (o FormatOptions) Merge(override FormatOptions) FormatOptions {
+	if v := strings.TrimSpace(override.Locale); v != "" {
+		o.Locale = v
+	}
+	if v := strings.TrimSpace(override.Units); v != "" {
+		o.Units = v
+	}
+	if v := strings.TrimSpace(override.Coordinates); v != "" {
+		o.Coordinates = v
+	}
+	return o
+}
+
+// Formatter 按语言、单位制和坐标格式生成易读文本，创建后只读，可并发使用
+type Formatter struct {
+	locale      Locale
+	units       UnitSystem
+	coordinates CoordinateStyle
+	text        *localeText
+}
+
+// NewFormatter 根据配置创建格式化器，默认为zh-CN、公制、度分秒
+func NewFormatter(opts FormatOptions) (*Formatter, error) {
+	f := &Formatter{locale: LocaleZhCN, units: UnitsMetric, coordinates: CoordinatesDMS}
+
+	if opts.Locale != "" {
+		switch Locale(opts.Locale) {
+		case LocaleZhCN, LocaleEnUS:
+			f.locale = Locale(opts.Locale)
+		default:
+			return nil, fmt.Errorf("不支持的语言: %s", opts.Locale)
+		}
+	}
+	if opts.Units != "" {
+		switch UnitSystem(strings.ToLower(opts.Units)) {
+		case UnitsMetric, UnitsImperial, UnitsNautical:
+			f.units = UnitSystem(strings.ToLower(opts.Units))
+		default:
+			return nil, fmt.Errorf("不支持的单位制: %s", opts.Units)
+		}
+	}
+	if opts.Coordinates != "" {
+		switch CoordinateStyle(strings.ToLower(opts.Coordinates)) {
+		case CoordinatesDecimal, CoordinatesDMS, CoordinatesDDM, CoordinatesUTM, CoordinatesMGRS:
+			f.coordinates = CoordinateStyle(strings.ToLower(opts.Coordinates))
+		default:
+			return nil, fmt.Errorf("不支持的坐标格式: %s", opts.Coordinates)
+		}
+	}
+
+	f.text = localeTexts[f.locale]
+	return f, nil
+}
+
+// defaultFormatter 未配置时使用的格式化器
+var defaultFormatter, _ = NewFormatter(FormatOptions{})
+
+// Locale 返回当前语言
+func (f *Formatter) Locale() Locale { return f.locale }
+
+// Units 返回当前单位制
+func (f *Formatter) Units() UnitSystem { return f.units }
+
+// Coordinates 返回当前坐标格式
+func (f *Formatter) Coordinates() CoordinateStyle { return f.coordinates }
+
+// localeText 各语言的显示文本
+type localeText struct {
+	meters      string
+	feet        string
+	compass     [8]string
+	fixQuality  [9]string
+	unknownFix  string
+	satellites  string
+	hdopQuality [6]string
+	active      string
+	warning     string
+	noSignal    string
+}
+
+var localeTexts = map[Locale]*localeText{
+	LocaleZhCN: {
+		meters:      "米",
+		feet:        "英尺",
+		compass:     [8]string{"北", "东北", "东", "东南", "南", "西南", "西", "西北"},
+		fixQuality:  [9]string{"无定位", "GPS定位", "差分GPS定位", "PPS定位", "RTK定位", "浮点RTK", "推算定位", "手动输入", "模拟定位"},
+		unknownFix:  "未知质量(%d)",
+		satellites:  "%d 颗卫星",
+		hdopQuality: [6]string{"优秀", "良好", "中等", "一般", "较差", "很差"},
+		active:      "定位有效",
+		warning:     "定位无效",
+		noSignal:    "未连接",
+	},
+	LocaleEnUS: {
+		meters:      "m",
+		feet:        "ft",
+		compass:     [8]string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"},
+		fixQuality:  [9]string{"No fix", "GPS fix", "DGPS fix", "PPS fix", "RTK fixed", "RTK float", "Dead reckoning", "Manual input", "Simulation"},
+		unknownFix:  "Unknown quality (%d)",
+		satellites:  "%d satellites",
+		hdopQuality: [6]string{"Excellent", "Good", "Moderate", "Fair", "Poor", "Very poor"},
+		active:      "ACTIVE",
+		warning:     "WARNING",
+		noSignal:    "DISCONNECTED",
+	},
+}
+
+// UTCTime 将HHMMSS.sss格式的UTC时间格式化为HH:MM:SS.sss
+func (f *Formatter) UTCTime(utcStr string) string {
+	if len(utcStr) < 6 {
+		return utcStr // 如果格式不正确，返回原始字符串
+	}
+	return fmt.Sprintf("%s:%s:%s", utcStr[0:2], utcStr[2:4], utcStr[4:])
+}
+
+// UTCDateTime 将UTC时刻格式化为YYYY-MM-DD HH:MM:SS.sss，闰秒23:59:60显示为23:59:59.999
+func (f *Formatter) UTCDateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000")
+}
+
+// Coordinate 格式化单个纬度或经度，direction为空时按符号推断半球。
+// UTM和MGRS需要完整的经纬度，单独格式化一个分量时使用十进制度。
+func (f *Formatter) Coordinate(decimal float64, isLatitude bool, direction string) string {
+	if direction == "" {
+		direction = hemisphere(decimal, isLatitude)
+	}
+	abs := math.Abs(decimal)
+
+	switch f.coordinates {
+	case CoordinatesDMS:
+		if decimal == 0.0 {
+			return "0°00'00.0\""
+		}
+		// 先按0.1秒取整，避免出现60.0秒
+		tenths := int64(math.Round(abs * 36000))
+		degrees := tenths / 36000
+		minutes := tenths % 36000 / 600
+		seconds := float64(tenths%600) / 10
+		return fmt.Sprintf("%d°%02d'%04.1f\"%s", degrees, minutes, seconds, direction)
+	case CoordinatesDDM:
+		tenThousandths := int64(math.Round(abs * 600000))
+		degrees := tenThousandths / 600000
+		minutes := float64(tenThousandths%600000) / 10000
+		return fmt.Sprintf("%d°%07.4f'%s", degrees, minutes, direction)
+	default:
+		return fmt.Sprintf("%.6f°%s", abs, direction)
+	}
+}
+
+// Position 按坐标格式格式化完整位置，UTM/MGRS无法投影（极区）时回退到十进制度
+func (f *Formatter) Position(lat, lon float64) string {
+	pos := LatLon{Lat: lat, Lon: lon}
+	switch f.coordinates {
+	case CoordinatesUTM:
+		if u, err := toUTM(pos); err == nil {
+			return u.String()
+		}
+	case CoordinatesMGRS:
+		if mgrs, err := toMGRS(pos); err == nil {
+			return mgrs
+		}
+	case CoordinatesDMS, CoordinatesDDM:
+		return f.Coordinate(lat, true, "") + " " + f.Coordinate(lon, false, "")
+	}
+	return fmt.Sprintf("%.6f°%s %.6f°%s",
+		math.Abs(lat), hemisphere(lat, true), math.Abs(lon), hemisphere(lon, false))
+}
+
+// hemisphere 根据符号返回半球字母
+func hemisphere(decimal float64, isLatitude bool) string {
+	switch {
+	case isLatitude && decimal < 0:
+		return "S"
+	case isLatitude:
+		return "N"
+	case decimal < 0:
+		return "W"
+	default:
+		return "E"
+	}
+}
+
+// Speed 按单位制格式化速度，speedMps单位为米/秒
+func (f *Formatter) Speed(speedMps float64) string {
+	switch f.units {
+	case UnitsImperial:
+		return fmt.Sprintf("%.2f mph", speedMps*mphPerMps)
+	case UnitsNautical:
+		return fmt.Sprintf("%.2f kn", speedMps/mpsPerKnot)
+	default:
+		return fmt.Sprintf("%.2f km/h", speedMps*3.6)
+	}
+}
+
+// Course 格式化航向并附带八方位描述
+func (f *Formatter) Course(course float64) string {
+	sector := int(math.Floor(math.Mod(course+22.5, 360)/45)) % 8
+	if sector < 0 {
+		sector += 8
+	}
+	return fmt.Sprintf("%.1f° (%s)", course, f.text.compass[sector])
+}
+
+// Altitude 按单位制格式化高度，altitude单位为米；海里制沿用米
+func (f *Formatter) Altitude(altitude float64) string {
+	if f.units == UnitsImperial {
+		return fmt.Sprintf("%.1f %s", altitude*feetPerMeter, f.text.feet)
+	}
+	return fmt.Sprintf("%.1f %s", altitude, f.text.meters)
+}
+
+// FixQuality 格式化GGA定位质量
+func (f *Formatter) FixQuality(quality int32) string {
+	if quality < 0 || int(quality) >= len(f.text.fixQuality) {
+		return fmt.Sprintf(f.text.unknownFix, quality)
+	}
+	return f.text.fixQuality[quality]
+}
+
+// SatelliteCount 格式化卫星数量
+func (f *Formatter) SatelliteCount(count int32) string {
+	return fmt.Sprintf(f.text.satellites, count)
+}
+
+// HDOP 格式化水平精度因子并附带等级描述
+func (f *Formatter) HDOP(hdop float64) string {
+	var level int
+	switch {
+	case hdop <= 1:
+		level = 0
+	case hdop <= 2:
+		level = 1
+	case hdop <= 5:
+		level = 2
+	case hdop <= 10:
+		level = 3
+	case hdop <= 20:
+		level = 4
+	default:
+		level = 5
+	}
+	return fmt.Sprintf("%.2f (%s)", hdop, f.text.hdopQuality[level])
+}
+
+// Status 按显示语言格式化接收机状态（gps_status_text），connected为false表示尚未收到RMC
+func (f *Formatter) Status(connected bool, rmcStatus string) string {
+	switch {
+	case !connected:
+		return f.text.noSignal
+	case rmcStatus == "A":
+		return f.text.active
+	default:
+		return f.text.warning
+	}
+}
@@ -0,0 +1,167 @@
+package driver
+
+import (
+	"math"
+	"testing"
+
+	"github.com/edgexfoundry/device-sdk-go/v4/run/config"
+)
+
+func TestToUTM(t *testing.T) {
+	tests := []struct {
+		pos               LatLon
+		zone              int
+		band              byte
+		easting, northing float64
+	}{
+		{LatLon{0, 0}, 31, 'N', 166021.44, 0},
+		{LatLon{0, 3}, 31, 'N', 500000, 0},
+		{LatLon{48.8583, 2.2945}, 31, 'U', 448251.90, 5411943.79},
+		{LatLon{-33.8568, 151.2153}, 56, 'H', 334900.57, 6252288.75},
+		{LatLon{60, 5}, 32, 'V', 276979.93, 6658157.20}, // 挪威特殊分带
+		{LatLon{78, 15}, 33, 'X', 500000, 8658369.59},   // 斯瓦尔巴特殊分带
+	}
+
+	for _, test := range tests {
+		u, err := toUTM(test.pos)
+		if err != nil {
+			t.Errorf("toUTM(%v) failed: %v", test.pos, err)
+			continue
+		}
+		if u.Zone != test.zone || u.Band != test.band {
+			t.Errorf("toUTM(%v) zone = %d%c, expected %d%c", test.pos, u.Zone, u.Band, test.zone, test.band)
+		}
+		if math.Abs(u.Easting-test.easting) > 0.01 || math.Abs(u.Northing-test.northing) > 0.01 {
+			t.Errorf("toUTM(%v) = %.2f %.2f, expected %.2f %.2f", test.pos, u.Easting, u.Northing, test.easting, test.northing)
+		}
+	}
+
+	if _, err := toUTM(LatLon{85, 0}); err == nil {
+		t.Error("toUTM at 85°N succeeded, expected error")
+	}
+
+	// 指定投影带时不按经度换带，东经6°在31带中与东经0°对称
+	if u, err := toUTMZone(LatLon{0, 6}, 31); err != nil || u.Zone != 31 || math.Abs(u.Easting-833978.56) > 0.01 {
+		t.Errorf("toUTMZone(0, 6, 31) = %+v, %v", u, err)
+	}
+	if _, err := toUTMZone(LatLon{0, 6}, 61); err == nil {
+		t.Error("toUTMZone with zone 61 succeeded, expected error")
+	}
+}
+
+func TestToMGRS(t *testing.T) {
+	tests := []struct {
+		pos      LatLon
+		expected string
+	}{
+		{LatLon{0, 0}, "31N AA 66021 00000"},
+		{LatLon{48.8583, 2.2945}, "31U DQ 48251 11943"},
+		{LatLon{-33.8568, 151.2153}, "56H LH 34900 52288"},
+		{LatLon{30.739479, 103.959134}, "48R VV 00360 01192"},
+	}
+
+	for _, test := range tests {
+		got, err := toMGRS(test.pos)
+		if err != nil {
+			t.Errorf("toMGRS(%v) failed: %v", test.pos, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("toMGRS(%v) = %s, expected %s", test.pos, got, test.expected)
+		}
+	}
+}
+
+func TestFormatterDefaults(t *testing.T) {
+	f, err := NewFormatter(FormatOptions{})
+	if err != nil {
+		t.Fatalf("NewFormatter failed: %v", err)
+	}
+
+	tests := []struct {
+		got, expected string
+	}{
+		{f.UTCTime("123456.00"), "12:34:56.00"},
+		{f.Coordinate(39.969056, true, "N"), "39°58'08.6\"N"},
+		{f.Coordinate(30.99999999, true, "N"), "31°00'00.0\"N"},
+		{f.Speed(20), "72.00 km/h"},
+		{f.Course(45), "45.0° (东北)"},
+		{f.Course(350), "350.0° (北)"},
+		{f.Altitude(123.45), "123.5 米"},
+		{f.FixQuality(4), "RTK定位"},
+		{f.FixQuality(9), "未知质量(9)"},
+		{f.SatelliteCount(8), "8 颗卫星"},
+		{f.HDOP(1.5), "1.50 (良好)"},
+		{f.Status(true, "A"), "定位有效"},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("got %q, expected %q", test.got, test.expected)
+		}
+	}
+}
+
+func TestFormatterOptions(t *testing.T) {
+	f, err := NewFormatter(FormatOptions{Locale: "en-US", Units: "imperial", Coordinates: "ddm"})
+	if err != nil {
+		t.Fatalf("NewFormatter failed: %v", err)
+	}
+
+	tests := []struct {
+		got, expected string
+	}{
+		{f.Coordinate(-33.5, true, ""), "33°30.0000'S"},
+		{f.Position(30.5, -104.25), "30°30.0000'N 104°15.0000'W"},
+		{f.Speed(10), "22.37 mph"},
+		{f.Altitude(100), "328.1 ft"},
+		{f.Course(200), "200.0° (S)"},
+		{f.FixQuality(5), "RTK float"},
+		{f.SatelliteCount(12), "12 satellites"},
+		{f.HDOP(25), "25.00 (Very poor)"},
+		{f.Status(false, ""), "DISCONNECTED"},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("got %q, expected %q", test.got, test.expected)
+		}
+	}
+
+	nautical, _ := NewFormatter(FormatOptions{Units: "nautical", Coordinates: "mgrs"})
+	if got := nautical.Speed(mpsPerKnot * 10); got != "10.00 kn" {
+		t.Errorf("nautical Speed = %q, expected 10.00 kn", got)
+	}
+	if got := nautical.Position(0, 0); got != "31N AA 66021 00000" {
+		t.Errorf("mgrs Position = %q", got)
+	}
+	if got := nautical.Position(89, 0); got != "89.000000°N 0.000000°E" {
+		t.Errorf("polar mgrs Position = %q, expected decimal fallback", got)
+	}
+
+	for _, opts := range []FormatOptions{{Locale: "fr-FR"}, {Units: "furlongs"}, {Coordinates: "geohash"}} {
+		if _, err := NewFormatter(opts); err == nil {
+			t.Errorf("NewFormatter(%+v) succeeded, expected error", opts)
+		}
+	}
+}
+
+func TestDriverFormatterConfig(t *testing.T) {
+	driver := newTestDriver(t)
+	if got := driver.FormatSpeed(36); got != "36.00 km/h" {
+		t.Errorf("default FormatSpeed = %q", got)
+	}
+
+	driver.formatBase = formatOptionsFromConfig(map[string]string{
+		configFormatLocale: "en-US",
+		configFormatUnits:  "nautical",
+	})
+	driver.processCustomConfigChanges(&config.GPSWritable{Format: config.FormatConfig{Units: "imperial"}})
+	if got := driver.FormatAltitude(10); got != "32.8 ft" {
+		t.Errorf("FormatAltitude after writable change = %q, expected 32.8 ft", got)
+	}
+
+	// 无效的可写配置不影响当前格式化器
+	driver.processCustomConfigChanges(&config.GPSWritable{Format: config.FormatConfig{Locale: "xx"}})
+	if got := driver.FormatSatelliteCount(3); got != "3 satellites" {
+		t.Errorf("FormatSatelliteCount after invalid change = %q", got)
+	}
+}
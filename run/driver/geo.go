@@ -0,0 +1,140 @@
+package driver
+
+import (
+	"math"
+)
+
+// earthRadius 地球平均半径（米）
+const earthRadius = 6371008.8
+
+// LatLon WGS84经纬度坐标（十进制度）
+type LatLon struct {
+	Lat float64
+	Lon float64
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// haversine 计算两点之间的大圆距离（米）
+func haversine(a, b LatLon) float64 {
+	lat1, lat2 := toRadians(a.Lat), toRadians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := toRadians(b.Lon - a.Lon)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// initialBearing 计算从a到b的初始方位角（度，0~360，真北为0）
+func initialBearing(a, b LatLon) float64 {
+	lat1, lat2 := toRadians(a.Lat), toRadians(b.Lat)
+	dLon := toRadians(b.Lon - a.Lon)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(toDegrees(math.Atan2(y, x))+360, 360)
+}
+
+// destination 计算从p沿方位角bearing行进dist米后到达的点
+func destination(p LatLon, bearing, dist float64) LatLon {
+	lat1, lon1 := toRadians(p.Lat), toRadians(p.Lon)
+	brng := toRadians(bearing)
+	d := dist / earthRadius
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(brng))
+	lon2 := lon1 + math.Atan2(math.Sin(brng)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return LatLon{Lat: toDegrees(lat2), Lon: math.Mod(toDegrees(lon2)+540, 360) - 180}
+}
+
+// offsetMeters 在局部平面近似下把p向北、向东各偏移若干米，适用于小范围偏移
+func offsetMeters(p LatLon, north, east float64) LatLon {
+	dLat := toDegrees(north / earthRadius)
+	dLon := toDegrees(east / (earthRadius * math.Cos(toRadians(p.Lat))))
+	return LatLon{Lat: p.Lat + dLat, Lon: p.Lon + dLon}
+}
+
+// vincenty 用Vincenty反解公式计算WGS84椭球面上两点间的距离（米），
+// 近对跖点不收敛时退回到haversine
+func vincenty(a, b LatLon) float64 {
+	const bAxis = wgs84A * (1 - wgs84F)
+	if a == b {
+		return 0
+	}
+
+	L := toRadians(b.Lon - a.Lon)
+	U1 := math.Atan((1 - wgs84F) * math.Tan(toRadians(a.Lat)))
+	U2 := math.Atan((1 - wgs84F) * math.Tan(toRadians(b.Lat)))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma := math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0
+		}
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha := 1 - sinAlpha*sinAlpha
+		cos2SigmaM := 0.0
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+		C := wgs84F / 16 * cos2Alpha * (4 + wgs84F*(4-3*cos2Alpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) > 1e-12 {
+			continue
+		}
+
+		u2 := cos2Alpha * (wgs84A*wgs84A - bAxis*bAxis) / (bAxis * bAxis)
+		A := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+		B := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+		deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		return bAxis * A * (sigma - deltaSigma)
+	}
+	return haversine(a, b)
+}
+
+// ecefToGeodetic 把WGS84地心地固坐标（米）转换为经纬度和椭球高（米），迭代求解纬度
+func ecefToGeodetic(x, y, z float64) (LatLon, float64) {
+	e2 := wgs84F * (2 - wgs84F)
+	lon := math.Atan2(y, x)
+	p := math.Hypot(x, y)
+	if p < 1e-6 {
+		// 位于极轴上时经度任意，纬度为±90°
+		b := wgs84A * (1 - wgs84F)
+		return LatLon{Lat: math.Copysign(90, z), Lon: 0}, math.Abs(z) - b
+	}
+
+	lat := math.Atan2(z, p*(1-e2))
+	var height float64
+	for i := 0; i < 10; i++ {
+		sinLat := math.Sin(lat)
+		n := wgs84A / math.Sqrt(1-e2*sinLat*sinLat)
+		height = p/math.Cos(lat) - n
+		lat = math.Atan2(z, p*(1-e2*n/(n+height)))
+	}
+	return LatLon{Lat: toDegrees(lat), Lon: toDegrees(lon)}, height
+}
+
+// geodeticToECEF 把经纬度和椭球高（米）转换为WGS84地心地固坐标（米）
+func geodeticToECEF(pos LatLon, height float64) (x, y, z float64) {
+	e2 := wgs84F * (2 - wgs84F)
+	lat, lon := toRadians(pos.Lat), toRadians(pos.Lon)
+	sinLat := math.Sin(lat)
+	n := wgs84A / math.Sqrt(1-e2*sinLat*sinLat)
+	x = (n + height) * math.Cos(lat) * math.Cos(lon)
+	y = (n + height) * math.Cos(lat) * math.Sin(lon)
+	z = (n*(1-e2) + height) * sinLat
+	return x, y, z
+}
@@ -0,0 +1,396 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+// 围栏事件类型
+const (
+	GeofenceEnter = "enter"
+	GeofenceExit  = "exit"
+	GeofenceDwell = "dwell"
+)
+
+// Geofence 地理围栏，Radius大于0时为圆形，否则为多边形
+type Geofence struct {
+	ID         string
+	Name       string
+	Center     LatLon        // 圆心
+	Radius     float64       // 圆形半径（米）
+	Rings      [][]LatLon    // 多边形：第一个为外环，其余为内环（洞）
+	Hysteresis float64       // 离开时须越出边界的距离（米），避免在边界附近反复进出
+	MinDwell   time.Duration // 进入或离开须持续的时间，短于该时间的越界被忽略
+	DwellTime  time.Duration // 停留超过该时间时发出dwell事件，0表示不发
+}
+
+// geofenceDefaults 未在围栏中单独设置时使用的参数
+type geofenceDefaults struct {
+	Hysteresis float64
+	MinDwell   time.Duration
+	DwellTime  time.Duration
+}
+
+// validate 校验围栏几何形状
+func (g *Geofence) validate() error {
+	if g.ID == "" {
+		return errors.New("围栏缺少ID")
+	}
+	if g.Hysteresis < 0 || g.MinDwell < 0 || g.DwellTime < 0 {
+		return fmt.Errorf("围栏%s的迟滞距离和停留时间不能为负", g.ID)
+	}
+	if g.Radius > 0 {
+		if !validLatLon(g.Center) {
+			return fmt.Errorf("围栏%s的圆心无效", g.ID)
+		}
+		return nil
+	}
+	if len(g.Rings) == 0 {
+		return fmt.Errorf("围栏%s既不是圆形也不是多边形", g.ID)
+	}
+	for _, ring := range g.Rings {
+		if len(ring) < 3 {
+			return fmt.Errorf("围栏%s的多边形至少需要3个顶点", g.ID)
+		}
+		for _, p := range ring {
+			if !validLatLon(p) {
+				return fmt.Errorf("围栏%s的顶点坐标无效", g.ID)
+			}
+		}
+	}
+	return nil
+}
+
+func validLatLon(p LatLon) bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// Distance 返回p到围栏边界的有符号距离（米），在围栏内为负。
+// 多边形在以p为原点的局部平面内计算，适用于场站级别的围栏。
+func (g *Geofence) Distance(p LatLon) float64 {
+	if g.Radius > 0 {
+		return haversine(g.Center, p) - g.Radius
+	}
+
+	kx := toRadians(1) * earthRadius * math.Cos(toRadians(p.Lat))
+	ky := toRadians(1) * earthRadius
+	inside := false
+	nearest := math.Inf(1)
+	for i, ring := range g.Rings {
+		in := false
+		for j := range ring {
+			a, b := ring[j], ring[(j+1)%len(ring)]
+			ax, ay := (a.Lon-p.Lon)*kx, (a.Lat-p.Lat)*ky
+			bx, by := (b.Lon-p.Lon)*kx, (b.Lat-p.Lat)*ky
+			if (ay > 0) != (by > 0) && ax+(0-ay)*(bx-ax)/(by-ay) > 0 {
+				in = !in
+			}
+			nearest = math.Min(nearest, segmentDistance(ax, ay, bx, by))
+		}
+		if i == 0 {
+			inside = in
+		} else if in {
+			inside = false
+		}
+	}
+	if inside {
+		return -nearest
+	}
+	return nearest
+}
+
+// segmentDistance 原点到线段ab的距离
+func segmentDistance(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// GeofenceSet 所有设备共用的围栏集合，可并发使用
+type GeofenceSet struct {
+	mutex  sync.RWMutex
+	fences map[string]*Geofence
+}
+
+// NewGeofenceSet 创建围栏集合
+func NewGeofenceSet() *GeofenceSet {
+	return &GeofenceSet{fences: make(map[string]*Geofence)}
+}
+
+// All 按ID顺序返回所有围栏
+func (s *GeofenceSet) All() []*Geofence {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	fences := make([]*Geofence, 0, len(s.fences))
+	for _, g := range s.fences {
+		fences = append(fences, g)
+	}
+	sort.Slice(fences, func(i, j int) bool { return fences[i].ID < fences[j].ID })
+	return fences
+}
+
+// Get 按ID查找围栏
+func (s *GeofenceSet) Get(id string) (*Geofence, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	g, ok := s.fences[id]
+	return g, ok
+}
+
+// Put 添加或替换围栏
+func (s *GeofenceSet) Put(fences ...*Geofence) error {
+	for _, g := range fences {
+		if err := g.validate(); err != nil {
+			return err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, g := range fences {
+		s.fences[g.ID] = g
+	}
+	return nil
+}
+
+// Replace 用fences替换全部围栏
+func (s *GeofenceSet) Replace(fences []*Geofence) error {
+	replaced := make(map[string]*Geofence, len(fences))
+	for _, g := range fences {
+		if err := g.validate(); err != nil {
+			return err
+		}
+		replaced[g.ID] = g
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fences = replaced
+	return nil
+}
+
+// Clone 复制围栏集合，围栏本身共用
+func (s *GeofenceSet) Clone() *GeofenceSet {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	clone := make(map[string]*Geofence, len(s.fences))
+	for id, g := range s.fences {
+		clone[id] = g
+	}
+	return &GeofenceSet{fences: clone}
+}
+
+// Delete 删除围栏，围栏不存在时返回false
+func (s *GeofenceSet) Delete(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.fences[id]
+	delete(s.fences, id)
+	return ok
+}
+
+// GeofenceEvent geofence_event读数的内容，每次越界或停留超时产生一条
+type GeofenceEvent struct {
+	FenceID      string  `json:"fenceId"`
+	FenceName    string  `json:"fenceName,omitempty"`
+	Transition   string  `json:"transition"` // enter/exit/dwell
+	Time         string  `json:"time"`       // 定位时间，RFC3339
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	Distance     float64 `json:"distance"`               // 到边界的有符号距离（米），围栏内为负
+	DwellSeconds float64 `json:"dwellSeconds,omitempty"` // exit和dwell事件：在围栏内停留的时长
+}
+
+// GeofenceMember 设备当前所在的一个围栏
+type GeofenceMember struct {
+	FenceID      string  `json:"fenceId"`
+	FenceName    string  `json:"fenceName,omitempty"`
+	Since        string  `json:"since"`
+	DwellSeconds float64 `json:"dwellSeconds"`
+}
+
+// GeofenceMembership geofence_membership读数的内容
+type GeofenceMembership struct {
+	Time   string           `json:"time,omitempty"` // 最近一次评估的定位时间
+	Fences []GeofenceMember `json:"fences"`
+}
+
+// geofenceState 设备相对一个围栏的状态
+type geofenceState struct {
+	inside    bool
+	since     time.Time // 进入当前状态的时间
+	pending   bool      // 已越界但未满MinDwell
+	crossedAt time.Time
+	dwellSent bool
+}
+
+// GeofenceTracker 单台设备的围栏状态，在每个新的定位结果上更新
+type GeofenceTracker struct {
+	mutex  sync.Mutex
+	states map[string]*geofenceState
+	names  map[string]string
+	last   time.Time
+	event  *GeofenceEvent // 最近一条事件
+}
+
+// NewGeofenceTracker 创建围栏状态，初始时设备不在任何围栏内
+func NewGeofenceTracker() *GeofenceTracker {
+	return &GeofenceTracker{states: make(map[string]*geofenceState), names: make(map[string]string)}
+}
+
+// Update 根据定位结果更新各围栏的状态，返回产生的事件。
+// 设备在围栏外时越过边界即视为进入，在围栏内时须越出Hysteresis米才视为离开；
+// 两种越界都要持续MinDwell才确认，事件时间为确认时的定位时间。
+func (t *GeofenceTracker) Update(fences []*Geofence, fix Fix) []GeofenceEvent {
+	p := LatLon{fix.Latitude, fix.Longitude}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.last = fix.Time
+
+	current := make(map[string]bool, len(fences))
+	var events []GeofenceEvent
+	for _, g := range fences {
+		current[g.ID] = true
+		t.names[g.ID] = g.Name
+		st, ok := t.states[g.ID]
+		if !ok {
+			st = &geofenceState{since: fix.Time}
+			t.states[g.ID] = st
+		}
+
+		d := g.Distance(p)
+		event := func(transition string, dwell time.Duration) {
+			events = append(events, GeofenceEvent{
+				FenceID:      g.ID,
+				FenceName:    g.Name,
+				Transition:   transition,
+				Time:         fix.Time.UTC().Format(time.RFC3339Nano),
+				Latitude:     fix.Latitude,
+				Longitude:    fix.Longitude,
+				Distance:     math.Round(d*100) / 100,
+				DwellSeconds: dwell.Seconds(),
+			})
+		}
+
+		inside := d <= 0
+		if st.inside {
+			inside = d <= g.Hysteresis
+		}
+		switch {
+		case inside == st.inside:
+			st.pending = false
+		case !st.pending:
+			st.pending, st.crossedAt = true, fix.Time
+		}
+
+		if st.pending && fix.Time.Sub(st.crossedAt) >= g.MinDwell {
+			dwell := st.crossedAt.Sub(st.since)
+			st.inside, st.since, st.pending, st.dwellSent = inside, st.crossedAt, false, false
+			if inside {
+				event(GeofenceEnter, 0)
+			} else {
+				event(GeofenceExit, dwell)
+			}
+		}
+
+		if st.inside && g.DwellTime > 0 && !st.dwellSent && fix.Time.Sub(st.since) >= g.DwellTime {
+			st.dwellSent = true
+			event(GeofenceDwell, fix.Time.Sub(st.since))
+		}
+	}
+
+	// 已删除的围栏不再跟踪
+	for id := range t.states {
+		if !current[id] {
+			delete(t.states, id)
+			delete(t.names, id)
+		}
+	}
+
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		t.event = &last
+	}
+	return events
+}
+
+// LastEvent 返回最近一条事件
+func (t *GeofenceTracker) LastEvent() (GeofenceEvent, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.event == nil {
+		return GeofenceEvent{}, false
+	}
+	return *t.event, true
+}
+
+// Membership 返回设备当前所在的围栏，按ID排序
+func (t *GeofenceTracker) Membership() GeofenceMembership {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	membership := GeofenceMembership{Fences: []GeofenceMember{}}
+	if t.last.IsZero() {
+		return membership
+	}
+	membership.Time = t.last.UTC().Format(time.RFC3339Nano)
+	for id, st := range t.states {
+		if !st.inside {
+			continue
+		}
+		membership.Fences = append(membership.Fences, GeofenceMember{
+			FenceID:      id,
+			FenceName:    t.names[id],
+			Since:        st.since.UTC().Format(time.RFC3339Nano),
+			DwellSeconds: t.last.Sub(st.since).Seconds(),
+		})
+	}
+	sort.Slice(membership.Fences, func(i, j int) bool { return membership.Fences[i].FenceID < membership.Fences[j].FenceID })
+	return membership
+}
+
+// checkGeofences 用新的定位结果更新设备的围栏状态，每个事件作为geofence_event异步上报
+func (s *Driver) checkGeofences(deviceName string, rcv *receiver, fix Fix) {
+	for _, event := range rcv.fences.Update(s.geofences.All(), fix) {
+		s.lc.Infof("设备%s %s围栏%s", deviceName, event.Transition, event.FenceID)
+		cv, err := dsModels.NewCommandValueWithOrigin("geofence_event", common.ValueTypeObject, event, fix.Time.UnixNano())
+		if err != nil {
+			s.lc.Errorf("创建geofence_event读数失败: %v", err)
+			continue
+		}
+		select {
+		case s.asyncCh <- &dsModels.AsyncValues{DeviceName: deviceName, SourceName: "geofence_event", CommandValues: []*dsModels.CommandValue{cv}}:
+		case <-rcv.gps.Done():
+			return
+		}
+	}
+}
+
+// getGeofenceEvent 获取最近一条围栏事件
+func (s *Driver) getGeofenceEvent(tracker *GeofenceTracker, req dsModels.CommandRequest) *dsModels.CommandValue {
+	event, ok := tracker.LastEvent()
+	if !ok {
+		return nil
+	}
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeObject, event)
+	return cv
+}
+
+// getGeofenceMembership 获取设备当前所在的围栏
+func (s *Driver) getGeofenceMembership(tracker *GeofenceTracker, req dsModels.CommandRequest) *dsModels.CommandValue {
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeObject, tracker.Membership())
+	return cv
+}
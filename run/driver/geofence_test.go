@@ -0,0 +1,274 @@
+package driver
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/labstack/echo/v4"
+)
+
+// squareFence 以center为中心、边长2*half米的正方形围栏
+func squareFence(id string, center LatLon, half float64) *Geofence {
+	return &Geofence{ID: id, Rings: [][]LatLon{{
+		offsetMeters(center, -half, -half),
+		offsetMeters(center, -half, half),
+		offsetMeters(center, half, half),
+		offsetMeters(center, half, -half),
+	}}}
+}
+
+func TestGeofenceDistance(t *testing.T) {
+	center := LatLon{30, 104}
+	circle := &Geofence{ID: "circle", Center: center, Radius: 100}
+	square := squareFence("square", center, 100)
+	// 中间挖去边长40米的洞
+	withHole := squareFence("hole", center, 100)
+	withHole.Rings = append(withHole.Rings, squareFence("", center, 20).Rings[0])
+
+	tests := []struct {
+		fence       *Geofence
+		north, east float64
+		expected    float64
+	}{
+		{circle, 0, 0, -100},
+		{circle, 0, 150, 50},
+		{square, 0, 0, -100},
+		{square, 90, 0, -10},
+		{square, 0, 130, 30},
+		{square, 130, 130, 30 * math.Sqrt2},
+		{withHole, 0, 0, 20},
+		{withHole, 0, 50, -30},
+	}
+	for _, test := range tests {
+		got := test.fence.Distance(offsetMeters(center, test.north, test.east))
+		if math.Abs(got-test.expected) > 0.5 {
+			t.Errorf("%s.Distance(%v N, %v E) = %.2f, expected %.2f", test.fence.ID, test.north, test.east, got, test.expected)
+		}
+	}
+}
+
+func TestGeofenceTracker(t *testing.T) {
+	center := LatLon{30, 104}
+	fence := &Geofence{ID: "depot", Name: "一号场站", Center: center, Radius: 100, Hysteresis: 10, MinDwell: 2 * time.Second, DwellTime: 5 * time.Second}
+	fences := []*Geofence{fence}
+	tracker := NewGeofenceTracker()
+
+	// 每秒一个定位，east为相对圆心向东的距离
+	path := []struct {
+		east     float64
+		expected string
+	}{
+		{150, ""},
+		{95, ""},  // 第一次越界
+		{150, ""}, // 未满MinDwell即返回，忽略
+		{95, ""},  // 再次越界
+		{90, ""},
+		{80, "enter"}, // 持续2秒，确认进入，进入时间为越界时间
+		{105, ""},     // 越出边界但在迟滞范围内
+		{100, ""},
+		{50, "dwell"}, // 从越界时算起停留5秒
+		{50, ""},      // dwell只发一次
+		{50, ""},
+		{120, ""}, // 越出迟滞范围
+		{120, ""},
+		{120, "exit"}, // 持续2秒，确认离开
+		{120, ""},
+	}
+	start := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	for i, step := range path {
+		fix := trackFix(0, 0)
+		fix.Time = start.Add(time.Duration(i) * time.Second)
+		p := offsetMeters(center, 0, step.east)
+		fix.Latitude, fix.Longitude = p.Lat, p.Lon
+
+		events := tracker.Update(fences, fix)
+		got := ""
+		if len(events) > 0 {
+			got = events[0].Transition
+		}
+		if got != step.expected || len(events) > 1 {
+			t.Errorf("step %d (%vm): events = %+v, expected %q", i, step.east, events, step.expected)
+		}
+		if got == "exit" && events[0].DwellSeconds != 8 {
+			t.Errorf("exit dwell = %vs, expected 8s", events[0].DwellSeconds)
+		}
+
+		membership := tracker.Membership()
+		if inside := i >= 5 && i < 13; inside != (len(membership.Fences) == 1) {
+			t.Errorf("step %d: membership = %+v", i, membership)
+		}
+	}
+
+	if event, ok := tracker.LastEvent(); !ok || event.Transition != GeofenceExit || event.FenceName != "一号场站" {
+		t.Errorf("LastEvent() = %+v, %v", event, ok)
+	}
+
+	// 删除围栏后不再跟踪
+	tracker.Update(nil, trackFix(30, 0))
+	if len(tracker.states) != 0 {
+		t.Errorf("states after fence removal = %v", tracker.states)
+	}
+}
+
+func TestParseGeofences(t *testing.T) {
+	defaults := geofenceDefaults{Hysteresis: 10, MinDwell: 3 * time.Second}
+	data := []byte(`{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "id": "depot-1", "properties": {"name": "一号场站", "hysteresis": 5, "dwell": "10m"},
+			 "geometry": {"type": "Polygon", "coordinates": [[[104.0, 30.0], [104.001, 30.0], [104.001, 30.001], [104.0, 30.001], [104.0, 30.0]]]}},
+			{"type": "Feature", "properties": {"id": "gate", "radius": 25},
+			 "geometry": {"type": "Point", "coordinates": [104.002, 30.002]}}
+		]
+	}`)
+	fences, err := parseGeofences(data, defaults)
+	if err != nil {
+		t.Fatalf("parseGeofences failed: %v", err)
+	}
+	depot, gate := fences[0], fences[1]
+	if depot.ID != "depot-1" || len(depot.Rings[0]) != 4 || depot.Hysteresis != 5 || depot.DwellTime != 10*time.Minute || depot.MinDwell != 3*time.Second {
+		t.Errorf("depot = %+v", depot)
+	}
+	if gate.ID != "gate" || gate.Radius != 25 || gate.Center != (LatLon{30.002, 104.002}) || gate.Hysteresis != 10 {
+		t.Errorf("gate = %+v", gate)
+	}
+
+	// 转换回GeoJSON后再解析得到相同的围栏
+	for _, fence := range fences {
+		again, err := parseGeofences(mustJSON(t, geofenceFeatureOf(fence)), geofenceDefaults{})
+		if err != nil || len(again) != 1 {
+			t.Fatalf("round trip of %s: %v", fence.ID, err)
+		}
+		if got, expected := mustJSON(t, again[0]), mustJSON(t, fence); string(got) != string(expected) {
+			t.Errorf("round trip of %s = %s, expected %s", fence.ID, got, expected)
+		}
+	}
+
+	for _, bad := range []string{
+		`{"type": "Point", "coordinates": [104, 30]}`,
+		`{"type": "Feature", "properties": {"id": "c"}, "geometry": {"type": "Point", "coordinates": [104, 30]}}`,
+		`{"type": "Feature", "properties": {"id": "p"}, "geometry": {"type": "Polygon", "coordinates": [[[104, 30], [104.1, 30]]]}}`,
+		`{"type": "Feature", "properties": {"radius": 10}, "geometry": {"type": "Point", "coordinates": [104, 30]}}`,
+		`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"id": "a", "radius": 10}, "geometry": {"type": "Point", "coordinates": [104, 30]}},
+			{"type": "Feature", "properties": {"id": "a", "radius": 20}, "geometry": {"type": "Point", "coordinates": [104, 30]}}]}`,
+	} {
+		if _, err := parseGeofences([]byte(bad), defaults); err == nil {
+			t.Errorf("parseGeofences(%s) succeeded, expected error", bad)
+		}
+	}
+}
+
+func TestDriverGeofences(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "geofences.json")
+	configs := map[string]string{configGeofenceFile: file, configGeofenceMinDwell: "0"}
+	driver, asyncCh := newTestDriverWithConfig(t, configs, models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	e := echo.New()
+	e.GET(GeofencesRoute, driver.handleListGeofences)
+	e.POST(GeofencesRoute, driver.handleAddGeofences)
+	e.GET(GeofenceRoute, driver.handleGetGeofence)
+	e.PUT(GeofenceRoute, driver.handlePutGeofence)
+	e.DELETE(GeofenceRoute, driver.handleDeleteGeofence)
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	depot := `{"type": "Feature", "properties": {"id": "depot", "radius": 100}, "geometry": {"type": "Point", "coordinates": [104.0, 30.0]}}`
+	if rec := request(http.MethodPost, GeofencesRoute, depot); rec.Code != http.StatusCreated {
+		t.Fatalf("POST = %d %s", rec.Code, rec.Body.String())
+	}
+	if saved, err := loadGeofenceFile(file, geofenceDefaults{}); err != nil || len(saved) != 1 || saved[0].ID != "depot" {
+		t.Errorf("saved geofences = %v, %v", saved, err)
+	}
+
+	// 模拟接收机在围栏内，下一个定位即上报进入事件
+	for received := false; !received; {
+		select {
+		case values := <-asyncCh:
+			if values.SourceName == "motion_state" || values.SourceName == "gnss_integrity" {
+				continue
+			}
+			event, ok := values.CommandValues[0].Value.(GeofenceEvent)
+			if values.SourceName != "geofence_event" || !ok || event.FenceID != "depot" || event.Transition != GeofenceEnter {
+				t.Errorf("async values = %+v", values.CommandValues[0])
+			}
+			received = true
+		case <-time.After(3 * time.Second):
+			t.Fatal("等待超时: geofence_event")
+		}
+	}
+	membership, ok := readResource(t, driver, "GPS-A", "geofence_membership").Value.(GeofenceMembership)
+	if !ok || len(membership.Fences) != 1 || membership.Fences[0].FenceID != "depot" {
+		t.Errorf("membership = %+v", membership)
+	}
+
+	if rec := request(http.MethodGet, GeofencesRoute, ""); !strings.Contains(rec.Body.String(), `"id":"depot"`) {
+		t.Errorf("GET geofences = %s", rec.Body.String())
+	}
+	if rec := request(http.MethodGet, "/api/v3/gps/geofences/depot", ""); rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentType) != contentTypeGeoJSON {
+		t.Errorf("GET geofence = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := request(http.MethodPut, "/api/v3/gps/geofences/gate", depot); rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT with mismatched ID = %d, expected 400", rec.Code)
+	}
+	if rec := request(http.MethodDelete, "/api/v3/gps/geofences/depot", ""); rec.Code != http.StatusOK {
+		t.Errorf("DELETE = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := request(http.MethodDelete, "/api/v3/gps/geofences/depot", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d, expected 404", rec.Code)
+	}
+	if saved, _ := loadGeofenceFile(file, geofenceDefaults{}); len(saved) != 0 {
+		t.Errorf("saved geofences after DELETE = %v", saved)
+	}
+}
+
+func TestDriverGeofencesSaveFailure(t *testing.T) {
+	// 围栏文件所在目录不存在，保存失败
+	dir := filepath.Join(t.TempDir(), "missing")
+	driver, _ := newTestDriverWithConfig(t, map[string]string{configGeofenceFile: filepath.Join(dir, "geofences.json")})
+
+	e := echo.New()
+	e.POST(GeofencesRoute, driver.handleAddGeofences)
+	e.DELETE(GeofenceRoute, driver.handleDeleteGeofence)
+	depot := `{"type": "Feature", "properties": {"id": "depot", "radius": 100}, "geometry": {"type": "Point", "coordinates": [104.0, 30.0]}}`
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, GeofencesRoute, strings.NewReader(depot)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("POST = %d %s, expected 500", rec.Code, rec.Body.String())
+	}
+	if fences := driver.geofences.All(); len(fences) != 0 {
+		t.Errorf("geofences after failed save = %v", fences)
+	}
+
+	// 目录创建后再保存成功，删除失败时围栏保留
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, GeofencesRoute, strings.NewReader(depot)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST = %d %s", rec.Code, rec.Body.String())
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v3/gps/geofences/depot", nil))
+	if _, ok := driver.geofences.Get("depot"); rec.Code != http.StatusInternalServerError || !ok {
+		t.Errorf("DELETE = %d, depot kept = %v", rec.Code, ok)
+	}
+}
@@ -0,0 +1,377 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-sdk-go/v4/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/dtos/common"
+	"github.com/labstack/echo/v4"
+)
+
+// 围栏管理接口。围栏以GeoJSON Feature表示：Polygon为多边形围栏，
+// Point加上properties.radius（米）为圆形围栏。properties中的其他字段：
+//
+//	id          围栏ID，必填（也可以放在Feature的id中）
+//	name        名称
+//	hysteresis  迟滞距离（米），不填使用GeofenceHysteresis
+//	minDwell    越界确认时间，如"5s"，不填使用GeofenceMinDwell
+//	dwell       停留事件时间，如"10m"，不填使用GeofenceDwellTime
+//
+// GeofencesRoute支持GET（FeatureCollection）、POST（添加或替换Feature/FeatureCollection中的围栏）
+// 和PUT（用FeatureCollection替换全部围栏）；GeofenceRoute支持单个围栏的GET、PUT和DELETE。
+const (
+	GeofencesRoute = "/api/v3/gps/geofences"
+	GeofenceRoute  = "/api/v3/gps/geofences/:id"
+)
+
+// geofenceProperties 围栏Feature的properties
+type geofenceProperties struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name,omitempty"`
+	Radius     float64  `json:"radius,omitempty"`
+	Hysteresis *float64 `json:"hysteresis,omitempty"`
+	MinDwell   string   `json:"minDwell,omitempty"`
+	Dwell      string   `json:"dwell,omitempty"`
+}
+
+// geofenceFeature 围栏的GeoJSON Feature
+type geofenceFeature struct {
+	Type       string             `json:"type"`
+	ID         string             `json:"id,omitempty"`
+	Properties geofenceProperties `json:"properties"`
+	Geometry   *geofenceGeometry  `json:"geometry"`
+}
+
+// geofenceGeometry 围栏的几何对象，坐标按类型延迟解析
+type geofenceGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// geofenceDocument 解析时同时接受Feature和FeatureCollection
+type geofenceDocument struct {
+	geofenceFeature
+	Features []geofenceFeature `json:"features"`
+}
+
+// parseGeofences 解析GeoJSON Feature或FeatureCollection，未单独设置的参数取defaults
+func parseGeofences(data []byte, defaults geofenceDefaults) ([]*Geofence, error) {
+	var doc geofenceDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析围栏GeoJSON失败: %w", err)
+	}
+
+	var features []geofenceFeature
+	switch doc.Type {
+	case "FeatureCollection":
+		features = doc.Features
+	case "Feature":
+		features = []geofenceFeature{doc.geofenceFeature}
+	default:
+		return nil, fmt.Errorf("围栏须为Feature或FeatureCollection: %s", doc.Type)
+	}
+
+	fences := make([]*Geofence, 0, len(features))
+	seen := make(map[string]bool, len(features))
+	for _, feature := range features {
+		g, err := geofenceFromFeature(feature, defaults)
+		if err != nil {
+			return nil, err
+		}
+		if seen[g.ID] {
+			return nil, fmt.Errorf("围栏ID重复: %s", g.ID)
+		}
+		seen[g.ID] = true
+		fences = append(fences, g)
+	}
+	return fences, nil
+}
+
+// geofenceFromFeature 将Feature转换为围栏
+func geofenceFromFeature(feature geofenceFeature, defaults geofenceDefaults) (*Geofence, error) {
+	props := feature.Properties
+	g := &Geofence{
+		ID:         props.ID,
+		Name:       props.Name,
+		Hysteresis: defaults.Hysteresis,
+		MinDwell:   defaults.MinDwell,
+		DwellTime:  defaults.DwellTime,
+	}
+	if g.ID == "" {
+		g.ID = feature.ID
+	}
+	if props.Hysteresis != nil {
+		g.Hysteresis = *props.Hysteresis
+	}
+	var err error
+	if props.MinDwell != "" {
+		if g.MinDwell, err = time.ParseDuration(props.MinDwell); err != nil {
+			return nil, fmt.Errorf("围栏%s的minDwell无效: %s", g.ID, props.MinDwell)
+		}
+	}
+	if props.Dwell != "" {
+		if g.DwellTime, err = time.ParseDuration(props.Dwell); err != nil {
+			return nil, fmt.Errorf("围栏%s的dwell无效: %s", g.ID, props.Dwell)
+		}
+	}
+
+	if feature.Geometry == nil {
+		return nil, fmt.Errorf("围栏%s缺少geometry", g.ID)
+	}
+	switch feature.Geometry.Type {
+	case "Point":
+		var coords []float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &coords); err != nil || len(coords) < 2 {
+			return nil, fmt.Errorf("围栏%s的圆心坐标无效", g.ID)
+		}
+		if props.Radius <= 0 {
+			return nil, fmt.Errorf("圆形围栏%s须设置大于0的radius", g.ID)
+		}
+		g.Center, g.Radius = LatLon{Lat: coords[1], Lon: coords[0]}, props.Radius
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &rings); err != nil {
+			return nil, fmt.Errorf("围栏%s的多边形坐标无效", g.ID)
+		}
+		for _, ring := range rings {
+			points := geoJSONCoords(ring)
+			// GeoJSON的环首尾相同，去掉重复的最后一个点
+			if n := len(points); n > 1 && points[0] == points[n-1] {
+				points = points[:n-1]
+			}
+			g.Rings = append(g.Rings, points)
+		}
+	default:
+		return nil, fmt.Errorf("围栏%s不支持的几何类型: %s", g.ID, feature.Geometry.Type)
+	}
+
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// geofenceFeatureOf 将围栏转换为Feature，参数都显式写出
+func geofenceFeatureOf(g *Geofence) geofenceFeature {
+	hysteresis := g.Hysteresis
+	feature := geofenceFeature{
+		Type: "Feature",
+		ID:   g.ID,
+		Properties: geofenceProperties{
+			ID:         g.ID,
+			Name:       g.Name,
+			Hysteresis: &hysteresis,
+			MinDwell:   g.MinDwell.String(),
+			Dwell:      g.DwellTime.String(),
+		},
+	}
+
+	var coordinates any
+	if g.Radius > 0 {
+		feature.Properties.Radius = g.Radius
+		feature.Geometry = &geofenceGeometry{Type: "Point"}
+		coordinates = []float64{g.Center.Lon, g.Center.Lat}
+	} else {
+		feature.Geometry = &geofenceGeometry{Type: "Polygon"}
+		rings := make([][][]float64, 0, len(g.Rings))
+		for _, ring := range g.Rings {
+			coords := make([][]float64, 0, len(ring)+1)
+			for _, p := range ring {
+				coords = append(coords, []float64{p.Lon, p.Lat})
+			}
+			rings = append(rings, append(coords, coords[0]))
+		}
+		coordinates = rings
+	}
+	feature.Geometry.Coordinates, _ = json.Marshal(coordinates)
+	return feature
+}
+
+// geofenceCollection 将围栏转换为FeatureCollection
+func geofenceCollection(fences []*Geofence) geofenceDocument {
+	doc := geofenceDocument{geofenceFeature: geofenceFeature{Type: "FeatureCollection"}, Features: []geofenceFeature{}}
+	for _, g := range fences {
+		doc.Features = append(doc.Features, geofenceFeatureOf(g))
+	}
+	return doc
+}
+
+// MarshalJSON FeatureCollection只输出type和features
+func (d geofenceDocument) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type     string            `json:"type"`
+		Features []geofenceFeature `json:"features"`
+	}{d.Type, d.Features})
+}
+
+// loadGeofenceFile 从GeoJSON文件加载围栏，文件不存在时返回空集合
+func loadGeofenceFile(path string, defaults geofenceDefaults) ([]*Geofence, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取围栏文件失败: %w", err)
+	}
+	return parseGeofences(data, defaults)
+}
+
+// saveGeofenceFile 将围栏写回GeoJSON文件
+func saveGeofenceFile(path string, fences []*Geofence) error {
+	data, err := json.MarshalIndent(geofenceCollection(fences), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("保存围栏文件失败: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("保存围栏文件失败: %w", err)
+	}
+	return nil
+}
+
+// initGeofences 创建围栏集合，配置了GeofenceFile时从文件加载
+func (s *Driver) initGeofences() error {
+	s.geofences = NewGeofenceSet()
+	if s.config.GeofenceFile == "" {
+		return nil
+	}
+	fences, err := loadGeofenceFile(s.config.GeofenceFile, s.config.Geofence)
+	if err != nil {
+		return err
+	}
+	s.lc.Infof("从%s加载了%d个围栏", s.config.GeofenceFile, len(fences))
+	return s.geofences.Replace(fences)
+}
+
+// addGeofenceRoutes 注册围栏管理接口
+func (s *Driver) addGeofenceRoutes() error {
+	routes := []struct {
+		route   string
+		method  string
+		handler echo.HandlerFunc
+	}{
+		{GeofencesRoute, http.MethodGet, s.handleListGeofences},
+		{GeofencesRoute, http.MethodPost, s.handleAddGeofences},
+		{GeofencesRoute, http.MethodPut, s.handleReplaceGeofences},
+		{GeofenceRoute, http.MethodGet, s.handleGetGeofence},
+		{GeofenceRoute, http.MethodPut, s.handlePutGeofence},
+		{GeofenceRoute, http.MethodDelete, s.handleDeleteGeofence},
+	}
+	for _, r := range routes {
+		if err := s.sdk.AddCustomRoute(r.route, interfaces.Authenticated, r.handler, r.method); err != nil {
+			return fmt.Errorf("注册围栏接口%s %s失败: %w", r.method, r.route, err)
+		}
+	}
+	return nil
+}
+
+// updateGeofences 在锁内修改围栏集合的副本，配置了GeofenceFile时先保存到文件，
+// 成功后才替换正在使用的集合，保存失败时内存和文件保持一致
+func (s *Driver) updateGeofences(update func(fences *GeofenceSet) error) error {
+	s.geofenceLock.Lock()
+	defer s.geofenceLock.Unlock()
+
+	staged := s.geofences.Clone()
+	if err := update(staged); err != nil {
+		return err
+	}
+	fences := staged.All()
+	if s.config.GeofenceFile != "" {
+		if err := saveGeofenceFile(s.config.GeofenceFile, fences); err != nil {
+			return err
+		}
+	}
+	return s.geofences.Replace(fences)
+}
+
+// readGeofences 解析请求体中的围栏
+func (s *Driver) readGeofences(c echo.Context) ([]*Geofence, error) {
+	data, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseGeofences(data, s.config.Geofence)
+}
+
+// geofenceError 返回错误响应
+func geofenceError(c echo.Context, status int, err error) error {
+	return c.JSON(status, common.NewBaseResponse("", err.Error(), status))
+}
+
+func (s *Driver) handleListGeofences(c echo.Context) error {
+	return jsonBlob(c, contentTypeGeoJSON, geofenceCollection(s.geofences.All()))
+}
+
+func (s *Driver) handleAddGeofences(c echo.Context) error {
+	fences, err := s.readGeofences(c)
+	if err != nil {
+		return geofenceError(c, http.StatusBadRequest, err)
+	}
+	if err := s.updateGeofences(func(set *GeofenceSet) error { return set.Put(fences...) }); err != nil {
+		return geofenceError(c, http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusCreated, common.NewBaseResponse("", fmt.Sprintf("已保存%d个围栏", len(fences)), http.StatusCreated))
+}
+
+func (s *Driver) handleReplaceGeofences(c echo.Context) error {
+	fences, err := s.readGeofences(c)
+	if err != nil {
+		return geofenceError(c, http.StatusBadRequest, err)
+	}
+	if err := s.updateGeofences(func(set *GeofenceSet) error { return set.Replace(fences) }); err != nil {
+		return geofenceError(c, http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, common.NewBaseResponse("", fmt.Sprintf("已替换为%d个围栏", len(fences)), http.StatusOK))
+}
+
+func (s *Driver) handleGetGeofence(c echo.Context) error {
+	g, ok := s.geofences.Get(c.Param("id"))
+	if !ok {
+		return geofenceError(c, http.StatusNotFound, fmt.Errorf("围栏%s不存在", c.Param("id")))
+	}
+	return jsonBlob(c, contentTypeGeoJSON, geofenceFeatureOf(g))
+}
+
+func (s *Driver) handlePutGeofence(c echo.Context) error {
+	fences, err := s.readGeofences(c)
+	if err == nil && len(fences) != 1 {
+		err = errors.New("请求体须为单个围栏Feature")
+	}
+	if err == nil && fences[0].ID != c.Param("id") {
+		err = fmt.Errorf("围栏ID %s与路径中的%s不一致", fences[0].ID, c.Param("id"))
+	}
+	if err != nil {
+		return geofenceError(c, http.StatusBadRequest, err)
+	}
+	if err := s.updateGeofences(func(set *GeofenceSet) error { return set.Put(fences[0]) }); err != nil {
+		return geofenceError(c, http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, common.NewBaseResponse("", "", http.StatusOK))
+}
+
+func (s *Driver) handleDeleteGeofence(c echo.Context) error {
+	// 保存文件失败也可能是ErrNotExist，不能据此判断围栏不存在
+	id := c.Param("id")
+	found := true
+	err := s.updateGeofences(func(set *GeofenceSet) error {
+		if found = set.Delete(id); !found {
+			return fmt.Errorf("围栏%s不存在", id)
+		}
+		return nil
+	})
+	if !found {
+		return geofenceError(c, http.StatusNotFound, err)
+	}
+	if err != nil {
+		return geofenceError(c, http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, common.NewBaseResponse("", "", http.StatusOK))
+}
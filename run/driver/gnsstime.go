@@ -0,0 +1,197 @@
+package driver
+
+import (
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+const (
+	// gpsRolloverWeeks GPS导航电文中的周数只有10位，每1024周翻转一次
+	gpsRolloverWeeks = 1024
+	// taiMinusGPS TAI与GPS时间的固定差值（秒）
+	taiMinusGPS    = 19
+	secondsPerWeek = 7 * 24 * 3600
+)
+
+// gpsEpoch GPS时间的起点
+var gpsEpoch = time.Date(1980, 1, 6, 0, 0, 0, 0, time.UTC)
+
+// releaseDate 驱动的发布日期（YYYY-MM-DD），构建时可通过
+// -ldflags "-X github.com/edgexfoundry/device-sdk-go/v4/run/driver.releaseDate=2025-06-01" 设置
+var releaseDate = "2024-01-01"
+
+// releaseFloor 解析后的发布日期，格式无效时使用2024-01-01
+var releaseFloor = func() time.Time {
+	if t, err := time.Parse("2006-01-02", releaseDate); err == nil {
+		return t
+	}
+	return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+}()
+
+// minReceiverDate 早于该日期的接收机日期视为GPS周数翻转导致的错误日期：取发布日期与本机时间前512周中较晚的一个，
+// 本机时钟未校准时只按发布日期判断
+func minReceiverDate(now time.Time) time.Time {
+	if floor := now.AddDate(0, 0, -7*gpsRolloverWeeks/2); floor.After(releaseFloor) {
+		return floor
+	}
+	return releaseFloor
+}
+
+// leapSecondTable GPS时间起点以来TAI−UTC的变化，每项为新值的生效时刻（IERS公报C）
+var leapSecondTable = []struct {
+	from   time.Time
+	offset int
+}{
+	{time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), 19},
+	{time.Date(1981, 7, 1, 0, 0, 0, 0, time.UTC), 20},
+	{time.Date(1982, 7, 1, 0, 0, 0, 0, time.UTC), 21},
+	{time.Date(1983, 7, 1, 0, 0, 0, 0, time.UTC), 22},
+	{time.Date(1985, 7, 1, 0, 0, 0, 0, time.UTC), 23},
+	{time.Date(1988, 1, 1, 0, 0, 0, 0, time.UTC), 24},
+	{time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), 25},
+	{time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC), 26},
+	{time.Date(1992, 7, 1, 0, 0, 0, 0, time.UTC), 27},
+	{time.Date(1993, 7, 1, 0, 0, 0, 0, time.UTC), 28},
+	{time.Date(1994, 7, 1, 0, 0, 0, 0, time.UTC), 29},
+	{time.Date(1996, 1, 1, 0, 0, 0, 0, time.UTC), 30},
+	{time.Date(1997, 7, 1, 0, 0, 0, 0, time.UTC), 31},
+	{time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC), 32},
+	{time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC), 33},
+	{time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC), 34},
+	{time.Date(2012, 7, 1, 0, 0, 0, 0, time.UTC), 35},
+	{time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC), 36},
+	{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), 37},
+}
+
+// tableTAIMinusUTC 按闰秒表查询UTC时刻t的TAI−UTC（秒）
+func tableTAIMinusUTC(t time.Time) int {
+	offset := leapSecondTable[0].offset
+	for _, entry := range leapSecondTable {
+		if t.Before(entry.from) {
+			break
+		}
+		offset = entry.offset
+	}
+	return offset
+}
+
+// LeapSeconds 跟踪TAI−UTC。默认按内置闰秒表，接收机输出闰秒表中没有的23:59:60时，
+// 从下一个午夜起加1秒；配置了固定值时始终使用该值。可并发使用。
+type LeapSeconds struct {
+	mutex    sync.Mutex
+	override int         // 配置的TAI−UTC，0表示自动
+	observed []time.Time // 观测到的、闰秒表中没有的闰秒生效时刻
+}
+
+// NewLeapSeconds 创建闰秒跟踪，gpsMinusUTC为配置的GPS−UTC秒数，0表示自动
+func NewLeapSeconds(gpsMinusUTC int) *LeapSeconds {
+	l := &LeapSeconds{}
+	if gpsMinusUTC > 0 {
+		l.override = gpsMinusUTC + taiMinusGPS
+	}
+	return l
+}
+
+// Observe 记录定位中的闰秒，返回是否为闰秒表中没有的新闰秒
+func (l *LeapSeconds) Observe(fix Fix) bool {
+	if !fix.LeapSecond || !fix.HasTime {
+		return false
+	}
+	// Time为23:59:59.999，闰秒从下一个午夜起生效
+	effective := fix.Time.Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if tableTAIMinusUTC(effective) != tableTAIMinusUTC(effective.Add(-time.Second)) {
+		return false
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, at := range l.observed {
+		if at.Equal(effective) {
+			return false
+		}
+	}
+	l.observed = append(l.observed, effective)
+	return true
+}
+
+// TAIMinusUTC 返回UTC时刻t的TAI−UTC（秒）
+func (l *LeapSeconds) TAIMinusUTC(t time.Time) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.override > 0 {
+		return l.override
+	}
+	offset := tableTAIMinusUTC(t)
+	for _, at := range l.observed {
+		if !t.Before(at) {
+			offset++
+		}
+	}
+	return offset
+}
+
+// GNSSTime 一个UTC时刻在GPS时和TAI下的表示
+type GNSSTime struct {
+	UTC         time.Time
+	TAIMinusUTC int // 秒
+}
+
+// GPSMinusUTC GPS时间领先UTC的闰秒数
+func (g GNSSTime) GPSMinusUTC() int {
+	return g.TAIMinusUTC - taiMinusGPS
+}
+
+// TAI 国际原子时，以Unix时间戳的形式表示
+func (g GNSSTime) TAI() time.Time {
+	return g.UTC.Add(time.Duration(g.TAIMinusUTC) * time.Second)
+}
+
+// WeekTOW 完整的GPS周数（不按1024取模）和周内秒
+func (g GNSSTime) WeekTOW() (week int, tow float64) {
+	elapsed := g.UTC.Add(time.Duration(g.GPSMinusUTC()) * time.Second).Sub(gpsEpoch)
+	week = int(elapsed / (secondsPerWeek * time.Second))
+	return week, (elapsed - time.Duration(week)*secondsPerWeek*time.Second).Seconds()
+}
+
+// observeTime 记录闰秒并在接收机日期需要翻转修正时告警
+func (s *Driver) observeTime(deviceName string, rcv *receiver, fix Fix) {
+	if s.leapSeconds.Observe(fix) {
+		s.lc.Warnf("设备%s输出了闰秒表中没有的闰秒%s，此后TAI−UTC加1秒", deviceName, fix.Time.Format("2006-01-02 15:04:05"))
+	}
+	if fix.RolloverWeeks != rcv.rolloverWeeks {
+		rcv.rolloverWeeks = fix.RolloverWeeks
+		if fix.RolloverWeeks > 0 {
+			s.lc.Warnf("设备%s的日期早于%s，疑似固件未处理GPS周数翻转，已补加%d周",
+				deviceName, minReceiverDate(time.Now()).Format("2006-01-02"), fix.RolloverWeeks)
+		}
+	}
+}
+
+// getGNSSTimeValue 获取gps_week、gps_tow、tai_timestamp或leap_seconds资源，定位没有时间时返回nil
+func (s *Driver) getGNSSTimeValue(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	fix := gps.Fix()
+	if !fix.HasTime {
+		return nil
+	}
+	t := GNSSTime{UTC: fix.Time, TAIMinusUTC: s.leapSeconds.TAIMinusUTC(fix.Time)}
+
+	var cv *dsModels.CommandValue
+	switch req.DeviceResourceName {
+	case "gps_week":
+		week, _ := t.WeekTOW()
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt32, int32(week))
+	case "gps_tow":
+		_, tow := t.WeekTOW()
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, tow)
+	case "tai_timestamp":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt64, t.TAI().UnixMilli())
+	case "leap_seconds":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt32, int32(t.GPSMinusUTC()))
+	}
+	return cv
+}
@@ -0,0 +1,163 @@
+package driver
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+func TestFixTimeSources(t *testing.T) {
+	parse := func(body string) string { s := BuildNMEASentence(body); return s[:len(s)-2] }
+	zdaStr := parse("GNZDA,235959.800,10,06,2025,,")
+	ggaStr := parse("GNGGA,000000.000,3044.368753,N,10357.548051,E,1,08,1.20,129.3,M,-32.3,M,,")
+	zda := ParsNMEAZDA(zdaStr, len(zdaStr))
+	gga := ParsNMEAGGA(ggaStr, len(ggaStr))
+
+	// 只有ZDA时直接使用ZDA的日期和时间
+	var fix Fix
+	fix.applyZDA(zda, nil)
+	if !fix.HasTime || !fix.Time.Equal(time.Date(2025, 6, 10, 23, 59, 59, 800e6, time.UTC)) {
+		t.Errorf("ZDA time = %v, %v", fix.Time, fix.HasTime)
+	}
+	// GGA的时间跨过了午夜，日期加一天
+	fix = Fix{}
+	fix.applyZDA(zda, gga)
+	if !fix.Time.Equal(time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ZDA date with GGA time = %v", fix.Time)
+	}
+	// RMC已给出时间时不使用ZDA
+	fix = trackFix(0, 0)
+	fix.applyZDA(zda, gga)
+	if !fix.Time.Equal(trackFix(0, 0).Time) {
+		t.Errorf("RMC time replaced by ZDA: %v", fix.Time)
+	}
+
+	// 闰秒23:59:60
+	rmcStr := parse("GNRMC,235960.000,A,3044.368753,N,10357.548051,E,0.00,000.00,311216,,,A,V")
+	leap := fixFromNMEA(ParsNMEARMC(rmcStr, len(rmcStr)), nil, nil, nil)
+	if !leap.LeapSecond || !leap.Time.Equal(time.Date(2016, 12, 31, 23, 59, 59, 999e6, time.UTC)) {
+		t.Errorf("leap second fix = %v, LeapSecond %v", leap.Time, leap.LeapSecond)
+	}
+}
+
+func TestCheckRollover(t *testing.T) {
+	floor := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	actual := time.Date(2025, 6, 10, 5, 55, 25, 0, time.UTC)
+	tests := []struct {
+		reported time.Time
+		expected time.Time
+		weeks    int
+	}{
+		{actual, actual, 0},
+		{actual.AddDate(0, 0, -7*1024), actual, 1024},
+		{time.Date(1999, 8, 22, 0, 0, 0, 0, time.UTC), time.Date(2038, 11, 21, 0, 0, 0, 0, time.UTC), 2048},
+		// 晚于floor的日期照原样使用
+		{time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC), 0},
+	}
+	for _, test := range tests {
+		fix := Fix{Time: test.reported, HasTime: true}
+		fix.checkRollover(floor)
+		if !fix.HasTime || !fix.Time.Equal(test.expected) || fix.RolloverWeeks != test.weeks {
+			t.Errorf("checkRollover(%v) = %v, %v, %d weeks", test.reported, fix.Time, fix.HasTime, fix.RolloverWeeks)
+		}
+	}
+
+	// 本机时钟较新时下限随之推后，本机时钟未校准时使用发布日期
+	now := time.Date(2045, 3, 1, 0, 0, 0, 0, time.UTC)
+	if floor := minReceiverDate(now); !floor.Equal(now.AddDate(0, 0, -7*512)) {
+		t.Errorf("minReceiverDate(%v) = %v", now, floor)
+	}
+	if floor := minReceiverDate(time.Unix(0, 0)); !floor.Equal(releaseFloor) {
+		t.Errorf("minReceiverDate(1970) = %v, expected %v", floor, releaseFloor)
+	}
+}
+
+func TestReplayKeepsDate(t *testing.T) {
+	// 2019年的录制数据回放时不做周数翻转修正
+	rmc := BuildNMEASentence("GPRMC,055525.000,A,3044.368753,N,10357.548051,E,0.00,000.00,100619,,,A")
+	path := writeCapture(t, []string{rmc}, 10*time.Millisecond)
+
+	driver, _ := newTestDriverWithConfig(t, map[string]string{})
+	lcx6xz, replay, err := driver.openReplayReceiver("GPS-A", models.ProtocolProperties{"path": path, "speed": "10"})
+	if err != nil || replay == nil {
+		t.Fatalf("openReplayReceiver failed: %v", err)
+	}
+	defer lcx6xz.Close()
+
+	waitFor(t, time.Second, func() bool { return lcx6xz.Fix().HasTime }, "回放RMC解析")
+	if fix := lcx6xz.Fix(); fix.Time.Year() != 2019 || fix.RolloverWeeks != 0 {
+		t.Errorf("replayed fix time = %v, %d rollover weeks", fix.Time, fix.RolloverWeeks)
+	}
+}
+
+func TestLeapSeconds(t *testing.T) {
+	leaps := NewLeapSeconds(0)
+	for _, test := range []struct {
+		t        time.Time
+		expected int
+	}{
+		{time.Date(1980, 1, 6, 0, 0, 0, 0, time.UTC), 19},
+		{time.Date(2016, 12, 31, 23, 59, 59, 0, time.UTC), 36},
+		{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), 37},
+		{time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC), 37},
+	} {
+		if got := leaps.TAIMinusUTC(test.t); got != test.expected {
+			t.Errorf("TAIMinusUTC(%v) = %d, expected %d", test.t, got, test.expected)
+		}
+	}
+
+	// 闰秒表中已有的闰秒不重复计入，新的闰秒从下一个午夜起生效
+	known := Fix{Time: time.Date(2016, 12, 31, 23, 59, 59, 999e6, time.UTC), HasTime: true, LeapSecond: true}
+	future := Fix{Time: time.Date(2030, 6, 30, 23, 59, 59, 999e6, time.UTC), HasTime: true, LeapSecond: true}
+	if leaps.Observe(known) || !leaps.Observe(future) || leaps.Observe(future) {
+		t.Error("Observe() did not record exactly the unknown leap second")
+	}
+	if before, after := leaps.TAIMinusUTC(future.Time), leaps.TAIMinusUTC(future.Time.Add(time.Millisecond)); before != 37 || after != 38 {
+		t.Errorf("TAIMinusUTC around observed leap second = %d, %d", before, after)
+	}
+
+	if got := NewLeapSeconds(20).TAIMinusUTC(time.Now()); got != 39 {
+		t.Errorf("configured TAIMinusUTC = %d, expected 39", got)
+	}
+}
+
+func TestGNSSTime(t *testing.T) {
+	g := GNSSTime{UTC: time.Date(2025, 6, 10, 5, 55, 25, 500e6, time.UTC), TAIMinusUTC: 37}
+	if week, tow := g.WeekTOW(); week != 2370 || tow != 194143.5 {
+		t.Errorf("WeekTOW() = %d, %v, expected 2370, 194143.5", week, tow)
+	}
+	if g.GPSMinusUTC() != 18 || !g.TAI().Equal(g.UTC.Add(37*time.Second)) {
+		t.Errorf("GPSMinusUTC() = %d, TAI() = %v", g.GPSMinusUTC(), g.TAI())
+	}
+	if week, tow := (GNSSTime{UTC: gpsEpoch, TAIMinusUTC: taiMinusGPS}).WeekTOW(); week != 0 || tow != 0 {
+		t.Errorf("GPS epoch WeekTOW() = %d, %v", week, tow)
+	}
+}
+
+func TestDriverGNSSTime(t *testing.T) {
+	driver, _ := newTestDriverWithConfig(t, map[string]string{configLeapSeconds: "18"},
+		models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	utc, _ := readResource(t, driver, "GPS-A", "utc_timestamp").Int64Value()
+	tai, _ := readResource(t, driver, "GPS-A", "tai_timestamp").Int64Value()
+	if tai-utc < 37000 || tai-utc > 38000 {
+		t.Errorf("tai_timestamp - utc_timestamp = %d ms, expected about 37000", tai-utc)
+	}
+	if leap, _ := readResource(t, driver, "GPS-A", "leap_seconds").Int32Value(); leap != 18 {
+		t.Errorf("leap_seconds = %d, expected 18", leap)
+	}
+	week, _ := readResource(t, driver, "GPS-A", "gps_week").Int32Value()
+	tow, _ := readResource(t, driver, "GPS-A", "gps_tow").Float64Value()
+	gps := gpsEpoch.Add(time.Duration(week)*7*24*time.Hour + time.Duration(tow*float64(time.Second)))
+	if d := gps.Sub(time.UnixMilli(utc).Add(18 * time.Second)).Abs(); d > 2*time.Second {
+		t.Errorf("gps_week %d, gps_tow %v differ from utc_timestamp by %v", week, tow, d)
+	}
+	if text := readResource(t, driver, "GPS-A", "utc_time").ValueToString(); !strings.HasPrefix(text, time.Now().UTC().Format("2006-01-02")) {
+		t.Errorf("utc_time = %q, expected a full UTC date and time", text)
+	}
+}
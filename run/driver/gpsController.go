@@ -1,17 +1,29 @@
 package driver
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
-
-	"github.com/tarm/serial"
 )
 
 const BufSize = 2048
 
+const (
+	CommandTimeout    = time.Second            // 命令事务等待响应的超时时间
+	reconnectMinDelay = 100 * time.Millisecond // 串口重连的初始退避间隔
+	reconnectMaxDelay = 5 * time.Second        // 串口重连的最大退避间隔
+)
+
+// errBMIncomplete 二进制消息尚未接收完整，需要等待后续数据
+var errBMIncomplete = errors.New("二进制消息数据不完整")
+
+// errRTCMIncomplete RTCM3帧尚未接收完整，需要等待后续数据
+var errRTCMIncomplete = errors.New("RTCM3帧数据不完整")
+
 type LCX6XZ struct {
 	NMEA_RMC    *NMEA_RMC
 	NMEA_GGA    *NMEA_GGA
@@ -19,37 +31,80 @@ type LCX6XZ struct {
 	NMEA_VTG    *NMEA_VTG
 	NMEA_GSA    *NMEA_GSA
 	NMEA_GSV    *NMEA_GSV
+	NMEA_GST    *NMEA_GST
+	NMEA_ZDA    *NMEA_ZDA
 	OutputRates map[NMEA_SUB_ID]uint8 // 存储查询到的输出速率
 	ResData     []byte
 	mutex       sync.Mutex
-	uartAckCh   chan struct{}
-	uartFd      io.ReadWriteCloser
+	cmdLock     sync.Mutex    // 同一时刻只允许一个命令事务
+	pending     chan []byte   // 当前命令事务等待的二进制响应帧
+	port        *SerialPort   // 串口传输层
+	done        chan struct{} // 关闭后接收任务退出
+	closeOnce   sync.Once
+
+	gsaBySystem map[string]*NMEA_GSA // 按卫星系统保存的最近一条GSA
+	gsaTimes    map[string]time.Time // 各卫星系统GSA的接收时间
+	gsvGroups   map[string]*gsvGroup // 按卫星系统和信号累积的GSV
+
+	fixes      chan Fix  // 每个历元结束时推送一次定位结果
+	lastEpoch  string    // 最近一次推送的历元UTC
+	epochAt    time.Time // 最近一次历元结束的本机时间
+	pendingRMC string    // 已收到但尚未等到同历元GGA的RMC时间
+
+	replay   bool            // 数据来自抓包文件回放
+	rtcmOut  io.Writer       // 基准站模式下接收机输出的RTCM3帧的去向，nil时丢弃
+	surveyIn *SurveyInStatus // 最近一条PQTMSVINSTATUS
 }
 
 func UartRX_Task(lcx6xz *LCX6XZ) {
 	var dataBuffer []byte            // 累积数据缓冲区
 	readBuffer := make([]byte, 1024) // 单次读取缓冲区
+	idle := newEmptyReads(lcx6xz.port)
 
 	for {
+		select {
+		case <-lcx6xz.done:
+			return
+		default:
+		}
+
 		// 读取串口数据
-		n, err := lcx6xz.uartFd.Read(readBuffer)
+		started := time.Now()
+		n, err := lcx6xz.port.Read(readBuffer)
 
 		// 处理读取错误
-		if err != nil {
-			if err.Error() == "timeout" {
-				// 超时是正常的，继续读取
-				continue
+		if err != nil && !isReadTimeout(err) {
+			if lcx6xz.closed() {
+				return
 			}
 			fmt.Printf("串口读取错误: %v\n", err)
-			time.Sleep(100 * time.Millisecond) // 避免死循环
+			if !lcx6xz.reconnect() {
+				return
+			}
+			idle.reset()
+			dataBuffer = dataBuffer[:0] // 丢弃断线前的残留数据
 			continue
 		}
 
-		// 没有读取到数据
+		// 没有读取到数据：读超时是正常的，但挂断的tty会立即返回，持续空读时重连
 		if n == 0 {
-			time.Sleep(10 * time.Millisecond) // 避免CPU占用过高
+			if idle.empty(started) {
+				fmt.Println("串口持续无数据返回，可能已断开，重新连接")
+				if !lcx6xz.reconnect() {
+					return
+				}
+				idle.reset()
+				dataBuffer = dataBuffer[:0]
+				continue
+			}
+			select {
+			case <-lcx6xz.done:
+				return
+			case <-time.After(emptyReadDelay):
+			}
 			continue
 		}
+		idle.reset()
 
 		// 将新数据追加到累积缓冲区
 		dataBuffer = append(dataBuffer, readBuffer[:n]...)
@@ -93,6 +148,14 @@ func processNMEAData(data []byte, lcx6xz *LCX6XZ) []byte {
 		} else if i < len(data)-1 && data[i] == 0xF1 && data[i+1] == 0xD9 {
 			// 处理二进制协议（如果需要）
 			skip, err := ParsBM(data[i:], lcx6xz)
+			if errors.Is(err, errBMIncomplete) {
+				if nmeaFollows(data[i+2:]) {
+					i++ // 帧头之后已有完整语句，是误判的帧头
+					continue
+				}
+				// 等待剩余数据到达
+				break
+			}
 			if err != nil {
 				fmt.Printf("二进制协议解析错误: %v\n", err)
 				i++ // 跳过当前字节
@@ -100,6 +163,22 @@ func processNMEAData(data []byte, lcx6xz *LCX6XZ) []byte {
 				i += skip
 				processed = i
 			}
+		} else if data[i] == rtcmPreamble && lcx6xz.outputsRTCM() {
+			// 基准站模式下接收机输出的RTCM3差分电文，其他情况下0xD3只是噪声
+			skip, err := ParsRTCM(data[i:], lcx6xz)
+			if errors.Is(err, errRTCMIncomplete) {
+				if nmeaFollows(data[i+1:]) {
+					i++
+					continue
+				}
+				break
+			}
+			if err != nil {
+				i++ // 不是RTCM3帧，跳过当前字节
+			} else {
+				i += skip
+				processed = i
+			}
 		} else {
 			// 跳过无效字节
 			i++
@@ -113,6 +192,50 @@ func processNMEAData(data []byte, lcx6xz *LCX6XZ) []byte {
 	return data
 }
 
+// isReadTimeout 判断是否为读超时。tarm/serial 在超时后返回 io.EOF
+func isReadTimeout(err error) bool {
+	return errors.Is(err, io.EOF) || err.Error() == "timeout"
+}
+
+// reconnect 串口异常后按退避间隔重新打开，直到成功或设备被关闭
+func (lcx6xz *LCX6XZ) reconnect() bool {
+	if !lcx6xz.port.CanReopen() {
+		time.Sleep(100 * time.Millisecond) // 避免死循环
+		return true
+	}
+
+	delay := reconnectMinDelay
+	for {
+		select {
+		case <-lcx6xz.done:
+			return false
+		case <-time.After(delay):
+		}
+
+		err := lcx6xz.port.Reopen()
+		if err == nil {
+			if lcx6xz.closed() {
+				_ = lcx6xz.port.Close()
+				return false
+			}
+			fmt.Println("✅ 串口重新连接成功")
+			return true
+		}
+		fmt.Printf("串口重新连接失败: %v\n", err)
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+// closed 判断设备是否已关闭
+func (lcx6xz *LCX6XZ) closed() bool {
+	select {
+	case <-lcx6xz.done:
+		return true
+	default:
+		return false
+	}
+}
+
 // findNMEAEnd 查找NMEA语句的结束位置
 func findNMEAEnd(data []byte) int {
 	for i := 0; i < len(data)-1; i++ {
@@ -123,6 +246,29 @@ func findNMEAEnd(data []byte) int {
 	return -1 // 没有找到结束标记
 }
 
+// nmeaFollows 判断data中是否已有以"*hh\r\n"结尾的完整NMEA语句。二进制帧头之后已出现完整语句时，
+// 帧头是数据中偶然出现的字节，不再等待帧的剩余数据，否则NMEA会被扣留到凑满误判的帧长
+func nmeaFollows(data []byte) bool {
+	start := bytes.IndexByte(data, '$')
+	if start == -1 {
+		return false
+	}
+	for {
+		end := findNMEAEnd(data[start:])
+		if end == -1 {
+			return false
+		}
+		if end >= 6 && data[start+end-5] == '*' {
+			return true
+		}
+		next := bytes.IndexByte(data[start+1:], '$')
+		if next == -1 {
+			return false
+		}
+		start += 1 + next
+	}
+}
+
 // parseNMEASentence 解析单个NMEA语句
 func parseNMEASentence(sentence []byte, lcx6xz *LCX6XZ) error {
 	// 移除\r\n
@@ -136,6 +282,12 @@ func parseNMEASentence(sentence []byte, lcx6xz *LCX6XZ) error {
 		return fmt.Errorf("NMEA语句太短: %s", sentenceStr)
 	}
 
+	// Quectel专有语句：命令应答和基准站勘测状态
+	if strings.HasPrefix(sentenceStr, "$PQTM") {
+		lcx6xz.parseProprietary(sentenceStr)
+		return nil
+	}
+
 	// 解析NMEA语句类型
 	nmeaType := ParsNMEAType(sentenceStr, len(sentenceStr))
 
@@ -151,6 +303,7 @@ func parseNMEASentence(sentence []byte, lcx6xz *LCX6XZ) error {
 			fmt.Printf("✅ RMC: 时间=%s, 纬度=%s%s, 经度=%s%s, 状态=%s\n",
 				trimNullBytes(rmc.UTC[:]), trimNullBytes(rmc.Lat[:]), trimNullBytes(rmc.N_S[:]),
 				trimNullBytes(rmc.Lon[:]), trimNullBytes(rmc.E_W[:]), trimNullBytes(rmc.Status[:]))
+			lcx6xz.checkEpoch(NMEA_RMC_TYPE)
 		}
 	case NMEA_GGA_TYPE:
 		gga := ParsNMEAGGA(sentenceStr, len(sentenceStr))
@@ -160,6 +313,7 @@ func parseNMEASentence(sentence []byte, lcx6xz *LCX6XZ) error {
 				trimNullBytes(gga.UTC[:]), trimNullBytes(gga.Lat[:]), trimNullBytes(gga.N_S[:]),
 				trimNullBytes(gga.Lon[:]), trimNullBytes(gga.E_W[:]),
 				trimNullBytes(gga.Quality[:]), trimNullBytes(gga.NumSatUsed[:]))
+			lcx6xz.checkEpoch(NMEA_GGA_TYPE)
 		}
 	case NMEA_GLL_TYPE:
 		gll := ParsNMEAGLL(sentenceStr, len(sentenceStr))
@@ -174,6 +328,7 @@ func parseNMEASentence(sentence []byte, lcx6xz *LCX6XZ) error {
 		gsa := ParsNMEAGSA(sentenceStr, len(sentenceStr))
 		if gsa != nil {
 			lcx6xz.NMEA_GSA = gsa // 存储GSA数据
+			lcx6xz.storeGSA(gsa, time.Now())
 			fmt.Printf("✅ GSA: 模式=%s, 定位模式=%s, PDOP=%s, HDOP=%s, VDOP=%s\n",
 				trimNullBytes(gsa.Mode[:]), trimNullBytes(gsa.FixMode[:]),
 				trimNullBytes(gsa.PDOP[:]), trimNullBytes(gsa.HDOP[:]), trimNullBytes(gsa.VDOP[:]))
@@ -182,6 +337,7 @@ func parseNMEASentence(sentence []byte, lcx6xz *LCX6XZ) error {
 		gsv := ParsNMEAGSV(sentenceStr, len(sentenceStr))
 		if gsv != nil {
 			lcx6xz.NMEA_GSV = gsv // 存储GSV数据
+			lcx6xz.storeGSV(gsv, time.Now())
 			fmt.Printf("✅ GSV: 总语句数=%s, 语句号=%s, 可视卫星数=%s\n",
 				trimNullBytes(gsv.TotalNumSen[:]), trimNullBytes(gsv.SenNum[:]),
 				trimNullBytes(gsv.TotalNumSat[:]))
@@ -193,6 +349,22 @@ func parseNMEASentence(sentence []byte, lcx6xz *LCX6XZ) error {
 			fmt.Printf("✅ VTG: 航向=%s, 速度(节)=%s, 速度(km/h)=%s\n",
 				trimNullBytes(vtg.COGT[:]), trimNullBytes(vtg.SOGN[:]), trimNullBytes(vtg.SOGK[:]))
 		}
+	case NMEA_GST_TYPE:
+		gst := ParsNMEAGST(sentenceStr, len(sentenceStr))
+		if gst != nil {
+			lcx6xz.NMEA_GST = gst // 存储GST数据
+			fmt.Printf("✅ GST: 时间=%s, RMS=%s, 椭圆=%s/%s/%s, 标准差=%s/%s/%s\n",
+				trimNullBytes(gst.UTC[:]), trimNullBytes(gst.RMS_D[:]),
+				trimNullBytes(gst.MajorD[:]), trimNullBytes(gst.MinorD[:]), trimNullBytes(gst.Orient[:]),
+				trimNullBytes(gst.LatD[:]), trimNullBytes(gst.LonD[:]), trimNullBytes(gst.AltD[:]))
+		}
+	case NMEA_ZDA_TYPE:
+		zda := ParsNMEAZDA(sentenceStr, len(sentenceStr))
+		if zda != nil {
+			lcx6xz.NMEA_ZDA = zda // 存储ZDA数据
+			fmt.Printf("✅ ZDA: 时间=%s, 日期=%s-%s-%s\n",
+				trimNullBytes(zda.UTC[:]), trimNullBytes(zda.Year[:]), trimNullBytes(zda.Month[:]), trimNullBytes(zda.Day[:]))
+		}
 	default:
 		if len(sentenceStr) >= 6 {
 			fmt.Printf("⚠️  未知NMEA语句类型: %s\n", sentenceStr[:6])
@@ -202,6 +374,41 @@ func parseNMEASentence(sentence []byte, lcx6xz *LCX6XZ) error {
 	return nil
 }
 
+// gsaEpochWindow 同一历元内各卫星系统的GSA相继到达，早于最新GSA超过该时间的视为过期
+const gsaEpochWindow = 500 * time.Millisecond
+
+// storeGSA 按卫星系统保存GSA，调用方需持有mutex
+func (lcx6xz *LCX6XZ) storeGSA(gsa *NMEA_GSA, now time.Time) {
+	if lcx6xz.gsaBySystem == nil {
+		lcx6xz.gsaBySystem = make(map[string]*NMEA_GSA)
+		lcx6xz.gsaTimes = make(map[string]time.Time)
+	}
+	key := trimNullBytes(gsa.SystemID[:])
+	if key == "" {
+		key = trimNullBytes(gsa.Nmea.TalkerID[:])
+	}
+	lcx6xz.gsaBySystem[key] = gsa
+	lcx6xz.gsaTimes[key] = now
+}
+
+// currentGSAs 返回最近一个历元内各卫星系统的GSA，调用方需持有mutex
+func (lcx6xz *LCX6XZ) currentGSAs() []*NMEA_GSA {
+	var latest time.Time
+	for _, t := range lcx6xz.gsaTimes {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+
+	gsas := make([]*NMEA_GSA, 0, len(lcx6xz.gsaBySystem))
+	for key, gsa := range lcx6xz.gsaBySystem {
+		if latest.Sub(lcx6xz.gsaTimes[key]) <= gsaEpochWindow {
+			gsas = append(gsas, gsa)
+		}
+	}
+	return gsas
+}
+
 // trimNullBytes 移除字节数组中的空字节
 func trimNullBytes(data []byte) string {
 	for i, b := range data {
@@ -232,7 +439,7 @@ func ParsNMEA(buffer []byte, lcx6xz *LCX6XZ) (int, error) {
 // ParsBM 解析二进制协议语句
 func ParsBM(buffer []byte, lcx6xz *LCX6XZ) (int, error) {
 	if len(buffer) < 8 {
-		return 0, errors.New("二进制消息太短")
+		return 0, errBMIncomplete
 	}
 
 	// 检查帧头 0xF1 0xD9
@@ -247,7 +454,7 @@ func ParsBM(buffer []byte, lcx6xz *LCX6XZ) (int, error) {
 	// 检查消息长度
 	totalLen := int(6 + length + 2) // 头部6字节 + 载荷 + 校验和2字节
 	if len(buffer) < totalLen {
-		return 0, errors.New("二进制消息数据不完整")
+		return 0, errBMIncomplete
 	}
 
 	// 验证校验和
@@ -292,18 +499,152 @@ func ParsBM(buffer []byte, lcx6xz *LCX6XZ) (int, error) {
 		}
 	}
 
+	// 交给正在等待响应的命令事务
+	if lcx6xz != nil {
+		lcx6xz.deliver(buffer[:totalLen])
+	}
+
 	return totalLen, nil
 }
 
+// ParsRTCM 解析接收机输出的RTCM3帧，校验通过后写入RTCM输出
+func ParsRTCM(buffer []byte, lcx6xz *LCX6XZ) (int, error) {
+	if len(buffer) < rtcmHeaderSize {
+		return 0, errRTCMIncomplete
+	}
+	if buffer[0] != rtcmPreamble || buffer[1]&0xFC != 0 {
+		return 0, errors.New("无效的RTCM3帧头")
+	}
+	total := rtcmHeaderSize + (int(buffer[1]&0x03)<<8 | int(buffer[2])) + rtcmCRCSize
+	if len(buffer) < total {
+		return 0, errRTCMIncomplete
+	}
+	crc := uint32(buffer[total-3])<<16 | uint32(buffer[total-2])<<8 | uint32(buffer[total-1])
+	if crc24q(buffer[:total-rtcmCRCSize]) != crc {
+		return 0, errors.New("RTCM3帧CRC校验错误")
+	}
+
+	if lcx6xz != nil {
+		lcx6xz.mutex.Lock()
+		out := lcx6xz.rtcmOut
+		lcx6xz.mutex.Unlock()
+		if out != nil {
+			_, _ = out.Write(buffer[:total])
+		}
+	}
+	return total, nil
+}
+
+// SetRTCMOutput 设置接收机输出的RTCM3帧的去向，每次写入一个完整帧，nil表示丢弃
+func (lcx6xz *LCX6XZ) SetRTCMOutput(w io.Writer) {
+	lcx6xz.mutex.Lock()
+	defer lcx6xz.mutex.Unlock()
+	lcx6xz.rtcmOut = w
+}
+
+// outputsRTCM 判断是否设置了RTCM3帧的去向，只有基准站模式下才按RTCM3帧解析0xD3
+func (lcx6xz *LCX6XZ) outputsRTCM() bool {
+	lcx6xz.mutex.Lock()
+	defer lcx6xz.mutex.Unlock()
+	return lcx6xz.rtcmOut != nil
+}
+
+// deliver 将二进制响应帧或专有语句应答转交给当前命令事务，没有事务等待时丢弃
+func (lcx6xz *LCX6XZ) deliver(frame []byte) {
+	lcx6xz.mutex.Lock()
+	defer lcx6xz.mutex.Unlock()
+
+	if lcx6xz.pending == nil {
+		return
+	}
+	select {
+	case lcx6xz.pending <- append([]byte(nil), frame...):
+	default:
+	}
+}
+
+// transact 发送一条命令（二进制帧或专有NMEA语句）并等待匹配的响应。
+// match 对收到的每一帧返回 (事务是否结束, 错误)，超时未结束则返回错误。
+func (lcx6xz *LCX6XZ) transact(cmd []byte, match func(frame []byte) (bool, error)) error {
+	if lcx6xz == nil || lcx6xz.port == nil {
+		return errors.New("GPS设备未连接")
+	}
+
+	lcx6xz.cmdLock.Lock()
+	defer lcx6xz.cmdLock.Unlock()
+
+	frames := make(chan []byte, 8)
+	lcx6xz.mutex.Lock()
+	lcx6xz.pending = frames
+	lcx6xz.mutex.Unlock()
+	defer func() {
+		lcx6xz.mutex.Lock()
+		lcx6xz.pending = nil
+		lcx6xz.mutex.Unlock()
+	}()
+
+	if err := SendBinaryCommand(lcx6xz, cmd); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(CommandTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case frame := <-frames:
+			if finished, err := match(frame); finished {
+				return err
+			}
+		case <-timer.C:
+			return fmt.Errorf("等待设备响应超时(%v)", CommandTimeout)
+		case <-lcx6xz.done:
+			return errors.New("GPS设备已关闭")
+		}
+	}
+}
+
+// nakError 判断响应帧是否为针对指定消息的NAK
+func nakError(frame []byte, gid GroupID, sid uint8) error {
+	if len(frame) >= 10 && GroupID(frame[2]) == BIN_RES_GID && BIN_RES_SID(frame[3]) == BM_NAK_SID &&
+		frame[6] == uint8(gid) && frame[7] == sid {
+		return fmt.Errorf("设备拒绝命令: GroupID=0x%02X, SubID=0x%02X", frame[6], frame[7])
+	}
+	return nil
+}
+
+// ackMatcher 匹配针对指定消息的ACK/NAK
+func ackMatcher(gid GroupID, sid uint8) func([]byte) (bool, error) {
+	return func(frame []byte) (bool, error) {
+		if err := nakError(frame, gid, sid); err != nil {
+			return true, err
+		}
+		ack := len(frame) >= 10 && GroupID(frame[2]) == BIN_RES_GID && BIN_RES_SID(frame[3]) == BM_ACK_SID &&
+			frame[6] == uint8(gid) && frame[7] == sid
+		return ack, nil
+	}
+}
+
+// outputRateMatcher 匹配指定NMEA消息的输出速率查询响应
+func outputRateMatcher(nmeaType NMEA_SUB_ID) func([]byte) (bool, error) {
+	return func(frame []byte) (bool, error) {
+		if err := nakError(frame, BIN_CFG_GID, uint8(BM_MSG_SID)); err != nil {
+			return true, err
+		}
+		rate := len(frame) >= 11 && GroupID(frame[2]) == BIN_CFG_GID && BIN_CFG_SID(frame[3]) == BM_MSG_SID &&
+			GroupID(frame[6]) == NMEA_GID && NMEA_SUB_ID(frame[7]) == nmeaType
+		return rate, nil
+	}
+}
+
 // SendBinaryCommand 发送二进制命令到GPS设备
 func SendBinaryCommand(lcx6xz *LCX6XZ, data []byte) error {
-	if lcx6xz == nil || lcx6xz.uartFd == nil {
+	if lcx6xz == nil || lcx6xz.port == nil {
 		return errors.New("GPS设备未连接")
 	}
 
 	fmt.Printf("📤 发送二进制命令: %X\n", data)
 
-	_, err := lcx6xz.uartFd.Write(data)
+	_, err := lcx6xz.port.Write(data)
 	if err != nil {
 		return fmt.Errorf("发送二进制命令失败: %v", err)
 	}
@@ -311,7 +652,7 @@ func SendBinaryCommand(lcx6xz *LCX6XZ, data []byte) error {
 	return nil
 }
 
-// SetNMEAOutputRate 设置NMEA消息输出速率
+// SetNMEAOutputRate 设置NMEA消息输出速率，等待设备ACK
 func SetNMEAOutputRate(lcx6xz *LCX6XZ, nmeaType NMEA_SUB_ID, rate uint8) error {
 	// 创建设置输出速率的配置消息
 	cfgMsg := CfgMsgSetOutRate(NMEA_GID, nmeaType, rate)
@@ -325,10 +666,10 @@ func SetNMEAOutputRate(lcx6xz *LCX6XZ, nmeaType NMEA_SUB_ID, rate uint8) error {
 		return errors.New("转换配置消息失败")
 	}
 
-	return SendBinaryCommand(lcx6xz, msgBytes)
+	return lcx6xz.transact(msgBytes, ackMatcher(BIN_CFG_GID, uint8(BM_MSG_SID)))
 }
 
-// GetNMEAOutputRate 查询NMEA消息输出速率
+// GetNMEAOutputRate 查询NMEA消息输出速率，结果存入OutputRates
 func GetNMEAOutputRate(lcx6xz *LCX6XZ, nmeaType NMEA_SUB_ID) error {
 	// 创建查询输出速率的配置消息
 	cfgMsg := CfgMsgQueOutRate(NMEA_GID, nmeaType)
@@ -342,35 +683,62 @@ func GetNMEAOutputRate(lcx6xz *LCX6XZ, nmeaType NMEA_SUB_ID) error {
 		return errors.New("转换查询消息失败")
 	}
 
-	return SendBinaryCommand(lcx6xz, msgBytes)
+	return lcx6xz.transact(msgBytes, outputRateMatcher(nmeaType))
 }
 
 // 初始化LCX6XZ
 func InitLCX6XZ(Name string, Baud int, ReadTimeout int) (*LCX6XZ, error) {
+	port, err := NewSerialPort(Name, Baud, time.Duration(ReadTimeout)*time.Millisecond)
+	if err != nil {
+		return nil, fmt.Errorf("open uart device: %w", err)
+	}
+
+	return NewLCX6XZ(port), nil
+}
+
+// NewLCX6XZ 基于已建立的传输层创建LCX6XZ并启动接收任务
+func NewLCX6XZ(port *SerialPort) *LCX6XZ {
+	return newLCX6XZ(port, false)
+}
+
+// newLCX6XZ 创建接收机，replay为true表示数据来自抓包文件回放
+func newLCX6XZ(port *SerialPort, replay bool) *LCX6XZ {
 	lcx6xz := &LCX6XZ{
+		replay:      replay,
 		OutputRates: make(map[NMEA_SUB_ID]uint8),
 		ResData:     make([]byte, 1024),
-		uartAckCh:   make(chan struct{}),
+		port:        port,
+		done:        make(chan struct{}),
+		fixes:       make(chan Fix, fixQueueSize),
 	}
 
-	// 配置串口
-	config := &serial.Config{
-		Name:        Name,
-		Baud:        Baud,
-		Parity:      serial.ParityNone,
-		StopBits:    serial.Stop1,
-		ReadTimeout: time.Duration(ReadTimeout) * time.Millisecond,
-	}
+	// 启动接收任务
+	go UartRX_Task(lcx6xz)
 
-	port, err := serial.OpenPort(config)
-	if err != nil {
-		return nil, fmt.Errorf("open uart device: %w", err)
-	}
+	return lcx6xz
+}
 
-	lcx6xz.uartFd = port
+// Close 停止接收任务并关闭串口
+func (lcx6xz *LCX6XZ) Close() error {
+	var err error
+	lcx6xz.closeOnce.Do(func() {
+		close(lcx6xz.done)
+		err = lcx6xz.port.Close()
+	})
+	return err
+}
 
-	// 启动接收任务
-	go UartRX_Task(lcx6xz)
+// Fixes 返回历元定位结果通道，接收机关闭后不再有新结果
+func (lcx6xz *LCX6XZ) Fixes() <-chan Fix {
+	return lcx6xz.fixes
+}
+
+// Done 返回接收机关闭时关闭的通道
+func (lcx6xz *LCX6XZ) Done() <-chan struct{} {
+	return lcx6xz.done
+}
 
-	return lcx6xz, nil
+// Port 返回接收机使用的传输层
+func (lcx6xz *LCX6XZ) Port() *SerialPort {
+	return lcx6xz.port
 }
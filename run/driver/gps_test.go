@@ -3,6 +3,7 @@ package driver
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParsNMEAType(t *testing.T) {
@@ -136,3 +137,31 @@ func TestConvertDMSToDecimal(t *testing.T) {
 		}
 	}
 }
+
+func TestParsNMEAGST(t *testing.T) {
+	sentence := "$GPGST,172814.0,0.006,0.023,0.020,273.6,0.023,0.020,0.031*6A"
+	gst := ParsNMEAGST(sentence, len(sentence))
+	if gst == nil {
+		t.Fatalf("ParsNMEAGST(%s) returned nil", sentence)
+	}
+	if got := trimNullBytes(gst.Orient[:]); got != "273.6" {
+		t.Errorf("Orient = %s, expected 273.6", got)
+	}
+	if got := trimNullBytes(gst.AltD[:]); got != "0.031" {
+		t.Errorf("AltD = %s, expected 0.031 without checksum", got)
+	}
+}
+
+func TestParsNMEAZDA(t *testing.T) {
+	sentence := BuildNMEASentence("GNZDA,055525.000,10,06,2025,,")
+	zda := ParsNMEAZDA(sentence[:len(sentence)-2], len(sentence)-2)
+	if zda == nil {
+		t.Fatalf("ParsNMEAZDA(%s) returned nil", sentence)
+	}
+	if got, ok := parseZDATime(zda); !ok || !got.Equal(time.Date(2025, 6, 10, 5, 55, 25, 0, time.UTC)) {
+		t.Errorf("parseZDATime() = %v, %v", got, ok)
+	}
+	if ParsNMEAZDA("$GNZDA,055525.000,10,06,2025,,*00", 33) != nil {
+		t.Error("ParsNMEAZDA accepted a bad checksum")
+	}
+}
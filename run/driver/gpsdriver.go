@@ -24,25 +24,44 @@ package driver
 import (
 	errorDefault "errors"
 	"fmt"
-	"math/rand/v2"
+	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/edgexfoundry/device-sdk-go/v4/pkg/interfaces"
 	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/device-sdk-go/v4/run/config"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
-	"github.com/spf13/cast"
 )
 
 type Driver struct {
-	sdk       interfaces.DeviceServiceSDK
-	lc        logger.LoggingClient
-	asyncCh   chan<- *dsModels.AsyncValues
-	deviceCh  chan<- []dsModels.DiscoveredDevice
-	gpsDevice *LCX6XZ // GPS设备实例
+	sdk      interfaces.DeviceServiceSDK
+	lc       logger.LoggingClient
+	asyncCh  chan<- *dsModels.AsyncValues
+	deviceCh chan<- []dsModels.DiscoveredDevice
+
+	receivers     map[string]*receiver // 设备名称到接收机连接的映射
+	receiversLock sync.RWMutex
+
+	config       driverConfig
+	geofences    *GeofenceSet // 所有设备共用的地理围栏
+	geofenceLock sync.Mutex   // 串行化围栏的修改和保存
+
+	odometerTotals map[string]float64 // 已保存的各设备总里程
+	odometerSaved  time.Time
+	odometerLock   sync.Mutex
+
+	leapSeconds *LeapSeconds // 所有设备共用的TAI−UTC
+
+	serviceConfig config.ServiceConfig
+	formatBase    FormatOptions // Driver段中的格式设置，可被GPSCustom.Writable.Format覆盖
+	formatter     *Formatter
+	formatterLock sync.RWMutex
 }
 
 // Initialize performs protocol-specific initialization for the device
@@ -52,82 +71,46 @@ func (s *Driver) Initialize(sdk interfaces.DeviceServiceSDK) error {
 	s.lc = sdk.LoggingClient()
 	s.asyncCh = sdk.AsyncValuesChannel() // 获取异步上报通道
 	s.deviceCh = sdk.DiscoveredDeviceChannel()
-	// 启动一个 goroutine 模拟异步上报数据
-	go s.simulateAsyncReporting()
-	return nil
-}
-
-// 模拟异步主动上报数据
-func (s *Driver) simulateAsyncReporting() {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		deviceName := "GPS-Device-01"
-		resourceName := "AsyncTest"
-		origin := time.Now().UnixNano()
-
-		// 构造湿度 CommandValue
-		asyncValue1, err := dsModels.NewCommandValue("AsyncTest", common.ValueTypeInt64, rand.Int64())
-		if err != nil {
-			s.lc.Error(fmt.Sprintf("Failed to create AsyncTest CommandValue: %v", err))
-			continue
-		}
-		asyncValue2, err := dsModels.NewCommandValue("AsyncTest", common.ValueTypeInt32, rand.Int64())
-		if err != nil {
-			s.lc.Error(fmt.Sprintf("Failed to create AsyncTest CommandValue: %v", err))
-			continue
-		}
-		asyncValue1.Origin = origin
-		asyncValue2.Origin = origin
-
-		// 封装 AsyncValues
-		asyncValues := &dsModels.AsyncValues{
-			DeviceName:    deviceName,
-			SourceName:    resourceName,
-			CommandValues: []*dsModels.CommandValue{asyncValue1, asyncValue2},
-		}
+	s.receivers = make(map[string]*receiver)
 
-		// 推送到 SDK 的异步通道
-		s.asyncCh <- asyncValues
+	cfg, err := parseDriverConfig(sdk.DriverConfigs())
+	if err != nil {
+		return fmt.Errorf("驱动配置无效: %w", err)
+	}
+	s.config = cfg
+	s.leapSeconds = NewLeapSeconds(cfg.LeapSeconds)
 
-		s.lc.Debugf("AsyncTest Values pushed: %+v", asyncValues)
+	if err := s.initGeofences(); err != nil {
+		return fmt.Errorf("加载地理围栏失败: %w", err)
 	}
+	if s.odometerTotals, err = loadOdometers(s.config.OdometerFile); err != nil {
+		return err
+	}
+	return s.initFormatter()
 }
 
 // Start runs device service startup tasks after the SDK has been completely
 // initialized. This allows device service to safely use DeviceServiceSDK
 // interface features in this function call
 func (s *Driver) Start() error {
-
-	// 获取 UART 配置信息
-	// 通过结构体字段访问 Protocols
-	var deviceLocation string
-	var baudRate int
-	var ReadTimeout int
-	uartConfig, err := s.sdk.GetDeviceByName("GPS-Device-01")
-	if err != nil {
-		s.lc.Errorf("加载服务配置失败！")
-	}
-	for i, protocol := range uartConfig.Protocols {
-		deviceLocation = fmt.Sprintf("%v", protocol["deviceLocation"])
-		baudRate, _ = cast.ToIntE(protocol["baudRate"])
-		ReadTimeout, _ = cast.ToIntE(protocol["ReadTimeout"])
-		s.lc.Debugf("Driver.HandleReadCommands(): protocol = %v, device location = %v, baud rate = %v readTimeout=%v dataBits %v ",
-			i, deviceLocation, baudRate, ReadTimeout)
-	}
-
 	s.lc.Info("🚀 初始化GPS设备服务")
 
-	// 初始化GPS设备
-	gpsDevice, err := InitLCX6XZ(deviceLocation, baudRate, ReadTimeout)
-	if err != nil {
-		s.lc.Errorf("❌ GPS设备初始化失败: %v", err)
+	if err := s.sdk.AddCustomRoute(TrackRoute, interfaces.Authenticated, s.handleTrack, http.MethodGet); err != nil {
+		return fmt.Errorf("注册轨迹导出接口失败: %w", err)
+	}
+	if err := s.addGeofenceRoutes(); err != nil {
 		return err
 	}
 
-	s.gpsDevice = gpsDevice
-	s.lc.Info("✅ GPS设备初始化成功")
+	// 启动前已存在的设备不会触发AddDevice，在这里为它们打开接收机
+	for _, device := range s.sdk.Devices() {
+		if s.hasReceiver(device.Name) {
+			continue
+		}
+		if err := s.addReceiver(device.Name, device.Protocols); err != nil {
+			s.lc.Errorf("❌ GPS设备%s初始化失败: %v", device.Name, err)
+		}
+	}
 
 	return nil
 }
@@ -136,47 +119,117 @@ func (s *Driver) Start() error {
 func (s *Driver) HandleReadCommands(deviceName string, protocols map[string]models.ProtocolProperties, reqs []dsModels.CommandRequest) (res []*dsModels.CommandValue, err error) {
 	s.lc.Debugf("📖 处理设备 %s 的读取命令", deviceName)
 
-	if s.gpsDevice == nil {
-		return nil, fmt.Errorf("GPS设备未初始化")
+	rcv, err := s.receiverFor(deviceName)
+	if err != nil {
+		return nil, err
 	}
 
+	// 定位无效时不返回过期或不可靠的位置，请求的资源全部受约束时返回错误，否则只略去受约束的读数
+	validity := s.fixValidity(rcv, reqs)
+	if validity != nil && s.config.Validity.OnInvalid == InvalidFixError && allGated(reqs) {
+		return nil, fmt.Errorf("设备%s: %w", deviceName, validity)
+	}
+
+	// 读数时间使用GNSS历元时间（RMC日期+UTC），而不是本机时间
+	origin := epochOrigin(rcv.gps.Fix())
+
 	res = make([]*dsModels.CommandValue, 0, len(reqs))
 
 	for _, req := range reqs {
 		s.lc.Debugf("处理资源: %s", req.DeviceResourceName)
 
+		if s.withheld(req, validity) {
+			s.lc.Debugf("略去%s: %v", req.DeviceResourceName, validity)
+			continue
+		}
+
+		// 静止保持期间位置、速度和航向读取保持的值
+		if cv, held := s.getHeldValue(rcv, req); held {
+			if cv != nil {
+				s.annotateReading(cv, origin, validity)
+				res = append(res, cv)
+			}
+			continue
+		}
+
 		var cv *dsModels.CommandValue
 
 		switch req.DeviceResourceName {
 		case "latitude":
-			cv = s.getLatitude(req)
+			cv = s.getLatitude(rcv.gps, req)
 			s.lc.Debugf("latitude: %v", cv)
 		case "longitude":
-			cv = s.getLongitude(req)
+			cv = s.getLongitude(rcv.gps, req)
+		case "position":
+			cv = s.getPosition(rcv.gps, req)
 		case "altitude":
-			cv = s.getAltitude(req)
+			cv = s.getAltitude(rcv.gps, req)
 		case "speed":
-			cv = s.getSpeed(req)
+			cv = s.getSpeed(rcv.gps, req)
 		case "course":
-			cv = s.getCourse(req)
+			cv = s.getCourse(rcv.gps, req)
 		case "utc_time":
-			cv = s.getUTCTime(req)
+			cv = s.getUTCTime(rcv.gps, req)
 		case "fix_quality":
-			cv = s.getFixQuality(req)
-		case "satellites_used":
-			cv = s.getSatellitesUsed(req)
-		case "hdop":
-			cv = s.getHDOP(req)
-		case "gps_status":
-			cv = s.getGPSStatus(req)
+			cv = s.getFixQuality(rcv.gps, req)
+		case "satellites_used_text":
+			cv = s.getSatellitesUsed(rcv.gps, req)
+		case "hdop_text":
+			cv = s.getHDOP(rcv.gps, req)
+		case "estimated_horizontal_accuracy_m":
+			cv = s.getHorizontalAccuracy(rcv.gps, req)
+		case "gps_status", "gps_status_text":
+			cv = s.getGPSStatus(rcv.gps, req)
 		case "get_output_rates":
-			cv = s.getOutputRates(req)
+			cv = s.getOutputRates(rcv.gps, req)
+		case "wire_trace":
+			cv = s.getWireTrace(rcv.gps, req)
+		case "geofence_event":
+			cv = s.getGeofenceEvent(rcv.fences, req)
+		case "geofence_membership":
+			cv = s.getGeofenceMembership(rcv.fences, req)
+		case "odometer_m", "trip_distance_m", "trip_duration", "max_speed", "avg_speed":
+			cv = s.getOdometerValue(rcv.odometer, req)
+		case "motion_state":
+			cv = s.getMotionState(rcv.motion, req)
+		case "fix_age_ms":
+			cv = s.getFixAge(rcv.gps, req)
+		case "filtered_latitude", "filtered_longitude", "filtered_speed", "filtered_course":
+			cv = s.getKalmanValue(rcv.kalman, req)
+		case "gps_week", "gps_tow", "tai_timestamp", "leap_seconds":
+			cv = s.getGNSSTimeValue(rcv.gps, req)
+		case "gnss_integrity":
+			cv = s.getIntegrity(rcv.integrity, req)
+		case "time_offset_ms", "time_lock":
+			cv = s.getTimeValue(rcv.clock, req)
+		case "ntrip_status", "correction_age_s", "correction_data_rate":
+			cv = s.getNTRIPValue(rcv.ntrip, req)
+		case "corrections_status":
+			cv = s.getCorrectionsStatus(rcv.corrections, req)
+		case "base_status", "base_position", "base_uptime_s", "base_clients":
+			cv = s.getBaseValue(rcv, req)
+		case "constellation_stats", "sky_plot":
+			cv = s.getConstellationValue(rcv.gps, req)
 		default:
-			s.lc.Warnf("未知的资源名称: %s", req.DeviceResourceName)
-			continue
+			if crsResources[req.DeviceResourceName] {
+				if cv, err = s.getCRSValue(rcv, req); err != nil {
+					return nil, fmt.Errorf("设备%s: %w", deviceName, err)
+				}
+				break
+			}
+			if _, ok := constellationResources[req.DeviceResourceName]; ok {
+				cv = s.getConstellationValue(rcv.gps, req)
+				break
+			}
+			if _, ok := fixResources[req.DeviceResourceName]; !ok {
+				s.lc.Warnf("未知的资源名称: %s", req.DeviceResourceName)
+				continue
+			}
+			cv = s.getFixValue(rcv.gps, req)
 		}
 
 		if cv != nil {
+			s.annotateReading(cv, origin, validity)
 			res = append(res, cv)
 		}
 	}
@@ -184,6 +237,22 @@ func (s *Driver) HandleReadCommands(deviceName string, protocols map[string]mode
 	return res, nil
 }
 
+// epochOrigin 定位的GNSS历元时间（纳秒），RMC日期或时间无效时返回0，由SDK使用本机时间
+func epochOrigin(fix Fix) int64 {
+	if !fix.HasTime {
+		return 0
+	}
+	return fix.Time.UnixNano()
+}
+
+// annotateReading 为读数设置历元时间，并按有效性策略打标签
+func (s *Driver) annotateReading(cv *dsModels.CommandValue, origin int64, validity error) {
+	if cv.Origin == 0 {
+		cv.Origin = origin
+	}
+	s.tagFixValidity(cv, validity)
+}
+
 // HandleWriteCommands passes a slice of CommandRequest struct each representing
 // a ResourceOperation for a specific device resource.
 // Since the commands are actuation commands, params provide parameters for the individual
@@ -192,8 +261,9 @@ func (s *Driver) HandleWriteCommands(deviceName string, protocols map[string]mod
 	params []*dsModels.CommandValue) error {
 	s.lc.Debugf("✍️ 处理设备 %s 的写入命令", deviceName)
 
-	if s.gpsDevice == nil {
-		return fmt.Errorf("GPS设备未初始化")
+	rcv, err := s.receiverFor(deviceName)
+	if err != nil {
+		return err
 	}
 
 	for i, req := range reqs {
@@ -201,17 +271,35 @@ func (s *Driver) HandleWriteCommands(deviceName string, protocols map[string]mod
 
 		switch req.DeviceResourceName {
 		case "set_output_rate":
-			err := s.setOutputRate(req, params[i])
+			err := s.setOutputRate(rcv.gps, req, params[i])
 			if err != nil {
 				s.lc.Errorf("设置输出速率失败: %v", err)
 				return err
 			}
 		case "set_all_rates":
-			err := s.setAllOutputRates(req, params[i])
+			err := s.setAllOutputRates(rcv.gps, req, params[i])
 			if err != nil {
 				s.lc.Errorf("批量设置输出速率失败: %v", err)
 				return err
 			}
+		case "replay_step":
+			err := s.replayStep(rcv.replay, params[i])
+			if err != nil {
+				s.lc.Errorf("回放单步放行失败: %v", err)
+				return err
+			}
+		case "trip_reset":
+			err := s.resetTrip(rcv.odometer, params[i])
+			if err != nil {
+				s.lc.Errorf("行程复位失败: %v", err)
+				return err
+			}
+		case "base_survey_in", "base_fixed", "base_disable":
+			err := s.setBaseMode(deviceName, rcv, req, params[i])
+			if err != nil {
+				s.lc.Errorf("设置基准站模式失败: %v", err)
+				return err
+			}
 		default:
 			s.lc.Warnf("未知的写入资源名称: %s", req.DeviceResourceName)
 			return fmt.Errorf("不支持的写入操作: %s", req.DeviceResourceName)
@@ -233,26 +321,22 @@ func (s *Driver) Discover() error {
 // if validation failed and the incoming device will not be added into EdgeX
 func (s *Driver) ValidateDevice(device models.Device) error {
 
-	protocol, ok := device.Protocols["UART"]
-	if !ok {
-		return errorDefault.New("Missing 'UART' protocols")
+	if protocol, ok := device.Protocols[ProtocolFILE]; ok {
+		_, err := replayConfig(protocol)
+		return err
 	}
 
-	deviceLocation, ok := protocol["deviceLocation"]
-	if !ok {
-		return errorDefault.New("Missing 'deviceLocation' information")
-	} else if deviceLocation == "" {
-		return errorDefault.New("deviceLocation must not empty")
+	if protocol, ok := device.Protocols[ProtocolSIM]; ok {
+		_, err := simConfig(protocol)
+		return err
 	}
 
-	baudRate, ok := protocol["baudRate"]
+	protocol, ok := device.Protocols[ProtocolUART]
 	if !ok {
-		return errorDefault.New("Missing 'baudRate' information")
-	} else if baudRate == "" {
-		return errorDefault.New("baudRate must not empty")
+		return errorDefault.New("Missing 'UART', 'FILE' or 'SIM' protocols")
 	}
 
-	return nil
+	return validateUART(protocol)
 }
 
 // Stop the protocol-specific DS code to shutdown gracefully, or
@@ -264,6 +348,7 @@ func (s *Driver) Stop(force bool) error {
 	if s.lc != nil {
 		s.lc.Debugf(fmt.Sprintf("Driver.Stop called: force=%v", force))
 	}
+	s.closeReceivers()
 	return nil
 }
 
@@ -271,36 +356,44 @@ func (s *Driver) Stop(force bool) error {
 // when a new Device associated with this Device Service is added
 func (s *Driver) AddDevice(deviceName string, protocols map[string]models.ProtocolProperties, adminState models.AdminState) error {
 	s.lc.Debugf(fmt.Sprintf("a new Device is added: %s", deviceName))
-	return nil
+	return s.addReceiver(deviceName, protocols)
 }
 
 // UpdateDevice is a callback function that is invoked
 // when a Device associated with this Device Service is updated
 func (s *Driver) UpdateDevice(deviceName string, protocols map[string]models.ProtocolProperties, adminState models.AdminState) error {
 	s.lc.Debugf(fmt.Sprintf("Device %s is updated", deviceName))
-	return nil
+
+	// 只修改了管理状态等属性时保留现有连接
+	if rcv, err := s.receiverFor(deviceName); err == nil && reflect.DeepEqual(rcv.protocols, protocols) {
+		return nil
+	}
+
+	// addReceiver先关闭旧连接的串口再打开新连接，并保留轨迹等状态
+	return s.addReceiver(deviceName, protocols)
 }
 
 // RemoveDevice is a callback function that is invoked
 // when a Device associated with this Device Service is removed
 func (s *Driver) RemoveDevice(deviceName string, protocols map[string]models.ProtocolProperties) error {
 	s.lc.Debugf(fmt.Sprintf("Device %s is removed", deviceName))
+	s.removeReceiver(deviceName)
 	return nil
 }
 
 // GPS数据读取辅助方法
 
 // getLatitude 获取纬度
-func (s *Driver) getLatitude(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil || s.gpsDevice.NMEA_RMC == nil {
+func (s *Driver) getLatitude(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
+
+	if gps.NMEA_RMC == nil {
 		return nil
 	}
 
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
-
-	lat := s.cleanString(string(s.gpsDevice.NMEA_RMC.Lat[:]))
-	ns := s.cleanString(string(s.gpsDevice.NMEA_RMC.N_S[:]))
+	lat := s.cleanString(string(gps.NMEA_RMC.Lat[:]))
+	ns := s.cleanString(string(gps.NMEA_RMC.N_S[:]))
 
 	if lat == "" || ns == "" {
 		return nil
@@ -313,22 +406,22 @@ func (s *Driver) getLatitude(req dsModels.CommandRequest) *dsModels.CommandValue
 	}
 
 	// 格式化为易读格式
-	formattedLat := s.formatCoordinate(latValue, true, ns)
+	formattedLat := s.FormatCoordinate(latValue, true, ns)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedLat)
 	return cv
 }
 
 // getLongitude 获取经度
-func (s *Driver) getLongitude(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil || s.gpsDevice.NMEA_RMC == nil {
+func (s *Driver) getLongitude(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
+
+	if gps.NMEA_RMC == nil {
 		return nil
 	}
 
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
-
-	lon := s.cleanString(string(s.gpsDevice.NMEA_RMC.Lon[:]))
-	ew := s.cleanString(string(s.gpsDevice.NMEA_RMC.E_W[:]))
+	lon := s.cleanString(string(gps.NMEA_RMC.Lon[:]))
+	ew := s.cleanString(string(gps.NMEA_RMC.E_W[:]))
 
 	if lon == "" || ew == "" {
 		return nil
@@ -341,21 +434,32 @@ func (s *Driver) getLongitude(req dsModels.CommandRequest) *dsModels.CommandValu
 	}
 
 	// 格式化为易读格式
-	formattedLon := s.formatCoordinate(lonValue, false, ew)
+	formattedLon := s.FormatCoordinate(lonValue, false, ew)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedLon)
 	return cv
 }
 
-// getAltitude 获取海拔高度
-func (s *Driver) getAltitude(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil || s.gpsDevice.NMEA_GGA == nil {
+// getPosition 按当前坐标格式获取完整位置
+func (s *Driver) getPosition(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	fix := gps.Fix()
+	if !fix.HasPosition {
 		return nil
 	}
 
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, s.FormatPosition(fix.Latitude, fix.Longitude))
+	return cv
+}
 
-	altStr := s.cleanString(string(s.gpsDevice.NMEA_GGA.Alt[:]))
+// getAltitude 获取海拔高度
+func (s *Driver) getAltitude(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
+
+	if gps.NMEA_GGA == nil {
+		return nil
+	}
+
+	altStr := s.cleanString(string(gps.NMEA_GGA.Alt[:]))
 	if altStr == "" {
 		return nil
 	}
@@ -364,27 +468,23 @@ func (s *Driver) getAltitude(req dsModels.CommandRequest) *dsModels.CommandValue
 	altitude := s.parseFloat(altStr)
 
 	// 格式化为易读格式
-	formattedAlt := s.formatAltitude(altitude)
+	formattedAlt := s.FormatAltitude(altitude)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedAlt)
 	return cv
 }
 
 // getSpeed 获取速度
-func (s *Driver) getSpeed(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil {
-		return nil
-	}
-
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
+func (s *Driver) getSpeed(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
 
 	var speedKmh float64
 
 	var hasValidData bool
 
 	// 优先从VTG获取km/h速度
-	if s.gpsDevice.NMEA_VTG != nil {
-		sogkStr := s.cleanString(string(s.gpsDevice.NMEA_VTG.SOGK[:]))
+	if gps.NMEA_VTG != nil {
+		sogkStr := s.cleanString(string(gps.NMEA_VTG.SOGK[:]))
 		if sogkStr != "" {
 			speedKmh = s.parseFloat(sogkStr)
 			hasValidData = true
@@ -392,8 +492,8 @@ func (s *Driver) getSpeed(req dsModels.CommandRequest) *dsModels.CommandValue {
 	}
 
 	// 如果VTG中没有，从RMC获取节速度并转换
-	if !hasValidData && s.gpsDevice.NMEA_RMC != nil {
-		sogStr := s.cleanString(string(s.gpsDevice.NMEA_RMC.SOG[:]))
+	if !hasValidData && gps.NMEA_RMC != nil {
+		sogStr := s.cleanString(string(gps.NMEA_RMC.SOG[:]))
 		if sogStr != "" {
 			sog := s.parseFloat(sogStr)
 			speedKmh = sog * 1.852 // 1节 = 1.852 km/h
@@ -407,26 +507,22 @@ func (s *Driver) getSpeed(req dsModels.CommandRequest) *dsModels.CommandValue {
 	}
 
 	// 格式化为易读格式
-	formattedSpeed := s.formatSpeed(speedKmh)
+	formattedSpeed := s.FormatSpeed(speedKmh)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedSpeed)
 	return cv
 }
 
 // getCourse 获取航向
-func (s *Driver) getCourse(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil {
-		return nil
-	}
-
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
+func (s *Driver) getCourse(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
 
 	var course float64
 	var hasValidData bool
 
 	// 优先从VTG获取航向
-	if s.gpsDevice.NMEA_VTG != nil {
-		cogtStr := s.cleanString(string(s.gpsDevice.NMEA_VTG.COGT[:]))
+	if gps.NMEA_VTG != nil {
+		cogtStr := s.cleanString(string(gps.NMEA_VTG.COGT[:]))
 		if cogtStr != "" {
 			course = s.parseFloat(cogtStr)
 			hasValidData = true
@@ -434,8 +530,8 @@ func (s *Driver) getCourse(req dsModels.CommandRequest) *dsModels.CommandValue {
 	}
 
 	// 如果VTG中没有，从RMC获取
-	if !hasValidData && s.gpsDevice.NMEA_RMC != nil {
-		cogStr := s.cleanString(string(s.gpsDevice.NMEA_RMC.COG[:]))
+	if !hasValidData && gps.NMEA_RMC != nil {
+		cogStr := s.cleanString(string(gps.NMEA_RMC.COG[:]))
 		if cogStr != "" {
 			course = s.parseFloat(cogStr)
 			hasValidData = true
@@ -448,74 +544,75 @@ func (s *Driver) getCourse(req dsModels.CommandRequest) *dsModels.CommandValue {
 	}
 
 	// 格式化为易读格式
-	formattedCourse := s.formatCourse(course)
+	formattedCourse := s.FormatCourse(course)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedCourse)
 	return cv
 }
 
-// getUTCTime 获取UTC时间
-func (s *Driver) getUTCTime(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil || s.gpsDevice.NMEA_RMC == nil {
-		return nil
+// getUTCTime 获取UTC时间，有日期时给出完整的日期和时间，否则只有RMC的时间
+func (s *Driver) getUTCTime(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	if fix := gps.Fix(); fix.HasTime {
+		cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", s.Formatter().UTCDateTime(fix.Time))
+		return cv
 	}
 
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
+
+	if gps.NMEA_RMC == nil {
+		return nil
+	}
 
-	utcStr := s.cleanString(string(s.gpsDevice.NMEA_RMC.UTC[:]))
+	utcStr := s.cleanString(string(gps.NMEA_RMC.UTC[:]))
 	if utcStr == "" {
 		return nil
 	}
 
 	// 将UTC时间格式化为易读格式
-	formattedTime := s.formatUTCTime(utcStr)
+	formattedTime := s.FormatUTCTime(utcStr)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedTime)
 	return cv
 }
 
 // getFixQuality 获取定位质量
-func (s *Driver) getFixQuality(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil {
-		return nil
-	}
-
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
+func (s *Driver) getFixQuality(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
 
 	var quality int32
 
 	// 优先从GGA获取详细的定位质量
-	if s.gpsDevice.NMEA_GGA != nil {
-		qualityStr := s.cleanString(string(s.gpsDevice.NMEA_GGA.Quality[:]))
+	if gps.NMEA_GGA != nil {
+		qualityStr := s.cleanString(string(gps.NMEA_GGA.Quality[:]))
 		if qualityStr != "" {
 			quality = int32(s.parseFloat(qualityStr))
 		}
 	}
 
 	// 如果GGA中没有，从RMC状态推断
-	if quality == 0 && s.gpsDevice.NMEA_RMC != nil {
-		status := s.cleanString(string(s.gpsDevice.NMEA_RMC.Status[:]))
+	if quality == 0 && gps.NMEA_RMC != nil {
+		status := s.cleanString(string(gps.NMEA_RMC.Status[:]))
 		if status == "A" {
 			quality = 1 // 有效定位
 		}
 	}
 
 	// 格式化为易读格式
-	formattedQuality := s.formatFixQuality(quality)
+	formattedQuality := s.FormatFixQuality(quality)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedQuality)
 	return cv
 }
 
 // getSatellitesUsed 获取使用的卫星数
-func (s *Driver) getSatellitesUsed(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil || s.gpsDevice.NMEA_GGA == nil {
+func (s *Driver) getSatellitesUsed(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
+
+	if gps.NMEA_GGA == nil {
 		return nil
 	}
 
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
-
-	satStr := s.cleanString(string(s.gpsDevice.NMEA_GGA.NumSatUsed[:]))
+	satStr := s.cleanString(string(gps.NMEA_GGA.NumSatUsed[:]))
 	if satStr == "" {
 		return nil
 	}
@@ -524,30 +621,26 @@ func (s *Driver) getSatellitesUsed(req dsModels.CommandRequest) *dsModels.Comman
 	satCount := int32(s.parseFloat(satStr))
 
 	// 格式化为易读格式
-	formattedSatCount := s.formatSatelliteCount(satCount)
+	formattedSatCount := s.FormatSatelliteCount(satCount)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedSatCount)
 	return cv
 }
 
 // getHDOP 获取水平精度因子
-func (s *Driver) getHDOP(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil {
-		return nil
-	}
-
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
+func (s *Driver) getHDOP(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
 
 	var hdopStr string
 
 	// 优先从GGA获取HDOP
-	if s.gpsDevice.NMEA_GGA != nil {
-		hdopStr = s.cleanString(string(s.gpsDevice.NMEA_GGA.HDOP[:]))
+	if gps.NMEA_GGA != nil {
+		hdopStr = s.cleanString(string(gps.NMEA_GGA.HDOP[:]))
 	}
 
 	// 如果GGA中没有，尝试从GSA获取
-	if hdopStr == "" && s.gpsDevice.NMEA_GSA != nil {
-		hdopStr = s.cleanString(string(s.gpsDevice.NMEA_GSA.HDOP[:]))
+	if hdopStr == "" && gps.NMEA_GSA != nil {
+		hdopStr = s.cleanString(string(gps.NMEA_GSA.HDOP[:]))
 	}
 
 	if hdopStr == "" {
@@ -558,39 +651,49 @@ func (s *Driver) getHDOP(req dsModels.CommandRequest) *dsModels.CommandValue {
 	hdop := s.parseFloat(hdopStr)
 
 	// 格式化为易读格式
-	formattedHDOP := s.formatHDOP(hdop)
+	formattedHDOP := s.FormatHDOP(hdop)
 	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", formattedHDOP)
 	return cv
 }
 
-// getGPSStatus 获取GPS状态
-func (s *Driver) getGPSStatus(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil || s.gpsDevice.NMEA_RMC == nil {
-		cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", "DISCONNECTED")
-		return cv
+// gps_status的取值，不随显示语言变化，便于规则和北向应用比较
+const (
+	GPSStatusActive       = "ACTIVE"
+	GPSStatusWarning      = "WARNING"
+	GPSStatusDisconnected = "DISCONNECTED"
+)
+
+// gpsStatus 接收机状态，connected为false表示尚未收到RMC
+func gpsStatus(connected bool, rmcStatus string) string {
+	switch {
+	case !connected:
+		return GPSStatusDisconnected
+	case rmcStatus == "A":
+		return GPSStatusActive
+	default:
+		return GPSStatusWarning
 	}
+}
 
-	s.gpsDevice.mutex.Lock()
-	defer s.gpsDevice.mutex.Unlock()
+// getGPSStatus 获取GPS状态，gps_status_text按显示语言返回
+func (s *Driver) getGPSStatus(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	gps.mutex.Lock()
+	defer gps.mutex.Unlock()
 
-	status := s.cleanString(string(s.gpsDevice.NMEA_RMC.Status[:]))
-	var gpsStatus string
-	if status == "A" {
-		gpsStatus = "ACTIVE"
-	} else {
-		gpsStatus = "WARNING"
+	connected, status := gps.NMEA_RMC != nil, ""
+	if connected {
+		status = s.cleanString(string(gps.NMEA_RMC.Status[:]))
 	}
-
-	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", gpsStatus)
+	value := gpsStatus(connected, status)
+	if req.DeviceResourceName == "gps_status_text" {
+		value = s.Formatter().Status(connected, status)
+	}
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", value)
 	return cv
 }
 
 // getOutputRates 获取所有NMEA消息输出速率
-func (s *Driver) getOutputRates(req dsModels.CommandRequest) *dsModels.CommandValue {
-	if s.gpsDevice == nil {
-		return nil
-	}
-
+func (s *Driver) getOutputRates(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
 	s.lc.Info("开始查询所有NMEA消息输出速率")
 
 	// 查询支持的NMEA类型及其输出速率
@@ -612,20 +715,17 @@ func (s *Driver) getOutputRates(req dsModels.CommandRequest) *dsModels.CommandVa
 	var rateInfos []string
 
 	for _, nmea := range nmeaTypes {
-		// 发送查询命令
-		err := GetNMEAOutputRate(s.gpsDevice, nmea.sid)
+		// 发送查询命令并等待响应
+		err := GetNMEAOutputRate(gps, nmea.sid)
 		if err != nil {
 			s.lc.Errorf("查询%s输出速率失败: %v", nmea.name, err)
 			rateInfos = append(rateInfos, fmt.Sprintf("%s: 查询失败", nmea.name))
 			continue
 		}
 
-		// 等待响应
-		time.Sleep(200 * time.Millisecond)
-
 		// 从设备存储的查询结果中获取实际输出速率
-		s.gpsDevice.mutex.Lock()
-		if rate, exists := s.gpsDevice.OutputRates[nmea.sid]; exists {
+		gps.mutex.Lock()
+		if rate, exists := gps.OutputRates[nmea.sid]; exists {
 			var rateDesc string
 			switch rate {
 			case 0:
@@ -643,7 +743,7 @@ func (s *Driver) getOutputRates(req dsModels.CommandRequest) *dsModels.CommandVa
 		} else {
 			rateInfos = append(rateInfos, fmt.Sprintf("%s: 未知", nmea.name))
 		}
-		s.gpsDevice.mutex.Unlock()
+		gps.mutex.Unlock()
 
 		s.lc.Debugf("已查询%s输出速率", nmea.name)
 	}
@@ -655,8 +755,125 @@ func (s *Driver) getOutputRates(req dsModels.CommandRequest) *dsModels.CommandVa
 	return cv
 }
 
+// fixResources 数值资源及其从Fix中取值的方法，ok为false表示数据尚未就绪
+var fixResources = map[string]func(fix Fix) (valueType string, value any, ok bool){
+	"latitude_deg": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.Latitude, fix.HasPosition
+	},
+	"longitude_deg": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.Longitude, fix.HasPosition
+	},
+	"altitude_m": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.Altitude, fix.HasAltitude
+	},
+	"speed_mps": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.Speed, fix.HasSpeed
+	},
+	"course_deg": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.Course, fix.HasCourse
+	},
+	"hdop": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.HDOP, fix.HasHDOP
+	},
+	"pdop": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.PDOP, fix.HasPDOP
+	},
+	"vdop": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.VDOP, fix.HasVDOP
+	},
+	"sigma_lat_m": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.Errors.SigmaLat, fix.HasSigmas
+	},
+	"sigma_lon_m": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.Errors.SigmaLon, fix.HasSigmas
+	},
+	"sigma_alt_m": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeFloat64, fix.Errors.SigmaAlt, fix.HasSigmaAlt
+	},
+	"error_ellipse": func(fix Fix) (string, any, bool) {
+		ellipse := newFixErrorEllipse(fix)
+		if ellipse == nil {
+			return common.ValueTypeObject, nil, false
+		}
+		return common.ValueTypeObject, *ellipse, true
+	},
+	"satellites_used": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeInt32, int32(fix.Satellites), fix.HasSatellites
+	},
+	"utc_timestamp": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeInt64, fix.Time.UnixMilli(), fix.HasTime
+	},
+	"fix": func(fix Fix) (string, any, bool) {
+		return common.ValueTypeObject, NewFixDocument(fix), fix.HasTime || fix.HasPosition
+	},
+}
+
+// getFixValue 获取数值资源
+func (s *Driver) getFixValue(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	valueType, value, ok := fixResources[req.DeviceResourceName](gps.Fix())
+	if !ok {
+		return nil
+	}
+
+	cv, err := dsModels.NewCommandValue(req.DeviceResourceName, valueType, value)
+	if err != nil {
+		s.lc.Errorf("创建%s读数失败: %v", req.DeviceResourceName, err)
+		return nil
+	}
+	return cv
+}
+
+// getHorizontalAccuracy 获取估算的水平定位误差，没有GST时按HDOP和KalmanUERE估算
+func (s *Driver) getHorizontalAccuracy(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	accuracy, ok := gps.Fix().HorizontalAccuracy(s.config.Kalman.UERE)
+	if !ok {
+		return nil
+	}
+
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, accuracy)
+	return cv
+}
+
+// getWireTrace 获取串口线路跟踪（十六进制/ASCII对照）
+func (s *Driver) getWireTrace(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	dump := gps.Port().TraceDump()
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, "String", dump)
+	return cv
+}
+
+// registerPortMetrics 注册串口收发字节计数指标
+func (s *Driver) registerPortMetrics(deviceName string, port *SerialPort) {
+	metricsManager := s.sdk.MetricsManager()
+	if metricsManager == nil {
+		s.lc.Warn("MetricsManager不可用，串口字节计数指标不会上报")
+		return
+	}
+
+	for name, metric := range port.Metrics() {
+		registeredName := fmt.Sprintf("%s-%s", name, deviceName)
+		err := metricsManager.Register(registeredName, metric, map[string]string{"device": deviceName})
+		if err != nil {
+			s.lc.Errorf("注册指标%s失败: %v", registeredName, err)
+		} else {
+			s.lc.Debugf("已注册指标%s", registeredName)
+		}
+	}
+}
+
+// unregisterPortMetrics 注销串口收发字节计数指标，设备移除或重新配置时调用
+func (s *Driver) unregisterPortMetrics(deviceName string, port *SerialPort) {
+	metricsManager := s.sdk.MetricsManager()
+	if metricsManager == nil {
+		return
+	}
+
+	for name := range port.Metrics() {
+		metricsManager.Unregister(fmt.Sprintf("%s-%s", name, deviceName))
+	}
+}
+
 // setOutputRate 设置单个NMEA消息的输出速率
-func (s *Driver) setOutputRate(req dsModels.CommandRequest, param *dsModels.CommandValue) error {
+func (s *Driver) setOutputRate(gps *LCX6XZ, req dsModels.CommandRequest, param *dsModels.CommandValue) error {
 
 	// 参数格式: "GGA:1" 或 "RMC:5"
 	configStr, ok := param.Value.(string)
@@ -686,11 +903,11 @@ func (s *Driver) setOutputRate(req dsModels.CommandRequest, param *dsModels.Comm
 	}
 
 	s.lc.Infof("设置%s消息输出速率为%d", nmeaType, rate)
-	return SetNMEAOutputRate(s.gpsDevice, subID, rate)
+	return SetNMEAOutputRate(gps, subID, rate)
 }
 
 // setAllOutputRates 批量设置所有NMEA消息的输出速率
-func (s *Driver) setAllOutputRates(req dsModels.CommandRequest, param *dsModels.CommandValue) error {
+func (s *Driver) setAllOutputRates(gps *LCX6XZ, req dsModels.CommandRequest, param *dsModels.CommandValue) error {
 	if param == nil {
 		return fmt.Errorf("参数值为空")
 	}
@@ -720,7 +937,7 @@ func (s *Driver) setAllOutputRates(req dsModels.CommandRequest, param *dsModels.
 		}
 
 		// 发送设置命令
-		err = SetNMEAOutputRate(s.gpsDevice, subID, rate)
+		err = SetNMEAOutputRate(gps, subID, rate)
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("%s: %v", nmeaType, err))
 			continue
@@ -740,6 +957,23 @@ func (s *Driver) setAllOutputRates(req dsModels.CommandRequest, param *dsModels.
 	return nil
 }
 
+// replayStep 单步回放模式下放行指定条数的记录
+func (s *Driver) replayStep(replay *ReplayPort, param *dsModels.CommandValue) error {
+	if replay == nil {
+		return fmt.Errorf("设备不是回放设备")
+	}
+
+	count, err := param.Int32Value()
+	if err != nil {
+		return fmt.Errorf("参数值必须是Int32: %v", err)
+	}
+	if count <= 0 {
+		return fmt.Errorf("放行条数必须大于0")
+	}
+
+	return replay.Step(int(count))
+}
+
 // parseMultipleRateConfig 解析多个输出速率配置字符串
 func (s *Driver) parseMultipleRateConfig(configStr string) (map[string]uint8, error) {
 	if configStr == "" {
@@ -819,58 +1053,7 @@ func (s *Driver) getNMEASubID(nmeaType string) (NMEA_SUB_ID, error) {
 
 // convertDMSToDecimalWithValidation 将度分秒格式转换为十进制度数，并返回是否有效
 func (s *Driver) convertDMSToDecimalWithValidation(dmsStr, direction string) (float64, bool) {
-	if dmsStr == "" || direction == "" {
-		return 0.0, false
-	}
-
-	// 清理字符串
-	dmsStr = s.cleanString(dmsStr)
-	if len(dmsStr) < 4 {
-		return 0.0, false
-	}
-
-	// 解析度分格式 (ddmm.mmmm 或 dddmm.mmmm)
-	var degrees, minutes float64
-	var err error
-
-	if strings.Contains(dmsStr, ".") {
-		// 查找小数点位置
-		dotIndex := strings.Index(dmsStr, ".")
-		if dotIndex >= 4 {
-			// 经度格式 dddmm.mmmm
-			degrees, err = strconv.ParseFloat(dmsStr[:dotIndex-2], 64)
-			if err != nil {
-				return 0.0, false
-			}
-			minutes, err = strconv.ParseFloat(dmsStr[dotIndex-2:], 64)
-			if err != nil {
-				return 0.0, false
-			}
-		} else if dotIndex >= 3 {
-			// 纬度格式 ddmm.mmmm
-			degrees, err = strconv.ParseFloat(dmsStr[:dotIndex-2], 64)
-			if err != nil {
-				return 0.0, false
-			}
-			minutes, err = strconv.ParseFloat(dmsStr[dotIndex-2:], 64)
-			if err != nil {
-				return 0.0, false
-			}
-		} else {
-			return 0.0, false
-		}
-	} else {
-		return 0.0, false
-	}
-
-	decimal := degrees + minutes/60.0
-
-	// 根据方向调整符号
-	if direction == "S" || direction == "W" {
-		decimal = -decimal
-	}
-
-	return decimal, true
+	return parseNMEACoordinate(s.cleanString(dmsStr), direction)
 }
 
 // convertDMSToDecimal 将度分秒格式转换为十进制度数（保持向后兼容）
@@ -903,174 +1086,114 @@ func (s *Driver) cleanString(str string) string {
 	return cleaned
 }
 
-// formatUTCTime 将UTC时间字符串格式化为易读格式
-// 输入格式: HHMMSS.sss (例如: 123456.00)
-// 输出格式: HH:MM:SS.sss (例如: 12:34:56.00)
-func (s *Driver) formatUTCTime(utcStr string) string {
-	if len(utcStr) < 6 {
-		return utcStr // 如果格式不正确，返回原始字符串
-	}
-
-	// 解析时分秒
-	hour := utcStr[0:2]
-	minute := utcStr[2:4]
-	second := utcStr[4:]
+// initFormatter 根据Driver段和GPSCustom可写配置创建格式化器，并监听可写配置的变化
+func (s *Driver) initFormatter() error {
+	s.formatBase = formatOptionsFromConfig(s.sdk.DriverConfigs())
+	opts := s.formatBase
 
-	// 格式化为 HH:MM:SS.sss
-	return fmt.Sprintf("%s:%s:%s", hour, minute, second)
-}
-
-// formatCoordinate 格式化坐标为易读格式
-// 输入: 十进制度数 (例如: 39.969056)
-// 输出: 度分秒格式 (例如: 39°58'08.6"N)
-func (s *Driver) formatCoordinate(decimal float64, isLatitude bool, direction string) string {
-	if decimal == 0.0 {
-		return "0°00'00.0\""
+	if err := s.sdk.LoadCustomConfig(&s.serviceConfig, gpsCustomSection); err != nil {
+		s.lc.Warnf("未加载%s配置，仅使用Driver段的格式设置: %v", gpsCustomSection, err)
+	} else {
+		opts = opts.Merge(formatOptionsFromWritable(s.serviceConfig.GPSCustom.Writable))
+		if err := s.sdk.ListenForCustomConfigChanges(&s.serviceConfig.GPSCustom.Writable, gpsCustomWritableSection, s.processCustomConfigChanges); err != nil {
+			s.lc.Warnf("无法监听%s配置变化: %v", gpsCustomWritableSection, err)
+		}
 	}
 
-	// 取绝对值进行计算
-	absDecimal := decimal
-	if absDecimal < 0 {
-		absDecimal = -absDecimal
+	formatter, err := NewFormatter(opts)
+	if err != nil {
+		return fmt.Errorf("格式配置无效: %w", err)
 	}
-
-	// 计算度分秒
-	degrees := int(absDecimal)
-	minutes := (absDecimal - float64(degrees)) * 60
-	minutesInt := int(minutes)
-	seconds := (minutes - float64(minutesInt)) * 60
-
-	// 格式化输出
-	return fmt.Sprintf("%d°%02d'%04.1f\"%s", degrees, minutesInt, seconds, direction)
-}
-
-// formatSpeed 格式化速度为易读格式
-func (s *Driver) formatSpeed(speedKmh float64) string {
-	return fmt.Sprintf("%.2f km/h", speedKmh)
+	s.setFormatter(formatter)
+	return nil
 }
 
-// formatCourse 格式化航向为易读格式
-func (s *Driver) formatCourse(course float64) string {
-	// 添加方向描述
-	var direction string
-	switch {
-	case course >= 0 && course < 22.5:
-		direction = "北"
-	case course >= 22.5 && course < 67.5:
-		direction = "东北"
-	case course >= 67.5 && course < 112.5:
-		direction = "东"
-	case course >= 112.5 && course < 157.5:
-		direction = "东南"
-	case course >= 157.5 && course < 202.5:
-		direction = "南"
-	case course >= 202.5 && course < 247.5:
-		direction = "西南"
-	case course >= 247.5 && course < 292.5:
-		direction = "西"
-	case course >= 292.5 && course < 337.5:
-		direction = "西北"
-	default:
-		direction = "北"
+// processCustomConfigChanges 可写配置变化时重新创建格式化器，配置无效时保留原格式化器
+func (s *Driver) processCustomConfigChanges(rawWritableConfig interface{}) {
+	updated, ok := rawWritableConfig.(*config.GPSWritable)
+	if !ok {
+		s.lc.Errorf("无法将%s配置转换为GPSWritable", gpsCustomWritableSection)
+		return
 	}
 
-	return fmt.Sprintf("%.1f° (%s)", course, direction)
-}
-
-// formatAltitude 格式化海拔高度为易读格式
-func (s *Driver) formatAltitude(altitude float64) string {
-	return fmt.Sprintf("%.1f 米", altitude)
+	formatter, err := NewFormatter(s.formatBase.Merge(formatOptionsFromWritable(*updated)))
+	if err != nil {
+		s.lc.Errorf("格式配置无效，保持原设置: %v", err)
+		return
+	}
+	s.setFormatter(formatter)
+	s.lc.Infof("格式配置已更新: %s/%s/%s", formatter.Locale(), formatter.Units(), formatter.Coordinates())
 }
 
-// formatFixQuality 格式化定位质量为易读格式
-func (s *Driver) formatFixQuality(quality int32) string {
-	switch quality {
-	case 0:
-		return "无定位"
-	case 1:
-		return "GPS定位"
-	case 2:
-		return "差分GPS定位"
-	case 3:
-		return "PPS定位"
-	case 4:
-		return "RTK定位"
-	case 5:
-		return "浮点RTK"
-	case 6:
-		return "推算定位"
-	case 7:
-		return "手动输入"
-	case 8:
-		return "模拟定位"
-	default:
-		return fmt.Sprintf("未知质量(%d)", quality)
+// formatOptionsFromWritable 读取可写配置中的格式设置
+func formatOptionsFromWritable(writable config.GPSWritable) FormatOptions {
+	return FormatOptions{
+		Locale:      writable.Format.Locale,
+		Units:       writable.Format.Units,
+		Coordinates: writable.Format.Coordinates,
 	}
 }
 
-// formatSatelliteCount 格式化卫星数量为易读格式
-func (s *Driver) formatSatelliteCount(count int32) string {
-	return fmt.Sprintf("%d 颗卫星", count)
+// setFormatter 替换当前格式化器
+func (s *Driver) setFormatter(formatter *Formatter) {
+	s.formatterLock.Lock()
+	defer s.formatterLock.Unlock()
+	s.formatter = formatter
 }
 
-// formatHDOP 格式化水平精度因子为易读格式
-func (s *Driver) formatHDOP(hdop float64) string {
-	var quality string
-	switch {
-	case hdop <= 1:
-		quality = "优秀"
-	case hdop <= 2:
-		quality = "良好"
-	case hdop <= 5:
-		quality = "中等"
-	case hdop <= 10:
-		quality = "一般"
-	case hdop <= 20:
-		quality = "较差"
-	default:
-		quality = "很差"
+// Formatter 返回当前选定的格式化器，未初始化时为默认格式化器
+func (s *Driver) Formatter() *Formatter {
+	s.formatterLock.RLock()
+	defer s.formatterLock.RUnlock()
+	if s.formatter == nil {
+		return defaultFormatter
 	}
-	return fmt.Sprintf("%.2f (%s)", hdop, quality)
+	return s.formatter
 }
 
-// 公共格式化方法，供外部调用
+// 公共格式化方法，供外部调用，均使用当前选定的格式化器
 
 // FormatUTCTime 公共方法：格式化UTC时间
 func (s *Driver) FormatUTCTime(utcStr string) string {
-	return s.formatUTCTime(utcStr)
+	return s.Formatter().UTCTime(utcStr)
 }
 
 // FormatCoordinate 公共方法：格式化坐标
 func (s *Driver) FormatCoordinate(decimal float64, isLatitude bool, direction string) string {
-	return s.formatCoordinate(decimal, isLatitude, direction)
+	return s.Formatter().Coordinate(decimal, isLatitude, direction)
+}
+
+// FormatPosition 公共方法：格式化完整位置，UTM和MGRS格式需要使用此方法
+func (s *Driver) FormatPosition(lat, lon float64) string {
+	return s.Formatter().Position(lat, lon)
 }
 
 // FormatSpeed 公共方法：格式化速度
 func (s *Driver) FormatSpeed(speedKmh float64) string {
-	return s.formatSpeed(speedKmh)
+	return s.Formatter().Speed(speedKmh * mpsPerKmh)
 }
 
 // FormatCourse 公共方法：格式化航向
 func (s *Driver) FormatCourse(course float64) string {
-	return s.formatCourse(course)
+	return s.Formatter().Course(course)
 }
 
 // FormatAltitude 公共方法：格式化海拔
 func (s *Driver) FormatAltitude(altitude float64) string {
-	return s.formatAltitude(altitude)
+	return s.Formatter().Altitude(altitude)
 }
 
 // FormatFixQuality 公共方法：格式化定位质量
 func (s *Driver) FormatFixQuality(quality int32) string {
-	return s.formatFixQuality(quality)
+	return s.Formatter().FixQuality(quality)
 }
 
 // FormatSatelliteCount 公共方法：格式化卫星数量
 func (s *Driver) FormatSatelliteCount(count int32) string {
-	return s.formatSatelliteCount(count)
+	return s.Formatter().SatelliteCount(count)
 }
 
 // FormatHDOP 公共方法：格式化HDOP
 func (s *Driver) FormatHDOP(hdop float64) string {
-	return s.formatHDOP(hdop)
+	return s.Formatter().HDOP(hdop)
 }
@@ -0,0 +1,29 @@
+package driver
+
+import (
+	"testing"
+	"time"
+)
+
+// waitFor 轮询直到条件满足或超时
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool, what string) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("等待超时: %s", what)
+}
+
+// rmcLat 读取接收机最近一条RMC的纬度字段
+func rmcLat(lcx6xz *LCX6XZ) string {
+	lcx6xz.mutex.Lock()
+	defer lcx6xz.mutex.Unlock()
+	if lcx6xz.NMEA_RMC == nil {
+		return ""
+	}
+	return trimNullBytes(lcx6xz.NMEA_RMC.Lat[:])
+}
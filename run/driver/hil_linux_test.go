@@ -0,0 +1,160 @@
+package driver
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	hilRMC = "GBRMC,055525.000,A,3044.368753,N,10357.548051,E,0.00,000.00,100625,,,A,C"
+	hilGGA = "GBGGA,055525.000,3044.368753,N,10357.548051,E,1,04,2.40,129.3,M,-32.3,M,,"
+)
+
+// startHIL 启动模拟接收机，并通过真实的InitLCX6XZ路径连接它
+func startHIL(t *testing.T) (*receiverEmulator, *LCX6XZ) {
+	t.Helper()
+
+	emu := newReceiverEmulator(t)
+	lcx6xz, err := InitLCX6XZ(emu.link, 9600, 50)
+	if err != nil {
+		t.Fatalf("InitLCX6XZ failed: %v", err)
+	}
+	t.Cleanup(func() { _ = lcx6xz.Close() })
+	return emu, lcx6xz
+}
+
+func TestHILReadLoop(t *testing.T) {
+	emu, lcx6xz := startHIL(t)
+
+	emu.replay([]string{hilRMC, hilGGA}, 10*time.Millisecond)
+
+	waitFor(t, 2*time.Second, func() bool { return rmcLat(lcx6xz) == "3044.368753" }, "RMC解析")
+	waitFor(t, 2*time.Second, func() bool {
+		lcx6xz.mutex.Lock()
+		defer lcx6xz.mutex.Unlock()
+		return lcx6xz.NMEA_GGA != nil && trimNullBytes(lcx6xz.NMEA_GGA.NumSatUsed[:]) == "04"
+	}, "GGA解析")
+}
+
+func TestHILSetOutputRate(t *testing.T) {
+	emu, lcx6xz := startHIL(t)
+
+	if err := SetNMEAOutputRate(lcx6xz, NMEA_GSV_SID, 5); err != nil {
+		t.Fatalf("SetNMEAOutputRate failed: %v", err)
+	}
+	if rate, ok := emu.rate(NMEA_GSV_SID); !ok || rate != 5 {
+		t.Errorf("emulator GSV rate = %d, expected 5", rate)
+	}
+}
+
+func TestHILGetOutputRate(t *testing.T) {
+	_, lcx6xz := startHIL(t)
+
+	if err := GetNMEAOutputRate(lcx6xz, NMEA_GGA_SID); err != nil {
+		t.Fatalf("GetNMEAOutputRate failed: %v", err)
+	}
+	lcx6xz.mutex.Lock()
+	rate, ok := lcx6xz.OutputRates[NMEA_GGA_SID]
+	lcx6xz.mutex.Unlock()
+	if !ok || rate != 1 {
+		t.Errorf("OutputRates[GGA] = %d, expected 1", rate)
+	}
+}
+
+func TestHILCommandNAK(t *testing.T) {
+	emu, lcx6xz := startHIL(t)
+	emu.setNAK(NMEA_ZDA_SID)
+
+	if err := SetNMEAOutputRate(lcx6xz, NMEA_ZDA_SID, 1); err == nil {
+		t.Error("SetNMEAOutputRate succeeded, expected NAK error")
+	}
+}
+
+func TestHILCommandTimeout(t *testing.T) {
+	emu, lcx6xz := startHIL(t)
+	emu.setMute(true)
+
+	start := time.Now()
+	if err := SetNMEAOutputRate(lcx6xz, NMEA_GST_SID, 1); err == nil {
+		t.Error("SetNMEAOutputRate succeeded, expected timeout")
+	}
+	if elapsed := time.Since(start); elapsed < CommandTimeout {
+		t.Errorf("returned after %v, expected at least %v", elapsed, CommandTimeout)
+	}
+}
+
+func TestHILConcurrentCommands(t *testing.T) {
+	emu, lcx6xz := startHIL(t)
+
+	sids := []NMEA_SUB_ID{NMEA_GGA_SID, NMEA_GLL_SID, NMEA_GSA_SID, NMEA_GSV_SID, NMEA_RMC_SID, NMEA_VTG_SID}
+	errs := make([]error, len(sids))
+	var wg sync.WaitGroup
+	for i, sid := range sids {
+		wg.Add(1)
+		go func(i int, sid NMEA_SUB_ID) {
+			defer wg.Done()
+			errs[i] = SetNMEAOutputRate(lcx6xz, sid, uint8(i+1))
+		}(i, sid)
+	}
+	wg.Wait()
+
+	for i, sid := range sids {
+		if errs[i] != nil {
+			t.Errorf("SetNMEAOutputRate(%s) failed: %v", sid, errs[i])
+		}
+		if rate, _ := emu.rate(sid); rate != uint8(i+1) {
+			t.Errorf("emulator %s rate = %d, expected %d", sid, rate, i+1)
+		}
+	}
+	if frames := emu.frames(); len(frames) != len(sids) {
+		t.Errorf("emulator received %d frames, expected %d", len(frames), len(sids))
+	}
+}
+
+func TestHILReconnect(t *testing.T) {
+	emu, lcx6xz := startHIL(t)
+
+	emu.sendNMEA(hilRMC)
+	waitFor(t, 2*time.Second, func() bool { return rmcLat(lcx6xz) == "3044.368753" }, "断线前RMC解析")
+
+	// 拔出后重新枚举为新的伪终端
+	emu.unplug()
+	if err := emu.plug(); err != nil {
+		t.Fatalf("plug failed: %v", err)
+	}
+
+	lcx6xz.mutex.Lock()
+	lcx6xz.NMEA_RMC = nil
+	lcx6xz.mutex.Unlock()
+
+	// 重连完成前写入的数据会丢失，因此持续发送直到解析成功
+	waitFor(t, 5*time.Second, func() bool {
+		emu.sendNMEA(hilRMC)
+		return rmcLat(lcx6xz) == "3044.368753"
+	}, "重连后RMC解析")
+
+	if err := GetNMEAOutputRate(lcx6xz, NMEA_RMC_SID); err != nil {
+		t.Errorf("GetNMEAOutputRate after reconnect failed: %v", err)
+	}
+}
+
+func TestHILHangup(t *testing.T) {
+	emu, lcx6xz := startHIL(t)
+
+	emu.sendNMEA(hilRMC)
+	waitFor(t, 2*time.Second, func() bool { return rmcLat(lcx6xz) == "3044.368753" }, "挂断前RMC解析")
+
+	// 挂断后读取立即返回EOF，驱动需要在约一个读超时后重新打开串口
+	if err := emu.hangup(); err != nil {
+		t.Skipf("无法挂断伪终端: %v", err)
+	}
+	lcx6xz.mutex.Lock()
+	lcx6xz.NMEA_RMC = nil
+	lcx6xz.mutex.Unlock()
+
+	waitFor(t, 5*time.Second, func() bool {
+		emu.sendNMEA(hilRMC)
+		return rmcLat(lcx6xz) == "3044.368753"
+	}, "挂断后重连并解析RMC")
+}
@@ -0,0 +1,316 @@
+package driver
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+// 完好性状态
+const (
+	IntegrityOK    = "ok"
+	IntegrityAlarm = "alarm"
+)
+
+// 完好性告警类型
+const (
+	AlarmCN0Drop       = "cn0_drop"       // 平均载噪比相对基线骤降，疑似压制干扰
+	AlarmCN0Uniform    = "cn0_uniform"    // 各卫星载噪比过于一致，疑似欺骗信号
+	AlarmSatelliteLoss = "satellite_loss" // 跟踪的卫星数短时间内骤减
+	AlarmPositionJump  = "position_jump"  // 位置跳变超出速度能解释的范围
+	AlarmClockJump     = "clock_jump"     // GNSS时间相对本机时间跳变
+)
+
+// 完好性系统事件的类型和动作
+const (
+	IntegrityEventType  = "gnss"
+	IntegrityEventRaise = "integrity_alarm"
+	IntegrityEventClear = "integrity_clear"
+)
+
+const (
+	integrityWindow      = 5 * time.Second // 卫星骤减的比较窗口
+	integrityWarmup      = 10              // 载噪比基线至少积累的历元数
+	integrityBaselineEMA = 0.05            // 载噪比基线的指数平滑系数
+	integrityMinUniform  = 6               // 判断载噪比一致性至少需要的跟踪卫星数
+)
+
+// IntegrityConfig 完好性监测配置，各阈值为0时不做对应检查
+type IntegrityConfig struct {
+	CN0Drop       float64       // 平均载噪比低于基线的幅度（dB-Hz）
+	CN0MinSpread  float64       // 各卫星载噪比标准差的下限（dB-Hz）
+	SatelliteLoss int           // integrityWindow内减少的跟踪卫星数
+	JumpDistance  float64       // 超出速度×间隔的位置变化（米）
+	ClockJump     time.Duration // 本机时间与GNSS时间之差的变化
+	Hold          time.Duration // 告警条件消失后保持的时间，避免反复触发
+}
+
+// IntegrityReport 完好性状态，作为gnss_integrity资源和事件的值
+type IntegrityReport struct {
+	Status      string    `json:"status"`
+	Alarms      []string  `json:"alarms"`
+	CN0Mean     float64   `json:"cn0Mean"`     // 跟踪卫星的平均载噪比（dB-Hz）
+	CN0Baseline float64   `json:"cn0Baseline"` // 正常状态下平均载噪比的基线
+	CN0Spread   float64   `json:"cn0Spread"`   // 跟踪卫星载噪比的标准差
+	Tracked     int       `json:"tracked"`     // 跟踪的卫星数
+	Time        time.Time `json:"time"`
+}
+
+// IntegrityEvent 一个告警的触发或解除
+type IntegrityEvent struct {
+	Device string    `json:"device"`
+	Alarm  string    `json:"alarm"`
+	Active bool      `json:"active"`
+	Detail string    `json:"detail,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// trackedSample 某一时刻跟踪的卫星数
+type trackedSample struct {
+	at    time.Time
+	count int
+}
+
+// IntegrityMonitor 单台设备的GNSS完好性监测，根据载噪比、跟踪卫星数、位置和时间的异常判断干扰和欺骗。
+// 时间窗口以历元结束时的本机时间计算。可并发使用。
+type IntegrityMonitor struct {
+	mutex    sync.Mutex
+	cfg      IntegrityConfig
+	baseline float64
+	samples  int
+	tracked  []trackedSample
+	last     Fix // 最近一个有效定位
+	hasLast  bool
+	raised   map[string]time.Time // 各告警最近一次满足条件的时间
+	details  map[string]string
+	active   map[string]bool // 最近一次上报的有效告警
+	report   IntegrityReport
+	hasData  bool
+}
+
+// NewIntegrityMonitor 创建完好性监测
+func NewIntegrityMonitor(cfg IntegrityConfig) *IntegrityMonitor {
+	return &IntegrityMonitor{
+		cfg:     cfg,
+		raised:  make(map[string]time.Time),
+		details: make(map[string]string),
+		active:  make(map[string]bool),
+	}
+}
+
+// Update 用一个历元的定位和可视卫星更新状态，返回状态变化时产生的告警触发和解除事件
+func (m *IntegrityMonitor) Update(fix Fix, sats []SatelliteInfo) (IntegrityReport, []IntegrityEvent) {
+	now := fix.Received
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	triggered := make(map[string]string)
+	mean, spread, tracked := cn0Stats(sats)
+	m.checkCN0(mean, spread, tracked, triggered)
+	m.checkSatelliteLoss(now, tracked, triggered)
+	if validTrackFix(fix) && fix.Status == "A" {
+		m.checkFix(fix, triggered)
+		m.last, m.hasLast = fix, true
+	}
+
+	// 没有告警时才更新基线，避免干扰期间基线被拉低
+	if tracked > 0 && len(triggered) == 0 && len(m.active) == 0 {
+		if m.samples == 0 {
+			m.baseline = mean
+		} else {
+			m.baseline += integrityBaselineEMA * (mean - m.baseline)
+		}
+		m.samples++
+	}
+
+	before := m.active
+	for alarm, detail := range triggered {
+		m.raised[alarm], m.details[alarm] = now, detail
+	}
+	after := m.activeLocked(now)
+
+	var events []IntegrityEvent
+	for alarm := range after {
+		if !before[alarm] {
+			events = append(events, IntegrityEvent{Alarm: alarm, Active: true, Detail: m.details[alarm], Time: now})
+		}
+	}
+	for alarm := range before {
+		if !after[alarm] {
+			events = append(events, IntegrityEvent{Alarm: alarm, Active: false, Time: now})
+			delete(m.raised, alarm)
+			delete(m.details, alarm)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Alarm < events[j].Alarm })
+	m.active = after
+
+	m.report = IntegrityReport{
+		Status:      IntegrityOK,
+		Alarms:      sortedKeys(after),
+		CN0Mean:     math.Round(mean*10) / 10,
+		CN0Baseline: math.Round(m.baseline*10) / 10,
+		CN0Spread:   math.Round(spread*10) / 10,
+		Tracked:     tracked,
+		Time:        now,
+	}
+	if len(after) > 0 {
+		m.report.Status = IntegrityAlarm
+	}
+	m.hasData = true
+	return m.report, events
+}
+
+// checkCN0 检查平均载噪比骤降和载噪比一致性
+func (m *IntegrityMonitor) checkCN0(mean, spread float64, tracked int, triggered map[string]string) {
+	if m.cfg.CN0Drop > 0 && m.samples >= integrityWarmup && m.baseline-mean >= m.cfg.CN0Drop {
+		triggered[AlarmCN0Drop] = fmt.Sprintf("平均载噪比%.1f dB-Hz，基线%.1f dB-Hz", mean, m.baseline)
+	}
+	if m.cfg.CN0MinSpread > 0 && tracked >= integrityMinUniform && spread < m.cfg.CN0MinSpread {
+		triggered[AlarmCN0Uniform] = fmt.Sprintf("%d颗卫星载噪比标准差%.2f dB-Hz", tracked, spread)
+	}
+}
+
+// checkSatelliteLoss 与窗口内跟踪卫星数的最大值比较
+func (m *IntegrityMonitor) checkSatelliteLoss(now time.Time, tracked int, triggered map[string]string) {
+	kept := m.tracked[:0]
+	peak := 0
+	for _, sample := range m.tracked {
+		if now.Sub(sample.at) <= integrityWindow && !sample.at.After(now) {
+			kept = append(kept, sample)
+			peak = max(peak, sample.count)
+		}
+	}
+	m.tracked = append(kept, trackedSample{now, tracked})
+
+	if m.cfg.SatelliteLoss > 0 && peak-tracked >= m.cfg.SatelliteLoss {
+		triggered[AlarmSatelliteLoss] = fmt.Sprintf("%v内跟踪卫星数从%d降至%d", integrityWindow, peak, tracked)
+	}
+}
+
+// checkFix 与上一个有效定位比较位置和时间
+func (m *IntegrityMonitor) checkFix(fix Fix, triggered map[string]string) {
+	if !m.hasLast {
+		return
+	}
+
+	if dt := fix.Time.Sub(m.last.Time).Seconds(); m.cfg.JumpDistance > 0 && dt > 0 {
+		distance := haversine(LatLon{m.last.Latitude, m.last.Longitude}, LatLon{fix.Latitude, fix.Longitude})
+		allowed := math.Max(m.last.Speed, fix.Speed)*dt + m.cfg.JumpDistance
+		if distance > allowed {
+			triggered[AlarmPositionJump] = fmt.Sprintf("%.1f秒内位置变化%.0f米，速度只能解释%.0f米", dt, distance, allowed)
+		}
+	}
+
+	if m.cfg.ClockJump > 0 && !fix.Received.IsZero() && !m.last.Received.IsZero() {
+		change := fix.Received.Sub(fix.Time) - m.last.Received.Sub(m.last.Time)
+		if change.Abs() > m.cfg.ClockJump {
+			triggered[AlarmClockJump] = fmt.Sprintf("GNSS时间相对本机时间跳变%v", change.Round(time.Millisecond))
+		}
+	}
+}
+
+// activeLocked 返回now时刻有效的告警，调用方需持有mutex
+func (m *IntegrityMonitor) activeLocked(now time.Time) map[string]bool {
+	active := make(map[string]bool)
+	for alarm, at := range m.raised {
+		if now.Sub(at) <= m.cfg.Hold {
+			active[alarm] = true
+		}
+	}
+	return active
+}
+
+// Report 返回最近一次更新后的状态，尚未更新时ok为false
+func (m *IntegrityMonitor) Report() (IntegrityReport, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.report, m.hasData
+}
+
+// cn0Stats 跟踪卫星载噪比的平均值和标准差
+func cn0Stats(sats []SatelliteInfo) (mean, spread float64, tracked int) {
+	var sum, sumSq float64
+	for _, sat := range sats {
+		if sat.Tracked {
+			sum += sat.CN0
+			sumSq += sat.CN0 * sat.CN0
+			tracked++
+		}
+	}
+	if tracked == 0 {
+		return 0, 0, 0
+	}
+	mean = sum / float64(tracked)
+	return mean, math.Sqrt(math.Max(0, sumSq/float64(tracked)-mean*mean)), tracked
+}
+
+// sortedKeys 返回排序后的键，没有键时返回空切片而不是nil，便于JSON输出[]
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkIntegrity 更新完好性状态，告警触发或解除时上报gnss_integrity读数并发布系统事件
+func (s *Driver) checkIntegrity(deviceName string, rcv *receiver, fix Fix) {
+	report, events := rcv.integrity.Update(fix, rcv.gps.Satellites())
+	if len(events) == 0 {
+		return
+	}
+
+	for _, event := range events {
+		event.Device = deviceName
+		action := IntegrityEventClear
+		if event.Active {
+			action = IntegrityEventRaise
+			s.lc.Warnf("设备%s完好性告警%s: %s", deviceName, event.Alarm, event.Detail)
+		} else {
+			s.lc.Infof("设备%s完好性告警%s已解除", deviceName, event.Alarm)
+		}
+		s.sdk.PublishGenericSystemEvent(IntegrityEventType, action, event)
+	}
+
+	// 受到欺骗时GNSS时间本身不可信，读数时间使用本机时间
+	cv, err := dsModels.NewCommandValueWithOrigin("gnss_integrity", common.ValueTypeObject, report, report.Time.UnixNano())
+	if err != nil {
+		s.lc.Errorf("创建gnss_integrity读数失败: %v", err)
+		return
+	}
+	select {
+	case s.asyncCh <- &dsModels.AsyncValues{DeviceName: deviceName, SourceName: "gnss_integrity", CommandValues: []*dsModels.CommandValue{cv}}:
+	case <-rcv.gps.Done():
+	}
+}
+
+// newIntegrityMonitor 为设备创建完好性监测；回放时本机接收时间由回放速度决定，不检测时间跳变
+func (s *Driver) newIntegrityMonitor(replay bool) *IntegrityMonitor {
+	cfg := s.config.Integrity
+	if replay {
+		cfg.ClockJump = 0
+	}
+	return NewIntegrityMonitor(cfg)
+}
+
+// getIntegrity 获取完好性状态，尚未收到历元时返回nil
+func (s *Driver) getIntegrity(monitor *IntegrityMonitor, req dsModels.CommandRequest) *dsModels.CommandValue {
+	report, ok := monitor.Report()
+	if !ok {
+		return nil
+	}
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeObject, report)
+	return cv
+}
@@ -0,0 +1,170 @@
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+// integritySats 生成n颗跟踪卫星，载噪比从base开始依次增加step
+func integritySats(n int, base, step float64) []SatelliteInfo {
+	sats := make([]SatelliteInfo, n)
+	for i := range sats {
+		sats[i] = SatelliteInfo{System: "GPS", ID: i + 1, CN0: base + float64(i)*step, Tracked: true}
+	}
+	return sats
+}
+
+func TestIntegrityMonitor(t *testing.T) {
+	cfg := IntegrityConfig{CN0Drop: 8, CN0MinSpread: 1, SatelliteLoss: 6, JumpDistance: 100, ClockJump: time.Second, Hold: 2 * time.Second}
+	monitor := NewIntegrityMonitor(cfg)
+	if _, ok := monitor.Report(); ok {
+		t.Error("Report() before Update reported data")
+	}
+
+	start := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	origin := LatLon{30, 104}
+	lag := 300 * time.Millisecond // 本机时间落后于GNSS时间的量
+	step := func(sec int, east float64, sats []SatelliteInfo) (IntegrityReport, []IntegrityEvent) {
+		fix := trackFix(0, 0)
+		fix.Time = start.Add(time.Duration(sec) * time.Second)
+		fix.Received = fix.Time.Add(lag)
+		p := offsetMeters(origin, 0, east)
+		fix.Latitude, fix.Longitude, fix.Speed, fix.Status = p.Lat, p.Lon, 0, "A"
+		return monitor.Update(fix, sats)
+	}
+
+	// 正常状态下积累基线
+	normal := integritySats(10, 35, 1.5)
+	for sec := 0; sec < 12; sec++ {
+		if report, events := step(sec, 0, normal); report.Status != IntegrityOK || len(events) != 0 {
+			t.Fatalf("second %d: report = %+v, events = %+v", sec, report, events)
+		}
+	}
+	if report, _ := monitor.Report(); report.CN0Baseline != 41.8 || report.Tracked != 10 {
+		t.Errorf("baseline report = %+v", report)
+	}
+
+	// 压制干扰：载噪比下降且卫星大量失锁
+	report, events := step(12, 0, integritySats(3, 30, 1))
+	if report.Status != IntegrityAlarm || len(events) != 2 || events[0].Alarm != AlarmCN0Drop || events[1].Alarm != AlarmSatelliteLoss || !events[0].Active {
+		t.Errorf("jamming: report = %+v, events = %+v", report, events)
+	}
+	// 告警期间不更新基线，保持时间内不重复触发
+	if report, events = step(13, 0, integritySats(3, 30, 1)); len(events) != 0 || report.CN0Baseline != 41.8 {
+		t.Errorf("during jamming: report = %+v, events = %+v", report, events)
+	}
+	// 条件消失并超过保持时间后解除
+	step(14, 0, normal)
+	if report, events = step(16, 0, normal); report.Status != IntegrityOK || len(report.Alarms) != 0 || len(events) != 2 || events[0].Active || events[1].Active {
+		t.Errorf("after jamming: report = %+v, events = %+v", report, events)
+	}
+
+	// 欺骗：载噪比一致、位置跳变、时间跳变
+	report, events = step(17, 0, integritySats(8, 42, 0.1))
+	if len(events) != 1 || events[0].Alarm != AlarmCN0Uniform {
+		t.Errorf("uniform C/N0: report = %+v, events = %+v", report, events)
+	}
+	if _, events = step(18, 500, normal); len(events) != 1 || events[0].Alarm != AlarmPositionJump {
+		t.Errorf("position jump: events = %+v", events)
+	}
+	if _, events = step(22, 500, normal); len(events) != 2 {
+		t.Errorf("after position jump: events = %+v", events)
+	}
+	lag = 2 * time.Second
+	if _, events = step(30, 500, normal); len(events) != 1 || events[0].Alarm != AlarmClockJump || events[0].Detail == "" {
+		t.Errorf("clock jump: events = %+v", events)
+	}
+}
+
+func TestSatellites(t *testing.T) {
+	gps := &LCX6XZ{}
+	now := time.Now()
+	feed := func(body string, at time.Time) {
+		sentence := BuildNMEASentence(body)
+		gsv := ParsNMEAGSV(sentence[:len(sentence)-2], len(sentence)-2)
+		if gsv == nil {
+			t.Fatalf("ParsNMEAGSV(%s) returned nil", sentence)
+		}
+		gps.mutex.Lock()
+		gps.storeGSV(gsv, at)
+		gps.mutex.Unlock()
+	}
+
+	// GPS两条语句，同一卫星的L5信号载噪比更高；GLONASS缺少第一条语句的一组被丢弃
+	feed("GPGSV,2,1,05,01,40,083,46,02,17,308,41,03,07,344,,04,22,228,45,1", now)
+	feed("GPGSV,2,2,05,05,75,050,39,1", now)
+	feed("GPGSV,1,1,01,01,40,083,48,8", now)
+	feed("GLGSV,2,2,01,70,20,100,30,1", now)
+	sats := gps.Satellites()
+	if len(sats) != 5 {
+		t.Fatalf("Satellites() = %+v", sats)
+	}
+	if sats[0].ID != 1 || sats[0].CN0 != 48 || sats[0].Signal != "8" || !sats[0].HasAngles {
+		t.Errorf("satellite 1 = %+v", sats[0])
+	}
+	if sats[2].Tracked || sats[2].CN0 != 0 {
+		t.Errorf("untracked satellite 3 = %+v", sats[2])
+	}
+
+	// 其他卫星系统更新后，过期的组不再计入
+	feed("GAGSV,1,1,01,07,30,200,40,7", now.Add(gsvMaxAge+time.Second))
+	if sats = gps.Satellites(); len(sats) != 1 || sats[0].System != "Galileo" {
+		t.Errorf("Satellites() after expiry = %+v", sats)
+	}
+}
+
+func TestDriverIntegrity(t *testing.T) {
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["jamEvery"] = "4s"
+	protocols[ProtocolSIM]["jamDuration"] = "1s"
+	driver, asyncCh := newTestDriverWithConfig(t, map[string]string{}, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	deadline := time.After(8 * time.Second)
+	for received := false; !received; {
+		select {
+		case values := <-asyncCh:
+			if values.SourceName != "gnss_integrity" {
+				continue
+			}
+			report, ok := values.CommandValues[0].Value.(IntegrityReport)
+			if !ok || report.Status != IntegrityAlarm || len(report.Alarms) == 0 || values.CommandValues[0].Origin == 0 {
+				t.Errorf("async values = %+v", values.CommandValues[0])
+			}
+			received = true
+		case <-deadline:
+			t.Fatal("等待超时: gnss_integrity")
+		}
+	}
+
+	value := readResource(t, driver, "GPS-A", "gnss_integrity").Value
+	if report, ok := value.(IntegrityReport); !ok || report.Tracked == 0 {
+		t.Errorf("gnss_integrity = %+v", value)
+	}
+}
+
+func TestReplayIntegrityClock(t *testing.T) {
+	driver, _ := newTestDriverWithConfig(t, map[string]string{})
+	start := time.Date(2019, 6, 10, 5, 55, 25, 0, time.UTC)
+	update := func(monitor *IntegrityMonitor, sec int, lag time.Duration) []IntegrityEvent {
+		fix := trackFix(0, 0)
+		fix.Time = start.Add(time.Duration(sec) * time.Second)
+		fix.Received = fix.Time.Add(lag)
+		_, events := monitor.Update(fix, integritySats(10, 35, 1.5))
+		return events
+	}
+
+	// 回放时接收时间与录制时的GNSS时间相差多年，暂停后继续回放也不触发时间跳变
+	for _, replay := range []bool{false, true} {
+		monitor := driver.newIntegrityMonitor(replay)
+		update(monitor, 0, 7*365*24*time.Hour)
+		events := update(monitor, 1, 7*365*24*time.Hour+time.Minute)
+		if jumped := len(events) == 1 && events[0].Alarm == AlarmClockJump; jumped == replay {
+			t.Errorf("replay = %v: events = %+v", replay, events)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package driver
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/spf13/cast"
+)
+
+const (
+	// kalmanDefaultHDOP 定位既没有GST标准差也没有HDOP时假定的HDOP
+	kalmanDefaultHDOP = 2.0
+	// kalmanMinSigma 位置量测标准差的下限（米），避免接收机报告的过小误差让滤波器完全信任量测
+	kalmanMinSigma = 0.1
+	// kalmanCourseSpeed 滤波速度低于该值（米/秒）时不更新航向，保持最近的航向
+	kalmanCourseSpeed = 0.5
+)
+
+// KalmanConfig 卡尔曼滤波配置
+type KalmanConfig struct {
+	Enabled       bool          // 设备未设置kalman协议属性时是否开启
+	ProcessNoise  float64       // 加速度噪声标准差（米/秒²），越大越跟随量测
+	UERE          float64       // 用户等效测距误差（米），没有GST时位置量测标准差 = HDOP × UERE
+	VelocityNoise float64       // 速度量测标准差（米/秒）
+	ResetGap      time.Duration // 相邻定位间隔超过该值时重新初始化
+}
+
+// kalmanAxis 单个方向上的匀速模型，状态为位置p和速度v，P为协方差矩阵
+type kalmanAxis struct {
+	p, v          float64
+	p00, p01, p11 float64
+}
+
+// predict 按匀速模型外推dt秒，q为加速度噪声方差
+func (a *kalmanAxis) predict(dt, q float64) {
+	a.p += a.v * dt
+	dt2 := dt * dt
+	a.p00 += 2*dt*a.p01 + dt2*a.p11 + q*dt2*dt2/4
+	a.p01 += dt*a.p11 + q*dt2*dt/2
+	a.p11 += q * dt2
+}
+
+// updatePosition 用方差为r的位置量测z更新状态
+func (a *kalmanAxis) updatePosition(z, r float64) {
+	s := a.p00 + r
+	k0, k1 := a.p00/s, a.p01/s
+	y := z - a.p
+	a.p += k0 * y
+	a.v += k1 * y
+	a.p00, a.p01, a.p11 = a.p00-k0*a.p00, a.p01-k0*a.p01, a.p11-k1*a.p01
+}
+
+// updateVelocity 用方差为r的速度量测z更新状态
+func (a *kalmanAxis) updateVelocity(z, r float64) {
+	s := a.p11 + r
+	k0, k1 := a.p01/s, a.p11/s
+	y := z - a.v
+	a.p += k0 * y
+	a.v += k1 * y
+	a.p00, a.p01, a.p11 = a.p00-k0*a.p01, a.p01-k0*a.p11, a.p11-k1*a.p11
+}
+
+// KalmanEstimate 滤波后的位置和速度
+type KalmanEstimate struct {
+	Position LatLon
+	Speed    float64 // 米/秒
+	Course   float64 // 度，真北为0
+}
+
+// KalmanFilter 单台设备的匀速模型卡尔曼滤波器，东向和北向各一个独立的位置-速度滤波器。
+// 状态以最近一次估计的位置为原点的局部平面表示，每次更新后把原点移到新的估计位置。可并发使用。
+type KalmanFilter struct {
+	mutex       sync.Mutex
+	cfg         KalmanConfig
+	origin      LatLon
+	north, east kalmanAxis
+	course      float64
+	last        time.Time // 最近一次量测的定位时间，零值表示未初始化
+}
+
+// NewKalmanFilter 创建卡尔曼滤波器，收到第一个定位后初始化
+func NewKalmanFilter(cfg KalmanConfig) *KalmanFilter {
+	return &KalmanFilter{cfg: cfg}
+}
+
+// Update 用有效定位更新滤波器。首次定位、定位中断超过ResetGap或时间倒退时重新初始化。
+func (k *KalmanFilter) Update(fix Fix) {
+	if !fix.HasPosition {
+		return
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	sigmaN, sigmaE := k.positionSigmas(fix)
+	vn, ve, hasVelocity := fixVelocity(fix)
+
+	dt := fix.Time.Sub(k.last).Seconds()
+	if k.last.IsZero() || dt < 0 || fix.Time.Sub(k.last) > k.cfg.ResetGap {
+		k.reset(fix, sigmaN, sigmaE, vn, ve, hasVelocity)
+		return
+	}
+	if dt == 0 {
+		return
+	}
+
+	q := k.cfg.ProcessNoise * k.cfg.ProcessNoise
+	k.north.predict(dt, q)
+	k.east.predict(dt, q)
+
+	// 量测换算到以当前原点为中心的局部平面
+	kx := toRadians(1) * earthRadius * math.Cos(toRadians(k.origin.Lat))
+	ky := toRadians(1) * earthRadius
+	k.north.updatePosition((fix.Latitude-k.origin.Lat)*ky, sigmaN*sigmaN)
+	k.east.updatePosition((fix.Longitude-k.origin.Lon)*kx, sigmaE*sigmaE)
+	if hasVelocity {
+		r := k.cfg.VelocityNoise * k.cfg.VelocityNoise
+		k.north.updateVelocity(vn, r)
+		k.east.updateVelocity(ve, r)
+	}
+
+	k.origin = offsetMeters(k.origin, k.north.p, k.east.p)
+	k.north.p, k.east.p = 0, 0
+	k.last = fix.Time
+	k.updateCourse()
+}
+
+// reset 以当前定位初始化状态，没有速度量测时速度取0并给较大的方差
+func (k *KalmanFilter) reset(fix Fix, sigmaN, sigmaE, vn, ve float64, hasVelocity bool) {
+	velocityVar := k.cfg.VelocityNoise * k.cfg.VelocityNoise
+	if !hasVelocity {
+		vn, ve, velocityVar = 0, 0, 100
+	}
+	k.origin = LatLon{fix.Latitude, fix.Longitude}
+	k.north = kalmanAxis{v: vn, p00: sigmaN * sigmaN, p11: velocityVar}
+	k.east = kalmanAxis{v: ve, p00: sigmaE * sigmaE, p11: velocityVar}
+	k.last = fix.Time
+	k.course = 0
+	if hasVelocity {
+		k.course = fix.Course
+	}
+	k.updateCourse()
+}
+
+// updateCourse 速度足够时按滤波速度更新航向
+func (k *KalmanFilter) updateCourse() {
+	if math.Hypot(k.north.v, k.east.v) >= kalmanCourseSpeed {
+		k.course = math.Mod(toDegrees(math.Atan2(k.east.v, k.north.v))+360, 360)
+	}
+}
+
+// positionSigmas 位置量测的北向和东向标准差，优先使用GST给出的纬度、经度标准差
+func (k *KalmanFilter) positionSigmas(fix Fix) (north, east float64) {
+	switch {
+	case fix.HasSigmas:
+		north, east = fix.Errors.SigmaLat, fix.Errors.SigmaLon
+	case fix.HasHDOP:
+		north = fix.HDOP * k.cfg.UERE
+		east = north
+	default:
+		north = kalmanDefaultHDOP * k.cfg.UERE
+		east = north
+	}
+	return math.Max(north, kalmanMinSigma), math.Max(east, kalmanMinSigma)
+}
+
+// fixVelocity 由对地速度和航向得到北向和东向速度
+func fixVelocity(fix Fix) (north, east float64, ok bool) {
+	if !fix.HasSpeed || !fix.HasCourse {
+		return 0, 0, false
+	}
+	sin, cos := math.Sincos(toRadians(fix.Course))
+	return fix.Speed * cos, fix.Speed * sin, true
+}
+
+// Estimate 返回当前估计，尚未初始化时ok为false
+func (k *KalmanFilter) Estimate() (estimate KalmanEstimate, ok bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.last.IsZero() {
+		return KalmanEstimate{}, false
+	}
+	return KalmanEstimate{
+		Position: k.origin,
+		Speed:    math.Hypot(k.north.v, k.east.v),
+		Course:   k.course,
+	}, true
+}
+
+// kalmanResources 滤波结果资源及其从估计中取值的方法
+var kalmanResources = map[string]func(estimate KalmanEstimate) float64{
+	"filtered_latitude":  func(estimate KalmanEstimate) float64 { return estimate.Position.Lat },
+	"filtered_longitude": func(estimate KalmanEstimate) float64 { return estimate.Position.Lon },
+	"filtered_speed":     func(estimate KalmanEstimate) float64 { return estimate.Speed },
+	"filtered_course":    func(estimate KalmanEstimate) float64 { return estimate.Course },
+}
+
+// getKalmanValue 获取滤波结果资源，设备未开启滤波或滤波器尚未初始化时返回nil
+func (s *Driver) getKalmanValue(filter *KalmanFilter, req dsModels.CommandRequest) *dsModels.CommandValue {
+	if filter == nil {
+		s.lc.Warnf("设备未开启卡尔曼滤波，无法读取%s", req.DeviceResourceName)
+		return nil
+	}
+	estimate, ok := filter.Estimate()
+	if !ok {
+		return nil
+	}
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, kalmanResources[req.DeviceResourceName](estimate))
+	return cv
+}
+
+// newKalmanFilter 按设备的kalman协议属性创建滤波器，未设置时按KalmanEnabled配置，未开启时返回nil
+func (s *Driver) newKalmanFilter(deviceName string, protocols map[string]models.ProtocolProperties) *KalmanFilter {
+	enabled := s.config.Kalman.Enabled
+	for _, protocol := range protocols {
+		value, ok := protocol["kalman"]
+		if !ok || value == "" {
+			continue
+		}
+		b, err := cast.ToBoolE(value)
+		if err != nil {
+			s.lc.Errorf("设备%s的kalman属性无效: %v，使用默认设置", deviceName, value)
+			continue
+		}
+		enabled = b
+	}
+	if !enabled {
+		return nil
+	}
+	return NewKalmanFilter(s.config.Kalman)
+}
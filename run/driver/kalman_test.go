@@ -0,0 +1,107 @@
+package driver
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+func TestKalmanFilter(t *testing.T) {
+	cfg := KalmanConfig{ProcessNoise: 0.5, UERE: 3, VelocityNoise: 0.3, ResetGap: 5 * time.Second}
+	filter := NewKalmanFilter(cfg)
+	if _, ok := filter.Estimate(); ok {
+		t.Fatal("Estimate() before first fix succeeded")
+	}
+
+	// 以10m/s向东行驶，位置噪声标准差3m（HDOP 1），速度噪声0.3m/s
+	rng := rand.New(rand.NewPCG(1, 2))
+	origin := LatLon{30, 104}
+	start := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	var rawErr, filteredErr float64
+	for sec := 0; sec < 120; sec++ {
+		truth := offsetMeters(origin, 0, float64(sec)*10)
+		measured := offsetMeters(truth, rng.NormFloat64()*3, rng.NormFloat64()*3)
+		fix := trackFix(0, 0)
+		fix.Time = start.Add(time.Duration(sec) * time.Second)
+		fix.Latitude, fix.Longitude = measured.Lat, measured.Lon
+		fix.HDOP, fix.HasHDOP = 1, true
+		fix.Speed, fix.HasSpeed = 10+rng.NormFloat64()*0.3, true
+		fix.Course, fix.HasCourse = 90+rng.NormFloat64()*2, true
+		filter.Update(fix)
+
+		estimate, _ := filter.Estimate()
+		if sec >= 20 {
+			rawErr += math.Pow(haversine(truth, measured), 2)
+			filteredErr += math.Pow(haversine(truth, estimate.Position), 2)
+		}
+	}
+	rawRMS, filteredRMS := math.Sqrt(rawErr/100), math.Sqrt(filteredErr/100)
+	if filteredRMS > rawRMS/2 {
+		t.Errorf("filtered RMS error = %.2fm, raw = %.2fm, expected at least 2x better", filteredRMS, rawRMS)
+	}
+	estimate, _ := filter.Estimate()
+	if math.Abs(estimate.Speed-10) > 0.3 || math.Abs(estimate.Course-90) > 2 {
+		t.Errorf("estimate speed = %.2f, course = %.2f", estimate.Speed, estimate.Course)
+	}
+
+	// 定位中断超过ResetGap后从新位置重新开始
+	fix := trackFix(0, 5000)
+	fix.Time = start.Add(3 * time.Minute)
+	filter.Update(fix)
+	estimate, _ = filter.Estimate()
+	if estimate.Position != (LatLon{fix.Latitude, fix.Longitude}) || estimate.Speed != 0 {
+		t.Errorf("estimate after outage = %+v", estimate)
+	}
+}
+
+func TestKalmanPositionSigmas(t *testing.T) {
+	filter := NewKalmanFilter(KalmanConfig{UERE: 3})
+	fix := trackFix(0, 0)
+	if n, e := filter.positionSigmas(fix); n != 6 || e != 6 {
+		t.Errorf("default sigmas = %v, %v", n, e)
+	}
+	fix.HDOP, fix.HasHDOP = 1.5, true
+	if n, e := filter.positionSigmas(fix); n != 4.5 || e != 4.5 {
+		t.Errorf("HDOP sigmas = %v, %v", n, e)
+	}
+	// GST标准差优先于HDOP
+	fix.Errors.SigmaLat, fix.Errors.SigmaLon, fix.HasSigmas = 1.2, 0.01, true
+	if n, e := filter.positionSigmas(fix); n != 1.2 || e != kalmanMinSigma {
+		t.Errorf("GST sigmas = %v, %v", n, e)
+	}
+}
+
+func TestDriverKalman(t *testing.T) {
+	filtered := simProtocols("30.0")
+	filtered[ProtocolSIM]["speed"] = "10"
+	filtered[ProtocolSIM]["radius"] = "500"
+	filtered[ProtocolSIM]["noise"] = "3"
+	filtered[ProtocolSIM]["kalman"] = "true"
+	driver, _ := newTestDriverWithConfig(t, map[string]string{},
+		models.Device{Name: "GPS-A", Protocols: filtered},
+		models.Device{Name: "GPS-B", Protocols: simProtocols("40.0")},
+	)
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	time.Sleep(time.Second)
+	if speed, _ := readResource(t, driver, "GPS-A", "filtered_speed").Float64Value(); math.Abs(speed-10) > 1 {
+		t.Errorf("filtered_speed = %v, expected about 10", speed)
+	}
+	lat, _ := readResource(t, driver, "GPS-A", "filtered_latitude").Float64Value()
+	if raw, _ := readResource(t, driver, "GPS-A", "latitude_deg").Float64Value(); math.Abs(lat-raw) > 1e-4 {
+		t.Errorf("filtered_latitude = %v, raw = %v", lat, raw)
+	}
+
+	// 未开启滤波的设备没有滤波结果
+	readResource(t, driver, "GPS-B", "latitude_deg")
+	res, err := driver.HandleReadCommands("GPS-B", nil, []dsModels.CommandRequest{{DeviceResourceName: "filtered_latitude"}})
+	if err != nil || len(res) != 0 {
+		t.Errorf("filtered_latitude without filter = %v, %v", res, err)
+	}
+}
@@ -0,0 +1,317 @@
+package driver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+// 运动状态
+const (
+	MotionUnknown    = "unknown"
+	MotionStationary = "stationary"
+	MotionMoving     = "moving"
+)
+
+// motionMinSamples 判定运动状态所需的最少定位点数
+const motionMinSamples = 3
+
+// MotionConfig 运动状态判定配置
+type MotionConfig struct {
+	Window           time.Duration // 参与判定的定位时间窗口
+	StationarySpeed  float64       // 窗口平均速度低于该值（米/秒）可判为静止
+	MovingSpeed      float64       // 窗口平均速度不低于该值（米/秒）可判为运动，两个阈值之间保持原状态
+	StationaryRadius float64       // 静止时位置离散的最大半径（米）
+	CourseJitter     float64       // 航向圆周标准差的上限（度），超过时航向不稳定，不判为运动
+	Hold             bool          // 静止时保持位置、速度和航向不变
+	ReceiverHold     float64       // 通过BM_SPDHOLD_SID设置的接收机静态速度阈值（米/秒），0表示不设置
+}
+
+// motionSample 窗口中的一个定位点
+type motionSample struct {
+	time      time.Time
+	position  LatLon
+	speed     float64
+	course    float64
+	hasSpeed  bool
+	hasCourse bool
+}
+
+// MotionClassifier 根据速度、航向稳定性和位置离散程度判定设备处于静止、运动还是未知状态，
+// 静止期间保持进入静止时的位置，避免位置漂移不断触发onChange自动事件。可并发使用。
+type MotionClassifier struct {
+	mutex     sync.Mutex
+	cfg       MotionConfig
+	samples   []motionSample
+	state     string
+	held      Fix     // 静止时保持的定位，位置为进入静止时窗口的中心
+	course    float64 // 最近一次速度达到MovingSpeed时的航向，低速时接收机输出的航向没有意义
+	hasCourse bool
+}
+
+// NewMotionClassifier 创建运动状态判定器，初始状态为unknown
+func NewMotionClassifier(cfg MotionConfig) *MotionClassifier {
+	return &MotionClassifier{cfg: cfg, state: MotionUnknown}
+}
+
+// Update 用新的有效定位更新运动状态，状态发生变化时changed为true。
+// 定位中断超过一个窗口时清空窗口并回到unknown。
+func (m *MotionClassifier) Update(fix Fix) (state string, changed bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if n := len(m.samples); n > 0 && fix.Time.Sub(m.samples[n-1].time) > m.cfg.Window {
+		m.samples = m.samples[:0]
+	}
+	m.samples = append(m.samples, motionSample{
+		time:      fix.Time,
+		position:  LatLon{fix.Latitude, fix.Longitude},
+		speed:     fix.Speed,
+		course:    fix.Course,
+		hasSpeed:  fix.HasSpeed,
+		hasCourse: fix.HasCourse,
+	})
+	if fix.HasCourse && fix.HasSpeed && fix.Speed >= m.cfg.MovingSpeed {
+		m.course, m.hasCourse = fix.Course, true
+	}
+	// 丢弃窗口之外的旧定位
+	drop := 0
+	for drop < len(m.samples) && fix.Time.Sub(m.samples[drop].time) > m.cfg.Window {
+		drop++
+	}
+	m.samples = append(m.samples[:0], m.samples[drop:]...)
+
+	state = m.classify()
+	if state == m.state {
+		return state, false
+	}
+	if state == MotionStationary {
+		c := m.centroid()
+		m.held = fix
+		m.held.Latitude, m.held.Longitude = c.Lat, c.Lon
+		m.held.Speed = 0
+		m.held.Course, m.held.HasCourse = m.course, m.hasCourse
+	}
+	m.state = state
+	return state, true
+}
+
+// classify 按当前窗口判定状态，不满足静止或运动条件时保持原状态
+func (m *MotionClassifier) classify() string {
+	if len(m.samples) < motionMinSamples {
+		return MotionUnknown
+	}
+	speed, ok := m.meanSpeed()
+	if !ok {
+		return MotionUnknown
+	}
+
+	jitter := m.courseJitter()
+	switch {
+	case speed < m.cfg.StationarySpeed && m.spread() <= m.cfg.StationaryRadius:
+		return MotionStationary
+	case speed >= m.cfg.MovingSpeed && jitter <= m.cfg.CourseJitter:
+		return MotionMoving
+	case m.displacement() > m.cfg.StationaryRadius && jitter <= m.cfg.CourseJitter:
+		// 速度很低但朝同一方向持续移动
+		return MotionMoving
+	}
+	return m.state
+}
+
+// meanSpeed 窗口内的平均速度
+func (m *MotionClassifier) meanSpeed() (float64, bool) {
+	sum, n := 0.0, 0
+	for _, sample := range m.samples {
+		if sample.hasSpeed {
+			sum += sample.speed
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// courseJitter 航向的圆周标准差（度），没有航向数据时返回0
+func (m *MotionClassifier) courseJitter() float64 {
+	var x, y float64
+	n := 0
+	for _, sample := range m.samples {
+		if sample.hasCourse {
+			sin, cos := math.Sincos(sample.course * math.Pi / 180)
+			x, y = x+cos, y+sin
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	r := math.Hypot(x, y) / float64(n)
+	if r <= 0 {
+		return 180
+	}
+	return math.Min(math.Sqrt(-2*math.Log(r))*180/math.Pi, 180)
+}
+
+// centroid 窗口内定位点的中心
+func (m *MotionClassifier) centroid() LatLon {
+	var c LatLon
+	for _, sample := range m.samples {
+		c.Lat += sample.position.Lat
+		c.Lon += sample.position.Lon
+	}
+	c.Lat /= float64(len(m.samples))
+	c.Lon /= float64(len(m.samples))
+	return c
+}
+
+// spread 窗口内定位点到中心的最大距离（米）
+func (m *MotionClassifier) spread() float64 {
+	c := m.centroid()
+	spread := 0.0
+	for _, sample := range m.samples {
+		spread = math.Max(spread, haversine(c, sample.position))
+	}
+	return spread
+}
+
+// displacement 窗口首尾定位点之间的距离（米）
+func (m *MotionClassifier) displacement() float64 {
+	return haversine(m.samples[0].position, m.samples[len(m.samples)-1].position)
+}
+
+// State 返回当前运动状态
+func (m *MotionClassifier) State() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.state
+}
+
+// Apply 静止且开启位置保持时，用保持的位置、速度和航向替换fix中的对应字段，held表示是否替换
+func (m *MotionClassifier) Apply(fix Fix) (_ Fix, held bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.cfg.Hold || m.state != MotionStationary || !fix.HasPosition {
+		return fix, false
+	}
+	fix.Latitude, fix.Longitude = m.held.Latitude, m.held.Longitude
+	if fix.HasSpeed {
+		fix.Speed = 0
+	}
+	fix.Course, fix.HasCourse = m.held.Course, m.held.HasCourse
+	return fix, true
+}
+
+// heldResources 静止时保持不变的资源
+var heldResources = map[string]bool{
+	"latitude":      true,
+	"longitude":     true,
+	"position":      true,
+	"speed":         true,
+	"course":        true,
+	"latitude_deg":  true,
+	"longitude_deg": true,
+	"speed_mps":     true,
+	"course_deg":    true,
+	"fix":           true,
+}
+
+// getHeldValue 静止保持期间读取位置、速度或航向资源，held为false时按正常方式读取
+func (s *Driver) getHeldValue(rcv *receiver, req dsModels.CommandRequest) (cv *dsModels.CommandValue, held bool) {
+	if !heldResources[req.DeviceResourceName] {
+		return nil, false
+	}
+	fix, held := rcv.motion.Apply(rcv.gps.Fix())
+	if !held {
+		return nil, false
+	}
+
+	switch req.DeviceResourceName {
+	case "latitude":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, s.FormatCoordinate(fix.Latitude, true, hemisphere(fix.Latitude, true)))
+	case "longitude":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, s.FormatCoordinate(fix.Longitude, false, hemisphere(fix.Longitude, false)))
+	case "position":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, s.FormatPosition(fix.Latitude, fix.Longitude))
+	case "speed":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, s.FormatSpeed(0))
+	case "course":
+		if !fix.HasCourse {
+			return nil, true
+		}
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, s.FormatCourse(fix.Course))
+	default:
+		valueType, value, ok := fixResources[req.DeviceResourceName](fix)
+		if !ok {
+			return nil, true
+		}
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, valueType, value)
+	}
+	return cv, true
+}
+
+// getMotionState 获取运动状态
+func (s *Driver) getMotionState(motion *MotionClassifier, req dsModels.CommandRequest) *dsModels.CommandValue {
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, motion.State())
+	return cv
+}
+
+// updateMotion 更新设备的运动状态，状态变化时通过异步通道上报motion_state
+func (s *Driver) updateMotion(deviceName string, rcv *receiver, fix Fix) string {
+	state, changed := rcv.motion.Update(fix)
+	if !changed {
+		return state
+	}
+
+	s.lc.Infof("设备%s运动状态变为%s", deviceName, state)
+	cv, err := dsModels.NewCommandValueWithOrigin("motion_state", common.ValueTypeString, state, fix.Time.UnixNano())
+	if err != nil {
+		s.lc.Errorf("创建motion_state读数失败: %v", err)
+		return state
+	}
+	select {
+	case s.asyncCh <- &dsModels.AsyncValues{DeviceName: deviceName, SourceName: "motion_state", CommandValues: []*dsModels.CommandValue{cv}}:
+	case <-rcv.gps.Done():
+	}
+	return state
+}
+
+// CfgSpdHold 构建BM_SPDHOLD_SID静态速度阈值配置命令。
+// 载荷为阈值（厘米/秒，uint16小端），接收机速度低于阈值时冻结输出位置，0表示关闭。
+func CfgSpdHold(threshold float64) ([]byte, error) {
+	cms := math.Round(threshold * 100)
+	if cms < 0 || cms > math.MaxUint16 {
+		return nil, fmt.Errorf("静态速度阈值超出范围: %v", threshold)
+	}
+	payload := binary.LittleEndian.AppendUint16(nil, uint16(cms))
+	return BuildBinaryFrame(BIN_CFG_GID, uint8(BM_SPDHOLD_SID), payload), nil
+}
+
+// SetSpeedHold 设置接收机的静态速度阈值，等待设备ACK
+func SetSpeedHold(lcx6xz *LCX6XZ, threshold float64) error {
+	cmd, err := CfgSpdHold(threshold)
+	if err != nil {
+		return err
+	}
+	return lcx6xz.transact(cmd, ackMatcher(BIN_CFG_GID, uint8(BM_SPDHOLD_SID)))
+}
+
+// setupSpeedHold 按ReceiverHold配置接收机的静态保持，回放设备不支持命令，跳过
+func (s *Driver) setupSpeedHold(deviceName string, rcv *receiver) {
+	if s.config.Motion.ReceiverHold <= 0 || rcv.replay != nil {
+		return
+	}
+	if err := SetSpeedHold(rcv.gps, s.config.Motion.ReceiverHold); err != nil {
+		s.lc.Errorf("设备%s设置静态速度阈值失败: %v", deviceName, err)
+		return
+	}
+	s.lc.Infof("设备%s静态速度阈值已设置为%vm/s", deviceName, s.config.Motion.ReceiverHold)
+}
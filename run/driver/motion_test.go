@@ -0,0 +1,135 @@
+package driver
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+func TestMotionClassifier(t *testing.T) {
+	cfg := MotionConfig{Window: 5 * time.Second, StationarySpeed: 0.3, MovingSpeed: 1, StationaryRadius: 5, CourseJitter: 60, Hold: true}
+	classifier := NewMotionClassifier(cfg)
+	start := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	origin := LatLon{30, 104}
+
+	// 每秒一个定位，north/east为相对origin的偏移
+	path := []struct {
+		north, east   float64
+		speed, course float64
+		expected      string
+	}{
+		{0, 0, 10, 90, MotionUnknown},
+		{0, 10, 10, 91, MotionUnknown},
+		{0, 20, 10, 89, MotionMoving}, // 窗口中有3个点
+		{0, 25, 5, 90, MotionMoving},
+		{0, 27, 0.1, 200, MotionMoving},
+		{1, 28, 0.1, 20, MotionMoving},
+		{-1, 26, 0.2, 310, MotionMoving},
+		{0, 27, 0.1, 100, MotionMoving},
+		{1, 27, 0.1, 170, MotionMoving},
+		{-1, 28, 0.1, 260, MotionStationary}, // 窗口内只剩静止的点
+		{2, 29, 0.6, 45, MotionStationary},
+		{0, 27, 0.1, 300, MotionStationary},
+		{0, 30, 3, 90, MotionStationary}, // 平均速度介于两个阈值之间，保持静止
+		{0, 33, 3, 90, MotionStationary}, // 速度已够，但窗口内航向不稳定
+		{0, 36, 3, 90, MotionStationary},
+		{0, 39, 3, 90, MotionMoving},
+	}
+	for i, step := range path {
+		fix := trackFix(0, 0)
+		fix.Time = start.Add(time.Duration(i) * time.Second)
+		p := offsetMeters(origin, step.north, step.east)
+		fix.Latitude, fix.Longitude = p.Lat, p.Lon
+		fix.Speed, fix.Course, fix.HasSpeed, fix.HasCourse = step.speed, step.course, true, true
+
+		state, changed := classifier.Update(fix)
+		if state != step.expected {
+			t.Errorf("step %d: state = %s, expected %s", i, state, step.expected)
+		}
+		if changed != (i == 2 || i == 9 || i == 15) {
+			t.Errorf("step %d: changed = %v", i, changed)
+		}
+
+		held, ok := classifier.Apply(fix)
+		if ok != (state == MotionStationary) {
+			t.Errorf("step %d: Apply held = %v", i, ok)
+		}
+		if !ok {
+			continue
+		}
+		// 保持进入静止时窗口的中心，速度为0，航向为停车前的航向
+		center := offsetMeters(origin, 0, 27.2)
+		if math.Abs(held.Latitude-center.Lat) > 1e-5 || math.Abs(held.Longitude-center.Lon) > 1e-5 || held.Speed != 0 || held.Course != 90 {
+			t.Errorf("step %d: held = (%v, %v) speed %v course %v", i, held.Latitude, held.Longitude, held.Speed, held.Course)
+		}
+	}
+
+	// 定位中断超过一个窗口后回到unknown
+	fix := trackFix(0, 0)
+	fix.Time = start.Add(time.Minute)
+	fix.Speed, fix.HasSpeed = 0, true
+	if state, changed := classifier.Update(fix); state != MotionUnknown || !changed {
+		t.Errorf("after outage: state = %s, changed = %v", state, changed)
+	}
+}
+
+func TestCfgSpdHold(t *testing.T) {
+	cmd, err := CfgSpdHold(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := BuildBinaryFrame(BIN_CFG_GID, uint8(BM_SPDHOLD_SID), []byte{0x32, 0x00})
+	if !bytes.Equal(cmd, expected) || !bytes.Equal(cmd[:8], []byte{0xF1, 0xD9, 0x06, 0x0F, 0x02, 0x00, 0x32, 0x00}) {
+		t.Errorf("CfgSpdHold(0.5) = % X", cmd)
+	}
+	if _, err := CfgSpdHold(1000); err == nil {
+		t.Error("CfgSpdHold(1000) succeeded, expected error")
+	}
+}
+
+func TestDriverMotion(t *testing.T) {
+	configs := map[string]string{configMotionReceiverHold: "0.2"}
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["noise"] = "1"
+	driver, asyncCh := newTestDriverWithConfig(t, configs, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	select {
+	case values := <-asyncCh:
+		if values.SourceName != "motion_state" || values.CommandValues[0].Value != MotionStationary || values.CommandValues[0].Origin == 0 {
+			t.Errorf("async values = %+v", values.CommandValues[0])
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待超时: motion_state")
+	}
+	if state := readResource(t, driver, "GPS-A", "motion_state").Value; state != MotionStationary {
+		t.Errorf("motion_state = %v", state)
+	}
+
+	// 静止期间位置保持不变
+	rcv, _ := driver.receiverFor("GPS-A")
+	held, _ := rcv.motion.Apply(rcv.gps.Fix())
+	for _, resource := range []string{"latitude_deg", "longitude_deg"} {
+		value, _ := readResource(t, driver, "GPS-A", resource).Float64Value()
+		expected := held.Latitude
+		if resource == "longitude_deg" {
+			expected = held.Longitude
+		}
+		if value != expected {
+			t.Errorf("%s = %v, expected held %v", resource, value, expected)
+		}
+	}
+	if speed, _ := readResource(t, driver, "GPS-A", "speed_mps").Float64Value(); speed != 0 {
+		t.Errorf("speed_mps = %v, expected 0", speed)
+	}
+
+	// 模拟接收机应答BM_SPDHOLD_SID
+	if err := SetSpeedHold(rcv.gps, 0.2); err != nil {
+		t.Errorf("SetSpeedHold failed: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package driver
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -359,6 +360,12 @@ func ValidateNMEAChecksum(sentence string, length int) bool {
 	return actualChecksum == byte(expectedChecksum)
 }
 
+// BuildNMEASentence 为语句主体（不含$和*）计算校验和，生成以\r\n结尾的完整NMEA语句
+func BuildNMEASentence(body string) string {
+	checksum := QlCheckXOR([]byte(body), uint(len(body)))
+	return fmt.Sprintf("$%s*%02X\r\n", body, checksum)
+}
+
 // ParsNMEAType 解析NMEA语句类型
 func ParsNMEAType(strNMEA string, length int) NMEA_TYPE {
 	if length < 6 {
@@ -400,8 +407,43 @@ func ParsNMEAType(strNMEA string, length int) NMEA_TYPE {
 
 // ParsNMEAGST 解析GST语句
 func ParsNMEAGST(strGST string, length int) *NMEA_GST {
-	// 实现解析GST语句的逻辑
-	return nil
+	if length < 10 {
+		return nil
+	}
+
+	// 验证校验和
+	if !ValidateNMEAChecksum(strGST, length) {
+		return nil
+	}
+
+	// 去掉校验和后分割字段
+	body, _, _ := strings.Cut(strGST, "*")
+	fields := strings.Split(body, ",")
+	if len(fields) < 9 {
+		return nil
+	}
+
+	gst := &NMEA_GST{}
+
+	// 解析TalkerID和Type
+	if len(fields[0]) >= 6 {
+		copy(gst.Nmea.TalkerID[:], fields[0][1:3])
+		copy(gst.Nmea.Type[:], fields[0][3:6])
+	}
+
+	// 解析UTC时间
+	if len(fields[1]) > 0 && len(fields[1]) <= 10 {
+		copy(gst.UTC[:], fields[1])
+	}
+
+	// 解析RMS值、误差椭圆和各方向标准差
+	for i, dst := range [][]byte{gst.RMS_D[:], gst.MajorD[:], gst.MinorD[:], gst.Orient[:], gst.LatD[:], gst.LonD[:], gst.AltD[:]} {
+		if field := fields[i+2]; len(field) > 0 && len(field) <= 9 {
+			copy(dst, field)
+		}
+	}
+
+	return gst
 }
 
 // ParsNMEAGRS 解析GRS语句
@@ -412,8 +454,50 @@ func ParsNMEAGRS(strGRS string, length int) *NMEA_GRS {
 
 // ParsNMEAZDA 解析ZDA语句
 func ParsNMEAZDA(strZDA string, length int) *NMEA_ZDA {
-	// 实现解析ZDA语句的逻辑
-	return nil
+	if length < 10 {
+		return nil
+	}
+
+	// 验证校验和
+	if !ValidateNMEAChecksum(strZDA, length) {
+		return nil
+	}
+
+	// 去掉校验和后分割字段
+	body, _, _ := strings.Cut(strZDA, "*")
+	fields := strings.Split(body, ",")
+	if len(fields) < 5 {
+		return nil
+	}
+
+	zda := &NMEA_ZDA{}
+
+	// 解析TalkerID和Type
+	if len(fields[0]) >= 6 {
+		copy(zda.Nmea.TalkerID[:], fields[0][1:3])
+		copy(zda.Nmea.Type[:], fields[0][3:6])
+	}
+
+	// 解析UTC时间
+	if len(fields[1]) > 0 && len(fields[1]) <= 10 {
+		copy(zda.UTC[:], fields[1])
+	}
+
+	// 解析日、月、年
+	for i, dst := range [][]byte{zda.Day[:], zda.Month[:], zda.Year[:]} {
+		if field := fields[i+2]; len(field) > 0 && len(field) < len(dst) {
+			copy(dst, field)
+		}
+	}
+
+	// 解析本时区时差（如果存在）
+	for i, dst := range [][]byte{zda.LocalHour[:], zda.LocalMin[:]} {
+		if len(fields) > i+5 && len(fields[i+5]) > 0 && len(fields[i+5]) < len(dst) {
+			copy(dst, fields[i+5])
+		}
+	}
+
+	return zda
 }
 
 // ParsNMEAGLL 解析GLL语句
@@ -563,8 +647,9 @@ func ParsNMEAGSA(strGSA string, length int) *NMEA_GSA {
 		return nil
 	}
 
-	// 分割字段
-	fields := strings.Split(strGSA, ",")
+	// 去掉校验和后分割字段，避免VDOP或系统标识符带上*hh
+	body, _, _ := strings.Cut(strGSA, "*")
+	fields := strings.Split(body, ",")
 	if len(fields) < 18 {
 		return nil
 	}
@@ -610,7 +695,7 @@ func ParsNMEAGSA(strGSA string, length int) *NMEA_GSA {
 	}
 
 	// 解析系统标识符（如果存在）
-	if len(fields) > 18 && len(fields[18]) > 0 {
+	if len(fields) > 18 && len(fields[18]) > 0 && len(fields[18]) <= 1 {
 		copy(gsa.SystemID[:], fields[18])
 	}
 
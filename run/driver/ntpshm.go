@@ -0,0 +1,97 @@
+package driver
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+const (
+	// ntpShmKey NTP共享内存0号单元的SysV IPC键（"NTP0"），单元n的键为ntpShmKey+n
+	ntpShmKey = 0x4e545030
+	// ntpShmSize 64位系统上struct shmTime的大小
+	ntpShmSize = 96
+)
+
+// struct shmTime各字段的偏移，与ntpd refclock_shm.c、chrony refclock_shm.c和gpsd ntpshm.c一致
+const (
+	shmMode        = 0
+	shmCount       = 4
+	shmClockSec    = 8
+	shmClockUSec   = 16
+	shmReceiveSec  = 24
+	shmReceiveUSec = 32
+	shmLeap        = 36
+	shmPrecision   = 40
+	shmNSamples    = 44
+	shmValid       = 48
+	shmClockNSec   = 52
+	shmReceiveNSec = 56
+)
+
+// TimeSample 写入NTP共享内存的一个时间样本
+type TimeSample struct {
+	Clock     time.Time // GNSS给出的真实时间
+	Receive   time.Time // 同一时刻的本机时间
+	Precision int       // 精度，2的幂次秒，如-1约0.5秒，-20约1微秒
+	Leap      int       // 闰秒提示，0表示无
+}
+
+// NTPShm 一个NTP共享内存单元，chronyd（refclock SHM n）或ntpd（127.127.28.n）从中读取时间样本
+type NTPShm struct {
+	mutex sync.Mutex
+	mem   []byte
+	close func() error
+}
+
+// NewNTPShm 基于已映射的内存创建共享内存单元，mem至少为ntpShmSize字节且按8字节对齐
+func NewNTPShm(mem []byte) *NTPShm {
+	return &NTPShm{mem: mem[:ntpShmSize]}
+}
+
+// Put 按mode 1协议写入样本：先清除valid并递增count，写完字段后再次递增count并置valid，
+// 读取方发现前后count不一致时丢弃该样本。关闭后写入的样本被丢弃。
+func (n *NTPShm) Put(sample TimeSample) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.mem == nil {
+		return
+	}
+
+	atomic.StoreInt32(n.int32At(shmValid), 0)
+	atomic.AddInt32(n.int32At(shmCount), 1)
+
+	order := binary.NativeEndian
+	order.PutUint32(n.mem[shmMode:], 1)
+	order.PutUint64(n.mem[shmClockSec:], uint64(sample.Clock.Unix()))
+	order.PutUint32(n.mem[shmClockUSec:], uint32(sample.Clock.Nanosecond()/1000))
+	order.PutUint32(n.mem[shmClockNSec:], uint32(sample.Clock.Nanosecond()))
+	order.PutUint64(n.mem[shmReceiveSec:], uint64(sample.Receive.Unix()))
+	order.PutUint32(n.mem[shmReceiveUSec:], uint32(sample.Receive.Nanosecond()/1000))
+	order.PutUint32(n.mem[shmReceiveNSec:], uint32(sample.Receive.Nanosecond()))
+	order.PutUint32(n.mem[shmLeap:], uint32(int32(sample.Leap)))
+	order.PutUint32(n.mem[shmPrecision:], uint32(int32(sample.Precision)))
+	order.PutUint32(n.mem[shmNSamples:], 3)
+
+	atomic.AddInt32(n.int32At(shmCount), 1)
+	atomic.StoreInt32(n.int32At(shmValid), 1)
+}
+
+// Close 解除共享内存映射，共享内存段本身保留给时间服务继续使用
+func (n *NTPShm) Close() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.mem = nil
+	if n.close == nil {
+		return nil
+	}
+	return n.close()
+}
+
+func (n *NTPShm) int32At(offset int) *int32 {
+	return (*int32)(unsafe.Pointer(&n.mem[offset]))
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package driver
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenNTPShm 打开（不存在时创建）NTP共享内存单元。与ntpd和gpsd的约定一致，
+// 0、1号单元只有root可访问，其余单元所有用户可读写。
+func OpenNTPShm(unit int) (*NTPShm, error) {
+	perm := 0o666
+	if unit < 2 {
+		perm = 0o600
+	}
+	id, err := unix.SysvShmGet(ntpShmKey+unit, ntpShmSize, unix.IPC_CREAT|perm)
+	if err != nil {
+		return nil, fmt.Errorf("获取NTP共享内存单元%d失败: %w", unit, err)
+	}
+	mem, err := unix.SysvShmAttach(id, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("映射NTP共享内存单元%d失败: %w", unit, err)
+	}
+	if len(mem) < ntpShmSize {
+		_ = unix.SysvShmDetach(mem)
+		return nil, fmt.Errorf("NTP共享内存单元%d只有%d字节，可能由32位程序创建", unit, len(mem))
+	}
+
+	shm := NewNTPShm(mem)
+	shm.close = func() error { return unix.SysvShmDetach(mem) }
+	return shm, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package driver
+
+import "errors"
+
+// OpenNTPShm 仅支持Linux
+func OpenNTPShm(unit int) (*NTPShm, error) {
+	return nil, errors.New("NTP共享内存仅支持Linux")
+}
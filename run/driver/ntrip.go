@@ -0,0 +1,432 @@
+package driver
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/spf13/cast"
+)
+
+// NTRIP客户端的连接状态
+const (
+	NTRIPDisconnected = "disconnected"
+	NTRIPConnecting   = "connecting"
+	NTRIPConnected    = "connected"
+)
+
+const (
+	ntripDefaultPort = "2101"
+	ntripUserAgent   = "NTRIP device-gps/1.0"
+	ntripRateWindow  = 10 * time.Second // 计算差分数据速率的时间窗口
+	ntripBufferSize  = 4096
+)
+
+// NTRIPConfig NTRIP客户端配置
+type NTRIPConfig struct {
+	Enabled        bool          // 设备未设置ntrip协议属性时是否开启
+	Caster         string        // 播发服务器地址，host:port，未指定端口时使用2101
+	Mountpoint     string        // 挂载点，设备可用ntripMountpoint协议属性覆盖
+	Version        int           // NTRIP协议版本，1或2
+	SecretName     string        // 保存username和password的密钥名称
+	GGAInterval    time.Duration // 向播发服务器回传GGA的间隔，0表示不回传
+	ReconnectDelay time.Duration // 连接断开后重连前的等待时间
+	Timeout        time.Duration // 连接超时，以及超过该时间没有收到差分数据时断开重连
+}
+
+// NTRIPStatus NTRIP客户端状态
+type NTRIPStatus struct {
+	State      string
+	HasData    bool          // 是否收到过差分数据
+	Age        time.Duration // 距最近一次收到差分数据的时间
+	Rate       float64       // ntripRateWindow内的差分数据速率（字节/秒）
+	Bytes      int64         // 累计收到的差分数据字节数
+	Reconnects int
+	LastError  string
+}
+
+// ntripSample 某一时刻收到的差分数据字节数
+type ntripSample struct {
+	at    time.Time
+	bytes int
+}
+
+// NTRIPClient 从NTRIP播发服务器接收RTCM3差分数据并写入接收机，断开后自动重连。可并发使用。
+type NTRIPClient struct {
+	cfg         NTRIPConfig
+	credentials func() (username, password string, err error) // 每次连接时获取，便于密钥轮换
+	position    func() (Fix, bool)                            // 当前定位，用于回传GGA
+	sink        io.Writer
+
+	mutex      sync.Mutex
+	state      string
+	connected  time.Time // 本次连接建立的时间
+	lastData   time.Time
+	samples    []ntripSample
+	bytes      int64
+	reconnects int
+	lastError  string
+}
+
+// NewNTRIPClient 创建NTRIP客户端，credentials和position可以为nil
+func NewNTRIPClient(cfg NTRIPConfig, sink io.Writer, credentials func() (string, string, error), position func() (Fix, bool)) *NTRIPClient {
+	return &NTRIPClient{cfg: cfg, sink: sink, credentials: credentials, position: position, state: NTRIPDisconnected}
+}
+
+// Run 连接播发服务器并转发差分数据，出错时调用report后等待ReconnectDelay重连，done关闭后返回
+func (c *NTRIPClient) Run(done <-chan struct{}, report func(error)) {
+	for {
+		err := c.session(done)
+		select {
+		case <-done:
+			c.setState(NTRIPDisconnected, nil)
+			return
+		default:
+		}
+
+		c.setState(NTRIPDisconnected, err)
+		if report != nil {
+			report(err)
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(c.cfg.ReconnectDelay):
+		}
+		c.mutex.Lock()
+		c.reconnects++
+		c.mutex.Unlock()
+	}
+}
+
+// session 建立一次连接并转发差分数据直到出错
+func (c *NTRIPClient) session(done <-chan struct{}) error {
+	c.setState(NTRIPConnecting, nil)
+
+	var username, password string
+	if c.credentials != nil {
+		var err error
+		if username, password, err = c.credentials(); err != nil {
+			return fmt.Errorf("获取NTRIP账号失败: %w", err)
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", ntripAddress(c.cfg.Caster), c.cfg.Timeout)
+	if err != nil {
+		return fmt.Errorf("连接NTRIP播发服务器失败: %w", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-done:
+		case <-stop:
+		}
+		_ = conn.Close()
+	}()
+
+	gga := c.gga()
+	if _, err := io.WriteString(conn, ntripRequest(c.cfg, username, password, gga)); err != nil {
+		return fmt.Errorf("发送NTRIP请求失败: %w", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(c.cfg.Timeout))
+	body, err := readNTRIPResponse(bufio.NewReader(conn))
+	if err != nil {
+		return err
+	}
+	c.setState(NTRIPConnected, nil)
+
+	if c.cfg.GGAInterval > 0 {
+		go c.sendGGA(conn, gga, stop)
+	}
+
+	buf := make([]byte, ntripBufferSize)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(c.cfg.Timeout))
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, writeErr := c.sink.Write(buf[:n]); writeErr != nil {
+				return fmt.Errorf("差分数据写入接收机失败: %w", writeErr)
+			}
+			c.record(n, time.Now())
+		}
+		if errors.Is(err, io.EOF) {
+			return errors.New("NTRIP播发服务器关闭了连接")
+		}
+		if err != nil {
+			return fmt.Errorf("接收差分数据失败: %w", err)
+		}
+	}
+}
+
+// sendGGA 连接建立后回传一次GGA，此后每隔GGAInterval回传一次，供VRS网络生成虚拟基站
+func (c *NTRIPClient) sendGGA(conn net.Conn, first string, stop <-chan struct{}) {
+	ticker := time.NewTicker(c.cfg.GGAInterval)
+	defer ticker.Stop()
+
+	for gga := first; ; gga = c.gga() {
+		if gga != "" {
+			_ = conn.SetWriteDeadline(time.Now().Add(c.cfg.Timeout))
+			if _, err := io.WriteString(conn, gga); err != nil {
+				return
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// gga 当前定位的GGA语句，没有有效定位或不需要回传时返回空字符串
+func (c *NTRIPClient) gga() string {
+	if c.position == nil || c.cfg.GGAInterval <= 0 {
+		return ""
+	}
+	fix, ok := c.position()
+	if !ok {
+		return ""
+	}
+	return ggaSentence(fix)
+}
+
+// record 记录收到的差分数据
+func (c *NTRIPClient) record(n int, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.lastData = now
+	c.bytes += int64(n)
+	kept := c.samples[:0]
+	for _, sample := range c.samples {
+		if now.Sub(sample.at) < ntripRateWindow {
+			kept = append(kept, sample)
+		}
+	}
+	c.samples = append(kept, ntripSample{now, n})
+}
+
+// setState 更新连接状态，err非nil时记录为最近一次错误
+func (c *NTRIPClient) setState(state string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if state == NTRIPConnected {
+		c.connected = time.Now()
+	}
+	c.state = state
+	if err != nil {
+		c.lastError = err.Error()
+	}
+}
+
+// Status 返回now时刻的客户端状态
+func (c *NTRIPClient) Status(now time.Time) NTRIPStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	status := NTRIPStatus{
+		State:      c.state,
+		HasData:    !c.lastData.IsZero(),
+		Bytes:      c.bytes,
+		Reconnects: c.reconnects,
+		LastError:  c.lastError,
+	}
+	if status.HasData {
+		status.Age = now.Sub(c.lastData)
+	}
+
+	var sum int
+	for _, sample := range c.samples {
+		if now.Sub(sample.at) < ntripRateWindow {
+			sum += sample.bytes
+		}
+	}
+	// 刚连接时按实际连接时长计算，至少按1秒计
+	window := ntripRateWindow
+	if c.state == NTRIPConnected && now.Sub(c.connected) < window {
+		window = max(now.Sub(c.connected), time.Second)
+	}
+	status.Rate = float64(sum) / window.Seconds()
+	return status
+}
+
+// ntripAddress 补全播发服务器的默认端口
+func ntripAddress(caster string) string {
+	if _, _, err := net.SplitHostPort(caster); err == nil {
+		return caster
+	}
+	return net.JoinHostPort(caster, ntripDefaultPort)
+}
+
+// ntripRequest 生成请求挂载点的HTTP请求，v2请求在Ntrip-GGA头中带上当前位置
+func ntripRequest(cfg NTRIPConfig, username, password, gga string) string {
+	var b strings.Builder
+	if cfg.Version == 1 {
+		fmt.Fprintf(&b, "GET /%s HTTP/1.0\r\n", cfg.Mountpoint)
+	} else {
+		fmt.Fprintf(&b, "GET /%s HTTP/1.1\r\n", cfg.Mountpoint)
+		fmt.Fprintf(&b, "Host: %s\r\n", ntripAddress(cfg.Caster))
+		b.WriteString("Ntrip-Version: Ntrip/2.0\r\n")
+		if gga != "" {
+			fmt.Fprintf(&b, "Ntrip-GGA: %s\r\n", strings.TrimSpace(gga))
+		}
+		b.WriteString("Connection: close\r\n")
+	}
+	fmt.Fprintf(&b, "User-Agent: %s\r\n", ntripUserAgent)
+	if username != "" {
+		token := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		fmt.Fprintf(&b, "Authorization: Basic %s\r\n", token)
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// readNTRIPResponse 读取播发服务器的应答，成功时返回差分数据流。
+// v1服务器以"ICY 200 OK"开头，之后即为数据；v2服务器返回标准HTTP应答，数据可能分块传输。
+func readNTRIPResponse(br *bufio.Reader) (io.Reader, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("读取NTRIP应答失败: %w", err)
+	}
+	status := strings.TrimSpace(line)
+
+	switch {
+	case strings.HasPrefix(status, "ICY 200"):
+		return br, nil
+	case strings.HasPrefix(status, "SOURCETABLE"):
+		return nil, errors.New("NTRIP挂载点不存在，服务器返回了源列表")
+	case !strings.HasPrefix(status, "HTTP/"):
+		return nil, fmt.Errorf("无效的NTRIP应答: %q", status)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(io.MultiReader(strings.NewReader(line), br)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("读取NTRIP应答失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("NTRIP播发服务器拒绝请求: %s", resp.Status)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "gnss/sourcetable") {
+		_ = resp.Body.Close()
+		return nil, errors.New("NTRIP挂载点不存在，服务器返回了源列表")
+	}
+	return resp.Body, nil
+}
+
+// ggaSentence 由定位生成GGA语句，没有有效定位时返回空字符串
+func ggaSentence(fix Fix) string {
+	if !validTrackFix(fix) {
+		return ""
+	}
+	quality := fix.Quality
+	if quality == 0 {
+		quality = 1
+	}
+	hdop := fix.HDOP
+	if !fix.HasHDOP {
+		hdop = 99.99
+	}
+	return BuildNMEASentence(fmt.Sprintf("GPGGA,%s,%s,%s,%d,%02d,%.2f,%.1f,M,%.1f,M,,",
+		fix.Time.UTC().Format("150405.00"), formatNMEALat(fix.Latitude), formatNMEALon(fix.Longitude),
+		quality, min(fix.Satellites, 99), hdop, fix.Altitude, fix.GeoidSeparation))
+}
+
+// newNTRIPClient 按驱动配置和设备协议属性创建NTRIP客户端，未开启时返回nil
+func (s *Driver) newNTRIPClient(deviceName string, rcv *receiver, protocols map[string]models.ProtocolProperties) *NTRIPClient {
+	cfg := s.config.NTRIP
+	for _, protocol := range protocols {
+		if value, ok := protocol["ntrip"]; ok && value != "" {
+			enabled, err := cast.ToBoolE(value)
+			if err != nil {
+				s.lc.Errorf("设备%s的ntrip属性无效: %v，使用默认设置", deviceName, value)
+			} else {
+				cfg.Enabled = enabled
+			}
+		}
+		if mountpoint := cast.ToString(protocol["ntripMountpoint"]); mountpoint != "" {
+			cfg.Mountpoint = mountpoint
+		}
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Caster == "" || cfg.Mountpoint == "" {
+		s.lc.Errorf("设备%s开启了NTRIP，但未配置%s或%s", deviceName, configNtripCaster, configNtripMountpoint)
+		return nil
+	}
+	if rcv.replay != nil {
+		s.lc.Warnf("设备%s为回放设备，不能接收差分数据", deviceName)
+		return nil
+	}
+
+	position := func() (Fix, bool) {
+		fix := rcv.gps.Fix()
+		return fix, validTrackFix(fix)
+	}
+	if rcv.corrections == nil {
+		rcv.corrections = NewRTCMMonitor()
+	}
+	// 先逐帧写入接收机，再统计电文
+	sink := io.MultiWriter(NewRTCMFrameWriter(rcv.gps.Port()), rcv.corrections.Stream(RTCMSourceNTRIP))
+	s.lc.Infof("设备%s将从%s/%s接收差分数据（NTRIP v%d）", deviceName, cfg.Caster, cfg.Mountpoint, cfg.Version)
+	return NewNTRIPClient(cfg, sink, s.secretCredentials(cfg.SecretName), position)
+}
+
+// secretCredentials 返回从密钥name读取用户名和密码的函数，name为空时返回空用户名
+func (s *Driver) secretCredentials(name string) func() (string, string, error) {
+	return func() (string, string, error) {
+		secrets := s.sdk.SecretProvider()
+		if secrets == nil || name == "" {
+			return "", "", nil
+		}
+		values, err := secrets.GetSecret(name, "username", "password")
+		if err != nil {
+			return "", "", err
+		}
+		return values["username"], values["password"], nil
+	}
+}
+
+// runNTRIP 运行设备的NTRIP客户端，接收机关闭后退出
+func (s *Driver) runNTRIP(deviceName string, rcv *receiver) {
+	rcv.ntrip.Run(rcv.gps.Done(), func(err error) {
+		s.lc.Warnf("设备%s的NTRIP连接断开: %v，%v后重连", deviceName, err, s.config.NTRIP.ReconnectDelay)
+	})
+	s.lc.Debugf("设备%s的NTRIP客户端已停止", deviceName)
+}
+
+// getNTRIPValue 获取ntrip_status、correction_age_s或correction_data_rate资源，设备未开启NTRIP时返回nil
+func (s *Driver) getNTRIPValue(client *NTRIPClient, req dsModels.CommandRequest) *dsModels.CommandValue {
+	if client == nil {
+		s.lc.Warnf("设备未开启NTRIP，无法读取%s", req.DeviceResourceName)
+		return nil
+	}
+	status := client.Status(time.Now())
+
+	var cv *dsModels.CommandValue
+	switch req.DeviceResourceName {
+	case "ntrip_status":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, status.State)
+	case "correction_age_s":
+		if !status.HasData {
+			return nil
+		}
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, status.Age.Seconds())
+	case "correction_data_rate":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, status.Rate)
+	}
+	return cv
+}
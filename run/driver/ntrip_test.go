@@ -0,0 +1,315 @@
+package driver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+// testCaster 本地模拟的NTRIP播发服务器，按请求的协议版本应答，发送frames后关闭连接
+type testCaster struct {
+	listener net.Listener
+	frames   [][]byte
+	user     string // 期望的Basic认证账号，为空时不认证
+
+	mutex    sync.Mutex
+	requests []*http.Request
+	gga      []string // 客户端在连接上回传的GGA
+}
+
+func startTestCaster(t *testing.T, user string, frames ...[]byte) *testCaster {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	caster := &testCaster{listener: listener, frames: frames, user: user}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go caster.serve(conn)
+		}
+	}()
+	return caster
+}
+
+func (c *testCaster) serve(conn net.Conn) {
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return
+	}
+	c.mutex.Lock()
+	c.requests = append(c.requests, req)
+	c.mutex.Unlock()
+
+	v2 := req.Header.Get("Ntrip-Version") == "Ntrip/2.0"
+	if user, password, ok := req.BasicAuth(); c.user != "" && (!ok || user+":"+password != c.user) {
+		fmt.Fprint(conn, "HTTP/1.0 401 Unauthorized\r\n\r\n")
+		return
+	}
+	if req.URL.Path != "/TEST" {
+		fmt.Fprint(conn, "SOURCETABLE 200 OK\r\n\r\nENDSOURCETABLE\r\n")
+		return
+	}
+
+	go func() {
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			c.mutex.Lock()
+			c.gga = append(c.gga, strings.TrimSpace(line))
+			c.mutex.Unlock()
+		}
+	}()
+
+	if !v2 {
+		fmt.Fprint(conn, "ICY 200 OK\r\n")
+		for _, frame := range c.frames {
+			_, _ = conn.Write(frame)
+			time.Sleep(50 * time.Millisecond)
+		}
+		return
+	}
+	fmt.Fprint(conn, "HTTP/1.1 200 OK\r\nContent-Type: gnss/data\r\nTransfer-Encoding: chunked\r\n\r\n")
+	chunked := httputil.NewChunkedWriter(conn)
+	for _, frame := range c.frames {
+		_, _ = chunked.Write(frame)
+		time.Sleep(50 * time.Millisecond)
+	}
+	_ = chunked.Close()
+	fmt.Fprint(conn, "\r\n")
+}
+
+func (c *testCaster) stats() (requests int, gga []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.requests), append([]string(nil), c.gga...)
+}
+
+func TestNTRIPRequest(t *testing.T) {
+	cfg := NTRIPConfig{Caster: "caster.example.com", Mountpoint: "RTCM3", Version: 1}
+	v1 := ntripRequest(cfg, "gps", "secret", "$GPGGA,...\r\n")
+	if !strings.HasPrefix(v1, "GET /RTCM3 HTTP/1.0\r\n") || !strings.Contains(v1, "Authorization: Basic Z3BzOnNlY3JldA==\r\n") ||
+		strings.Contains(v1, "Ntrip-") || !strings.HasSuffix(v1, "\r\n\r\n") {
+		t.Errorf("v1 request = %q", v1)
+	}
+
+	cfg.Version = 2
+	v2 := ntripRequest(cfg, "", "", "$GPGGA,...\r\n")
+	for _, header := range []string{"GET /RTCM3 HTTP/1.1\r\n", "Host: caster.example.com:2101\r\n", "Ntrip-Version: Ntrip/2.0\r\n", "Ntrip-GGA: $GPGGA,...\r\n"} {
+		if !strings.Contains(v2, header) {
+			t.Errorf("v2 request missing %q: %q", header, v2)
+		}
+	}
+	if strings.Contains(v2, "Authorization") {
+		t.Errorf("v2 request without credentials = %q", v2)
+	}
+}
+
+func TestReadNTRIPResponse(t *testing.T) {
+	tests := []struct {
+		response string
+		data     string // 为空表示期望出错
+	}{
+		{"ICY 200 OK\r\nRTCM", "RTCM"},
+		{"HTTP/1.1 200 OK\r\nContent-Type: gnss/data\r\nTransfer-Encoding: chunked\r\n\r\n4\r\nRTCM\r\n0\r\n\r\n", "RTCM"},
+		{"HTTP/1.0 401 Unauthorized\r\n\r\n", ""},
+		{"SOURCETABLE 200 OK\r\n\r\nENDSOURCETABLE\r\n", ""},
+		{"HTTP/1.1 200 OK\r\nContent-Type: gnss/sourcetable\r\nContent-Length: 16\r\n\r\nENDSOURCETABLE\r\n", ""},
+		{"220 ready\r\n", ""},
+	}
+	for _, tt := range tests {
+		body, err := readNTRIPResponse(bufio.NewReader(strings.NewReader(tt.response)))
+		if tt.data == "" {
+			if err == nil {
+				t.Errorf("readNTRIPResponse(%q) succeeded, expected error", tt.response)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readNTRIPResponse(%q) failed: %v", tt.response, err)
+			continue
+		}
+		var data bytes.Buffer
+		_, _ = data.ReadFrom(body)
+		if data.String() != tt.data {
+			t.Errorf("readNTRIPResponse(%q) data = %q", tt.response, data.String())
+		}
+	}
+}
+
+func TestGGASentence(t *testing.T) {
+	fix := trackFix(0, 0)
+	fix.Time = time.Date(2025, 6, 10, 5, 55, 25, 500e6, time.UTC)
+	fix.Latitude, fix.Longitude, fix.Altitude, fix.GeoidSeparation = 30.5, -104.25, 500.04, -32.3
+	fix.Quality, fix.Satellites, fix.HDOP, fix.HasHDOP = 4, 18, 0.8, true
+	expected := BuildNMEASentence("GPGGA,055525.50,3030.000000,N,10415.000000,W,4,18,0.80,500.0,M,-32.3,M,,")
+	if gga := ggaSentence(fix); gga != expected {
+		t.Errorf("ggaSentence() = %q, expected %q", gga, expected)
+	}
+
+	fix.HasPosition = false
+	if gga := ggaSentence(fix); gga != "" {
+		t.Errorf("ggaSentence() without position = %q", gga)
+	}
+}
+
+func TestNTRIPClient(t *testing.T) {
+	for _, version := range []int{1, 2} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			caster := startTestCaster(t, "gps:secret", []byte("\xd3\x00\x13frame-1"), []byte("\xd3\x00\x13frame-2"))
+			cfg := NTRIPConfig{
+				Caster:         caster.listener.Addr().String(),
+				Mountpoint:     "TEST",
+				Version:        version,
+				GGAInterval:    20 * time.Millisecond,
+				ReconnectDelay: 50 * time.Millisecond,
+				Timeout:        time.Second,
+			}
+			sink := &bufferPort{}
+			credentials := func() (string, string, error) { return "gps", "secret", nil }
+			position := func() (Fix, bool) { return trackFix(0, 0), true }
+			client := NewNTRIPClient(cfg, sink, credentials, position)
+			if status := client.Status(time.Now()); status.State != NTRIPDisconnected || status.HasData {
+				t.Errorf("initial Status() = %+v", status)
+			}
+
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				client.Run(done, nil)
+				close(stopped)
+			}()
+
+			// 播发服务器发完数据后断开，客户端重连后再次收到数据
+			waitFor(t, 5*time.Second, func() bool {
+				requests, _ := caster.stats()
+				return requests >= 2 && client.Status(time.Now()).Bytes >= 2*2*int64(len("\xd3\x00\x13frame-1"))
+			}, "reconnect")
+			status := client.Status(time.Now())
+			if !status.HasData || status.Age > time.Second || status.Rate <= 0 || status.Reconnects == 0 || status.LastError == "" {
+				t.Errorf("Status() = %+v", status)
+			}
+			sink.mutex.Lock()
+			if !bytes.HasPrefix(sink.tx.Bytes(), []byte("\xd3\x00\x13frame-1\xd3\x00\x13frame-2\xd3\x00\x13frame-1")) {
+				t.Errorf("sink = %q", sink.tx.Bytes())
+			}
+			sink.mutex.Unlock()
+			if _, gga := caster.stats(); len(gga) == 0 || !strings.HasPrefix(gga[0], "$GPGGA,") {
+				t.Errorf("caster received GGA = %q", gga)
+			}
+
+			close(done)
+			select {
+			case <-stopped:
+			case <-time.After(2 * time.Second):
+				t.Fatal("Run did not return after done was closed")
+			}
+			if state := client.Status(time.Now()).State; state != NTRIPDisconnected {
+				t.Errorf("state after stop = %s", state)
+			}
+		})
+	}
+
+	// 账号错误时记录错误并继续重试
+	caster := startTestCaster(t, "gps:secret")
+	cfg := NTRIPConfig{Caster: caster.listener.Addr().String(), Mountpoint: "TEST", Version: 2, ReconnectDelay: 20 * time.Millisecond, Timeout: time.Second}
+	credentials := func() (string, string, error) { return "gps", "wrong", nil }
+	client := NewNTRIPClient(cfg, &bufferPort{}, credentials, nil)
+	done := make(chan struct{})
+	defer close(done)
+	errs := make(chan error, 16)
+	go client.Run(done, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !strings.Contains(err.Error(), "401") {
+				t.Errorf("error = %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("等待超时: NTRIP错误")
+		}
+	}
+}
+
+func TestDriverNTRIP(t *testing.T) {
+	frames := make([][]byte, 100)
+	for i := range frames {
+		frames[i] = rtcmTestFrame(1077, 60)
+	}
+	frames[0], _ = hex.DecodeString(rtcm1005Sample)
+	caster := startTestCaster(t, "gps:secret", frames...)
+
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["ntrip"] = "true"
+	protocols[ProtocolSIM]["ntripMountpoint"] = "TEST"
+	configs := map[string]string{
+		configNtripCaster:      caster.listener.Addr().String(),
+		configNtripMountpoint:  "OTHER",
+		configNtripGGAInterval: "100ms",
+	}
+	driver, _ := newTestDriverWithConfig(t, configs,
+		models.Device{Name: "GPS-A", Protocols: protocols},
+		models.Device{Name: "GPS-B", Protocols: simProtocols("40.0")},
+	)
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	waitFor(t, 5*time.Second, func() bool {
+		rate, _ := readResource(t, driver, "GPS-A", "correction_data_rate").Float64Value()
+		return rate > 0
+	}, "correction data")
+	if state := readResource(t, driver, "GPS-A", "ntrip_status").ValueToString(); state != NTRIPConnected {
+		t.Errorf("ntrip_status = %s", state)
+	}
+	if age, _ := readResource(t, driver, "GPS-A", "correction_age_s").Float64Value(); age < 0 || age > 1 {
+		t.Errorf("correction_age_s = %v", age)
+	}
+
+	// 转发的电文同时计入差分数据统计
+	status, ok := readResource(t, driver, "GPS-A", "corrections_status").Value.(CorrectionsStatus)
+	if !ok || status.Frames == 0 || status.CRCErrors != 0 || status.Base == nil || status.Base.StationID != 2003 ||
+		len(status.Sources) != 1 || status.Sources[0] != RTCMSourceNTRIP {
+		t.Errorf("corrections_status = %+v", status)
+	}
+
+	// 有定位后回传GGA，纬度为模拟接收机的30度
+	waitFor(t, 5*time.Second, func() bool {
+		_, gga := caster.stats()
+		return len(gga) > 0 && strings.Contains(gga[len(gga)-1], ",30")
+	}, "GGA from the driver")
+
+	// 未开启NTRIP的设备没有差分资源
+	readResource(t, driver, "GPS-B", "latitude_deg")
+	res, err := driver.HandleReadCommands("GPS-B", nil, []dsModels.CommandRequest{{DeviceResourceName: "ntrip_status"}})
+	if err != nil || len(res) != 0 {
+		t.Errorf("ntrip_status without ntrip = %v, %v", res, err)
+	}
+}
@@ -0,0 +1,253 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+// 里程计算方法
+const (
+	DistanceHaversine = "haversine"
+	DistanceVincenty  = "vincenty"
+)
+
+const (
+	// odometerMaxJump 两个定位点之间的隐含速度超过该值（米/秒）时视为跳点，从新位置重新开始累计
+	odometerMaxJump = 150.0
+	// odometerSaveInterval 总里程写入文件的最小间隔
+	odometerSaveInterval = 10 * time.Second
+)
+
+// OdometerConfig 里程计配置
+type OdometerConfig struct {
+	Method    string        // haversine或vincenty
+	MinSpeed  float64       // 低于该速度（米/秒）的定位不累计里程，也视为静止
+	MaxHDOP   float64       // HDOP高于该值的定位不累计里程
+	StopDelay time.Duration // 静止超过该时长时结束当前行程
+}
+
+// Trip 一次行程的统计
+type Trip struct {
+	Start    time.Time
+	End      time.Time // 最后一次处于运动状态的时间
+	Distance float64   // 米
+	MaxSpeed float64   // 米/秒
+	Active   bool      // 行程进行中，静止超过StopDelay后结束
+}
+
+// Duration 行程时长
+func (t Trip) Duration() time.Duration {
+	if t.Start.IsZero() {
+		return 0
+	}
+	return t.End.Sub(t.Start)
+}
+
+// AvgSpeed 平均速度（米/秒）
+func (t Trip) AvgSpeed() float64 {
+	if d := t.Duration().Seconds(); d > 0 {
+		return t.Distance / d
+	}
+	return 0
+}
+
+// Odometer 单台设备的里程计和行程统计，可并发使用
+type Odometer struct {
+	mutex      sync.Mutex
+	cfg        OdometerConfig
+	total      float64 // 总里程（米）
+	anchor     *Fix    // 上一个计入里程的定位点
+	trip       Trip
+	stillSince time.Time // 开始静止的时间，运动时为零值
+}
+
+// NewOdometer 创建里程计，total为已保存的总里程
+func NewOdometer(cfg OdometerConfig, total float64) *Odometer {
+	return &Odometer{cfg: cfg, total: total}
+}
+
+// distance 按配置的方法计算距离
+func (o *Odometer) distance(a, b Fix) float64 {
+	p, q := LatLon{a.Latitude, a.Longitude}, LatLon{b.Latitude, b.Longitude}
+	if o.cfg.Method == DistanceVincenty {
+		return vincenty(p, q)
+	}
+	return haversine(p, q)
+}
+
+// Update 用新的有效定位和当前运动状态更新里程和行程，返回本次增加的里程（米）。
+// 低速、判定为静止或HDOP过高的定位不累计，但保留上一个计入的点，恢复后按两点间距离补上，
+// 因此静止时的漂移不会产生里程，慢速移动的距离也不会丢失。
+func (o *Odometer) Update(fix Fix, motion string) float64 {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	moving := fix.HasSpeed && fix.Speed >= o.cfg.MinSpeed && motion != MotionStationary
+	o.updateTrip(fix, moving)
+
+	if !moving || (fix.HasHDOP && fix.HDOP > o.cfg.MaxHDOP) {
+		if o.anchor == nil {
+			o.anchor = &fix
+		}
+		return 0
+	}
+	if o.anchor == nil {
+		o.anchor = &fix
+		return 0
+	}
+
+	d := o.distance(*o.anchor, fix)
+	if dt := fix.Time.Sub(o.anchor.Time).Seconds(); dt <= 0 || d/dt > odometerMaxJump {
+		o.anchor = &fix
+		return 0
+	}
+	o.anchor = &fix
+	o.total += d
+	if o.trip.Active {
+		o.trip.Distance += d
+	}
+	return d
+}
+
+// updateTrip 运动开始时开启行程，静止超过StopDelay后结束行程
+func (o *Odometer) updateTrip(fix Fix, moving bool) {
+	if !moving {
+		if o.stillSince.IsZero() {
+			o.stillSince = fix.Time
+		}
+		if o.trip.Active && fix.Time.Sub(o.stillSince) >= o.cfg.StopDelay {
+			o.trip.Active = false
+		}
+		return
+	}
+
+	o.stillSince = time.Time{}
+	if !o.trip.Active {
+		o.trip = Trip{Start: fix.Time, Active: true}
+	}
+	o.trip.End = fix.Time
+	o.trip.MaxSpeed = math.Max(o.trip.MaxSpeed, fix.Speed)
+}
+
+// Total 返回总里程（米）
+func (o *Odometer) Total() float64 {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.total
+}
+
+// Trip 返回当前行程，行程结束后返回最近一次行程直到下一次运动开始
+func (o *Odometer) Trip() Trip {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.trip
+}
+
+// ResetTrip 清零行程统计，运动中复位时立即开始新行程
+func (o *Odometer) ResetTrip() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	active := o.trip.Active
+	o.trip = Trip{}
+	if active && o.anchor != nil {
+		o.trip = Trip{Start: o.anchor.Time, End: o.anchor.Time, Active: true}
+	}
+}
+
+// odometerResources 里程计资源：资源名称到读数类型和取值函数的映射
+var odometerResources = map[string]func(o *Odometer) any{
+	"odometer_m":      func(o *Odometer) any { return o.Total() },
+	"trip_distance_m": func(o *Odometer) any { return o.Trip().Distance },
+	"trip_duration":   func(o *Odometer) any { return o.Trip().Duration().Seconds() },
+	"max_speed":       func(o *Odometer) any { return o.Trip().MaxSpeed },
+	"avg_speed":       func(o *Odometer) any { return o.Trip().AvgSpeed() },
+}
+
+// getOdometerValue 获取里程计资源
+func (s *Driver) getOdometerValue(odometer *Odometer, req dsModels.CommandRequest) *dsModels.CommandValue {
+	cv, err := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, odometerResources[req.DeviceResourceName](odometer))
+	if err != nil {
+		s.lc.Errorf("创建%s读数失败: %v", req.DeviceResourceName, err)
+		return nil
+	}
+	return cv
+}
+
+// resetTrip 处理trip_reset写入，写入true时清零行程
+func (s *Driver) resetTrip(odometer *Odometer, param *dsModels.CommandValue) error {
+	reset, err := param.BoolValue()
+	if err != nil {
+		return fmt.Errorf("参数值必须是Bool: %v", err)
+	}
+	if reset {
+		odometer.ResetTrip()
+	}
+	return nil
+}
+
+// loadOdometers 读取保存的各设备总里程，文件不存在时返回空表
+func loadOdometers(path string) (map[string]float64, error) {
+	totals := make(map[string]float64)
+	if path == "" {
+		return totals, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return totals, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &totals)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取里程文件失败: %w", err)
+	}
+	return totals, nil
+}
+
+// saveOdometers 保存各设备总里程，force为false时距上次保存不足odometerSaveInterval则跳过
+func (s *Driver) saveOdometers(force bool) {
+	if s.config.OdometerFile == "" {
+		return
+	}
+
+	s.odometerLock.Lock()
+	defer s.odometerLock.Unlock()
+	if !force && time.Since(s.odometerSaved) < odometerSaveInterval {
+		return
+	}
+
+	s.receiversLock.RLock()
+	for deviceName, rcv := range s.receivers {
+		s.odometerTotals[deviceName] = rcv.odometer.Total()
+	}
+	s.receiversLock.RUnlock()
+
+	data, err := json.MarshalIndent(s.odometerTotals, "", "  ")
+	if err == nil {
+		err = os.WriteFile(s.config.OdometerFile+".tmp", data, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(s.config.OdometerFile+".tmp", s.config.OdometerFile)
+	}
+	if err != nil {
+		s.lc.Errorf("保存里程文件失败: %v", err)
+		return
+	}
+	s.odometerSaved = time.Now()
+}
+
+// newOdometer 为设备创建里程计，总里程从已保存的值继续
+func (s *Driver) newOdometer(deviceName string) *Odometer {
+	s.odometerLock.Lock()
+	defer s.odometerLock.Unlock()
+	return NewOdometer(s.config.Odometer, s.odometerTotals[deviceName])
+}
@@ -0,0 +1,145 @@
+package driver
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+func TestVincenty(t *testing.T) {
+	tests := []struct {
+		a, b     LatLon
+		expected float64
+	}{
+		// Vincenty论文中的Flinders Peak到Buninyong
+		{LatLon{-37.95103342, 144.42486789}, LatLon{-37.65282114, 143.92649554}, 54972.271},
+		{LatLon{0, 0}, LatLon{0, 1}, 111319.491},
+		{LatLon{30, 104}, LatLon{30, 104}, 0},
+	}
+	for _, test := range tests {
+		if got := vincenty(test.a, test.b); math.Abs(got-test.expected) > 0.001 {
+			t.Errorf("vincenty(%v, %v) = %.4f, expected %.3f", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+func TestOdometer(t *testing.T) {
+	for _, method := range []string{DistanceHaversine, DistanceVincenty} {
+		odometer := NewOdometer(OdometerConfig{Method: method, MinSpeed: 0.5, MaxHDOP: 5, StopDelay: 10 * time.Second}, 1000)
+		start := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+		origin := LatLon{30, 104}
+		update := func(sec int, east, speed, hdop float64) float64 {
+			fix := trackFix(0, 0)
+			fix.Time = start.Add(time.Duration(sec) * time.Second)
+			p := offsetMeters(origin, 0, east)
+			fix.Latitude, fix.Longitude = p.Lat, p.Lon
+			fix.Speed, fix.HasSpeed = speed, true
+			fix.HDOP, fix.HasHDOP = hdop, true
+			return odometer.Update(fix, MotionUnknown)
+		}
+
+		// 静止时的位置抖动不累计
+		for sec, east := range []float64{0, 2, -1, 3, 0} {
+			update(sec, east, 0.1, 1)
+		}
+		if odometer.Total() != 1000 || odometer.Trip().Active {
+			t.Errorf("%s: after jitter total = %v, trip = %+v", method, odometer.Total(), odometer.Trip())
+		}
+
+		// 以10m/s向东行驶10秒，中间一个HDOP过高的点不影响总距离
+		for sec := 5; sec <= 15; sec++ {
+			hdop := 1.0
+			if sec == 9 {
+				hdop = 8
+			}
+			update(sec, float64(sec-5)*10, 10, hdop)
+		}
+		update(16, 5000, 10, 1) // 1秒跳出5km，视为跳点
+		update(17, 5010, 12, 1)
+		if total := odometer.Total(); math.Abs(total-1110) > 0.5 {
+			t.Errorf("%s: total = %.2f, expected 1110", method, total)
+		}
+
+		trip := odometer.Trip()
+		if !trip.Active || math.Abs(trip.Distance-110) > 0.5 || trip.MaxSpeed != 12 || trip.Duration() != 12*time.Second {
+			t.Errorf("%s: trip = %+v", method, trip)
+		}
+
+		// 静止超过StopDelay后行程结束，统计保留
+		for sec := 18; sec <= 30; sec++ {
+			update(sec, 5010, 0, 1)
+		}
+		trip = odometer.Trip()
+		if trip.Active || math.Abs(trip.AvgSpeed()-110.0/12) > 0.05 {
+			t.Errorf("%s: finished trip = %+v, avg = %v", method, trip, trip.AvgSpeed())
+		}
+
+		// 再次出发开始新行程，从最后一个计入点起算
+		update(31, 5020, 10, 1)
+		if trip = odometer.Trip(); !trip.Active || math.Abs(trip.Distance-10) > 0.1 || !trip.Start.Equal(start.Add(31*time.Second)) {
+			t.Errorf("%s: new trip = %+v", method, trip)
+		}
+		odometer.ResetTrip()
+		if trip = odometer.Trip(); !trip.Active || trip.Distance != 0 || trip.Duration() != 0 {
+			t.Errorf("%s: trip after reset = %+v", method, trip)
+		}
+		if total := odometer.Total(); math.Abs(total-1120) > 0.5 {
+			t.Errorf("%s: total after reset = %.2f, expected 1120", method, total)
+		}
+	}
+}
+
+func TestDriverOdometer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "odometer.json")
+	configs := map[string]string{configOdometerFile: file, configOdometerMethod: "vincenty"}
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["speed"] = "20"
+	protocols[ProtocolSIM]["radius"] = "200"
+
+	driver, _ := newTestDriverWithConfig(t, configs, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	rcv, _ := driver.receiverFor("GPS-A")
+	waitFor(t, 3*time.Second, func() bool { return rcv.odometer.Total() > 5 }, "里程")
+
+	odometer := readResource(t, driver, "GPS-A", "odometer_m")
+	if odometer.Type != common.ValueTypeFloat64 {
+		t.Errorf("odometer_m type = %s", odometer.Type)
+	}
+	trip, _ := readResource(t, driver, "GPS-A", "trip_distance_m").Float64Value()
+	if trip <= 0 {
+		t.Errorf("trip_distance_m = %v, expected > 0", trip)
+	}
+
+	reset, _ := dsModels.NewCommandValue("trip_reset", common.ValueTypeBool, true)
+	if err := driver.HandleWriteCommands("GPS-A", nil, []dsModels.CommandRequest{{DeviceResourceName: "trip_reset"}}, []*dsModels.CommandValue{reset}); err != nil {
+		t.Fatalf("trip_reset failed: %v", err)
+	}
+	if got := rcv.odometer.Trip().Distance; got >= trip {
+		t.Errorf("trip distance after reset = %v, expected < %v", got, trip)
+	}
+
+	// 停止后总里程写入文件，重新启动时继续累计
+	if err := driver.Stop(false); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loadOdometers(file)
+	if err != nil || saved["GPS-A"] < 5 {
+		t.Fatalf("saved odometers = %v, %v", saved, err)
+	}
+
+	driver, _ = newTestDriverWithConfig(t, configs, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	rcv, _ = driver.receiverFor("GPS-A")
+	if total := rcv.odometer.Total(); total < saved["GPS-A"] {
+		t.Errorf("odometer after restart = %v, expected >= %v", total, saved["GPS-A"])
+	}
+}
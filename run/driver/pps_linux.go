@@ -0,0 +1,52 @@
+//go:build linux
+
+package driver
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// PPSDevice Linux内核PPS设备（/dev/ppsN），通过PPS_FETCH等待秒脉冲上升沿
+type PPSDevice struct {
+	file     *os.File
+	sequence uint32
+}
+
+// OpenPPS 打开内核PPS设备
+func OpenPPS(path string) (PPSSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开PPS设备%s失败: %w", path, err)
+	}
+	return &PPSDevice{file: file}, nil
+}
+
+// Fetch 等待下一个秒脉冲上升沿，返回内核记录的本机时间。超时返回errPPSTimeout。
+func (p *PPSDevice) Fetch(timeout time.Duration) (time.Time, error) {
+	var data unix.PPSFData
+	data.Timeout.Sec = int64(timeout / time.Second)
+	data.Timeout.Nsec = int32(timeout % time.Second)
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, p.file.Fd(), unix.PPS_FETCH, uintptr(unsafe.Pointer(&data)))
+	switch {
+	case errno == unix.ETIMEDOUT || errno == unix.EINTR:
+		return time.Time{}, errPPSTimeout
+	case errno != 0:
+		return time.Time{}, fmt.Errorf("PPS_FETCH失败: %w", errno)
+	case data.Info.Assert_sequence == p.sequence:
+		// 没有新的上升沿
+		return time.Time{}, errPPSTimeout
+	}
+	p.sequence = data.Info.Assert_sequence
+	return time.Unix(data.Info.Assert_tu.Sec, int64(data.Info.Assert_tu.Nsec)), nil
+}
+
+// Close 关闭PPS设备
+func (p *PPSDevice) Close() error {
+	return p.file.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package driver
+
+import "errors"
+
+// OpenPPS 仅支持Linux
+func OpenPPS(path string) (PPSSource, error) {
+	return nil, errors.New("PPS设备仅支持Linux")
+}
@@ -0,0 +1,405 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/spf13/cast"
+)
+
+// 设备支持的协议
+const (
+	ProtocolUART = "UART" // 真实接收机，通过串口连接
+	ProtocolFILE = "FILE" // 虚拟接收机，回放抓包文件
+	ProtocolSIM  = "SIM"  // 虚拟接收机，内置GNSS模拟器
+)
+
+// receiver 一台设备对应的接收机连接
+type receiver struct {
+	gps       *LCX6XZ
+	replay    *ReplayPort // 回放设备，仅FILE协议下非nil
+	protocols map[string]models.ProtocolProperties
+	track     *TrackBuffer // 最近的轨迹，设备配置更新重新打开接收机时保留
+	store     *TrackStore  // 磁盘轨迹日志，未配置TrackStoreDir时为nil，同样在重新打开时保留
+	fences    *GeofenceTracker
+	odometer  *Odometer
+	motion    *MotionClassifier
+	integrity *IntegrityMonitor
+	kalman    *KalmanFilter // 未开启卡尔曼滤波时为nil，重新打开接收机时重新初始化
+	clock     *TimeService  // 未配置ntpShm时为nil，重新打开接收机时重新创建
+	ntrip     *NTRIPClient  // 未开启NTRIP时为nil，重新打开接收机时重新创建
+	// 差分数据统计，既未开启NTRIP也未配置rtcmPort时为nil，重新打开接收机时重新创建
+	corrections *RTCMMonitor
+	// 基准站播发服务，未开启基准站模式时为nil，配置不变时重新打开接收机后沿用
+	base *BaseCaster
+
+	rolloverWeeks int // 最近一次告警时的周数翻转修正，只在processFixes中访问
+}
+
+// receiverFor 按设备名称查找接收机连接
+func (s *Driver) receiverFor(deviceName string) (*receiver, error) {
+	s.receiversLock.RLock()
+	defer s.receiversLock.RUnlock()
+
+	rcv, ok := s.receivers[deviceName]
+	if !ok {
+		return nil, fmt.Errorf("GPS设备%s未初始化", deviceName)
+	}
+	return rcv, nil
+}
+
+// hasReceiver 判断设备是否已打开接收机连接
+func (s *Driver) hasReceiver(deviceName string) bool {
+	_, err := s.receiverFor(deviceName)
+	return err == nil
+}
+
+// addReceiver 为设备打开接收机连接。设备已有连接时先关闭旧连接的串口，避免同一串口被重复打开，
+// 轨迹、围栏状态、里程、运动状态和完好性基线交给新连接（实时与回放之间切换时完好性重新开始），基准站播发服务在配置不变时沿用；
+// 新连接打开失败时设备不再有连接，保留的状态随之释放。
+func (s *Driver) addReceiver(deviceName string, protocols map[string]models.ProtocolProperties) error {
+	old := s.takeReceiver(deviceName)
+
+	gpsDevice, replay, err := s.openReceiver(deviceName, protocols)
+	if err != nil {
+		if old != nil {
+			s.closeTrackStore(deviceName, old.store)
+			s.closeBaseCaster(deviceName, old.base)
+		}
+		return err
+	}
+
+	rcv := &receiver{gps: gpsDevice, replay: replay, protocols: protocols, kalman: s.newKalmanFilter(deviceName, protocols)}
+	var pps PPSSource
+	rcv.clock, pps = s.openTimeService(deviceName, protocols)
+	rtcmPort, forward := s.openRTCMPort(deviceName, protocols)
+	if rtcmPort != nil {
+		rcv.corrections = NewRTCMMonitor()
+	}
+	rcv.ntrip = s.newNTRIPClient(deviceName, rcv, protocols)
+
+	if old != nil {
+		rcv.track, rcv.store, rcv.fences, rcv.odometer, rcv.motion = old.track, old.store, old.fences, old.odometer, old.motion
+		rcv.integrity = old.integrity
+		if (old.replay != nil) != (replay != nil) {
+			rcv.integrity = s.newIntegrityMonitor(replay != nil)
+		}
+		rcv.base = s.newBaseCaster(deviceName, gpsDevice, old.base, protocols)
+	} else {
+		rcv.track = NewTrackBuffer(s.config.TrackBufferSize)
+		rcv.store = s.openTrackStore(deviceName)
+		rcv.fences = NewGeofenceTracker()
+		rcv.odometer = s.newOdometer(deviceName)
+		rcv.motion = NewMotionClassifier(s.config.Motion)
+		rcv.integrity = s.newIntegrityMonitor(replay != nil)
+		rcv.base = s.newBaseCaster(deviceName, gpsDevice, nil, protocols)
+	}
+
+	s.receiversLock.Lock()
+	s.receivers[deviceName] = rcv
+	s.receiversLock.Unlock()
+
+	if rcv.clock != nil {
+		s.registerTimeMetrics(deviceName, rcv.clock)
+	}
+	if pps != nil {
+		go s.runPPS(deviceName, rcv, pps)
+	}
+	if rcv.ntrip != nil {
+		go s.runNTRIP(deviceName, rcv)
+	}
+	if rtcmPort != nil {
+		go s.runRTCMPort(deviceName, rcv, rtcmPort, forward)
+	}
+	go s.processFixes(deviceName, rcv)
+	go s.setupSpeedHold(deviceName, rcv)
+	go s.setupBase(deviceName, rcv)
+	s.lc.Infof("✅ GPS设备%s初始化成功", deviceName)
+	return nil
+}
+
+// processFixes 处理接收机每个历元的定位结果，接收机关闭后退出
+func (s *Driver) processFixes(deviceName string, rcv *receiver) {
+	for {
+		select {
+		case fix := <-rcv.gps.Fixes():
+			// 完好性监测需要包括失去定位在内的每个历元
+			s.checkIntegrity(deviceName, rcv, fix)
+			// 只处理新的有效定位
+			if !rcv.track.Add(fix) {
+				continue
+			}
+			if rcv.store != nil {
+				if err := rcv.store.Append(NewFixDocument(fix)); err != nil {
+					s.lc.Errorf("设备%s写入磁盘轨迹日志失败: %v", deviceName, err)
+				}
+			}
+			s.observeTime(deviceName, rcv, fix)
+			if rcv.clock != nil {
+				rcv.clock.Fix(fix)
+			}
+			s.checkGeofences(deviceName, rcv, fix)
+			if rcv.kalman != nil {
+				rcv.kalman.Update(fix)
+			}
+			state := s.updateMotion(deviceName, rcv, fix)
+			if rcv.odometer.Update(fix, state) > 0 {
+				s.saveOdometers(false)
+			}
+		case <-rcv.gps.Done():
+			s.lc.Debugf("设备%s的定位处理已停止", deviceName)
+			return
+		}
+	}
+}
+
+// removeReceiver 关闭并移除设备的接收机连接
+func (s *Driver) removeReceiver(deviceName string) {
+	if rcv := s.takeReceiver(deviceName); rcv != nil {
+		s.closeTrackStore(deviceName, rcv.store)
+		s.closeBaseCaster(deviceName, rcv.base)
+	}
+}
+
+// takeReceiver 保存里程后从表中移除设备的接收机连接并关闭接收机，
+// 轨迹日志和基准站播发服务由调用方关闭或交给新连接；设备没有连接时返回nil
+func (s *Driver) takeReceiver(deviceName string) *receiver {
+	s.saveOdometers(true)
+
+	s.receiversLock.Lock()
+	rcv, ok := s.receivers[deviceName]
+	delete(s.receivers, deviceName)
+	s.receiversLock.Unlock()
+
+	if !ok {
+		return nil
+	}
+	s.closeReceiver(deviceName, rcv)
+	return rcv
+}
+
+// closeReceivers 关闭所有接收机连接
+func (s *Driver) closeReceivers() {
+	s.saveOdometers(true)
+
+	s.receiversLock.Lock()
+	receivers := s.receivers
+	s.receivers = make(map[string]*receiver)
+	s.receiversLock.Unlock()
+
+	for deviceName, rcv := range receivers {
+		s.closeReceiver(deviceName, rcv)
+		s.closeTrackStore(deviceName, rcv.store)
+		s.closeBaseCaster(deviceName, rcv.base)
+	}
+}
+
+// closeReceiver 注销指标并关闭接收机
+func (s *Driver) closeReceiver(deviceName string, rcv *receiver) {
+	s.unregisterPortMetrics(deviceName, rcv.gps.Port())
+	s.closeTimeService(deviceName, rcv.clock)
+	if err := rcv.gps.Close(); err != nil {
+		s.lc.Errorf("关闭GPS设备%s失败: %v", deviceName, err)
+		return
+	}
+	s.lc.Infof("GPS设备%s已关闭", deviceName)
+}
+
+// openReceiver 根据设备协议属性打开接收机，返回的ReplayPort仅在FILE协议下非nil
+func (s *Driver) openReceiver(deviceName string, protocols map[string]models.ProtocolProperties) (*LCX6XZ, *ReplayPort, error) {
+	if protocol, ok := protocols[ProtocolFILE]; ok {
+		return s.openReplayReceiver(deviceName, protocol)
+	}
+	if protocol, ok := protocols[ProtocolSIM]; ok {
+		gpsDevice, err := s.openSimReceiver(deviceName, protocol)
+		return gpsDevice, nil, err
+	}
+
+	protocol, ok := protocols[ProtocolUART]
+	if !ok {
+		return nil, nil, fmt.Errorf("设备%s缺少UART、FILE或SIM协议", deviceName)
+	}
+
+	deviceLocation := cast.ToString(protocol["deviceLocation"])
+	baudRate, _ := cast.ToIntE(protocol["baudRate"])
+	readTimeout, _ := cast.ToIntE(protocol["ReadTimeout"])
+	s.lc.Debugf("设备%s: device location = %v, baud rate = %v, readTimeout = %v",
+		deviceName, deviceLocation, baudRate, readTimeout)
+
+	gpsDevice, err := InitLCX6XZ(deviceLocation, baudRate, readTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.setupPort(deviceName, gpsDevice.Port(), protocol)
+
+	if recordFile := cast.ToString(protocol["recordFile"]); recordFile != "" {
+		recorder, err := NewRecorder(recordFile)
+		if err != nil {
+			s.lc.Errorf("设备%s开启录制失败: %v", deviceName, err)
+		} else {
+			gpsDevice.Port().SetRecorder(recorder)
+			s.lc.Infof("设备%s的原始数据将录制到%s", deviceName, recordFile)
+		}
+	}
+
+	return gpsDevice, nil, nil
+}
+
+// openReplayReceiver 打开抓包文件回放设备
+func (s *Driver) openReplayReceiver(deviceName string, protocol models.ProtocolProperties) (*LCX6XZ, *ReplayPort, error) {
+	cfg, err := replayConfig(protocol)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replay, err := NewReplayPort(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.lc.Infof("设备%s回放文件%s，速度=%v，单步=%v，循环=%v", deviceName, cfg.Path, cfg.Speed, cfg.Step, cfg.Loop)
+
+	port := NewSerialPortFrom(replay)
+	s.setupPort(deviceName, port, protocol)
+	return newLCX6XZ(port, true), replay, nil
+}
+
+// openSimReceiver 打开内置模拟接收机
+func (s *Driver) openSimReceiver(deviceName string, protocol models.ProtocolProperties) (*LCX6XZ, error) {
+	cfg, err := simConfig(protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	sim, err := NewSimPort(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.lc.Infof("设备%s使用模拟接收机，路线长度=%.0fm，速度=%vm/s，频率=%vHz",
+		deviceName, cfg.Route.Length(), cfg.Speed, cfg.UpdateRate)
+
+	port := NewSerialPortFrom(sim)
+	s.setupPort(deviceName, port, protocol)
+	return NewLCX6XZ(port), nil
+}
+
+// setupPort 按协议属性开启线路跟踪并注册字节计数指标
+func (s *Driver) setupPort(deviceName string, port *SerialPort, protocol models.ProtocolProperties) {
+	if traceSize, _ := cast.ToIntE(protocol["traceSize"]); traceSize > 0 {
+		port.EnableTrace(traceSize)
+		s.lc.Infof("设备%s已开启线路跟踪，缓冲区大小: %d", deviceName, traceSize)
+	}
+	s.registerPortMetrics(deviceName, port)
+}
+
+// replayConfig 从FILE协议属性解析回放配置
+func replayConfig(protocol models.ProtocolProperties) (ReplayConfig, error) {
+	path := cast.ToString(protocol["path"])
+	if path == "" {
+		return ReplayConfig{}, errors.New("FILE协议缺少'path'")
+	}
+
+	speed, step, err := ParseReplaySpeed(cast.ToString(protocol["speed"]))
+	if err != nil {
+		return ReplayConfig{}, err
+	}
+
+	loop, err := cast.ToBoolE(protocol["loop"])
+	if err != nil && protocol["loop"] != nil {
+		return ReplayConfig{}, fmt.Errorf("无效的'loop'属性: %v", protocol["loop"])
+	}
+
+	return ReplayConfig{Path: path, Speed: speed, Step: step, Loop: loop}, nil
+}
+
+// simConfig 从SIM协议属性解析模拟接收机配置，未设置的属性使用默认值
+func simConfig(protocol models.ProtocolProperties) (SimConfig, error) {
+	var err error
+	number := func(key string, def float64) float64 {
+		value, ok := protocol[key]
+		if !ok || value == "" || err != nil {
+			return def
+		}
+		v, convErr := cast.ToFloat64E(value)
+		if convErr != nil {
+			err = fmt.Errorf("无效的'%s'属性: %v", key, value)
+		}
+		return v
+	}
+	duration := func(key string) time.Duration {
+		value, ok := protocol[key]
+		if !ok || value == "" || err != nil {
+			return 0
+		}
+		d, convErr := time.ParseDuration(cast.ToString(value))
+		if convErr != nil {
+			err = fmt.Errorf("无效的'%s'属性: %v", key, value)
+		}
+		return d
+	}
+
+	cfg := SimConfig{
+		Speed:           number("speed", 10),
+		Altitude:        number("altitude", 50),
+		Noise:           number("noise", 2),
+		Satellites:      int(number("satellites", 12)),
+		UpdateRate:      number("updateRate", 1),
+		DropoutEvery:    duration("dropoutEvery"),
+		DropoutDuration: duration("dropoutDuration"),
+		JamEvery:        duration("jamEvery"),
+		JamDuration:     duration("jamDuration"),
+		Seed:            uint64(number("seed", 1)),
+	}
+	center := LatLon{Lat: number("centerLat", 30.739479), Lon: number("centerLon", 103.959134)}
+	radius := number("radius", 200)
+	width, height, spacing := number("width", 500), number("height", 500), number("spacing", 50)
+	if err != nil {
+		return SimConfig{}, err
+	}
+
+	switch route := cast.ToString(protocol["route"]); route {
+	case "", "circle":
+		cfg.Route, err = CircleRoute(center, radius)
+	case "grid":
+		cfg.Route, err = GridRoute(center, width, height, spacing)
+	case "file":
+		path := cast.ToString(protocol["routeFile"])
+		if path == "" {
+			return SimConfig{}, errors.New("SIM协议的route为file时缺少'routeFile'")
+		}
+		cfg.Route, err = LoadRouteFile(path)
+	default:
+		return SimConfig{}, fmt.Errorf("不支持的模拟路线类型: %s", route)
+	}
+	if err != nil {
+		return SimConfig{}, err
+	}
+
+	if cfg.UpdateRate <= 0 || cfg.UpdateRate > 10 {
+		return SimConfig{}, fmt.Errorf("定位频率必须在0~10Hz之间: %v", cfg.UpdateRate)
+	}
+	if cfg.DropoutDuration > cfg.DropoutEvery || cfg.JamDuration > cfg.JamEvery {
+		return SimConfig{}, errors.New("失锁或干扰时长不能超过其周期")
+	}
+	return cfg, nil
+}
+
+// validateUART 校验UART协议属性
+func validateUART(protocol models.ProtocolProperties) error {
+	deviceLocation, ok := protocol["deviceLocation"]
+	if !ok {
+		return errors.New("Missing 'deviceLocation' information")
+	} else if deviceLocation == "" {
+		return errors.New("deviceLocation must not empty")
+	}
+
+	baudRate, ok := protocol["baudRate"]
+	if !ok {
+		return errors.New("Missing 'baudRate' information")
+	} else if baudRate == "" {
+		return errors.New("baudRate must not empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package driver
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Route 模拟接收机沿之行驶的闭合折线路线
+type Route struct {
+	points []LatLon
+	cum    []float64 // 从起点到每个顶点的累计距离（米）
+	length float64   // 路线总长（米）
+}
+
+// NewRoute 由顶点创建路线，终点不等于起点时自动闭合
+func NewRoute(points []LatLon) (*Route, error) {
+	if len(points) < 2 {
+		return nil, errors.New("路线至少需要两个点")
+	}
+
+	pts := append([]LatLon(nil), points...)
+	if pts[0] != pts[len(pts)-1] {
+		pts = append(pts, pts[0])
+	}
+
+	cum := make([]float64, len(pts))
+	for i := 1; i < len(pts); i++ {
+		cum[i] = cum[i-1] + haversine(pts[i-1], pts[i])
+	}
+	if cum[len(cum)-1] == 0 {
+		return nil, errors.New("路线长度为0")
+	}
+
+	return &Route{points: pts, cum: cum, length: cum[len(cum)-1]}, nil
+}
+
+// Length 返回路线总长（米）
+func (r *Route) Length() float64 {
+	return r.length
+}
+
+// At 返回沿路线行驶dist米后的位置和当前航向，超过总长后从头循环
+func (r *Route) At(dist float64) (LatLon, float64) {
+	dist = math.Mod(dist, r.length)
+	if dist < 0 {
+		dist += r.length
+	}
+
+	// 找到dist所在的线段：第一个累计距离大于dist的顶点
+	i := sort.Search(len(r.cum), func(k int) bool { return r.cum[k] > dist })
+
+	a, b := r.points[i-1], r.points[i]
+	bearing := initialBearing(a, b)
+	return destination(a, bearing, dist-r.cum[i-1]), bearing
+}
+
+// CircleRoute 以center为圆心、radius米为半径的圆形路线（顺时针）
+func CircleRoute(center LatLon, radius float64) (*Route, error) {
+	if radius <= 0 {
+		return nil, errors.New("圆形路线半径必须大于0")
+	}
+
+	const segments = 72
+	points := make([]LatLon, 0, segments)
+	for i := 0; i < segments; i++ {
+		points = append(points, destination(center, float64(i)*360/segments, radius))
+	}
+	return NewRoute(points)
+}
+
+// GridRoute 以origin为西南角的往返扫描（割草机式）路线
+func GridRoute(origin LatLon, width, height, spacing float64) (*Route, error) {
+	if width <= 0 || height <= 0 || spacing <= 0 {
+		return nil, errors.New("网格路线的宽、高和间距必须大于0")
+	}
+
+	var points []LatLon
+	for row := 0; float64(row)*spacing <= height; row++ {
+		north := float64(row) * spacing
+		west := offsetMeters(origin, north, 0)
+		east := offsetMeters(origin, north, width)
+		if row%2 == 0 {
+			points = append(points, west, east)
+		} else {
+			points = append(points, east, west)
+		}
+	}
+	return NewRoute(points)
+}
+
+// LoadRouteFile 从GPX或GeoJSON文件加载路线，按扩展名识别格式
+func LoadRouteFile(path string) (*Route, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取路线文件失败: %w", err)
+	}
+
+	var points []LatLon
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpx":
+		points, err = parseGPXPoints(data)
+	case ".geojson", ".json":
+		points, err = parseGeoJSONPoints(data)
+	default:
+		return nil, fmt.Errorf("不支持的路线文件格式: %s", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewRoute(points)
+}
+
+type gpxPoint struct {
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+}
+
+type gpxDocument struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+// parseGPXPoints 提取GPX中所有航迹点，没有航迹时使用航线点
+func parseGPXPoints(data []byte) ([]LatLon, error) {
+	var doc gpxDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析GPX失败: %w", err)
+	}
+
+	var points []LatLon
+	for _, trk := range doc.Tracks {
+		for _, seg := range trk.Segments {
+			for _, p := range seg.Points {
+				points = append(points, LatLon{Lat: p.Lat, Lon: p.Lon})
+			}
+		}
+	}
+	if len(points) == 0 {
+		for _, rte := range doc.Routes {
+			for _, p := range rte.Points {
+				points = append(points, LatLon{Lat: p.Lat, Lon: p.Lon})
+			}
+		}
+	}
+	return points, nil
+}
+
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Features    []geoJSONObject `json:"features"`
+}
+
+// parseGeoJSONPoints 提取GeoJSON中第一条LineString（支持Feature和FeatureCollection）
+func parseGeoJSONPoints(data []byte) ([]LatLon, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("解析GeoJSON失败: %w", err)
+	}
+	points := geoJSONLine(&obj)
+	if len(points) == 0 {
+		return nil, errors.New("GeoJSON中没有LineString")
+	}
+	return points, nil
+}
+
+func geoJSONLine(obj *geoJSONObject) []LatLon {
+	switch obj.Type {
+	case "FeatureCollection":
+		for i := range obj.Features {
+			if points := geoJSONLine(&obj.Features[i]); len(points) > 0 {
+				return points
+			}
+		}
+	case "Feature":
+		if obj.Geometry != nil {
+			return geoJSONLine(obj.Geometry)
+		}
+	case "LineString":
+		var coords [][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coords); err == nil {
+			return geoJSONCoords(coords)
+		}
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &lines); err == nil && len(lines) > 0 {
+			return geoJSONCoords(lines[0])
+		}
+	}
+	return nil
+}
+
+// geoJSONCoords GeoJSON坐标顺序为[经度, 纬度]
+func geoJSONCoords(coords [][]float64) []LatLon {
+	points := make([]LatLon, 0, len(coords))
+	for _, c := range coords {
+		if len(c) >= 2 {
+			points = append(points, LatLon{Lat: c[1], Lon: c[0]})
+		}
+	}
+	return points
+}
@@ -0,0 +1,158 @@
+package driver
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		a, b     LatLon
+		expected float64
+	}{
+		{LatLon{0, 0}, LatLon{0, 1}, 111195},
+		{LatLon{0, 0}, LatLon{1, 0}, 111195},
+		{LatLon{30.7, 104.0}, LatLon{30.7, 104.0}, 0},
+		{LatLon{39.9042, 116.4074}, LatLon{31.2304, 121.4737}, 1067000},
+	}
+
+	for _, test := range tests {
+		got := haversine(test.a, test.b)
+		if math.Abs(got-test.expected) > test.expected*0.001+1 {
+			t.Errorf("haversine(%v, %v) = %.0f, expected %.0f", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+func TestDestinationRoundTrip(t *testing.T) {
+	start := LatLon{30.739479, 103.959134}
+	for _, bearing := range []float64{0, 45, 90, 180, 270, 359} {
+		end := destination(start, bearing, 1000)
+		if d := haversine(start, end); math.Abs(d-1000) > 0.01 {
+			t.Errorf("bearing %v: distance = %v, expected 1000", bearing, d)
+		}
+		if b := initialBearing(start, end); math.Abs(math.Mod(b-bearing+540, 360)-180) > 0.01 {
+			t.Errorf("bearing %v: initialBearing = %v", bearing, b)
+		}
+	}
+}
+
+func TestCircleRoute(t *testing.T) {
+	center := LatLon{30.739479, 103.959134}
+	route, err := CircleRoute(center, 200)
+	if err != nil {
+		t.Fatalf("CircleRoute failed: %v", err)
+	}
+
+	// 72边形周长略小于圆周长
+	if circumference := 2 * math.Pi * 200; math.Abs(route.Length()-circumference) > 1 {
+		t.Errorf("Length() = %v, expected about %v", route.Length(), circumference)
+	}
+	for _, dist := range []float64{0, 100, 600, route.Length() + 50, -50} {
+		pos, _ := route.At(dist)
+		if r := haversine(center, pos); r < 199 || r > 200.01 {
+			t.Errorf("At(%v) is %vm from center, expected 200m", dist, r)
+		}
+	}
+
+	// 起点在正北，顺时针行驶时航向朝东
+	if _, bearing := route.At(1); bearing < 80 || bearing > 100 {
+		t.Errorf("bearing at start = %v, expected about 90", bearing)
+	}
+}
+
+func TestRouteAt(t *testing.T) {
+	a := LatLon{30, 104}
+	b := offsetMeters(a, 0, 1000)
+	route, err := NewRoute([]LatLon{a, b})
+	if err != nil {
+		t.Fatalf("NewRoute failed: %v", err)
+	}
+
+	if math.Abs(route.Length()-2000) > 1 {
+		t.Errorf("Length() = %v, expected 2000 (closed loop)", route.Length())
+	}
+	pos, bearing := route.At(500)
+	if d := haversine(a, pos); math.Abs(d-500) > 0.1 {
+		t.Errorf("At(500) is %vm from start", d)
+	}
+	if math.Abs(bearing-90) > 0.1 {
+		t.Errorf("At(500) bearing = %v, expected 90", bearing)
+	}
+	if _, bearing := route.At(1500); math.Abs(bearing-270) > 0.1 {
+		t.Errorf("At(1500) bearing = %v, expected 270", bearing)
+	}
+
+	if _, err := NewRoute([]LatLon{a}); err == nil {
+		t.Error("NewRoute with one point succeeded, expected error")
+	}
+	if _, err := NewRoute([]LatLon{a, a}); err == nil {
+		t.Error("NewRoute with zero length succeeded, expected error")
+	}
+}
+
+func TestGridRoute(t *testing.T) {
+	origin := LatLon{30, 104}
+	route, err := GridRoute(origin, 100, 20, 10)
+	if err != nil {
+		t.Fatalf("GridRoute failed: %v", err)
+	}
+
+	// 3行各100m，2段10m行间距，再加上从东北角返回西南角的闭合段
+	expected := 300 + 20 + math.Hypot(100, 20)
+	if math.Abs(route.Length()-expected) > 1 {
+		t.Errorf("Length() = %v, expected about %v", route.Length(), expected)
+	}
+}
+
+func TestLoadRouteFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"track.gpx": `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="30.0" lon="104.0"></trkpt>
+    <trkpt lat="30.0" lon="104.01"></trkpt>
+    <trkpt lat="30.01" lon="104.01"></trkpt>
+  </trkseg></trk>
+</gpx>`,
+		"route.gpx": `<gpx><rte>
+    <rtept lat="30.0" lon="104.0"/>
+    <rtept lat="30.0" lon="104.01"/>
+    <rtept lat="30.01" lon="104.01"/>
+</rte></gpx>`,
+		"line.geojson": `{"type":"FeatureCollection","features":[
+  {"type":"Feature","geometry":{"type":"Point","coordinates":[104.0,30.0]}},
+  {"type":"Feature","geometry":{"type":"LineString","coordinates":[[104.0,30.0],[104.01,30.0],[104.01,30.01]]}}
+]}`,
+		"multi.json": `{"type":"MultiLineString","coordinates":[[[104.0,30.0],[104.01,30.0],[104.01,30.01]]]}`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		route, err := LoadRouteFile(path)
+		if err != nil {
+			t.Errorf("LoadRouteFile(%s) failed: %v", name, err)
+			continue
+		}
+		if len(route.points) != 4 {
+			t.Errorf("%s: %d points, expected 4 (closed)", name, len(route.points))
+		}
+		if pos, _ := route.At(0); haversine(pos, LatLon{30, 104}) > 0.01 {
+			t.Errorf("%s: start = %v, expected (30, 104)", name, pos)
+		}
+	}
+
+	bad := filepath.Join(dir, "route.kml")
+	if err := os.WriteFile(bad, []byte("<kml/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRouteFile(bad); err == nil {
+		t.Error("LoadRouteFile(.kml) succeeded, expected error")
+	}
+}
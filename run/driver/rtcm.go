@@ -0,0 +1,494 @@
+package driver
+
+import (
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/spf13/cast"
+)
+
+const (
+	rtcmDefaultBaudRate = 115200
+	rtcmReadTimeout     = 100 * time.Millisecond
+	rtcmReopenDelay     = time.Second
+
+	rtcmPreamble   = 0xD3
+	rtcmHeaderSize = 3
+	rtcmCRCSize    = 3
+	rtcmRateWindow = 30 * time.Second // 计算各类电文频率的时间窗口，基站坐标电文通常10秒一条
+)
+
+// 差分数据来源
+const (
+	RTCMSourceNTRIP    = "ntrip"
+	RTCMSourceSerial   = "serial"
+	RTCMSourceReceiver = "receiver" // 基准站模式下接收机自身的输出
+)
+
+// rtcmMessageNames 需要分类的RTCM3电文类型
+var rtcmMessageNames = map[int]string{
+	1005: "Station ARP",
+	1006: "Station ARP with antenna height",
+	1074: "GPS MSM4",
+	1077: "GPS MSM7",
+	1084: "GLONASS MSM4",
+	1087: "GLONASS MSM7",
+	1094: "Galileo MSM4",
+	1097: "Galileo MSM7",
+	1124: "BeiDou MSM4",
+	1127: "BeiDou MSM7",
+	1230: "GLONASS code-phase biases",
+}
+
+// crc24qTable CRC-24Q（多项式0x1864CFB）的查找表
+var crc24qTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 16
+		for j := 0; j < 8; j++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864CFB
+			}
+		}
+		table[i] = crc & 0xFFFFFF
+	}
+	return table
+}()
+
+// crc24q 计算RTCM3帧的CRC-24Q校验值
+func crc24q(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = (crc<<8)&0xFFFFFF ^ crc24qTable[byte(crc>>16)^b]
+	}
+	return crc
+}
+
+// EncodeRTCMFrame 为电文加上RTCM3帧头和CRC
+func EncodeRTCMFrame(payload []byte) []byte {
+	frame := make([]byte, 0, rtcmHeaderSize+len(payload)+rtcmCRCSize)
+	frame = append(frame, rtcmPreamble, byte(len(payload)>>8)&0x03, byte(len(payload)))
+	frame = append(frame, payload...)
+	crc := crc24q(frame)
+	return append(frame, byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+// rtcmBits 从电文的第pos位起读取n位无符号整数（大端位序）
+func rtcmBits(payload []byte, pos, n int) uint64 {
+	var v uint64
+	for i := pos; i < pos+n; i++ {
+		v = v<<1 | uint64(payload[i/8]>>(7-i%8)&1)
+	}
+	return v
+}
+
+// rtcmSignedBits 读取n位补码表示的有符号整数
+func rtcmSignedBits(payload []byte, pos, n int) int64 {
+	v := rtcmBits(payload, pos, n)
+	if v&(1<<(n-1)) != 0 {
+		return int64(v) - int64(1)<<n
+	}
+	return int64(v)
+}
+
+// putRTCMBits 把v的低n位写入电文的第pos位起
+func putRTCMBits(payload []byte, pos, n int, v uint64) {
+	for i := 0; i < n; i++ {
+		bit := pos + i
+		mask := byte(1) << (7 - bit%8)
+		if v>>(n-1-i)&1 != 0 {
+			payload[bit/8] |= mask
+		} else {
+			payload[bit/8] &^= mask
+		}
+	}
+}
+
+// RTCMFrame 一帧通过校验的RTCM3电文
+type RTCMFrame struct {
+	Type    int
+	Payload []byte
+}
+
+// RTCMDecoder 从字节流中分帧RTCM3电文，丢弃CRC错误的帧和帧之间的其他数据。不可并发使用。
+type RTCMDecoder struct {
+	buf []byte
+}
+
+// Feed 追加数据并返回其中完整的电文，以及CRC校验失败的帧数
+func (d *RTCMDecoder) Feed(p []byte) (frames []RTCMFrame, crcErrors int) {
+	d.buf = append(d.buf, p...)
+	for {
+		start := 0
+		for start < len(d.buf) && d.buf[start] != rtcmPreamble {
+			start++
+		}
+		d.buf = d.buf[start:]
+		if len(d.buf) < rtcmHeaderSize {
+			break
+		}
+		// 长度字段前6位为保留位，必须为0
+		if d.buf[1]&0xFC != 0 {
+			d.buf = d.buf[1:]
+			continue
+		}
+		length := int(d.buf[1]&0x03)<<8 | int(d.buf[2])
+		total := rtcmHeaderSize + length + rtcmCRCSize
+		if len(d.buf) < total {
+			break
+		}
+
+		frame := d.buf[:total]
+		crc := uint32(frame[total-3])<<16 | uint32(frame[total-2])<<8 | uint32(frame[total-1])
+		if crc24q(frame[:total-rtcmCRCSize]) != crc {
+			crcErrors++
+			d.buf = d.buf[1:]
+			continue
+		}
+		// 电文至少要有12位的类型号
+		if length < 2 {
+			d.buf = d.buf[total:]
+			continue
+		}
+		payload := append([]byte(nil), frame[rtcmHeaderSize:total-rtcmCRCSize]...)
+		frames = append(frames, RTCMFrame{Type: int(rtcmBits(payload, 0, 12)), Payload: payload})
+		d.buf = d.buf[total:]
+	}
+
+	// 剩余的不完整帧不超过一帧的最大长度，复制出来以释放已处理的数据
+	d.buf = append([]byte(nil), d.buf...)
+	return frames, crcErrors
+}
+
+// RTCMFrameWriter 把写入的数据分帧，只将完整且校验通过的电文逐帧写入w，
+// 避免半帧差分数据与发给接收机的命令交错。不可并发写入。
+type RTCMFrameWriter struct {
+	w       io.Writer
+	decoder RTCMDecoder
+}
+
+// NewRTCMFrameWriter 创建逐帧写入w的RTCMFrameWriter
+func NewRTCMFrameWriter(w io.Writer) *RTCMFrameWriter {
+	return &RTCMFrameWriter{w: w}
+}
+
+// Write 分帧后逐帧写入，不完整的帧留到下次写入，成功时返回len(p)
+func (f *RTCMFrameWriter) Write(p []byte) (int, error) {
+	frames, _ := f.decoder.Feed(p)
+	for _, frame := range frames {
+		if _, err := f.w.Write(EncodeRTCMFrame(frame.Payload)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// BaseStation 1005/1006电文给出的基准站天线参考点
+type BaseStation struct {
+	MessageType   int     `json:"messageType"`
+	StationID     int     `json:"stationId"`
+	X             float64 `json:"x"` // ECEF坐标（米）
+	Y             float64 `json:"y"`
+	Z             float64 `json:"z"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	Height        float64 `json:"height"`                  // 椭球高（米）
+	AntennaHeight float64 `json:"antennaHeight,omitempty"` // 天线高（米），仅1006
+}
+
+// decodeStationARP 解析1005/1006电文，长度不足时返回false
+func decodeStationARP(frame RTCMFrame) (BaseStation, bool) {
+	if (frame.Type == 1005 && len(frame.Payload) < 19) || (frame.Type == 1006 && len(frame.Payload) < 21) ||
+		(frame.Type != 1005 && frame.Type != 1006) {
+		return BaseStation{}, false
+	}
+	p := frame.Payload
+	base := BaseStation{
+		MessageType: frame.Type,
+		StationID:   int(rtcmBits(p, 12, 12)),
+		X:           float64(rtcmSignedBits(p, 34, 38)) / 1e4,
+		Y:           float64(rtcmSignedBits(p, 74, 38)) / 1e4,
+		Z:           float64(rtcmSignedBits(p, 114, 38)) / 1e4,
+	}
+	if frame.Type == 1006 {
+		base.AntennaHeight = float64(rtcmBits(p, 152, 16)) / 1e4
+	}
+	pos, height := ecefToGeodetic(base.X, base.Y, base.Z)
+	base.Latitude, base.Longitude = math.Round(pos.Lat*1e9)/1e9, math.Round(pos.Lon*1e9)/1e9
+	base.Height = math.Round(height*1e4) / 1e4
+	return base, true
+}
+
+// encodeStationARP 生成1005电文的载荷，坐标为地心地固坐标（米），只置GPS标志
+func encodeStationARP(stationID int, x, y, z float64) []byte {
+	p := make([]byte, 19)
+	putRTCMBits(p, 0, 12, 1005)
+	putRTCMBits(p, 12, 12, uint64(stationID))
+	putRTCMBits(p, 30, 1, 1)
+	putRTCMBits(p, 34, 38, uint64(int64(math.Round(x*1e4))))
+	putRTCMBits(p, 74, 38, uint64(int64(math.Round(y*1e4))))
+	putRTCMBits(p, 114, 38, uint64(int64(math.Round(z*1e4))))
+	return p
+}
+
+// RTCMMessageStatus 一种电文的统计
+type RTCMMessageStatus struct {
+	Type  int     `json:"type"`
+	Name  string  `json:"name,omitempty"`
+	Count int64   `json:"count"`
+	Rate  float64 `json:"rate"` // 最近rtcmRateWindow内的频率（Hz）
+	Age   float64 `json:"age"`  // 距最近一条的时间（秒）
+}
+
+// CorrectionsStatus 差分数据流的诊断信息，作为corrections_status资源的值
+type CorrectionsStatus struct {
+	Sources   []string            `json:"sources"` // 收到过数据的差分数据来源
+	Bytes     int64               `json:"bytes"`
+	Frames    int64               `json:"frames"`
+	CRCErrors int64               `json:"crcErrors"`
+	Age       *float64            `json:"age,omitempty"` // 距最近一帧的时间（秒），尚未收到时省略
+	Messages  []RTCMMessageStatus `json:"messages"`
+	Base      *BaseStation        `json:"base,omitempty"`
+}
+
+// rtcmTypeStats 一种电文的计数和窗口内的接收时间
+type rtcmTypeStats struct {
+	count int64
+	times []time.Time
+}
+
+// RTCMMonitor 统计一台设备收到的差分数据，每个数据来源通过Stream获得独立分帧的写入端。可并发使用。
+type RTCMMonitor struct {
+	mutex     sync.Mutex
+	sources   map[string]bool
+	bytes     int64
+	frames    int64
+	crcErrors int64
+	lastFrame time.Time
+	types     map[int]*rtcmTypeStats
+	base      *BaseStation
+}
+
+// NewRTCMMonitor 创建差分数据统计
+func NewRTCMMonitor() *RTCMMonitor {
+	return &RTCMMonitor{sources: make(map[string]bool), types: make(map[int]*rtcmTypeStats)}
+}
+
+// Stream 返回一个数据来源的写入端，写入的数据按RTCM3分帧后计入统计
+func (m *RTCMMonitor) Stream(source string) *RTCMStream {
+	return &RTCMStream{monitor: m, source: source}
+}
+
+// RTCMStream 单个差分数据来源，不可并发写入
+type RTCMStream struct {
+	monitor *RTCMMonitor
+	source  string
+	decoder RTCMDecoder
+}
+
+// Write 分帧并统计，总是返回len(p)，以便与io.MultiWriter组合
+func (s *RTCMStream) Write(p []byte) (int, error) {
+	frames, crcErrors := s.decoder.Feed(p)
+	s.monitor.record(s.source, len(p), frames, crcErrors, time.Now())
+	return len(p), nil
+}
+
+// record 计入一次写入的数据
+func (m *RTCMMonitor) record(source string, n int, frames []RTCMFrame, crcErrors int, now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if n > 0 {
+		m.sources[source] = true
+	}
+	m.bytes += int64(n)
+	m.crcErrors += int64(crcErrors)
+	for _, frame := range frames {
+		m.frames++
+		m.lastFrame = now
+		stats := m.types[frame.Type]
+		if stats == nil {
+			stats = &rtcmTypeStats{}
+			m.types[frame.Type] = stats
+		}
+		stats.count++
+		kept := stats.times[:0]
+		for _, at := range stats.times {
+			if now.Sub(at) < rtcmRateWindow {
+				kept = append(kept, at)
+			}
+		}
+		stats.times = append(kept, now)
+
+		if base, ok := decodeStationARP(frame); ok {
+			m.base = &base
+		}
+	}
+}
+
+// Status 返回now时刻的统计，电文按类型排序
+func (m *RTCMMonitor) Status(now time.Time) CorrectionsStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	status := CorrectionsStatus{
+		Sources:   make([]string, 0, len(m.sources)),
+		Bytes:     m.bytes,
+		Frames:    m.frames,
+		CRCErrors: m.crcErrors,
+		Messages:  make([]RTCMMessageStatus, 0, len(m.types)),
+	}
+	for source := range m.sources {
+		status.Sources = append(status.Sources, source)
+	}
+	sort.Strings(status.Sources)
+	if !m.lastFrame.IsZero() {
+		age := now.Sub(m.lastFrame).Seconds()
+		status.Age = &age
+	}
+	if m.base != nil {
+		base := *m.base
+		status.Base = &base
+	}
+
+	for msgType, stats := range m.types {
+		message := RTCMMessageStatus{Type: msgType, Name: rtcmMessageNames[msgType], Count: stats.count}
+		var recent []time.Time
+		for _, at := range stats.times {
+			if now.Sub(at) < rtcmRateWindow {
+				recent = append(recent, at)
+			}
+		}
+		// 按窗口内首末两条的间隔计算频率，不受窗口起点的影响
+		if len(recent) >= 2 {
+			if span := recent[len(recent)-1].Sub(recent[0]).Seconds(); span > 0 {
+				message.Rate = math.Round(float64(len(recent)-1)/span*100) / 100
+			}
+		}
+		if len(stats.times) > 0 {
+			message.Age = now.Sub(stats.times[len(stats.times)-1]).Seconds()
+		}
+		status.Messages = append(status.Messages, message)
+	}
+	sort.Slice(status.Messages, func(i, j int) bool { return status.Messages[i].Type < status.Messages[j].Type })
+	return status
+}
+
+// getCorrectionsStatus 获取corrections_status资源，设备没有差分数据来源时返回nil
+func (s *Driver) getCorrectionsStatus(monitor *RTCMMonitor, req dsModels.CommandRequest) *dsModels.CommandValue {
+	if monitor == nil {
+		s.lc.Warnf("设备未开启NTRIP或配置rtcmPort，无法读取%s", req.DeviceResourceName)
+		return nil
+	}
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeObject, monitor.Status(time.Now()))
+	return cv
+}
+
+// openRTCMPort 按rtcmPort协议属性打开第二个串口，用于读取电台等来源的差分数据；
+// rtcmForward为true时同时转发给接收机。未配置或打开失败时返回nil。
+func (s *Driver) openRTCMPort(deviceName string, protocols map[string]models.ProtocolProperties) (*SerialPort, bool) {
+	var path string
+	baudRate, forward := rtcmDefaultBaudRate, false
+	for _, protocol := range protocols {
+		if value := cast.ToString(protocol["rtcmPort"]); value != "" {
+			path = value
+		}
+		if value, ok := protocol["rtcmBaudRate"]; ok && value != "" {
+			if n, err := cast.ToIntE(value); err == nil && n > 0 {
+				baudRate = n
+			} else {
+				s.lc.Errorf("设备%s的rtcmBaudRate属性无效: %v，使用%d", deviceName, value, rtcmDefaultBaudRate)
+			}
+		}
+		if value, ok := protocol["rtcmForward"]; ok && value != "" {
+			if b, err := cast.ToBoolE(value); err == nil {
+				forward = b
+			} else {
+				s.lc.Errorf("设备%s的rtcmForward属性无效: %v，不转发", deviceName, value)
+			}
+		}
+	}
+	if path == "" {
+		return nil, false
+	}
+
+	port, err := NewSerialPort(path, baudRate, rtcmReadTimeout)
+	if err != nil {
+		s.lc.Errorf("设备%s打开差分数据串口%s失败: %v", deviceName, path, err)
+		return nil, false
+	}
+	s.lc.Infof("设备%s将从%s读取差分数据，转发给接收机=%v", deviceName, path, forward)
+	return port, forward
+}
+
+// runRTCMPort 从第二个串口读取差分数据并统计，接收机关闭后关闭串口并退出
+func (s *Driver) runRTCMPort(deviceName string, rcv *receiver, port *SerialPort, forward bool) {
+	defer func() {
+		if err := port.Close(); err != nil {
+			s.lc.Errorf("关闭设备%s的差分数据串口失败: %v", deviceName, err)
+		}
+	}()
+
+	stream := rcv.corrections.Stream(RTCMSourceSerial)
+	var receiverSink io.Writer
+	if forward {
+		receiverSink = NewRTCMFrameWriter(rcv.gps.Port())
+	}
+	idle := newEmptyReads(port)
+	buf := make([]byte, 1024)
+	for {
+		select {
+		case <-rcv.gps.Done():
+			return
+		default:
+		}
+
+		started := time.Now()
+		n, err := port.Read(buf)
+		if n > 0 {
+			idle.reset()
+			_, _ = stream.Write(buf[:n])
+			// 只转发完整的电文，避免与发给接收机的命令交错
+			if receiverSink != nil {
+				if _, err := receiverSink.Write(buf[:n]); err != nil {
+					s.lc.Errorf("设备%s转发差分数据失败: %v", deviceName, err)
+				}
+			}
+		}
+		switch {
+		case err != nil && !isReadTimeout(err):
+			s.lc.Errorf("设备%s读取差分数据串口失败: %v", deviceName, err)
+		case n == 0 && idle.empty(started):
+			s.lc.Warnf("设备%s的差分数据串口持续无数据返回，可能已断开", deviceName)
+		case n == 0:
+			// 挂断的tty读取立即返回，休眠避免空转
+			select {
+			case <-rcv.gps.Done():
+				return
+			case <-time.After(emptyReadDelay):
+			}
+			continue
+		default:
+			continue
+		}
+
+		idle.reset()
+		select {
+		case <-rcv.gps.Done():
+			return
+		case <-time.After(rtcmReopenDelay):
+		}
+		if port.CanReopen() {
+			if err := port.Reopen(); err != nil {
+				s.lc.Errorf("设备%s重新打开差分数据串口失败: %v", deviceName, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package driver
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+func TestDriverRTCMPort(t *testing.T) {
+	master, slave, err := openPty()
+	if err != nil {
+		t.Skipf("伪终端不可用: %v", err)
+	}
+	defer master.Close()
+
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["rtcmPort"] = slave
+	protocols[ProtocolSIM]["rtcmForward"] = "true"
+	driver, _ := newTestDriverWithConfig(t, map[string]string{}, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	sample, _ := hex.DecodeString(rtcm1005Sample)
+	stream := append(sample, rtcmTestFrame(1127, 80)...)
+	rcv, _ := driver.receiverFor("GPS-A")
+	sent := rcv.gps.Port().TxBytes()
+	waitFor(t, 5*time.Second, func() bool {
+		// 驱动打开从端之前写入的数据可能丢失，持续发送直到统计到电文
+		_, _ = master.Write(stream)
+		status, ok := readResource(t, driver, "GPS-A", "corrections_status").Value.(CorrectionsStatus)
+		return ok && status.Base != nil && len(status.Messages) == 2
+	}, "corrections from the RTCM port")
+
+	status := readResource(t, driver, "GPS-A", "corrections_status").Value.(CorrectionsStatus)
+	if status.Messages[1].Type != 1127 || status.Messages[1].Name != "BeiDou MSM7" || len(status.Sources) != 1 || status.Sources[0] != RTCMSourceSerial {
+		t.Errorf("corrections_status = %+v", status)
+	}
+	// rtcmForward为true时转发给接收机
+	if rcv.gps.Port().TxBytes() <= sent {
+		t.Error("corrections were not forwarded to the receiver")
+	}
+}
+
+func TestDriverRTCMPortHangup(t *testing.T) {
+	master, slave, err := openPty()
+	if err != nil {
+		t.Skipf("伪终端不可用: %v", err)
+	}
+	defer master.Close()
+
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["rtcmPort"] = slave
+	driver, _ := newTestDriverWithConfig(t, map[string]string{}, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	frames := func() int64 {
+		status, _ := readResource(t, driver, "GPS-A", "corrections_status").Value.(CorrectionsStatus)
+		return status.Frames
+	}
+	frame := rtcmTestFrame(1127, 80)
+	waitFor(t, 5*time.Second, func() bool {
+		_, _ = master.Write(frame)
+		return frames() > 0
+	}, "corrections before the hangup")
+
+	// 挂断后读取立即返回EOF，驱动需要在持续空读后重新打开差分数据串口
+	if err := hangupPty(slave); err != nil {
+		t.Skipf("无法挂断伪终端: %v", err)
+	}
+	time.Sleep(emptyReadWindow + rtcmReopenDelay + 500*time.Millisecond)
+	before := frames()
+	if _, err := master.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 2*time.Second, func() bool { return frames() > before }, "corrections after reopening the hung-up port")
+}
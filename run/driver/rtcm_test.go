@@ -0,0 +1,193 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+)
+
+// rtcm1005Sample RTCM 10403标准中的1005电文示例，基准站2003
+const rtcm1005Sample = "D300133ED7D30202980EDEEF34B4BD62AC0941986F33360B98"
+
+// rtcmTestFrame 生成指定类型、长度为size字节的RTCM3帧
+func rtcmTestFrame(msgType, size int) []byte {
+	payload := make([]byte, size)
+	putRTCMBits(payload, 0, 12, uint64(msgType))
+	return EncodeRTCMFrame(payload)
+}
+
+func TestCRC24Q(t *testing.T) {
+	frame, _ := hex.DecodeString(rtcm1005Sample)
+	if crc := crc24q(frame[:len(frame)-3]); crc != 0x360B98 {
+		t.Errorf("crc24q = %06X, expected 360B98", crc)
+	}
+	if encoded := EncodeRTCMFrame(frame[3 : len(frame)-3]); !bytes.Equal(encoded, frame) {
+		t.Errorf("EncodeRTCMFrame = % X", encoded)
+	}
+}
+
+func TestRTCMBits(t *testing.T) {
+	payload := make([]byte, 8)
+	putRTCMBits(payload, 3, 38, uint64(1<<38-12345))
+	if v := rtcmSignedBits(payload, 3, 38); v != -12345 {
+		t.Errorf("rtcmSignedBits = %d, expected -12345", v)
+	}
+	putRTCMBits(payload, 50, 12, 1077)
+	if v := rtcmBits(payload, 50, 12); v != 1077 {
+		t.Errorf("rtcmBits = %d, expected 1077", v)
+	}
+	if v := rtcmSignedBits(payload, 3, 38); v != -12345 {
+		t.Errorf("rtcmSignedBits after second write = %d", v)
+	}
+}
+
+func TestRTCMDecoder(t *testing.T) {
+	sample, _ := hex.DecodeString(rtcm1005Sample)
+	corrupt := rtcmTestFrame(1077, 40)
+	corrupt[10] ^= 0xFF
+
+	var stream []byte
+	stream = append(stream, "$GNGGA,garbage\r\n"...)
+	stream = append(stream, sample...)
+	stream = append(stream, corrupt...)
+	stream = append(stream, rtcmTestFrame(1077, 40)...)
+	stream = append(stream, EncodeRTCMFrame(nil)...) // 空帧
+	stream = append(stream, rtcmTestFrame(1230, 6)...)
+
+	// 逐字节写入，验证跨写入的分帧
+	var decoder RTCMDecoder
+	var types []int
+	errors := 0
+	for i := range stream {
+		frames, crcErrors := decoder.Feed(stream[i : i+1])
+		errors += crcErrors
+		for _, frame := range frames {
+			types = append(types, frame.Type)
+		}
+	}
+	if len(types) != 3 || types[0] != 1005 || types[1] != 1077 || types[2] != 1230 {
+		t.Errorf("frame types = %v", types)
+	}
+	if errors != 1 {
+		t.Errorf("crc errors = %d, expected 1", errors)
+	}
+	if len(decoder.buf) != 0 {
+		t.Errorf("decoder kept %d bytes", len(decoder.buf))
+	}
+}
+
+// writeCalls 记录每次Write调用的数据
+type writeCalls [][]byte
+
+func (w *writeCalls) Write(p []byte) (int, error) {
+	*w = append(*w, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestRTCMFrameWriter(t *testing.T) {
+	first, second := rtcmTestFrame(1077, 40), rtcmTestFrame(1230, 6)
+	var stream []byte
+	stream = append(stream, first...)
+	stream = append(stream, "garbage"...)
+	stream = append(stream, second...)
+
+	// 按TCP分片那样切开写入，接收机只收到完整的帧
+	var calls writeCalls
+	w := NewRTCMFrameWriter(&calls)
+	for _, chunk := range [][]byte{stream[:10], stream[10:50], stream[50:]} {
+		if n, err := w.Write(chunk); n != len(chunk) || err != nil {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+	if len(calls) != 2 || !bytes.Equal(calls[0], first) || !bytes.Equal(calls[1], second) {
+		t.Errorf("writes = %q", calls)
+	}
+}
+
+func TestDecodeStationARP(t *testing.T) {
+	sample, _ := hex.DecodeString(rtcm1005Sample)
+	base, ok := decodeStationARP(RTCMFrame{Type: 1005, Payload: sample[3 : len(sample)-3]})
+	if !ok || base.StationID != 2003 || base.X != 1114104.5999 || base.Y != -4850729.7108 || base.Z != 3975521.4643 {
+		t.Fatalf("decodeStationARP = %+v, %v", base, ok)
+	}
+	if math.Abs(base.Latitude-38.80475943) > 1e-8 || math.Abs(base.Longitude+77.06477360) > 1e-8 || math.Abs(base.Height-114.5611) > 1e-3 {
+		t.Errorf("geodetic = %v, %v, %v", base.Latitude, base.Longitude, base.Height)
+	}
+
+	// 1006在1005之后带16位天线高
+	payload := make([]byte, 21)
+	copy(payload, sample[3:len(sample)-3])
+	putRTCMBits(payload, 0, 12, 1006)
+	putRTCMBits(payload, 152, 16, 15000)
+	if base, ok = decodeStationARP(RTCMFrame{Type: 1006, Payload: payload}); !ok || base.AntennaHeight != 1.5 || base.StationID != 2003 {
+		t.Errorf("1006 = %+v, %v", base, ok)
+	}
+
+	if _, ok = decodeStationARP(RTCMFrame{Type: 1005, Payload: payload[:10]}); ok {
+		t.Error("decodeStationARP accepted a short 1005")
+	}
+}
+
+func TestECEFToGeodetic(t *testing.T) {
+	// 赤道、本初子午线和北极
+	tests := []struct {
+		x, y, z          float64
+		lat, lon, height float64
+	}{
+		{wgs84A + 100, 0, 0, 0, 0, 100},
+		{0, wgs84A, 0, 0, 90, 0},
+		{0, 0, wgs84A * (1 - wgs84F), 90, 0, 0},
+	}
+	for _, tt := range tests {
+		pos, height := ecefToGeodetic(tt.x, tt.y, tt.z)
+		if math.Abs(pos.Lat-tt.lat) > 1e-9 || math.Abs(pos.Lon-tt.lon) > 1e-9 || math.Abs(height-tt.height) > 1e-6 {
+			t.Errorf("ecefToGeodetic(%v, %v, %v) = %v, %v", tt.x, tt.y, tt.z, pos, height)
+		}
+	}
+}
+
+func TestRTCMMonitor(t *testing.T) {
+	monitor := NewRTCMMonitor()
+	if status := monitor.Status(time.Now()); status.Age != nil || len(status.Messages) != 0 || status.Base != nil {
+		t.Errorf("initial status = %+v", status)
+	}
+
+	start := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	sample, _ := hex.DecodeString(rtcm1005Sample)
+	var decoder RTCMDecoder
+	feed := func(at time.Time, data []byte) {
+		frames, crcErrors := decoder.Feed(data)
+		monitor.record(RTCMSourceNTRIP, len(data), frames, crcErrors, at)
+	}
+	// MSM7每秒一条，1005每10秒一条
+	for i := 0; i < 40; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		feed(at, rtcmTestFrame(1077, 60))
+		if i%10 == 0 {
+			feed(at, sample)
+		}
+	}
+	feed(start.Add(40*time.Second), []byte{0xD3, 0x00, 0x02, 0x12, 0x34, 0x00, 0x00, 0x00})
+
+	status := monitor.Status(start.Add(40 * time.Second))
+	if status.Frames != 44 || status.CRCErrors != 1 || len(status.Sources) != 1 || status.Sources[0] != RTCMSourceNTRIP {
+		t.Errorf("status = %+v", status)
+	}
+	if status.Age == nil || *status.Age != 1 {
+		t.Errorf("age = %v", status.Age)
+	}
+	if len(status.Messages) != 2 {
+		t.Fatalf("messages = %+v", status.Messages)
+	}
+	if msm := status.Messages[1]; msm.Type != 1077 || msm.Name != "GPS MSM7" || msm.Count != 40 || msm.Rate != 1 {
+		t.Errorf("1077 = %+v", msm)
+	}
+	if arp := status.Messages[0]; arp.Type != 1005 || arp.Count != 4 || arp.Rate != 0.1 || arp.Age != 10 {
+		t.Errorf("1005 = %+v", arp)
+	}
+	if status.Base == nil || status.Base.StationID != 2003 {
+		t.Errorf("base = %+v", status.Base)
+	}
+}
@@ -0,0 +1,173 @@
+package driver
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// gsvMaxAge 超过该时间没有更新的卫星系统GSV视为过期，不再计入可视卫星
+const gsvMaxAge = 5 * time.Second
+
+// SatelliteInfo GSV给出的一颗卫星的状态，Used来自同一历元的GSA
+type SatelliteInfo struct {
+	System    string  `json:"system"` // 卫星系统，无法确定时为空
+	ID        int     `json:"id"`
+	Signal    string  `json:"signal,omitempty"` // NMEA 4.11信号标识符
+	Elevation float64 `json:"elevation"`        // 仰角（度）
+	Azimuth   float64 `json:"azimuth"`          // 真方位角（度）
+	CN0       float64 `json:"cn0"`              // 载噪比（dB-Hz），未跟踪时为0
+	Tracked   bool    `json:"tracked"`
+	Used      bool    `json:"used"`
+	HasAngles bool    `json:"-"` // 仰角和方位角有效
+}
+
+// gsvGroup 同一卫星系统、同一信号的一组GSV语句
+type gsvGroup struct {
+	pending []SatelliteInfo // 正在接收的一组语句中的卫星
+	next    int             // 下一条期望的语句号，0表示等待第一条
+	view    []SatelliteInfo // 最近一组完整语句中的卫星
+	at      time.Time       // view的接收时间
+}
+
+// storeGSV 按卫星系统和信号累积GSV语句，一组语句收齐后替换该组的可视卫星。调用方需持有mutex。
+func (lcx6xz *LCX6XZ) storeGSV(gsv *NMEA_GSV, now time.Time) {
+	total, totalErr := strconv.Atoi(trimNullBytes(gsv.TotalNumSen[:]))
+	num, numErr := strconv.Atoi(trimNullBytes(gsv.SenNum[:]))
+	if totalErr != nil || numErr != nil || num < 1 || num > total {
+		return
+	}
+
+	talker := trimNullBytes(gsv.Nmea.TalkerID[:])
+	signal := trimNullBytes(gsv.SignalID[:])
+	key := talker + signal
+	if lcx6xz.gsvGroups == nil {
+		lcx6xz.gsvGroups = make(map[string]*gsvGroup)
+	}
+	group := lcx6xz.gsvGroups[key]
+	if group == nil {
+		group = &gsvGroup{}
+		lcx6xz.gsvGroups[key] = group
+	}
+
+	// 第一条语句开始新的一组，缺少语句时丢弃这一组
+	if num == 1 {
+		group.pending, group.next = nil, 1
+	}
+	if num != group.next {
+		group.next = 0
+		return
+	}
+	for _, status := range gsv.SatStatus {
+		if sat, ok := satelliteFromGSV(status, talker, signal); ok {
+			group.pending = append(group.pending, sat)
+		}
+	}
+	group.next++
+	if num == total {
+		group.view, group.at, group.next = group.pending, now, 0
+		group.pending = nil
+	}
+}
+
+// satelliteFromGSV 解析GSV中的一颗卫星，卫星号为空时返回false
+func satelliteFromGSV(status SAT_STATUS, talker, signal string) (SatelliteInfo, bool) {
+	id, err := strconv.Atoi(trimNullBytes(status.SatID[:]))
+	if err != nil {
+		return SatelliteInfo{}, false
+	}
+	sat := SatelliteInfo{System: constellationFromTalker(talker), ID: id, Signal: signal}
+	if sat.System == "" {
+		sat.System = constellationFromSatID(strconv.Itoa(id))
+	}
+	elev, elevOK := parseNMEAFloat(status.SatElev[:])
+	az, azOK := parseNMEAFloat(status.SatAz[:])
+	if elevOK && azOK {
+		sat.Elevation, sat.Azimuth, sat.HasAngles = elev, az, true
+	}
+	sat.CN0, sat.Tracked = parseNMEAFloat(status.SatCN0[:])
+	return sat, true
+}
+
+// Satellites 返回当前可视卫星，按卫星系统和卫星号排序。同一卫星有多个信号时只保留载噪比最高的一个。
+func (lcx6xz *LCX6XZ) Satellites() []SatelliteInfo {
+	type satKey struct {
+		system string
+		id     int
+	}
+	bySat := make(map[satKey]SatelliteInfo)
+	for _, sat := range lcx6xz.SatelliteSignals() {
+		key := satKey{sat.System, sat.ID}
+		if old, ok := bySat[key]; !ok || sat.CN0 > old.CN0 {
+			bySat[key] = sat
+		}
+	}
+
+	sats := make([]SatelliteInfo, 0, len(bySat))
+	for _, sat := range bySat {
+		sats = append(sats, sat)
+	}
+	sortSatellites(sats)
+	return sats
+}
+
+// SatelliteSignals 返回当前可视卫星的每个信号，按卫星系统、卫星号和信号排序
+func (lcx6xz *LCX6XZ) SatelliteSignals() []SatelliteInfo {
+	lcx6xz.mutex.Lock()
+	defer lcx6xz.mutex.Unlock()
+
+	var latest time.Time
+	for _, group := range lcx6xz.gsvGroups {
+		if group.at.After(latest) {
+			latest = group.at
+		}
+	}
+
+	used := usedSatellites(lcx6xz.currentGSAs())
+	var sats []SatelliteInfo
+	for _, group := range lcx6xz.gsvGroups {
+		if latest.Sub(group.at) > gsvMaxAge {
+			continue
+		}
+		for _, sat := range group.view {
+			// 只有卫星号的键来自无法确定卫星系统的GSA，只匹配同样无法确定卫星系统的卫星
+			sat.Used = used[sat.System+strconv.Itoa(sat.ID)] || (sat.System == "" && used[strconv.Itoa(sat.ID)])
+			sats = append(sats, sat)
+		}
+	}
+	sortSatellites(sats)
+	return sats
+}
+
+// sortSatellites 按卫星系统、卫星号和信号排序
+func sortSatellites(sats []SatelliteInfo) {
+	sort.Slice(sats, func(i, j int) bool {
+		if sats[i].System != sats[j].System {
+			return sats[i].System < sats[j].System
+		}
+		if sats[i].ID != sats[j].ID {
+			return sats[i].ID < sats[j].ID
+		}
+		return sats[i].Signal < sats[j].Signal
+	})
+}
+
+// usedSatellites 返回GSA中参与解算的卫星，键为卫星系统+卫星号；无法确定卫星系统时键只有卫星号
+func usedSatellites(gsas []*NMEA_GSA) map[string]bool {
+	used := make(map[string]bool)
+	for _, gsa := range gsas {
+		system := gsaConstellation(gsa)
+		for _, raw := range gsa.SatID {
+			id, err := strconv.Atoi(trimNullBytes(raw[:]))
+			if err != nil {
+				continue
+			}
+			satSystem := system
+			if satSystem == "" {
+				satSystem = constellationFromSatID(strconv.Itoa(id))
+			}
+			used[satSystem+strconv.Itoa(id)] = true
+		}
+	}
+	return used
+}
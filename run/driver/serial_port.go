@@ -1,65 +1,290 @@
 package driver
 
 import (
-	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
-	"log"
 	"strings"
 	"sync"
 	"time"
 
+	gometrics "github.com/rcrowley/go-metrics"
 	"github.com/tarm/serial"
 )
 
+// 线路跟踪方向
+const (
+	TraceRX = "RX"
+	TraceTX = "TX"
+)
+
+// 字节计数指标名称
+const (
+	rxBytesMetricName = "GpsRxBytes"
+	txBytesMetricName = "GpsTxBytes"
+)
+
+// TraceEntry 一条线路跟踪记录
+type TraceEntry struct {
+	Time time.Time
+	Dir  string // RX 或 TX
+	Data []byte
+}
+
+// String 以十六进制和ASCII对照的形式输出跟踪记录
+func (e TraceEntry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %d bytes\n", e.Time.Format("15:04:05.000"), e.Dir, len(e.Data))
+	sb.WriteString(hex.Dump(e.Data))
+	return sb.String()
+}
+
+// wireTrace 固定容量的跟踪环形缓冲区，写满后覆盖最旧的记录
+type wireTrace struct {
+	entries []TraceEntry
+	next    int
+	full    bool
+}
+
+func newWireTrace(size int) *wireTrace {
+	return &wireTrace{entries: make([]TraceEntry, size)}
+}
+
+func (t *wireTrace) add(dir string, data []byte) {
+	buf := make([]byte, len(data))
+	copy(buf, data)
+	t.entries[t.next] = TraceEntry{Time: time.Now(), Dir: dir, Data: buf}
+	t.next = (t.next + 1) % len(t.entries)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// snapshot 按时间先后顺序返回所有记录
+func (t *wireTrace) snapshot() []TraceEntry {
+	if !t.full {
+		return append([]TraceEntry(nil), t.entries[:t.next]...)
+	}
+	result := make([]TraceEntry, 0, len(t.entries))
+	result = append(result, t.entries[t.next:]...)
+	return append(result, t.entries[:t.next]...)
+}
+
+// SerialPort 接收机的串口传输层。
+// 所有接收机都通过它读写，写操作串行化，避免并发的配置帧交错；
+// 同时统计收发字节数，并可选地记录线路跟踪。
 type SerialPort struct {
-	port   *serial.Port
-	reader *bufio.Reader
-	lock   sync.Mutex
-	Debug  bool
+	open      func() (io.ReadWriteCloser, error) // 重新打开底层设备，为nil时不支持重连
+	timeout   time.Duration                      // 读超时，0表示不是带超时的串口
+	connLock  sync.RWMutex
+	port      io.ReadWriteCloser
+	writeLock sync.Mutex
+	traceLock sync.Mutex
+	trace     *wireTrace
+	recorder  *Recorder
+	rxBytes   gometrics.Counter
+	txBytes   gometrics.Counter
 }
 
-func NewSerialPort(portName string, baud int, debug bool, timeout time.Duration) (*SerialPort, error) {
+// NewSerialPort 打开串口设备并创建传输层
+func NewSerialPort(portName string, baud int, timeout time.Duration) (*SerialPort, error) {
 	cfg := &serial.Config{
 		Name:        portName,
 		Baud:        baud,
+		Parity:      serial.ParityNone,
+		StopBits:    serial.Stop1,
 		ReadTimeout: timeout,
 	}
-	p, err := serial.OpenPort(cfg)
+	open := func() (io.ReadWriteCloser, error) {
+		return serial.OpenPort(cfg)
+	}
+	p, err := open()
 	if err != nil {
 		return nil, err
 	}
+	port := NewSerialPortFrom(p)
+	port.open = open
+	port.timeout = timeout
+	return port, nil
+}
+
+// NewSerialPortFrom 基于已打开的读写端创建传输层
+func NewSerialPortFrom(rwc io.ReadWriteCloser) *SerialPort {
 	return &SerialPort{
-		port:   p,
-		reader: bufio.NewReader(p),
-		Debug:  debug,
-	}, nil
+		port:    rwc,
+		rxBytes: gometrics.NewCounter(),
+		txBytes: gometrics.NewCounter(),
+	}
 }
 
+// Read 从串口读取数据
+func (s *SerialPort) Read(data []byte) (int, error) {
+	n, err := s.current().Read(data)
+	if n > 0 {
+		s.rxBytes.Inc(int64(n))
+		s.record(TraceRX, data[:n])
+	}
+	return n, err
+}
+
+// Write 向串口写入数据，同一时刻只允许一个写操作
 func (s *SerialPort) Write(data []byte) (int, error) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
 
-	n, err := s.port.Write(data)
-	if s.Debug {
-		log.Printf("SerialPort.Write: wrote %d bytes, data: %s", n, data)
+	n, err := s.current().Write(data)
+	if n > 0 {
+		s.txBytes.Inc(int64(n))
+		s.record(TraceTX, data[:n])
 	}
 	return n, err
 }
 
-func (s *SerialPort) ReadLine() ([]byte, error) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+// Close 关闭串口，同时结束录制
+func (s *SerialPort) Close() error {
+	s.SetRecorder(nil)
+	return s.current().Close()
+}
+
+// CanReopen 判断底层设备是否支持重新打开
+func (s *SerialPort) CanReopen() bool {
+	return s.open != nil
+}
 
-	line, err := s.reader.ReadBytes('\n')
-	if err != nil && err != io.EOF {
-		return nil, err
+// Reopen 关闭当前连接并重新打开底层设备，计数和跟踪记录保持不变
+func (s *SerialPort) Reopen() error {
+	if s.open == nil {
+		return errors.New("串口不支持重新打开")
 	}
-	if s.Debug {
-		log.Printf("SerialPort.ReadLine: %d bytes, line: %s", len(line), strings.TrimRight(string(line), "\r\n"))
+
+	_ = s.current().Close()
+	rwc, err := s.open()
+	if err != nil {
+		return err
 	}
-	return line, err
+
+	s.connLock.Lock()
+	s.port = rwc
+	s.connLock.Unlock()
+	return nil
 }
 
-func (s *SerialPort) Close() error {
-	return s.port.Close()
+// emptyReads 统计连续立即返回的空读。tarm/serial在读超时和tty挂断时都返回io.EOF，
+// 挂断（如USB接收机被拔出）后每次读取立即返回0字节，需要休眠并在持续一个读超时后重连。
+type emptyReads struct {
+	timeout time.Duration // 串口读超时
+	since   time.Time     // 连续空读开始的时间，零值表示最近一次读到了数据
+}
+
+// 连续空读的处理参数
+const (
+	emptyReadDelay  = 100 * time.Millisecond // 两次空读之间的休眠
+	emptyReadWindow = time.Second            // 视为断线前连续空读的最短持续时间
+)
+
+// newEmptyReads 按串口的读超时创建空读计数
+func newEmptyReads(port *SerialPort) *emptyReads {
+	return &emptyReads{timeout: port.timeout}
+}
+
+// reset 读到数据后清零
+func (e *emptyReads) reset() {
+	e.since = time.Time{}
+}
+
+// empty 记录一次从started开始的空读，返回true表示空读已持续超过一个读超时，应当重连。
+// 阻塞到读超时才返回的空读说明串口仍然在线，不计入。
+func (e *emptyReads) empty(started time.Time) bool {
+	now := time.Now()
+	if e.timeout > 0 && now.Sub(started) >= e.timeout/2 {
+		e.since = time.Time{}
+		return false
+	}
+	if e.since.IsZero() {
+		e.since = now
+	}
+	return now.Sub(e.since) >= max(e.timeout, emptyReadWindow)
+}
+
+func (s *SerialPort) current() io.ReadWriteCloser {
+	s.connLock.RLock()
+	defer s.connLock.RUnlock()
+	return s.port
+}
+
+// EnableTrace 开启线路跟踪，size 为环形缓冲区保留的记录条数；size<=0 时关闭跟踪
+func (s *SerialPort) EnableTrace(size int) {
+	s.traceLock.Lock()
+	defer s.traceLock.Unlock()
+
+	if size <= 0 {
+		s.trace = nil
+		return
+	}
+	s.trace = newWireTrace(size)
+}
+
+// Trace 返回当前环形缓冲区中的跟踪记录；未开启跟踪时返回nil
+func (s *SerialPort) Trace() []TraceEntry {
+	s.traceLock.Lock()
+	defer s.traceLock.Unlock()
+
+	if s.trace == nil {
+		return nil
+	}
+	return s.trace.snapshot()
+}
+
+// SetRecorder 设置接收数据的录制器，传nil停止录制并关闭原录制器
+func (s *SerialPort) SetRecorder(recorder *Recorder) {
+	s.traceLock.Lock()
+	old := s.recorder
+	s.recorder = recorder
+	s.traceLock.Unlock()
+
+	if old != nil && old != recorder {
+		_ = old.Close()
+	}
+}
+
+// TraceDump 以文本形式输出全部跟踪记录
+func (s *SerialPort) TraceDump() string {
+	var sb strings.Builder
+	for _, entry := range s.Trace() {
+		sb.WriteString(entry.String())
+	}
+	return sb.String()
+}
+
+// RxBytes 返回累计接收字节数
+func (s *SerialPort) RxBytes() int64 {
+	return s.rxBytes.Count()
+}
+
+// TxBytes 返回累计发送字节数
+func (s *SerialPort) TxBytes() int64 {
+	return s.txBytes.Count()
+}
+
+// Metrics 返回需要注册到MetricsManager的字节计数指标
+func (s *SerialPort) Metrics() map[string]interface{} {
+	return map[string]interface{}{
+		rxBytesMetricName: s.rxBytes,
+		txBytesMetricName: s.txBytes,
+	}
+}
+
+func (s *SerialPort) record(dir string, data []byte) {
+	s.traceLock.Lock()
+	defer s.traceLock.Unlock()
+
+	if s.trace != nil {
+		s.trace.add(dir, data)
+	}
+	if s.recorder != nil && dir == TraceRX {
+		if err := s.recorder.Record(time.Now(), data); err != nil {
+			fmt.Printf("录制串口数据失败: %v\n", err)
+		}
+	}
 }
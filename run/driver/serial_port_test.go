@@ -0,0 +1,127 @@
+package driver
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bufferPort 用内存缓冲区模拟串口
+type bufferPort struct {
+	mutex sync.Mutex
+	rx    bytes.Buffer
+	tx    bytes.Buffer
+}
+
+func (b *bufferPort) Read(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.rx.Read(p)
+}
+
+func (b *bufferPort) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.tx.Write(p)
+}
+
+func (b *bufferPort) Close() error {
+	return nil
+}
+
+func TestSerialPortCounters(t *testing.T) {
+	raw := &bufferPort{}
+	raw.rx.WriteString("$GBVTG,000.00,T,,M,0.00,N,0.00,K,A*2F\r\n")
+	port := NewSerialPortFrom(raw)
+
+	buf := make([]byte, 64)
+	n, err := port.Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if port.RxBytes() != int64(n) {
+		t.Errorf("RxBytes = %d, expected %d", port.RxBytes(), n)
+	}
+
+	msg := CfgMsgQueOutRate(NMEA_GID, NMEA_GGA_SID).ToBytes()
+	if _, err := port.Write(msg); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if port.TxBytes() != int64(len(msg)) {
+		t.Errorf("TxBytes = %d, expected %d", port.TxBytes(), len(msg))
+	}
+
+	// 未开启跟踪时不记录
+	if trace := port.Trace(); trace != nil {
+		t.Errorf("Trace = %v, expected nil", trace)
+	}
+}
+
+func TestSerialPortTraceRing(t *testing.T) {
+	port := NewSerialPortFrom(&bufferPort{})
+	port.EnableTrace(2)
+
+	for _, frame := range []string{"one", "two", "three"} {
+		if _, err := port.Write([]byte(frame)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	trace := port.Trace()
+	if len(trace) != 2 {
+		t.Fatalf("len(Trace) = %d, expected 2", len(trace))
+	}
+	if string(trace[0].Data) != "two" || string(trace[1].Data) != "three" {
+		t.Errorf("Trace = [%s %s], expected [two three]", trace[0].Data, trace[1].Data)
+	}
+	if trace[0].Dir != TraceTX {
+		t.Errorf("Dir = %s, expected %s", trace[0].Dir, TraceTX)
+	}
+}
+
+func TestSerialPortConcurrentWrites(t *testing.T) {
+	raw := &bufferPort{}
+	port := NewSerialPortFrom(raw)
+	frame := CfgMsgSetOutRate(NMEA_GID, NMEA_RMC_SID, 1).ToBytes()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = port.Write(frame)
+		}()
+	}
+	wg.Wait()
+
+	// 每一帧都必须完整出现，不能交错
+	out := raw.tx.Bytes()
+	for i := 0; i < 50; i++ {
+		if !bytes.Equal(out[i*len(frame):(i+1)*len(frame)], frame) {
+			t.Fatalf("frame %d interleaved: %X", i, out[i*len(frame):(i+1)*len(frame)])
+		}
+	}
+}
+
+func TestEmptyReads(t *testing.T) {
+	idle := &emptyReads{timeout: 200 * time.Millisecond}
+	now := time.Now()
+
+	// 阻塞到读超时才返回的空读不计入
+	if idle.empty(now.Add(-200*time.Millisecond)) || !idle.since.IsZero() {
+		t.Error("a read that waited for the timeout was counted")
+	}
+	// 立即返回的空读持续超过窗口后要求重连
+	if idle.empty(now) {
+		t.Error("first immediate empty read requested a reconnect")
+	}
+	idle.since = now.Add(-emptyReadWindow)
+	if !idle.empty(time.Now()) {
+		t.Error("immediate empty reads for a whole window did not request a reconnect")
+	}
+	idle.reset()
+	if idle.empty(time.Now()) {
+		t.Error("empty read after reset requested a reconnect")
+	}
+}
@@ -0,0 +1,598 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	simGeoidSeparation = -32.3 // 模拟输出的大地水准面差距（米）
+	simUERE            = 2.0   // 用户等效测距误差（米），用于估算GST误差
+	simElevationMask   = 5     // 参与解算的最低仰角（度）
+	simTrackCN0        = 20    // 低于该载噪比（dB-Hz）视为失锁
+	simJamAttenuation  = 20    // 干扰期间载噪比下降量（dB-Hz）
+	simMaxUsed         = 12    // 单个系统参与解算的最大卫星数
+	simSurveyAccuracy  = 10    // 基准站勘测第一个历元的三维精度（米），随历元数的平方根减小
+	simStationID       = 1     // 基准站模式下RTCM电文中的基准站ID
+	simMaxCommand      = 256   // 专有语句命令的最大长度，超过仍未结束的数据被丢弃
+	knotsPerMps        = 1.943844
+)
+
+// SimConfig 模拟接收机配置，对应设备的SIM协议属性
+type SimConfig struct {
+	Route           *Route        // 行驶路线
+	Speed           float64       // 行驶速度（米/秒），0为静止在路线起点
+	Altitude        float64       // 海拔（米）
+	Noise           float64       // 水平位置噪声标准差（米）
+	Satellites      int           // 可视卫星数，GPS和北斗各占一半
+	UpdateRate      float64       // 定位频率（Hz）
+	DropoutEvery    time.Duration // 每隔多长时间失去一次定位，0为不失锁
+	DropoutDuration time.Duration // 每次失去定位的时长
+	JamEvery        time.Duration // 每隔多长时间受到一次干扰，0为无干扰
+	JamDuration     time.Duration // 每次干扰的时长
+	Seed            uint64        // 随机数种子，相同种子输出相同的噪声
+}
+
+// simSatellite 模拟卫星，仰角和方位角随时间缓慢变化
+type simSatellite struct {
+	talker string // GP或GB
+	system int    // GSA中的系统标识符：1=GPS 4=北斗
+	id     int
+	elev0  float64
+	az0    float64
+	phase  float64
+	cn0    float64 // 仰角之外的载噪比偏差
+}
+
+// simSystems 模拟的卫星系统，顺序与GSA/GSV输出顺序一致
+var simSystems = []struct {
+	talker string
+	system int
+}{
+	{"GP", 1},
+	{"GB", 4},
+}
+
+// SimPort 内置的GNSS模拟接收机。按SimConfig生成NMEA语句，并像真实接收机一样
+// 应答CFG-MSG命令和基准站配置语句，供SerialPort包装后交给LCX6XZ走与硬件相同的解析流程。
+type SimPort struct {
+	chunkSource
+	cfg     SimConfig
+	rng     *rand.Rand
+	sats    []simSatellite
+	start   time.Time
+	mutex   sync.Mutex
+	rates   map[NMEA_SUB_ID]uint8
+	written []byte // 尚未凑成完整帧的命令字节
+
+	base       BaseModeConfig // PQTMCFGSVIN设置的基准站模式
+	surveyFrom time.Duration  // 开始勘测的时刻，-1表示从下一个历元开始
+}
+
+// NewSimPort 创建模拟接收机并开始输出
+func NewSimPort(cfg SimConfig) (*SimPort, error) {
+	s, err := newSimPort(cfg)
+	if err != nil {
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+// newSimPort 创建模拟接收机但不启动输出
+func newSimPort(cfg SimConfig) (*SimPort, error) {
+	if cfg.Route == nil {
+		return nil, errors.New("模拟接收机缺少路线")
+	}
+	if cfg.UpdateRate <= 0 {
+		return nil, fmt.Errorf("无效的定位频率: %v", cfg.UpdateRate)
+	}
+	if cfg.Speed < 0 || cfg.Noise < 0 {
+		return nil, errors.New("模拟速度和噪声不能为负数")
+	}
+	if cfg.Satellites <= 0 {
+		cfg.Satellites = 12
+	}
+	if cfg.Satellites > 64 {
+		return nil, fmt.Errorf("模拟卫星数不能超过64: %d", cfg.Satellites)
+	}
+
+	s := &SimPort{
+		chunkSource: newChunkSource(),
+		cfg:         cfg,
+		rng:         rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		start:       time.Now().UTC(),
+		rates: map[NMEA_SUB_ID]uint8{
+			NMEA_GGA_SID: 1,
+			NMEA_GSA_SID: 1,
+			NMEA_GSV_SID: 1,
+			NMEA_RMC_SID: 1,
+			NMEA_VTG_SID: 1,
+		},
+		base: BaseModeConfig{Mode: BaseModeDisabled},
+	}
+	s.sats = s.newConstellation(cfg.Satellites)
+	return s, nil
+}
+
+// newConstellation 生成分布在天空中的卫星
+func (s *SimPort) newConstellation(n int) []simSatellite {
+	sats := make([]simSatellite, 0, n)
+	for i := 0; i < n; i++ {
+		sys := simSystems[i%len(simSystems)]
+		sats = append(sats, simSatellite{
+			talker: sys.talker,
+			system: sys.system,
+			id:     i/len(simSystems) + 1,
+			elev0:  10 + s.rng.Float64()*70,
+			az0:    float64(i) * 360 / float64(n),
+			phase:  s.rng.Float64() * 2 * math.Pi,
+			cn0:    s.rng.Float64()*6 - 3,
+		})
+	}
+	return sats
+}
+
+func (s *SimPort) run() {
+	interval := time.Duration(float64(time.Second) / s.cfg.UpdateRate)
+	for epoch := 0; ; epoch++ {
+		if err := s.push(s.epoch(epoch, interval)); err != nil {
+			return
+		}
+		if err := s.sleep(interval); err != nil {
+			return
+		}
+	}
+}
+
+// simFix 一个历元的模拟定位结果
+type simFix struct {
+	time    time.Time
+	valid   bool
+	pos     LatLon
+	course  float64
+	speed   float64 // 米/秒
+	cn0     []int   // 每颗卫星的载噪比，失锁为-1
+	elev    []int
+	az      []int
+	used    []bool
+	numUsed int
+	hdop    float64
+}
+
+// epoch 生成第n个历元的全部输出语句
+func (s *SimPort) epoch(n int, interval time.Duration) []byte {
+	elapsed := time.Duration(n) * interval
+	fix := s.solve(elapsed)
+
+	s.mutex.Lock()
+	rates := make(map[NMEA_SUB_ID]uint8, len(s.rates))
+	for sid, rate := range s.rates {
+		rates[sid] = rate
+	}
+	base := s.base
+	if base.Mode == BaseModeSurveyIn && s.surveyFrom < 0 {
+		s.surveyFrom = elapsed
+	}
+	surveyed := elapsed - s.surveyFrom
+	s.mutex.Unlock()
+
+	var out strings.Builder
+	emit := func(sid NMEA_SUB_ID, bodies ...string) {
+		if rate := rates[sid]; rate == 0 || n%int(rate) != 0 {
+			return
+		}
+		for _, body := range bodies {
+			out.WriteString(BuildNMEASentence(body))
+		}
+	}
+
+	emit(NMEA_RMC_SID, s.rmc(fix))
+	emit(NMEA_VTG_SID, s.vtg(fix))
+	emit(NMEA_GGA_SID, s.gga(fix))
+	emit(NMEA_GSA_SID, s.gsa(fix)...)
+	emit(NMEA_GSV_SID, s.gsv(fix)...)
+	emit(NMEA_GLL_SID, s.gll(fix))
+	emit(NMEA_ZDA_SID, s.zda(fix))
+	emit(NMEA_GST_SID, s.gst(fix))
+	out.WriteString(s.baseOutput(fix, base, surveyed))
+	return []byte(out.String())
+}
+
+// baseOutput 基准站模式下的勘测状态语句和RTCM3电文。勘测时位置为路线起点，
+// 精度随历元数提高，满足时长和精度门限后开始输出电文。
+func (s *SimPort) baseOutput(fix simFix, base BaseModeConfig, surveyed time.Duration) string {
+	var x, y, z float64
+	var out strings.Builder
+	switch base.Mode {
+	case BaseModeFixed:
+		x, y, z = base.X, base.Y, base.Z
+	case BaseModeSurveyIn:
+		start, _ := s.cfg.Route.At(0)
+		x, y, z = geodeticToECEF(start, s.cfg.Altitude+simGeoidSeparation)
+		observations := int(surveyed.Seconds())
+		accuracy := simSurveyAccuracy / math.Sqrt(float64(observations+1))
+		valid := 1
+		if observations >= base.MinDuration && (base.AccuracyLimit == 0 || accuracy <= base.AccuracyLimit) {
+			valid = 2
+		}
+		tow := (int(fix.time.Weekday())*86400+fix.time.Hour()*3600+fix.time.Minute()*60+fix.time.Second())*1000 +
+			fix.time.Nanosecond()/1e6
+		out.WriteString(BuildNMEASentence(fmt.Sprintf("PQTMSVINSTATUS,%d,%d,,,%d,%d,%.4f,%.4f,%.4f,%.4f",
+			tow, valid, observations, base.MinDuration, x, y, z, accuracy)))
+		if valid != 2 {
+			return out.String()
+		}
+	default:
+		return ""
+	}
+	if !fix.valid {
+		return out.String()
+	}
+
+	out.Write(EncodeRTCMFrame(encodeStationARP(simStationID, x, y, z)))
+	for _, msgType := range []int{1077, 1127} {
+		// MSM电文只模拟帧头，观测值为0
+		payload := make([]byte, 40)
+		putRTCMBits(payload, 0, 12, uint64(msgType))
+		putRTCMBits(payload, 12, 12, simStationID)
+		out.Write(EncodeRTCMFrame(payload))
+	}
+	return out.String()
+}
+
+// solve 计算经过elapsed时间后的位置和卫星状态
+func (s *SimPort) solve(elapsed time.Duration) simFix {
+	fix := simFix{time: s.start.Add(elapsed)}
+
+	jammed := inWindow(elapsed, s.cfg.JamEvery, s.cfg.JamDuration)
+	minutes := elapsed.Minutes()
+	for _, sat := range s.sats {
+		elev := sat.elev0 + 8*math.Sin(sat.phase+minutes/30)
+		az := math.Mod(sat.az0+minutes*0.25, 360)
+		cn0 := 22 + 0.3*elev + sat.cn0 + s.rng.NormFloat64()*1.5
+		if jammed {
+			cn0 -= simJamAttenuation
+		}
+
+		tracked := cn0 >= simTrackCN0
+		used := tracked && elev >= simElevationMask
+		if !tracked {
+			cn0 = -1
+		}
+		fix.elev = append(fix.elev, int(math.Round(elev)))
+		fix.az = append(fix.az, int(math.Round(az))%360)
+		fix.cn0 = append(fix.cn0, int(math.Min(99, math.Round(cn0))))
+		fix.used = append(fix.used, used)
+		if used {
+			fix.numUsed++
+		}
+	}
+
+	dropout := inWindow(elapsed, s.cfg.DropoutEvery, s.cfg.DropoutDuration)
+	fix.valid = !dropout && fix.numUsed >= 4
+	if !fix.valid {
+		return fix
+	}
+
+	pos, course := s.cfg.Route.At(s.cfg.Speed * elapsed.Seconds())
+	if s.cfg.Noise > 0 {
+		pos = offsetMeters(pos, s.rng.NormFloat64()*s.cfg.Noise, s.rng.NormFloat64()*s.cfg.Noise)
+	}
+	fix.pos = pos
+	fix.course = course
+	fix.speed = s.cfg.Speed
+	fix.hdop = math.Min(99.99, 0.6+4/float64(fix.numUsed))
+	return fix
+}
+
+// inWindow 判断elapsed是否处于周期性窗口内，窗口位于每个周期的末尾
+func inWindow(elapsed, every, duration time.Duration) bool {
+	if every <= 0 || duration <= 0 {
+		return false
+	}
+	return elapsed%every >= every-duration
+}
+
+func (s *SimPort) rmc(fix simFix) string {
+	utc, date := fix.time.Format("150405.000"), fix.time.Format("020106")
+	if !fix.valid {
+		return fmt.Sprintf("GNRMC,%s,V,,,,,,,%s,,,N,V", utc, date)
+	}
+	return fmt.Sprintf("GNRMC,%s,A,%s,%s,%.2f,%06.2f,%s,,,A,V",
+		utc, formatNMEALat(fix.pos.Lat), formatNMEALon(fix.pos.Lon), fix.speed*knotsPerMps, fix.course, date)
+}
+
+func (s *SimPort) vtg(fix simFix) string {
+	if !fix.valid {
+		return "GNVTG,,T,,M,,N,,K,N"
+	}
+	return fmt.Sprintf("GNVTG,%06.2f,T,,M,%s,N,%s,K,A",
+		fix.course, formatVTGSpeed(fix.speed*knotsPerMps), formatVTGSpeed(fix.speed*3.6))
+}
+
+func (s *SimPort) gga(fix simFix) string {
+	utc := fix.time.Format("150405.000")
+	if !fix.valid {
+		return fmt.Sprintf("GNGGA,%s,,,,,0,00,99.99,,M,,M,,", utc)
+	}
+	return fmt.Sprintf("GNGGA,%s,%s,%s,1,%02d,%.2f,%.1f,M,%.1f,M,,",
+		utc, formatNMEALat(fix.pos.Lat), formatNMEALon(fix.pos.Lon),
+		min(fix.numUsed, 99), fix.hdop, s.cfg.Altitude, simGeoidSeparation)
+}
+
+func (s *SimPort) gll(fix simFix) string {
+	utc := fix.time.Format("150405.000")
+	if !fix.valid {
+		return fmt.Sprintf("GNGLL,,,,,%s,V,N", utc)
+	}
+	return fmt.Sprintf("GNGLL,%s,%s,A,A", formatNMEALatLon(fix.pos), utc)
+}
+
+func (s *SimPort) zda(fix simFix) string {
+	return fmt.Sprintf("GNZDA,%s,%s,,", fix.time.Format("150405.000"), fix.time.Format("02,01,2006"))
+}
+
+// gsa 每个卫星系统输出一条GSA
+func (s *SimPort) gsa(fix simFix) []string {
+	var bodies []string
+	for _, sys := range simSystems {
+		fields := []string{"GNGSA", "A", "1"}
+		ids := make([]string, 0, simMaxUsed)
+		if fix.valid {
+			fields[2] = "3"
+			for i, sat := range s.sats {
+				if sat.system == sys.system && fix.used[i] && len(ids) < simMaxUsed {
+					ids = append(ids, fmt.Sprintf("%02d", sat.id))
+				}
+			}
+		}
+		for len(ids) < simMaxUsed {
+			ids = append(ids, "")
+		}
+		fields = append(fields, ids...)
+
+		pdop, hdop, vdop := "99.99", "99.99", "99.99"
+		if fix.valid {
+			pdop = fmt.Sprintf("%.2f", math.Min(99.99, 1.6*fix.hdop))
+			hdop = fmt.Sprintf("%.2f", fix.hdop)
+			vdop = fmt.Sprintf("%.2f", math.Min(99.99, 1.3*fix.hdop))
+		}
+		fields = append(fields, pdop, hdop, vdop, fmt.Sprint(sys.system))
+		bodies = append(bodies, strings.Join(fields, ","))
+	}
+	return bodies
+}
+
+// gsv 每个卫星系统按每条4颗卫星分组输出GSV
+func (s *SimPort) gsv(fix simFix) []string {
+	var bodies []string
+	for _, sys := range simSystems {
+		var idx []int
+		for i, sat := range s.sats {
+			if sat.talker == sys.talker && fix.elev[i] > 0 {
+				idx = append(idx, i)
+			}
+		}
+		total := (len(idx) + 3) / 4
+		for n := 0; n < total; n++ {
+			fields := []string{sys.talker + "GSV", fmt.Sprint(total), fmt.Sprint(n + 1), fmt.Sprintf("%02d", len(idx))}
+			for _, i := range idx[n*4 : min(len(idx), n*4+4)] {
+				cn0 := ""
+				if fix.cn0[i] >= 0 {
+					cn0 = fmt.Sprintf("%02d", fix.cn0[i])
+				}
+				fields = append(fields, fmt.Sprintf("%02d", s.sats[i].id),
+					fmt.Sprintf("%02d", fix.elev[i]), fmt.Sprintf("%03d", fix.az[i]), cn0)
+			}
+			fields = append(fields, "1")
+			bodies = append(bodies, strings.Join(fields, ","))
+		}
+	}
+	return bodies
+}
+
+// gst 伪距误差统计，误差按HDOP和UERE估算
+func (s *SimPort) gst(fix simFix) string {
+	utc := fix.time.Format("150405.000")
+	if !fix.valid {
+		return fmt.Sprintf("GNGST,%s,,,,,,,", utc)
+	}
+	sigma := math.Max(s.cfg.Noise, fix.hdop*simUERE/math.Sqrt2)
+	return fmt.Sprintf("GNGST,%s,%.1f,%.1f,%.1f,%.1f,%.1f,%.1f,%.1f",
+		utc, simUERE, sigma, sigma, 0.0, sigma, sigma, 1.6*sigma)
+}
+
+// formatNMEALat 把十进制纬度格式化为ddmm.mmmmmm和半球标识
+func formatNMEALat(lat float64) string {
+	hemi := "N"
+	if lat < 0 {
+		hemi = "S"
+	}
+	deg, minutes := splitDegrees(math.Abs(lat))
+	return fmt.Sprintf("%02d%09.6f,%s", deg, minutes, hemi)
+}
+
+// formatNMEALon 把十进制经度格式化为dddmm.mmmmmm和半球标识
+func formatNMEALon(lon float64) string {
+	hemi := "E"
+	if lon < 0 {
+		hemi = "W"
+	}
+	deg, minutes := splitDegrees(math.Abs(lon))
+	return fmt.Sprintf("%03d%09.6f,%s", deg, minutes, hemi)
+}
+
+func formatNMEALatLon(p LatLon) string {
+	return formatNMEALat(p.Lat) + "," + formatNMEALon(p.Lon)
+}
+
+// splitDegrees 拆分为整度数和分，先按输出精度取整，避免出现60分
+func splitDegrees(deg float64) (int, float64) {
+	micro := int64(math.Round(deg * 60e6))
+	return int(micro / 60e6), float64(micro%60e6) / 1e6
+}
+
+// formatVTGSpeed VTG速度字段最多4个字符
+func formatVTGSpeed(v float64) string {
+	if v < 100 {
+		return fmt.Sprintf("%.1f", v)
+	}
+	return fmt.Sprintf("%.0f", math.Min(v, 9999))
+}
+
+// Write 接收驱动下发的二进制命令和专有语句，并把应答放入输出流
+func (s *SimPort) Write(p []byte) (int, error) {
+	if s.isClosed() {
+		return 0, errors.New("模拟接收机已关闭")
+	}
+
+	s.mutex.Lock()
+	s.written = append(s.written, p...)
+	var replies [][]byte
+	for {
+		cmd, rest, ok := nextSimCommand(s.written)
+		s.written = rest
+		if !ok {
+			break
+		}
+		var reply []byte
+		if cmd[0] == '$' {
+			reply = s.respondText(string(cmd))
+		} else {
+			reply = s.respond(cmd)
+		}
+		if reply != nil {
+			replies = append(replies, reply)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, reply := range replies {
+		if err := s.push(reply); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// respond 生成命令的应答帧，调用方需持有mutex
+func (s *SimPort) respond(frame []byte) []byte {
+	gid, sid := frame[2], frame[3]
+	payload := frame[6 : len(frame)-2]
+	ack := []byte{gid, sid}
+
+	if GroupID(gid) != BIN_CFG_GID {
+		return BuildBinaryFrame(BIN_RES_GID, uint8(BM_NAK_SID), ack)
+	}
+	if BIN_CFG_SID(sid) != BM_MSG_SID {
+		return BuildBinaryFrame(BIN_RES_GID, uint8(BM_ACK_SID), ack)
+	}
+
+	switch {
+	case len(payload) == 2 && GroupID(payload[0]) == NMEA_GID:
+		rate := s.rates[NMEA_SUB_ID(payload[1])]
+		return BuildBinaryFrame(BIN_CFG_GID, uint8(BM_MSG_SID), []byte{payload[0], payload[1], rate})
+	case len(payload) == 3 && GroupID(payload[0]) == NMEA_GID:
+		s.rates[NMEA_SUB_ID(payload[1])] = payload[2]
+		return BuildBinaryFrame(BIN_RES_GID, uint8(BM_ACK_SID), ack)
+	}
+	return BuildBinaryFrame(BIN_RES_GID, uint8(BM_NAK_SID), ack)
+}
+
+// respondText 生成专有语句命令的应答，调用方需持有mutex
+func (s *SimPort) respondText(cmd string) []byte {
+	cmd = strings.TrimSpace(cmd)
+	if !ValidateNMEAChecksum(cmd, len(cmd)) {
+		return nil
+	}
+	body, _, _ := strings.Cut(cmd[1:], "*")
+	fields := strings.Split(body, ",")
+
+	reply := fields[0] + ",ERROR,1"
+	switch {
+	case fields[0] == "PQTMCFGSVIN" && len(fields) == 2 && fields[1] == "R":
+		code := 0
+		for i, mode := range baseModeCodes {
+			if mode == s.base.Mode {
+				code = i
+			}
+		}
+		reply = fmt.Sprintf("PQTMCFGSVIN,OK,%d,%d,%.4f,%.4f,%.4f,%.4f",
+			code, s.base.MinDuration, s.base.AccuracyLimit, s.base.X, s.base.Y, s.base.Z)
+	case fields[0] == "PQTMCFGSVIN" && len(fields) == 8 && fields[1] == "W":
+		code, err := strconv.Atoi(fields[2])
+		if err != nil || code < 0 || code >= len(baseModeCodes) {
+			reply = "PQTMCFGSVIN,ERROR,2"
+			break
+		}
+		base := BaseModeConfig{Mode: baseModeCodes[code]}
+		base.MinDuration, _ = strconv.Atoi(fields[3])
+		base.AccuracyLimit, _ = strconv.ParseFloat(fields[4], 64)
+		base.X, _ = strconv.ParseFloat(fields[5], 64)
+		base.Y, _ = strconv.ParseFloat(fields[6], 64)
+		base.Z, _ = strconv.ParseFloat(fields[7], 64)
+		s.base, s.surveyFrom = base, -1
+		reply = "PQTMCFGSVIN,OK"
+	case fields[0] == "PQTMSAVEPAR":
+		reply = "PQTMSAVEPAR,OK"
+	}
+	return []byte(BuildNMEASentence(reply))
+}
+
+// nextSimCommand 从data中取出第一条命令（二进制帧或以$开头、\r\n结尾的专有语句），返回剩余数据。
+// 语句中间出现二进制帧时丢弃该语句，避免差分数据中的'$'阻塞后续命令。
+func nextSimCommand(data []byte) ([]byte, []byte, bool) {
+	text := bytes.IndexByte(data, '$')
+	frame := bytes.Index(data, []byte{0xF1, 0xD9})
+	if text >= 0 && (frame < 0 || text < frame) {
+		if end := bytes.Index(data[text:], []byte("\r\n")); end >= 0 && (frame < 0 || text+end < frame) {
+			end += text + 2
+			return append([]byte(nil), data[text:end]...), data[end:], true
+		}
+		if frame < 0 {
+			if len(data)-text > simMaxCommand {
+				return nil, nil, false
+			}
+			return nil, data[text:], false
+		}
+	}
+	return nextBinaryFrame(data)
+}
+
+// nextBinaryFrame 从data中取出第一个校验正确的二进制帧，返回剩余数据
+func nextBinaryFrame(data []byte) ([]byte, []byte, bool) {
+	for {
+		start := -1
+		for i := 0; i+1 < len(data); i++ {
+			if data[i] == 0xF1 && data[i+1] == 0xD9 {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return nil, nil, false
+		}
+		data = data[start:]
+		if len(data) < 8 {
+			return nil, data, false
+		}
+		total := 8 + int(binary.LittleEndian.Uint16(data[4:6]))
+		if len(data) < total {
+			return nil, data, false
+		}
+		frame := data[:total]
+		if QlCheckQuectel(frame[:total-2]) == binary.LittleEndian.Uint16(frame[total-2:]) {
+			return append([]byte(nil), frame...), data[total:], true
+		}
+		data = data[2:]
+	}
+}
@@ -0,0 +1,184 @@
+package driver
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startSim 启动模拟接收机，并通过与硬件相同的解析流程连接它
+func startSim(t *testing.T, cfg SimConfig) *LCX6XZ {
+	t.Helper()
+
+	if cfg.Route == nil {
+		route, err := CircleRoute(LatLon{30.739479, 103.959134}, 200)
+		if err != nil {
+			t.Fatalf("CircleRoute failed: %v", err)
+		}
+		cfg.Route = route
+	}
+	if cfg.UpdateRate == 0 {
+		cfg.UpdateRate = 50
+	}
+	sim, err := NewSimPort(cfg)
+	if err != nil {
+		t.Fatalf("NewSimPort failed: %v", err)
+	}
+	lcx6xz := NewLCX6XZ(NewSerialPortFrom(sim))
+	t.Cleanup(func() { _ = lcx6xz.Close() })
+	return lcx6xz
+}
+
+func TestFormatNMEACoord(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		expected string
+	}{
+		{30.739479, 103.959134, "3044.368740,N,10357.548040,E"},
+		{-33.5, -70.25, "3330.000000,S,07015.000000,W"},
+		{0.99999999999, 0, "0100.000000,N,00000.000000,E"},
+	}
+
+	for _, test := range tests {
+		if got := formatNMEALatLon(LatLon{test.lat, test.lon}); got != test.expected {
+			t.Errorf("formatNMEALatLon(%v, %v) = %s, expected %s", test.lat, test.lon, got, test.expected)
+		}
+	}
+}
+
+func TestSimDrivesParser(t *testing.T) {
+	center := LatLon{30.739479, 103.959134}
+	lcx6xz := startSim(t, SimConfig{Speed: 20, Altitude: 123.4})
+
+	waitFor(t, 2*time.Second, func() bool {
+		lcx6xz.mutex.Lock()
+		defer lcx6xz.mutex.Unlock()
+		return lcx6xz.NMEA_RMC != nil && lcx6xz.NMEA_GGA != nil && lcx6xz.NMEA_GSA != nil &&
+			lcx6xz.NMEA_GSV != nil && lcx6xz.NMEA_VTG != nil
+	}, "模拟语句解析")
+
+	lcx6xz.mutex.Lock()
+	rmc, gga, vtg := *lcx6xz.NMEA_RMC, *lcx6xz.NMEA_GGA, *lcx6xz.NMEA_VTG
+	lcx6xz.mutex.Unlock()
+
+	if status := trimNullBytes(rmc.Status[:]); status != "A" {
+		t.Errorf("RMC status = %s, expected A", status)
+	}
+	lat, _ := parseNMEACoordinate(trimNullBytes(rmc.Lat[:]), trimNullBytes(rmc.N_S[:]))
+	lon, _ := parseNMEACoordinate(trimNullBytes(rmc.Lon[:]), trimNullBytes(rmc.E_W[:]))
+	pos := LatLon{Lat: lat, Lon: lon}
+	if r := haversine(center, pos); math.Abs(r-200) > 1 {
+		t.Errorf("simulated position is %.1fm from center, expected 200m", r)
+	}
+	if alt := trimNullBytes(gga.Alt[:]); alt != "123.4" {
+		t.Errorf("GGA altitude = %s, expected 123.4", alt)
+	}
+	if quality := trimNullBytes(gga.Quality[:]); quality != "1" {
+		t.Errorf("GGA quality = %s, expected 1", quality)
+	}
+	if sogk := trimNullBytes(vtg.SOGK[:]); sogk != "72.0" {
+		t.Errorf("VTG speed = %s km/h, expected 72.0", sogk)
+	}
+}
+
+func TestSimDropout(t *testing.T) {
+	sim, err := newSimPort(SimConfig{
+		Route:           mustCircle(t),
+		UpdateRate:      1,
+		DropoutEvery:    10 * time.Second,
+		DropoutDuration: 3 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("newSimPort failed: %v", err)
+	}
+	defer sim.Close()
+
+	for n, valid := range map[int]bool{0: true, 6: true, 7: false, 9: false, 10: true} {
+		fix := sim.solve(time.Duration(n) * time.Second)
+		if fix.valid != valid {
+			t.Errorf("epoch %d valid = %v, expected %v", n, fix.valid, valid)
+		}
+		if rmc := sim.rmc(fix); strings.Contains(rmc, ",A,") != valid {
+			t.Errorf("epoch %d RMC = %s", n, rmc)
+		}
+	}
+}
+
+func TestSimJamming(t *testing.T) {
+	sim, err := newSimPort(SimConfig{
+		Route:       mustCircle(t),
+		UpdateRate:  1,
+		JamEvery:    time.Minute,
+		JamDuration: 30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("newSimPort failed: %v", err)
+	}
+	defer sim.Close()
+
+	clear := sim.solve(0)
+	jammed := sim.solve(45 * time.Second)
+	if !clear.valid || clear.numUsed < 4 {
+		t.Fatalf("clear sky: valid = %v, used = %d", clear.valid, clear.numUsed)
+	}
+	if jammed.numUsed >= clear.numUsed {
+		t.Errorf("jammed used = %d, expected fewer than %d", jammed.numUsed, clear.numUsed)
+	}
+	if jammed.valid != (jammed.numUsed >= 4) {
+		t.Errorf("jammed valid = %v with %d satellites used", jammed.valid, jammed.numUsed)
+	}
+}
+
+func TestSimOutputRate(t *testing.T) {
+	lcx6xz := startSim(t, SimConfig{})
+
+	if err := SetNMEAOutputRate(lcx6xz, NMEA_GLL_SID, 1); err != nil {
+		t.Fatalf("SetNMEAOutputRate failed: %v", err)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		lcx6xz.mutex.Lock()
+		defer lcx6xz.mutex.Unlock()
+		return lcx6xz.NMEA_GLL != nil
+	}, "开启后GLL解析")
+
+	if err := GetNMEAOutputRate(lcx6xz, NMEA_GLL_SID); err != nil {
+		t.Fatalf("GetNMEAOutputRate failed: %v", err)
+	}
+	lcx6xz.mutex.Lock()
+	rate := lcx6xz.OutputRates[NMEA_GLL_SID]
+	lcx6xz.mutex.Unlock()
+	if rate != 1 {
+		t.Errorf("OutputRates[GLL] = %d, expected 1", rate)
+	}
+}
+
+func TestSimDeterministic(t *testing.T) {
+	cfg := SimConfig{Route: mustCircle(t), Speed: 5, Noise: 3, UpdateRate: 1, Seed: 42}
+	a, err := newSimPort(cfg)
+	if err != nil {
+		t.Fatalf("newSimPort failed: %v", err)
+	}
+	defer a.Close()
+	b, err := newSimPort(cfg)
+	if err != nil {
+		t.Fatalf("newSimPort failed: %v", err)
+	}
+	defer b.Close()
+
+	for n := 0; n < 5; n++ {
+		elapsed := time.Duration(n) * time.Second
+		if fa, fb := a.solve(elapsed), b.solve(elapsed); fa.pos != fb.pos {
+			t.Errorf("epoch %d: positions differ: %v vs %v", n, fa.pos, fb.pos)
+		}
+	}
+}
+
+func mustCircle(t *testing.T) *Route {
+	t.Helper()
+	route, err := CircleRoute(LatLon{30.739479, 103.959134}, 200)
+	if err != nil {
+		t.Fatalf("CircleRoute failed: %v", err)
+	}
+	return route
+}
@@ -0,0 +1,270 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/spf13/cast"
+)
+
+// 授时锁定状态
+const (
+	TimeUnlocked = "unlocked" // 没有最近的时间样本
+	TimeLockNMEA = "nmea"     // 只有NMEA报文时间，精度受输出延迟影响，约数十到数百毫秒
+	TimeLockPPS  = "pps"      // 秒脉冲锁定，精度约微秒
+)
+
+const (
+	// timeLockTimeout 时间样本超过该时长未更新视为失锁
+	timeLockTimeout = 3 * time.Second
+	// ppsGuard 由NMEA时间推算的秒脉冲时刻离整秒不足该值时无法确定是哪一秒，丢弃该脉冲
+	ppsGuard = 20 * time.Millisecond
+	// ppsFetchTimeout 每次等待秒脉冲的时长，超时后检查接收机是否已关闭
+	ppsFetchTimeout = 2 * time.Second
+
+	nmeaPrecision = -1  // 约0.5秒
+	ppsPrecision  = -20 // 约1微秒
+)
+
+// 授时指标名称，注册时加上设备名后缀
+const (
+	timeOffsetMetricName = "GpsTimeOffset"
+	timeLockMetricName   = "GpsTimeLock"
+)
+
+// errPPSTimeout 等待秒脉冲超时
+var errPPSTimeout = errors.New("等待PPS秒脉冲超时")
+
+// PPSSource 秒脉冲来源
+type PPSSource interface {
+	// Fetch 等待下一个秒脉冲上升沿，返回上升沿时刻的本机时间，超时返回errPPSTimeout
+	Fetch(timeout time.Duration) (time.Time, error)
+	Close() error
+}
+
+// TimeSink 时间样本的接收方，通常是NTP共享内存单元
+type TimeSink interface {
+	Put(sample TimeSample)
+}
+
+// TimeService 把GNSS时间提供给chronyd或ntpd：有效定位的UTC时间写入NMEA单元，
+// 秒脉冲上升沿写入PPS单元（NMEA单元号+1）。可并发使用。
+type TimeService struct {
+	mutex      sync.Mutex
+	nmea       TimeSink
+	pps        TimeSink      // 未配置PPS设备时为nil
+	nmeaOffset time.Duration // 本机时间减GNSS时间，含接收机的NMEA输出延迟
+	ppsOffset  time.Duration // 本机时间减GNSS时间，由秒脉冲测得
+	lastNMEA   time.Time     // 最近一次NMEA样本的本机时间
+	lastPPS    time.Time     // 最近一次PPS样本的本机时间
+}
+
+// NewTimeService 创建授时服务，pps为nil表示不使用秒脉冲
+func NewTimeService(nmea, pps TimeSink) *TimeService {
+	return &TimeService{nmea: nmea, pps: pps}
+}
+
+// Fix 用定位的UTC时间写入NMEA样本，只接受RMC状态为A且带时间的定位。
+// 样本的本机时间取历元结束的时刻，因此偏差中包含接收机的输出延迟，应在chronyd中用offset抵消。
+func (t *TimeService) Fix(fix Fix) bool {
+	if !fix.HasTime || fix.Status != "A" || fix.Received.IsZero() {
+		return false
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.nmea.Put(TimeSample{Clock: fix.Time, Receive: fix.Received, Precision: nmeaPrecision})
+	t.nmeaOffset = fix.Received.Sub(fix.Time)
+	t.lastNMEA = fix.Received
+	return true
+}
+
+// PPSEdge 处理秒脉冲上升沿。上升沿对应的GNSS整秒由最近的NMEA偏差推算：NMEA报文总在该秒开始之后输出，
+// 所以上升沿时刻减去NMEA偏差后向上取整即为该秒。没有最近的NMEA样本或无法确定是哪一秒时丢弃。
+func (t *TimeService) PPSEdge(edge time.Time) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.pps == nil || t.lastNMEA.IsZero() || edge.Sub(t.lastNMEA).Abs() > timeLockTimeout {
+		return false
+	}
+	estimate := edge.Add(-t.nmeaOffset)
+	second := estimate.Truncate(time.Second).Add(time.Second)
+	if latency := second.Sub(estimate); latency < ppsGuard || latency > time.Second-ppsGuard {
+		return false
+	}
+
+	t.pps.Put(TimeSample{Clock: second, Receive: edge, Precision: ppsPrecision})
+	t.ppsOffset = edge.Sub(second)
+	t.lastPPS = edge
+	return true
+}
+
+// Status 返回当前的锁定状态和本机时间相对GNSS时间的偏差（正值表示本机时间超前），
+// 秒脉冲锁定时使用秒脉冲测得的偏差。失锁时ok为false。
+func (t *TimeService) Status(now time.Time) (lock string, offset time.Duration, ok bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch {
+	case !t.lastPPS.IsZero() && now.Sub(t.lastPPS) <= timeLockTimeout:
+		return TimeLockPPS, t.ppsOffset, true
+	case !t.lastNMEA.IsZero() && now.Sub(t.lastNMEA) <= timeLockTimeout:
+		return TimeLockNMEA, t.nmeaOffset, true
+	}
+	return TimeUnlocked, 0, false
+}
+
+// Metrics 授时偏差（毫秒）和锁定状态（0失锁，1 NMEA，2 PPS）指标，读取时按当前状态计算
+func (t *TimeService) Metrics() map[string]interface{} {
+	return map[string]interface{}{
+		timeOffsetMetricName: gometrics.NewFunctionalGaugeFloat64(func() float64 {
+			_, offset, _ := t.Status(time.Now())
+			return float64(offset) / float64(time.Millisecond)
+		}),
+		timeLockMetricName: gometrics.NewFunctionalGauge(func() int64 {
+			lock, _, _ := t.Status(time.Now())
+			return map[string]int64{TimeUnlocked: 0, TimeLockNMEA: 1, TimeLockPPS: 2}[lock]
+		}),
+	}
+}
+
+// openTimeService 按设备的ntpShm和ppsDevice协议属性创建授时服务，未设置ntpShm时返回nil
+func (s *Driver) openTimeService(deviceName string, protocols map[string]models.ProtocolProperties) (*TimeService, PPSSource) {
+	var unitValue, ppsPath string
+	for _, protocol := range protocols {
+		if value := cast.ToString(protocol["ntpShm"]); value != "" {
+			unitValue = value
+		}
+		if value := cast.ToString(protocol["ppsDevice"]); value != "" {
+			ppsPath = value
+		}
+	}
+	if unitValue == "" {
+		return nil, nil
+	}
+	unit, err := cast.ToIntE(unitValue)
+	if err != nil || unit < 0 {
+		s.lc.Errorf("设备%s的ntpShm属性无效: %v，不提供授时", deviceName, unitValue)
+		return nil, nil
+	}
+
+	nmea, err := OpenNTPShm(unit)
+	if err != nil {
+		s.lc.Errorf("设备%s: %v，不提供授时", deviceName, err)
+		return nil, nil
+	}
+	if ppsPath == "" {
+		s.lc.Infof("设备%s的定位时间将写入NTP共享内存单元%d", deviceName, unit)
+		return NewTimeService(nmea, nil), nil
+	}
+
+	source, err := OpenPPS(ppsPath)
+	if err != nil {
+		s.lc.Errorf("设备%s: %v，只提供NMEA授时", deviceName, err)
+		return NewTimeService(nmea, nil), nil
+	}
+	pps, err := OpenNTPShm(unit + 1)
+	if err != nil {
+		_ = source.Close()
+		s.lc.Errorf("设备%s: %v，只提供NMEA授时", deviceName, err)
+		return NewTimeService(nmea, nil), nil
+	}
+	s.lc.Infof("设备%s的定位时间和%s的秒脉冲将写入NTP共享内存单元%d和%d", deviceName, ppsPath, unit, unit+1)
+	return NewTimeService(nmea, pps), source
+}
+
+// runPPS 读取秒脉冲并交给授时服务，接收机关闭后关闭PPS设备并退出
+func (s *Driver) runPPS(deviceName string, rcv *receiver, source PPSSource) {
+	defer func() {
+		if err := source.Close(); err != nil {
+			s.lc.Errorf("关闭设备%s的PPS设备失败: %v", deviceName, err)
+		}
+	}()
+
+	for {
+		select {
+		case <-rcv.gps.Done():
+			return
+		default:
+		}
+
+		edge, err := source.Fetch(ppsFetchTimeout)
+		if errors.Is(err, errPPSTimeout) {
+			continue
+		}
+		if err != nil {
+			s.lc.Errorf("设备%s读取秒脉冲失败: %v", deviceName, err)
+			select {
+			case <-rcv.gps.Done():
+				return
+			case <-time.After(ppsFetchTimeout):
+			}
+			continue
+		}
+		rcv.clock.PPSEdge(edge)
+	}
+}
+
+// closeTimeService 注销授时指标并解除共享内存映射
+func (s *Driver) closeTimeService(deviceName string, clock *TimeService) {
+	if clock == nil {
+		return
+	}
+	if metricsManager := s.sdk.MetricsManager(); metricsManager != nil {
+		for name := range clock.Metrics() {
+			metricsManager.Unregister(fmt.Sprintf("%s-%s", name, deviceName))
+		}
+	}
+	for _, sink := range []TimeSink{clock.nmea, clock.pps} {
+		if shm, ok := sink.(*NTPShm); ok {
+			if err := shm.Close(); err != nil {
+				s.lc.Errorf("解除设备%s的NTP共享内存映射失败: %v", deviceName, err)
+			}
+		}
+	}
+}
+
+// registerTimeMetrics 注册授时偏差和锁定状态指标
+func (s *Driver) registerTimeMetrics(deviceName string, clock *TimeService) {
+	metricsManager := s.sdk.MetricsManager()
+	if metricsManager == nil {
+		s.lc.Warn("MetricsManager不可用，授时指标不会上报")
+		return
+	}
+
+	for name, metric := range clock.Metrics() {
+		registeredName := fmt.Sprintf("%s-%s", name, deviceName)
+		if err := metricsManager.Register(registeredName, metric, map[string]string{"device": deviceName}); err != nil {
+			s.lc.Errorf("注册指标%s失败: %v", registeredName, err)
+		}
+	}
+}
+
+// getTimeValue 获取time_offset_ms或time_lock资源，设备未开启授时时返回nil
+func (s *Driver) getTimeValue(clock *TimeService, req dsModels.CommandRequest) *dsModels.CommandValue {
+	if clock == nil {
+		s.lc.Warnf("设备未配置ntpShm，无法读取%s", req.DeviceResourceName)
+		return nil
+	}
+	lock, offset, ok := clock.Status(time.Now())
+
+	var cv *dsModels.CommandValue
+	switch req.DeviceResourceName {
+	case "time_lock":
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, lock)
+	case "time_offset_ms":
+		if !ok {
+			return nil
+		}
+		cv, _ = dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat64, float64(offset)/float64(time.Millisecond))
+	}
+	return cv
+}
@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"golang.org/x/sys/unix"
+)
+
+// testShmUnit 测试使用的NTP共享内存单元，避开chronyd和gpsd常用的0到3号单元
+const testShmUnit = 57
+
+// openTestShm 打开测试用的共享内存单元，测试结束后删除；系统不支持SysV共享内存时跳过测试
+func openTestShm(t *testing.T, unit int) *NTPShm {
+	t.Helper()
+
+	shm, err := OpenNTPShm(unit)
+	if err != nil {
+		t.Skipf("SysV shared memory unavailable: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = shm.Close()
+		if id, err := unix.SysvShmGet(ntpShmKey+unit, ntpShmSize, 0); err == nil {
+			_, _ = unix.SysvShmCtl(id, unix.IPC_RMID, nil)
+		}
+	})
+	return shm
+}
+
+func TestOpenNTPShm(t *testing.T) {
+	writer := openTestShm(t, testShmUnit)
+	reader := openTestShm(t, testShmUnit)
+
+	clock := time.Date(2025, 6, 10, 5, 55, 25, 0, time.UTC)
+	writer.Put(TimeSample{Clock: clock, Receive: clock.Add(time.Millisecond), Precision: -1})
+	if sample, mode, _, valid := readShm(reader.mem); mode != 1 || valid != 1 || !sample.Clock.Equal(clock) {
+		t.Errorf("sample from second attachment = %+v, mode %d, valid %d", sample, mode, valid)
+	}
+}
+
+func TestDriverTimeService(t *testing.T) {
+	reader := openTestShm(t, testShmUnit)
+
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["ntpShm"] = "57"
+	driver, _ := newTestDriverWithConfig(t, map[string]string{},
+		models.Device{Name: "GPS-A", Protocols: protocols},
+		models.Device{Name: "GPS-B", Protocols: simProtocols("40.0")},
+	)
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	waitFor(t, 5*time.Second, func() bool {
+		return readResource(t, driver, "GPS-A", "time_lock").ValueToString() == TimeLockNMEA
+	}, "NMEA time lock")
+	// 模拟接收机的UTC时间取自本机时间，偏差只有历元处理的延迟
+	offset, _ := readResource(t, driver, "GPS-A", "time_offset_ms").Float64Value()
+	if math.Abs(offset) > 500 {
+		t.Errorf("time_offset_ms = %v", offset)
+	}
+	sample, _, _, valid := readShm(reader.mem)
+	if valid != 1 || time.Since(sample.Clock).Abs() > 5*time.Second {
+		t.Errorf("shared memory sample = %+v, valid %d", sample, valid)
+	}
+
+	// 未配置ntpShm的设备没有授时资源
+	readResource(t, driver, "GPS-B", "latitude_deg")
+	res, err := driver.HandleReadCommands("GPS-B", nil, []dsModels.CommandRequest{{DeviceResourceName: "time_lock"}})
+	if err != nil || len(res) != 0 {
+		t.Errorf("time_lock without ntpShm = %v, %v", res, err)
+	}
+}
@@ -0,0 +1,113 @@
+package driver
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// readShm 按chronyd的方式读取共享内存单元中的样本
+func readShm(mem []byte) (sample TimeSample, mode, count, valid int32) {
+	order := binary.NativeEndian
+	i32 := func(offset int) int32 { return int32(order.Uint32(mem[offset:])) }
+	sample.Clock = time.Unix(int64(order.Uint64(mem[shmClockSec:])), int64(order.Uint32(mem[shmClockNSec:])))
+	sample.Receive = time.Unix(int64(order.Uint64(mem[shmReceiveSec:])), int64(order.Uint32(mem[shmReceiveNSec:])))
+	sample.Precision = int(i32(shmPrecision))
+	sample.Leap = int(i32(shmLeap))
+	return sample, i32(shmMode), i32(shmCount), i32(shmValid)
+}
+
+// sinkRecorder 记录写入的时间样本
+type sinkRecorder []TimeSample
+
+func (r *sinkRecorder) Put(sample TimeSample) {
+	*r = append(*r, sample)
+}
+
+func TestNTPShm(t *testing.T) {
+	mem := make([]byte, ntpShmSize)
+	shm := NewNTPShm(mem)
+	clock := time.Date(2025, 6, 10, 5, 55, 25, 0, time.UTC)
+	receive := clock.Add(123456789 * time.Nanosecond)
+	shm.Put(TimeSample{Clock: clock, Receive: receive, Precision: -1})
+
+	sample, mode, count, valid := readShm(mem)
+	if mode != 1 || count != 2 || valid != 1 {
+		t.Errorf("mode = %d, count = %d, valid = %d", mode, count, valid)
+	}
+	if !sample.Clock.Equal(clock) || !sample.Receive.Equal(receive) || sample.Precision != -1 {
+		t.Errorf("sample = %+v", sample)
+	}
+	if usec := binary.NativeEndian.Uint32(mem[shmReceiveUSec:]); usec != 123456 {
+		t.Errorf("receive usec = %d, expected 123456", usec)
+	}
+
+	// 关闭后不再写入
+	_ = shm.Close()
+	shm.Put(TimeSample{Clock: clock.Add(time.Second), Receive: receive.Add(time.Second)})
+	if _, _, count, _ := readShm(mem); count != 2 {
+		t.Errorf("count after Close = %d, expected 2", count)
+	}
+}
+
+func TestTimeService(t *testing.T) {
+	var nmea, pps sinkRecorder
+	service := NewTimeService(&nmea, &pps)
+	start := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	if lock, _, ok := service.Status(start); ok || lock != TimeUnlocked {
+		t.Errorf("initial Status() = %s, %v", lock, ok)
+	}
+
+	// 只接受RMC状态为A的定位
+	fix := trackFix(0, 0)
+	fix.Time, fix.Received, fix.Status = start, start.Add(350*time.Millisecond), "V"
+	if service.Fix(fix) || len(nmea) != 0 {
+		t.Error("Fix() accepted a void fix")
+	}
+	fix.Status = "A"
+	if !service.Fix(fix) || len(nmea) != 1 || nmea[0].Precision != nmeaPrecision {
+		t.Fatalf("Fix() samples = %+v", nmea)
+	}
+	if lock, offset, _ := service.Status(fix.Received); lock != TimeLockNMEA || offset != 350*time.Millisecond {
+		t.Errorf("NMEA Status() = %s, %v", lock, offset)
+	}
+
+	// 本机时间超前2ms时的秒脉冲
+	edge := start.Add(time.Second + 2*time.Millisecond)
+	if !service.PPSEdge(edge) || len(pps) != 1 {
+		t.Fatalf("PPSEdge() samples = %+v", pps)
+	}
+	if !pps[0].Clock.Equal(start.Add(time.Second)) || !pps[0].Receive.Equal(edge) || pps[0].Precision != ppsPrecision {
+		t.Errorf("PPS sample = %+v", pps[0])
+	}
+	if lock, offset, _ := service.Status(edge); lock != TimeLockPPS || offset != 2*time.Millisecond {
+		t.Errorf("PPS Status() = %s, %v", lock, offset)
+	}
+
+	// 推算时刻紧挨整秒时无法确定是哪一秒，NMEA样本过旧时无法推算
+	for _, edge := range []time.Time{
+		start.Add(1355 * time.Millisecond),
+		start.Add(10*time.Second + 2*time.Millisecond),
+	} {
+		if service.PPSEdge(edge) {
+			t.Errorf("PPSEdge(%v) accepted", edge)
+		}
+	}
+
+	// 秒脉冲中断后回落到NMEA，全部中断后失锁
+	fix.Time, fix.Received = start.Add(4*time.Second), start.Add(4350*time.Millisecond)
+	service.Fix(fix)
+	if lock, _, _ := service.Status(fix.Received); lock != TimeLockNMEA {
+		t.Errorf("Status() after PPS loss = %s", lock)
+	}
+	if lock, _, ok := service.Status(fix.Received.Add(timeLockTimeout + time.Millisecond)); ok || lock != TimeUnlocked {
+		t.Errorf("Status() after NMEA loss = %s, %v", lock, ok)
+	}
+
+	// 没有PPS单元时忽略秒脉冲
+	service = NewTimeService(&nmea, nil)
+	service.Fix(fix)
+	if service.PPSEdge(fix.Time.Add(time.Second)) {
+		t.Error("PPSEdge() accepted without a PPS sink")
+	}
+}
@@ -0,0 +1,94 @@
+package driver
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultTrackSize 未配置TrackBufferSize时每台设备保留的轨迹点数，1Hz下约1小时
+const defaultTrackSize = 3600
+
+// validTrackFix 判断定位结果能否作为轨迹点
+func validTrackFix(fix Fix) bool {
+	return fix.HasTime && fix.HasPosition && (fix.Status == "A" || fix.Quality > 0)
+}
+
+// TrackBuffer 保存最近定位点的环形缓冲区，写满后覆盖最旧的点，可并发使用
+type TrackBuffer struct {
+	mutex  sync.RWMutex
+	points []Fix
+	start  int // 最旧的点在points中的位置
+	count  int
+}
+
+// NewTrackBuffer 创建容量为size的轨迹缓冲区
+func NewTrackBuffer(size int) *TrackBuffer {
+	if size <= 0 {
+		size = defaultTrackSize
+	}
+	return &TrackBuffer{points: make([]Fix, size)}
+}
+
+// Add 追加一个定位结果，无效定位被忽略。时间倒退（接收机重启或回放从头开始）时清空已有轨迹。
+func (b *TrackBuffer) Add(fix Fix) bool {
+	if !validTrackFix(fix) {
+		return false
+	}
+	point := fix
+	point.SatellitesBySys = nil // 各系统卫星数不随轨迹导出，避免每个点保留一份map
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.count > 0 {
+		last := b.points[(b.start+b.count-1)%len(b.points)]
+		if !point.Time.After(last.Time) {
+			if point.Time.Equal(last.Time) {
+				return false
+			}
+			b.start, b.count = 0, 0
+		}
+	}
+
+	if b.count < len(b.points) {
+		b.points[(b.start+b.count)%len(b.points)] = point
+		b.count++
+	} else {
+		b.points[b.start] = point
+		b.start = (b.start + 1) % len(b.points)
+	}
+	return true
+}
+
+// Len 返回缓冲区中的点数
+func (b *TrackBuffer) Len() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.count
+}
+
+// Points 按时间顺序返回[since, until]内的点，零值表示不限。
+// minDistance大于0时，与上一个保留点距离小于minDistance米的点被跳过。
+func (b *TrackBuffer) Points(since, until time.Time, minDistance float64) []Fix {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	points := make([]Fix, 0, b.count)
+	for i := 0; i < b.count; i++ {
+		point := b.points[(b.start+i)%len(b.points)]
+		if !since.IsZero() && point.Time.Before(since) {
+			continue
+		}
+		if !until.IsZero() && point.Time.After(until) {
+			break
+		}
+		if minDistance > 0 && len(points) > 0 {
+			last := points[len(points)-1]
+			if haversine(LatLon{last.Latitude, last.Longitude}, LatLon{point.Latitude, point.Longitude}) < minDistance {
+				continue
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
@@ -0,0 +1,118 @@
+package driver
+
+import (
+	"testing"
+	"time"
+)
+
+// trackFix 生成一个有效的轨迹定位结果，起点在(30, 104)，每秒向东移动east米
+func trackFix(sec int, east float64) Fix {
+	pos := offsetMeters(LatLon{30, 104}, 0, east)
+	return Fix{
+		Time:        time.Date(2025, 6, 10, 0, 0, sec, 0, time.UTC),
+		Latitude:    pos.Lat,
+		Longitude:   pos.Lon,
+		Status:      "A",
+		Quality:     1,
+		HasTime:     true,
+		HasPosition: true,
+	}
+}
+
+func TestTrackBufferRing(t *testing.T) {
+	buffer := NewTrackBuffer(3)
+	for sec := 0; sec < 5; sec++ {
+		if !buffer.Add(trackFix(sec, float64(sec))) {
+			t.Errorf("Add(%d) rejected", sec)
+		}
+	}
+	if buffer.Len() != 3 {
+		t.Fatalf("Len() = %d, expected 3", buffer.Len())
+	}
+	points := buffer.Points(time.Time{}, time.Time{}, 0)
+	for i, sec := range []int{2, 3, 4} {
+		if points[i].Time.Second() != sec {
+			t.Errorf("points[%d] at %ds, expected %ds", i, points[i].Time.Second(), sec)
+		}
+	}
+
+	// 无效定位和重复历元被忽略
+	invalid := trackFix(5, 5)
+	invalid.Status, invalid.Quality = "V", 0
+	if buffer.Add(invalid) || buffer.Add(trackFix(4, 4)) {
+		t.Error("Add accepted an invalid or duplicate fix")
+	}
+
+	// 时间倒退时清空
+	buffer.Add(trackFix(1, 1))
+	if buffer.Len() != 1 {
+		t.Errorf("Len() after time went back = %d, expected 1", buffer.Len())
+	}
+}
+
+func TestTrackBufferFilters(t *testing.T) {
+	buffer := NewTrackBuffer(100)
+	for sec := 0; sec < 10; sec++ {
+		buffer.Add(trackFix(sec, float64(sec)*3))
+	}
+
+	since := time.Date(2025, 6, 10, 0, 0, 2, 0, time.UTC)
+	until := time.Date(2025, 6, 10, 0, 0, 6, 0, time.UTC)
+	if points := buffer.Points(since, until, 0); len(points) != 5 {
+		t.Errorf("time window returned %d points, expected 5", len(points))
+	}
+
+	// 相邻点相距3m，最小距离5m时隔一个保留一个
+	points := buffer.Points(time.Time{}, time.Time{}, 5)
+	if len(points) != 5 {
+		t.Errorf("minDistance 5 returned %d points, expected 5", len(points))
+	}
+	for i := 1; i < len(points); i++ {
+		d := haversine(LatLon{points[i-1].Latitude, points[i-1].Longitude}, LatLon{points[i].Latitude, points[i].Longitude})
+		if d < 5 {
+			t.Errorf("points %d and %d are %.1fm apart", i-1, i, d)
+		}
+	}
+}
+
+func TestParseDriverConfig(t *testing.T) {
+	cfg, err := parseDriverConfig(map[string]string{})
+	if err != nil || cfg.TrackBufferSize != defaultTrackSize || cfg.TrackStore.Dir != "" || cfg.TrackStore.SegmentSize != 4<<20 {
+		t.Errorf("defaults = %+v, %v", cfg, err)
+	}
+
+	cfg, err = parseDriverConfig(map[string]string{
+		configTrackStoreDir:      "/var/lib/device-gps/track",
+		configTrackSegmentSize:   "1",
+		configTrackRetentionSize: "16",
+		configTrackRetentionAge:  "24h",
+	})
+	if err != nil || cfg.TrackStore.SegmentSize != 1<<20 || cfg.TrackStore.RetentionSize != 16<<20 || cfg.TrackStore.RetentionAge != 24*time.Hour {
+		t.Errorf("configured = %+v, %v", cfg, err)
+	}
+
+	for _, raw := range []map[string]string{
+		{configTrackBufferSize: "0"},
+		{configTrackMaxRetries: "0"},
+		{configTrackSegmentAge: "hourly"},
+		{configTrackSegmentSize: "64", configTrackRetentionSize: "32"},
+		{configMotionHold: "maybe"},
+		{configMotionMovingSpeed: "0.1"},
+		{configFixMinType: "4d"},
+		{configFixOnInvalid: "ignore"},
+		{configKalmanUERE: "0"},
+		{configLeapSeconds: "-1"},
+		{configIntegrityCN0Drop: "-1"},
+		{configIntegrityClockJump: "-1s"},
+		{configNtripVersion: "3"},
+		{configNtripTimeout: "0"},
+		{configBaseMaxClients: "0"},
+		{configBaseEnabled: "maybe"},
+		{configElevationMask: "91"},
+		{configCRS: "tokyo"},
+	} {
+		if _, err := parseDriverConfig(raw); err == nil {
+			t.Errorf("parseDriverConfig(%v) succeeded, expected error", raw)
+		}
+	}
+}
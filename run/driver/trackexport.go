@@ -0,0 +1,280 @@
+package driver
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/dtos/common"
+	"github.com/labstack/echo/v4"
+)
+
+// TrackRoute 轨迹导出接口，:name为设备名称。查询参数：
+//
+//	format       geojson（默认，FeatureCollection）、linestring（LineString几何对象）或gpx（GPX 1.1）
+//	since        起始时间，RFC3339格式，或相对当前时间的时长如1h、30m
+//	until        结束时间，RFC3339格式
+//	minDistance  相邻两点的最小距离（米），更近的点被跳过
+const TrackRoute = "/api/v3/gps/track/:name"
+
+// 轨迹导出格式
+const (
+	TrackFormatGeoJSON    = "geojson"
+	TrackFormatLineString = "linestring"
+	TrackFormatGPX        = "gpx"
+)
+
+const (
+	contentTypeGeoJSON = "application/geo+json"
+	contentTypeGPX     = "application/gpx+xml"
+)
+
+// trackQuery 轨迹导出请求的查询参数
+type trackQuery struct {
+	format      string
+	since       time.Time
+	until       time.Time
+	minDistance float64
+}
+
+// parseTrackQuery 解析查询参数，相对时长以now为基准
+func parseTrackQuery(params map[string]string, now time.Time) (trackQuery, error) {
+	query := trackQuery{format: strings.ToLower(params["format"])}
+	switch query.format {
+	case "":
+		query.format = TrackFormatGeoJSON
+	case TrackFormatGeoJSON, TrackFormatLineString, TrackFormatGPX:
+	default:
+		return query, fmt.Errorf("不支持的轨迹格式: %s", params["format"])
+	}
+
+	if since := params["since"]; since != "" {
+		if d, err := time.ParseDuration(since); err == nil && d > 0 {
+			query.since = now.Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			query.since = t
+		} else {
+			return query, fmt.Errorf("无效的起始时间: %s", since)
+		}
+	}
+	if until := params["until"]; until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return query, fmt.Errorf("无效的结束时间: %s", until)
+		}
+		query.until = t
+	}
+	if !query.since.IsZero() && !query.until.IsZero() && query.until.Before(query.since) {
+		return query, errors.New("结束时间早于起始时间")
+	}
+
+	if minDistance := params["minDistance"]; minDistance != "" {
+		value, err := strconv.ParseFloat(minDistance, 64)
+		if err != nil || value < 0 {
+			return query, fmt.Errorf("无效的最小距离: %s", minDistance)
+		}
+		query.minDistance = value
+	}
+	return query, nil
+}
+
+// handleTrack 导出设备最近的轨迹
+func (s *Driver) handleTrack(c echo.Context) error {
+	deviceName := c.Param("name")
+	rcv, err := s.receiverFor(deviceName)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, common.NewBaseResponse("", err.Error(), http.StatusNotFound))
+	}
+
+	params := map[string]string{}
+	for _, key := range []string{"format", "since", "until", "minDistance"} {
+		params[key] = c.QueryParam(key)
+	}
+	query, err := parseTrackQuery(params, time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, common.NewBaseResponse("", err.Error(), http.StatusBadRequest))
+	}
+
+	points := rcv.track.Points(query.since, query.until, query.minDistance)
+	switch query.format {
+	case TrackFormatGPX:
+		data, err := trackGPX(deviceName, points)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, common.NewBaseResponse("", err.Error(), http.StatusInternalServerError))
+		}
+		return c.Blob(http.StatusOK, contentTypeGPX, data)
+	case TrackFormatLineString:
+		return jsonBlob(c, contentTypeGeoJSON, trackLineString(points))
+	default:
+		return jsonBlob(c, contentTypeGeoJSON, trackFeatureCollection(deviceName, points))
+	}
+}
+
+// jsonBlob 以指定的Content-Type返回JSON
+func jsonBlob(c echo.Context, contentType string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, common.NewBaseResponse("", err.Error(), http.StatusInternalServerError))
+	}
+	return c.Blob(http.StatusOK, contentType, data)
+}
+
+// geoJSONGeometry GeoJSON几何对象
+type geoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// geoJSONFeature GeoJSON要素
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+// geoJSONFeatureCollection GeoJSON要素集合
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// geoJSONPosition 返回[经度, 纬度, 海拔]，没有海拔时省略第三个值
+func geoJSONPosition(fix Fix) []float64 {
+	if fix.HasAltitude {
+		return []float64{fix.Longitude, fix.Latitude, fix.Altitude}
+	}
+	return []float64{fix.Longitude, fix.Latitude}
+}
+
+// trackLineString 将轨迹转换为LineString几何对象
+func trackLineString(points []Fix) geoJSONGeometry {
+	coordinates := make([][]float64, 0, len(points))
+	for _, point := range points {
+		coordinates = append(coordinates, geoJSONPosition(point))
+	}
+	return geoJSONGeometry{Type: "LineString", Coordinates: coordinates}
+}
+
+// trackFeatureCollection 将轨迹转换为FeatureCollection：一条LineString要素（不少于2个点时）
+// 加上每个点的Point要素，Point要素的属性包含时间、速度和航向
+func trackFeatureCollection(deviceName string, points []Fix) geoJSONFeatureCollection {
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	if len(points) >= 2 {
+		times := make([]string, 0, len(points))
+		for _, point := range points {
+			times = append(times, point.Time.UTC().Format(time.RFC3339Nano))
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: trackLineString(points),
+			Properties: map[string]any{
+				"device":     deviceName,
+				"start":      times[0],
+				"end":        times[len(times)-1],
+				"count":      len(points),
+				"coordTimes": times,
+			},
+		})
+	}
+
+	for _, point := range points {
+		properties := map[string]any{
+			"device": deviceName,
+			"time":   point.Time.UTC().Format(time.RFC3339Nano),
+		}
+		if point.HasSpeed {
+			properties["speed"] = point.Speed
+		}
+		if point.HasCourse {
+			properties["course"] = point.Course
+		}
+		if point.HasHDOP {
+			properties["hdop"] = point.HDOP
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: geoJSONPosition(point)},
+			Properties: properties,
+		})
+	}
+	return collection
+}
+
+// gpxExport GPX 1.1文档，只包含一条轨迹
+type gpxExport struct {
+	XMLName xml.Name       `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version string         `xml:"version,attr"`
+	Creator string         `xml:"creator,attr"`
+	Track   gpxExportTrack `xml:"trk"`
+}
+
+type gpxExportTrack struct {
+	Name    string           `xml:"name"`
+	Segment gpxExportSegment `xml:"trkseg"`
+}
+
+type gpxExportSegment struct {
+	Points []gpxExportPoint `xml:"trkpt"`
+}
+
+// gpxExportPoint 字段顺序须与GPX 1.1 wptType一致
+type gpxExportPoint struct {
+	Lat         float64  `xml:"lat,attr"`
+	Lon         float64  `xml:"lon,attr"`
+	Ele         *float64 `xml:"ele,omitempty"`
+	Time        string   `xml:"time"`
+	GeoidHeight *float64 `xml:"geoidheight,omitempty"`
+	Fix         string   `xml:"fix,omitempty"`
+	Sat         *int     `xml:"sat,omitempty"`
+	HDOP        *float64 `xml:"hdop,omitempty"`
+	VDOP        *float64 `xml:"vdop,omitempty"`
+	PDOP        *float64 `xml:"pdop,omitempty"`
+}
+
+// gpxFix 返回GPX的fix取值：none、2d、3d、dgps
+func gpxFix(fix Fix) string {
+	if fix.Quality == 2 {
+		return "dgps"
+	}
+	switch fixType(fix) {
+	case "3d":
+		return "3d"
+	case "2d":
+		return "2d"
+	}
+	return "none"
+}
+
+// trackGPX 将轨迹转换为GPX 1.1文档
+func trackGPX(deviceName string, points []Fix) ([]byte, error) {
+	doc := gpxExport{Version: "1.1", Creator: "device-gps", Track: gpxExportTrack{Name: deviceName}}
+	for _, point := range points {
+		trkpt := gpxExportPoint{
+			Lat:         point.Latitude,
+			Lon:         point.Longitude,
+			Ele:         optional(point.Altitude, point.HasAltitude),
+			Time:        point.Time.UTC().Format(time.RFC3339Nano),
+			GeoidHeight: optional(point.GeoidSeparation, point.HasGeoidSeparation),
+			Fix:         gpxFix(point),
+			HDOP:        optional(point.HDOP, point.HasHDOP),
+			VDOP:        optional(point.VDOP, point.HasVDOP),
+			PDOP:        optional(point.PDOP, point.HasPDOP),
+		}
+		if point.HasSatellites {
+			sat := point.Satellites
+			trkpt.Sat = &sat
+		}
+		doc.Track.Segment.Points = append(doc.Track.Segment.Points, trkpt)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("生成GPX失败: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,127 @@
+package driver
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/labstack/echo/v4"
+)
+
+func TestParseTrackQuery(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		params map[string]string
+		ok     bool
+		since  time.Time
+	}{
+		{map[string]string{}, true, time.Time{}},
+		{map[string]string{"since": "1h", "format": "GPX"}, true, now.Add(-time.Hour)},
+		{map[string]string{"since": "2025-06-10T11:30:00Z", "until": "2025-06-10T11:45:00Z"}, true, now.Add(-30 * time.Minute)},
+		{map[string]string{"format": "kml"}, false, time.Time{}},
+		{map[string]string{"since": "yesterday"}, false, time.Time{}},
+		{map[string]string{"since": "2025-06-10T11:30:00Z", "until": "2025-06-10T11:00:00Z"}, false, time.Time{}},
+		{map[string]string{"minDistance": "-1"}, false, time.Time{}},
+	}
+
+	for _, test := range tests {
+		query, err := parseTrackQuery(test.params, now)
+		if (err == nil) != test.ok {
+			t.Errorf("parseTrackQuery(%v) error = %v, expected ok = %v", test.params, err, test.ok)
+			continue
+		}
+		if test.ok && !query.since.Equal(test.since) {
+			t.Errorf("parseTrackQuery(%v) since = %v, expected %v", test.params, query.since, test.since)
+		}
+	}
+}
+
+func TestTrackGPX(t *testing.T) {
+	points := []Fix{trackFix(0, 0), trackFix(1, 10)}
+	points[0].Altitude, points[0].HasAltitude = 129.3, true
+	points[0].Satellites, points[0].HasSatellites = 8, true
+
+	data, err := trackGPX("GPS-A", points)
+	if err != nil {
+		t.Fatalf("trackGPX failed: %v", err)
+	}
+
+	// 导出的GPX可以被路线加载器重新读取
+	loaded, err := parseGPXPoints(data)
+	if err != nil || len(loaded) != 2 {
+		t.Fatalf("parseGPXPoints = %v, %v", loaded, err)
+	}
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Points  []struct {
+			Ele  string `xml:"ele"`
+			Time string `xml:"time"`
+			Sat  string `xml:"sat"`
+		} `xml:"trk>trkseg>trkpt"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != "1.1" || doc.Points[0].Ele != "129.3" || doc.Points[0].Sat != "8" || doc.Points[1].Time != "2025-06-10T00:00:01Z" {
+		t.Errorf("GPX = %s", data)
+	}
+}
+
+func TestDriverTrackRoute(t *testing.T) {
+	protocols := simProtocols("30.0")
+	protocols[ProtocolSIM]["speed"] = "20"
+	driver := newTestDriver(t, models.Device{Name: "GPS-A", Protocols: protocols})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	rcv, err := driver.receiverFor("GPS-A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 3*time.Second, func() bool { return rcv.track.Len() >= 3 }, "轨迹点")
+
+	request := func(target string) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.GET(TrackRoute, driver.handleTrack)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := request("/api/v3/gps/track/GPS-A")
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentType) != contentTypeGeoJSON {
+		t.Fatalf("geojson: %d %s", rec.Code, rec.Header().Get(echo.HeaderContentType))
+	}
+	var collection geoJSONFeatureCollection
+	if err := json.Unmarshal(rec.Body.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+	if collection.Type != "FeatureCollection" || collection.Features[0].Geometry.Type != "LineString" {
+		t.Errorf("collection = %s", rec.Body.String())
+	}
+
+	rec = request("/api/v3/gps/track/GPS-A?format=linestring&minDistance=1")
+	var line struct {
+		Type        string      `json:"type"`
+		Coordinates [][]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &line); err != nil || line.Type != "LineString" || len(line.Coordinates) < 2 {
+		t.Errorf("linestring = %s, %v", rec.Body.String(), err)
+	}
+
+	if rec = request("/api/v3/gps/track/GPS-A?format=gpx&since=1h"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<trkpt") {
+		t.Errorf("gpx = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = request("/api/v3/gps/track/GPS-X"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown device = %d, expected 404", rec.Code)
+	}
+	if rec = request("/api/v3/gps/track/GPS-A?format=kml"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad format = %d, expected 400", rec.Code)
+	}
+}
@@ -0,0 +1,146 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+// trackPublishTimeout 等待SDK返回发布结果的最长时间
+const trackPublishTimeout = 30 * time.Second
+
+// openTrackStore 按驱动配置打开设备的磁盘轨迹日志并启动转发，未配置目录时返回nil
+func (s *Driver) openTrackStore(deviceName string) *TrackStore {
+	if s.config.TrackStore.Dir == "" {
+		return nil
+	}
+	store, err := OpenTrackStore(s.config.TrackStore, deviceName)
+	if err != nil {
+		s.lc.Errorf("设备%s打开磁盘轨迹日志失败，定位结果不会发布到消息总线: %v", deviceName, err)
+		return nil
+	}
+	// 服务重启前未发布的记录同样属于补发
+	go s.forwardTrack(deviceName, store, store.Pending())
+	return store
+}
+
+// closeTrackStore 停止转发并关闭磁盘轨迹日志
+func (s *Driver) closeTrackStore(deviceName string, store *TrackStore) {
+	if store == nil {
+		return
+	}
+	if err := store.Close(); err != nil {
+		s.lc.Errorf("关闭设备%s的磁盘轨迹日志失败: %v", deviceName, err)
+	}
+}
+
+// forwardTrack 按顺序把轨迹日志中的记录作为fix事件发布到消息总线。
+// 发布失败时停在该记录上定期重试，恢复后补发积压的记录，补发的事件带有origin=replayed标签。
+// SDK拒绝的记录（无法生成事件、超过MaxEventSize等）重试也不会成功，记录日志后跳过；
+// 消息总线不可用等与记录无关的错误一直重试同一条记录，保证补发不丢失、不乱序。
+// 读取轨迹分段连续失败TrackMaxRetries次时跳过该分段的剩余记录。
+func (s *Driver) forwardTrack(deviceName string, store *TrackStore, replaying bool) {
+	if replaying {
+		s.lc.Infof("设备%s有未发布的轨迹记录，开始补发", deviceName)
+	}
+
+	attempts := 0
+	for {
+		if dropped := store.Dropped(); dropped > 0 {
+			s.lc.Warnf("设备%s有%d个轨迹分段未发布就被淘汰", deviceName, dropped)
+		}
+
+		doc, next, ok, err := store.Next()
+		switch {
+		case err != nil:
+			attempts++
+			s.lc.Errorf("读取设备%s的轨迹日志失败（第%d次）: %v", deviceName, attempts, err)
+			if attempts >= s.config.TrackMaxRetries {
+				s.lc.Errorf("设备%s的轨迹分段%d无法读取，跳过其中剩余的记录", deviceName, next.Segment)
+				s.ackTrackRecord(deviceName, store, trackCursor{Segment: next.Segment + 1}, &attempts)
+				continue
+			}
+		case !ok:
+			if replaying {
+				s.lc.Infof("设备%s的轨迹记录补发完成", deviceName)
+				replaying = false
+			}
+			select {
+			case <-store.Notify():
+				continue
+			case <-store.Done():
+				return
+			}
+		default:
+			err = s.publishFix(deviceName, doc, replaying, store.Done())
+			if err == nil {
+				s.ackTrackRecord(deviceName, store, next, &attempts)
+				continue
+			}
+			if errors.Is(err, dsModels.ErrAsyncValuesRejected) {
+				s.lc.Errorf("设备%s的定位结果（历元%d）无法发布，已跳过: %v", deviceName, doc.EpochMillis, err)
+				s.ackTrackRecord(deviceName, store, next, &attempts)
+				continue
+			}
+			if !replaying {
+				s.lc.Warnf("设备%s发布定位结果失败，恢复后将按顺序补发: %v", deviceName, err)
+				replaying = true
+			}
+		}
+
+		select {
+		case <-time.After(s.config.TrackRetryInterval):
+		case <-store.Done():
+			return
+		}
+	}
+}
+
+// ackTrackRecord 确认到next为止的记录（已发布或跳过）并清零重试次数
+func (s *Driver) ackTrackRecord(deviceName string, store *TrackStore, next trackCursor, attempts *int) {
+	*attempts = 0
+	if err := store.Ack(next); err != nil {
+		s.lc.Errorf("设备%s: %v", deviceName, err)
+	}
+}
+
+// publishFix 通过异步通道发布一条fix事件并等待发布结果
+func (s *Driver) publishFix(deviceName string, doc FixDocument, replayed bool, done <-chan struct{}) error {
+	cv, err := dsModels.NewCommandValueWithOrigin("fix", common.ValueTypeObject, doc, doc.EpochMillis*int64(time.Millisecond))
+	if err != nil {
+		return fmt.Errorf("%w: %w", dsModels.ErrAsyncValuesRejected, err)
+	}
+	if replayed {
+		cv.Tags[TagOrigin] = OriginReplayed
+	}
+
+	published := make(chan error, 1)
+	values := &dsModels.AsyncValues{
+		DeviceName:    deviceName,
+		SourceName:    "fix",
+		CommandValues: []*dsModels.CommandValue{cv},
+		Published:     published,
+	}
+
+	timeout := time.NewTimer(trackPublishTimeout)
+	defer timeout.Stop()
+	select {
+	case s.asyncCh <- values:
+	case <-done:
+		return errors.New("轨迹日志已关闭")
+	case <-timeout.C:
+		return errors.New("异步上报通道已满")
+	}
+
+	select {
+	case err := <-published:
+		return err
+	case <-done:
+		return errors.New("轨迹日志已关闭")
+	case <-timeout.C:
+		return errors.New("等待发布结果超时")
+	}
+}
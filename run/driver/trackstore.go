@@ -0,0 +1,356 @@
+package driver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 补发的事件带有的标签，消费者据此区分实时数据和断线期间的补发数据
+const (
+	TagOrigin      = "origin"
+	OriginReplayed = "replayed"
+)
+
+const (
+	trackSegmentExt  = ".log"
+	trackCursorFile  = "cursor.json"
+	trackSegmentName = "%016d" + trackSegmentExt
+)
+
+// TrackStoreConfig 磁盘轨迹日志配置
+type TrackStoreConfig struct {
+	Dir           string        // 根目录，每台设备一个子目录
+	SegmentSize   int64         // 单个分段的最大字节数，超过后切换到新分段
+	SegmentAge    time.Duration // 单个分段的最长写入时间
+	RetentionSize int64         // 所有分段的总字节数上限，超过后删除最旧的分段
+	RetentionAge  time.Duration // 最后写入早于该时长的分段被删除
+}
+
+// trackCursor 已确认发布的位置：分段序号和分段内的字节偏移
+type trackCursor struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// trackSegment 一个分段文件
+type trackSegment struct {
+	seq      uint64
+	size     int64
+	modified time.Time
+}
+
+// TrackStore 单台设备的只追加轨迹日志。每条记录是一行fix文档JSON，
+// 按分段文件存放并按大小和时间淘汰。发布确认的位置保存在cursor.json中，
+// 服务重启后从该位置继续补发。
+type TrackStore struct {
+	mutex    sync.Mutex
+	cfg      TrackStoreConfig
+	dir      string
+	segments []trackSegment // 按序号递增，最后一个为当前写入的分段
+	active   *os.File
+	opened   time.Time // 当前分段的创建时间
+	cursor   trackCursor
+	dropped  int // 未发布就被淘汰的分段数
+	notify   chan struct{}
+	done     chan struct{}
+	closed   bool
+}
+
+// OpenTrackStore 打开设备的轨迹日志，每次打开都新建一个分段，
+// 上次异常退出时可能写了一半的分段不再追加
+func OpenTrackStore(cfg TrackStoreConfig, deviceName string) (*TrackStore, error) {
+	dir := filepath.Join(cfg.Dir, url.PathEscape(deviceName))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建轨迹日志目录失败: %w", err)
+	}
+
+	store := &TrackStore{
+		cfg:    cfg,
+		dir:    dir,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+	if err := store.rotate(time.Now()); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// load 扫描已有分段并读取确认位置
+func (s *TrackStore) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("读取轨迹日志目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, trackSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, trackSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("读取轨迹分段%s失败: %w", name, err)
+		}
+		if info.Size() == 0 {
+			// 上次打开后没有写入的空分段
+			_ = os.Remove(filepath.Join(s.dir, name))
+			continue
+		}
+		s.segments = append(s.segments, trackSegment{seq: seq, size: info.Size(), modified: info.ModTime()})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+
+	data, err := os.ReadFile(filepath.Join(s.dir, trackCursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &s.cursor)
+	}
+	if err != nil {
+		return fmt.Errorf("读取轨迹日志确认位置失败: %w", err)
+	}
+	return nil
+}
+
+// segmentPath 返回分段文件路径
+func (s *TrackStore) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf(trackSegmentName, seq))
+}
+
+// rotate 关闭当前分段并新建一个分段
+func (s *TrackStore) rotate(now time.Time) error {
+	seq := uint64(1)
+	if len(s.segments) > 0 {
+		seq = s.segments[len(s.segments)-1].seq + 1
+	}
+	file, err := os.OpenFile(s.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("创建轨迹分段失败: %w", err)
+	}
+	if s.active != nil {
+		_ = s.active.Close()
+	}
+	s.active = file
+	s.opened = now
+	s.segments = append(s.segments, trackSegment{seq: seq, modified: now})
+	return nil
+}
+
+// Append 追加一条记录，必要时切换分段并淘汰旧分段
+func (s *TrackStore) Append(doc FixDocument) error {
+	line, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("编码轨迹记录失败: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return errors.New("轨迹日志已关闭")
+	}
+
+	now := time.Now()
+	current := &s.segments[len(s.segments)-1]
+	if current.size > 0 && (current.size+int64(len(line)) > s.cfg.SegmentSize || now.Sub(s.opened) >= s.cfg.SegmentAge) {
+		if err := s.rotate(now); err != nil {
+			return err
+		}
+		current = &s.segments[len(s.segments)-1]
+	}
+
+	if n, err := s.active.Write(line); err != nil {
+		// 不完整的记录留在分段末尾，下次追加时切换到新分段
+		current.size += int64(n)
+		s.opened = time.Time{}
+		return fmt.Errorf("写入轨迹分段失败: %w", err)
+	}
+	current.size += int64(len(line))
+	current.modified = now
+	s.enforceRetention(now)
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// enforceRetention 删除超出总大小或保留时长的旧分段，当前分段不会被删除
+func (s *TrackStore) enforceRetention(now time.Time) {
+	var total int64
+	for _, segment := range s.segments {
+		total += segment.size
+	}
+	for len(s.segments) > 1 {
+		oldest := s.segments[0]
+		if total <= s.cfg.RetentionSize && now.Sub(oldest.modified) <= s.cfg.RetentionAge {
+			break
+		}
+		if err := os.Remove(s.segmentPath(oldest.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if oldest.size > 0 && s.cursor.Segment <= oldest.seq && s.unreadLocked(oldest) {
+			s.dropped++
+		}
+		total -= oldest.size
+		s.segments = s.segments[1:]
+	}
+}
+
+// unreadLocked 判断分段中是否还有未确认发布的记录
+func (s *TrackStore) unreadLocked(segment trackSegment) bool {
+	return s.cursor.Segment < segment.seq || s.cursor.Offset < segment.size
+}
+
+// Next 读取确认位置之后的下一条记录，返回记录和读完该记录后的位置。
+// 没有未发布的记录时ok为false。
+func (s *TrackStore) Next() (doc FixDocument, next trackCursor, ok bool, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cursor := s.cursor
+	for _, segment := range s.segments {
+		if segment.seq < cursor.Segment {
+			continue
+		}
+		if segment.seq > cursor.Segment {
+			// 确认位置所在的分段已读完或已被淘汰
+			cursor = trackCursor{Segment: segment.seq}
+		}
+		for cursor.Offset < segment.size {
+			line, err := s.readLine(cursor)
+			if err != nil {
+				return doc, cursor, false, err
+			}
+			cursor.Offset += int64(len(line))
+			if line[len(line)-1] != '\n' {
+				// 异常退出留下的不完整记录
+				break
+			}
+			var record FixDocument
+			if json.Unmarshal(line, &record) == nil {
+				return record, cursor, true, nil
+			}
+		}
+		cursor = trackCursor{Segment: segment.seq, Offset: segment.size}
+	}
+	// 跳过的损坏记录不必再读一遍
+	s.cursor = cursor
+	return doc, cursor, false, nil
+}
+
+// readLine 读取位置处的一行
+func (s *TrackStore) readLine(cursor trackCursor) ([]byte, error) {
+	file, err := os.Open(s.segmentPath(cursor.Segment))
+	if err != nil {
+		return nil, fmt.Errorf("打开轨迹分段失败: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(cursor.Offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("定位轨迹分段失败: %w", err)
+	}
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return nil, fmt.Errorf("读取轨迹分段失败: %w", err)
+	}
+	return line, nil
+}
+
+// Ack 确认位置之前的记录已发布，并写入cursor.json
+func (s *TrackStore) Ack(cursor trackCursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return errors.New("轨迹日志已关闭")
+	}
+	s.cursor = cursor
+
+	path := filepath.Join(s.dir, trackCursorFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("保存轨迹日志确认位置失败: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("保存轨迹日志确认位置失败: %w", err)
+	}
+	return nil
+}
+
+// Pending 判断是否有未确认发布的记录
+func (s *TrackStore) Pending() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, segment := range s.segments {
+		if segment.seq >= s.cursor.Segment && segment.size > 0 && s.unreadLocked(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dropped 返回并清零未发布就被淘汰的分段数
+func (s *TrackStore) Dropped() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+// Size 返回所有分段的总字节数
+func (s *TrackStore) Size() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var total int64
+	for _, segment := range s.segments {
+		total += segment.size
+	}
+	return total
+}
+
+// Notify 追加新记录时发出通知
+func (s *TrackStore) Notify() <-chan struct{} {
+	return s.notify
+}
+
+// Done 轨迹日志关闭时关闭
+func (s *TrackStore) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close 关闭当前分段，重复调用无副作用
+func (s *TrackStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	return s.active.Close()
+}
@@ -0,0 +1,317 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+// storeDoc 生成第sec秒的fix文档，各记录编码后长度相同
+func storeDoc(sec int) FixDocument {
+	return NewFixDocument(trackFix(sec, 0))
+}
+
+func mustJSON(t *testing.T, value any) []byte {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// drainStore 读取并确认所有未发布的记录，返回记录的秒数
+func drainStore(t *testing.T, store *TrackStore) []int {
+	t.Helper()
+	var secs []int
+	for {
+		doc, next, ok, err := store.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if !ok {
+			return secs
+		}
+		secs = append(secs, int(doc.EpochMillis/1000%60))
+		if err := store.Ack(next); err != nil {
+			t.Fatalf("Ack failed: %v", err)
+		}
+	}
+}
+
+func testStoreConfig(dir string) TrackStoreConfig {
+	return TrackStoreConfig{Dir: dir, SegmentSize: 1 << 20, SegmentAge: time.Hour, RetentionSize: 1 << 30, RetentionAge: time.Hour}
+}
+
+func TestTrackStoreOrderAndResume(t *testing.T) {
+	cfg := testStoreConfig(t.TempDir())
+	store, err := OpenTrackStore(cfg, "GPS/A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for sec := 0; sec < 3; sec++ {
+		if err := store.Append(storeDoc(sec)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 只确认第一条，第二条读出后未确认
+	doc, next, ok, _ := store.Next()
+	if !ok || doc.EpochMillis != storeDoc(0).EpochMillis {
+		t.Fatalf("first record = %+v, %v", doc, ok)
+	}
+	if err := store.Ack(next); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok, _ := store.Next(); !ok {
+		t.Fatal("second record missing")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后从确认位置继续，新记录排在后面
+	store, err = OpenTrackStore(cfg, "GPS/A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if !store.Pending() {
+		t.Error("Pending() = false after reopen with unacknowledged records")
+	}
+	if err := store.Append(storeDoc(3)); err != nil {
+		t.Fatal(err)
+	}
+	if got := drainStore(t, store); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("records after reopen = %v, expected [1 2 3]", got)
+	}
+	if store.Pending() {
+		t.Error("Pending() = true after draining")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Dir, "GPS%2FA", trackCursorFile)); err != nil {
+		t.Errorf("cursor file: %v", err)
+	}
+}
+
+func TestTrackStoreRotationAndRetention(t *testing.T) {
+	cfg := testStoreConfig(t.TempDir())
+	line := int64(len(mustJSON(t, storeDoc(0))) + 1)
+	cfg.SegmentSize = 2 * line   // 每个分段两条记录
+	cfg.RetentionSize = 6 * line // 最多保留三个分段
+	store, err := OpenTrackStore(cfg, "GPS-A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for sec := 0; sec < 4; sec++ {
+		_ = store.Append(storeDoc(sec))
+	}
+	if got := drainStore(t, store); len(got) != 4 {
+		t.Fatalf("drained %v, expected 4 records", got)
+	}
+
+	// 积压10条，超出保留大小的最旧分段在发布前被淘汰
+	for sec := 4; sec < 14; sec++ {
+		if err := store.Append(storeDoc(sec)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if size := store.Size(); size > cfg.RetentionSize {
+		t.Errorf("Size() = %d, exceeds retention %d", size, cfg.RetentionSize)
+	}
+	if dropped := store.Dropped(); dropped != 2 {
+		t.Errorf("Dropped() = %d, expected 2", dropped)
+	}
+	got := drainStore(t, store)
+	if len(got) != 6 || got[0] != 8 || got[5] != 13 {
+		t.Errorf("records after retention = %v, expected 8..13", got)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(cfg.Dir, "GPS-A"))
+	segments := 0
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == trackSegmentExt {
+			segments++
+		}
+	}
+	if segments != 3 {
+		t.Errorf("%d segment files on disk, expected 3", segments)
+	}
+}
+
+func TestTrackStoreTruncatedRecord(t *testing.T) {
+	cfg := testStoreConfig(t.TempDir())
+	store, err := OpenTrackStore(cfg, "GPS-A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Append(storeDoc(0))
+	_ = store.Close()
+
+	// 模拟异常退出时写了一半的记录
+	path := store.segmentPath(1)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString(`{"schema":"device-gps/fix","ver`)
+	_ = file.Close()
+
+	store, err = OpenTrackStore(cfg, "GPS-A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	_ = store.Append(storeDoc(1))
+	if got := drainStore(t, store); len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Errorf("records = %v, expected [0 1]", got)
+	}
+}
+
+func TestDriverTrackForward(t *testing.T) {
+	configs := map[string]string{
+		configTrackStoreDir:      t.TempDir(),
+		configTrackRetryInterval: "20ms",
+	}
+	driver, asyncCh := newTestDriverWithConfig(t, configs, models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")})
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	next := func() *dsModels.AsyncValues {
+		t.Helper()
+		for {
+			select {
+			case values := <-asyncCh:
+				if values.SourceName == "motion_state" || values.SourceName == "gnss_integrity" {
+					continue
+				}
+				if values.DeviceName != "GPS-A" || values.SourceName != "fix" || values.Published == nil {
+					t.Fatalf("unexpected async values: %+v", values)
+				}
+				return values
+			case <-time.After(3 * time.Second):
+				t.Fatal("等待超时: fix事件")
+				return nil
+			}
+		}
+	}
+
+	var acked []int64
+	ack := func(values *dsModels.AsyncValues) {
+		values.Published <- nil
+		acked = append(acked, values.CommandValues[0].Origin)
+	}
+
+	// 消息总线正常时实时发布，不带补发标签
+	for i := 0; i < 2; i++ {
+		values := next()
+		if values.CommandValues[0].Tags[TagOrigin] != "" {
+			t.Errorf("live event tagged %v", values.CommandValues[0].Tags)
+		}
+		ack(values)
+	}
+
+	// 发布失败时停在同一条记录上重试
+	failed := next()
+	failed.Published <- errors.New("message bus unavailable")
+	for i := 0; i < 2; i++ {
+		retry := next()
+		if retry.CommandValues[0].Origin != failed.CommandValues[0].Origin {
+			t.Fatalf("retry origin = %d, expected %d", retry.CommandValues[0].Origin, failed.CommandValues[0].Origin)
+		}
+		retry.Published <- errors.New("message bus unavailable")
+	}
+	time.Sleep(200 * time.Millisecond) // 断线期间继续积压
+
+	// 恢复后按顺序补发，补发完成后回到实时发布
+	replayed := 0
+	for live := false; !live; {
+		values := next()
+		switch values.CommandValues[0].Tags[TagOrigin] {
+		case OriginReplayed:
+			replayed++
+		default:
+			live = true
+		}
+		ack(values)
+	}
+	if replayed < 2 {
+		t.Errorf("%d events replayed, expected the backlog of at least 2", replayed)
+	}
+	if acked[2] != failed.CommandValues[0].Origin {
+		t.Errorf("first replayed origin = %d, expected the failed one %d", acked[2], failed.CommandValues[0].Origin)
+	}
+	for i := 1; i < len(acked); i++ {
+		if acked[i] <= acked[i-1] {
+			t.Errorf("events out of order: %v", acked)
+			break
+		}
+	}
+}
+
+func TestDriverTrackForwardSkips(t *testing.T) {
+	configs := map[string]string{configTrackRetryInterval: "20ms", configTrackMaxRetries: "2"}
+	driver, asyncCh := newTestDriverWithConfig(t, configs)
+
+	// 每条记录单独一个分段，第一个分段被删除后无法读取
+	cfg := testStoreConfig(t.TempDir())
+	cfg.SegmentSize = 1
+	store, err := OpenTrackStore(cfg, "GPS-A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for sec := 0; sec < 5; sec++ {
+		if err := store.Append(storeDoc(sec)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(store.segmentPath(1)); err != nil {
+		t.Fatal(err)
+	}
+	go driver.forwardTrack("GPS-A", store, true)
+
+	next := func() (*dsModels.AsyncValues, int) {
+		t.Helper()
+		select {
+		case values := <-asyncCh:
+			return values, int(values.CommandValues[0].Origin / int64(time.Second) % 60)
+		case <-time.After(3 * time.Second):
+			t.Fatal("等待超时: fix事件")
+			return nil, 0
+		}
+	}
+	rejected := fmt.Errorf("%w: no readings to send", dsModels.ErrAsyncValuesRejected)
+	unavailable := errors.New("message bus unavailable")
+
+	// 无法读取的分段重试后跳过；SDK拒绝的记录不重试
+	values, sec := next()
+	if sec != 1 {
+		t.Fatalf("first record = %d, expected 1 after skipping the unreadable segment", sec)
+	}
+	values.Published <- rejected
+
+	// 消息总线不可用与记录无关，超过TrackMaxRetries也不跳过
+	for attempt := 0; attempt < 5; attempt++ {
+		if values, sec = next(); sec != 2 {
+			t.Fatalf("attempt %d record = %d, expected 2", attempt, sec)
+		}
+		values.Published <- unavailable
+	}
+	for _, expected := range []int{2, 3, 4} {
+		if values, sec = next(); sec != expected {
+			t.Fatalf("record = %d, expected %d", sec, expected)
+		}
+		values.Published <- nil
+	}
+	waitFor(t, time.Second, func() bool { return !store.Pending() }, "全部记录确认")
+}
@@ -0,0 +1,145 @@
+package driver
+
+import (
+	"fmt"
+	"math"
+)
+
+// WGS84椭球参数
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+
+	utmScale         = 0.9996
+	utmFalseEasting  = 500000.0
+	utmFalseNorthing = 10000000.0 // 南半球北向偏移
+)
+
+// UTM/MGRS的纬度带字母，每带8°，X带为72°~84°
+const utmBands = "CDEFGHJKLMNPQRSTUVWX"
+
+// MGRS 10万米方格的列、行字母（不含I和O）
+const (
+	mgrsColumnLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	mgrsRowLetters    = "ABCDEFGHJKLMNPQRSTUV"
+)
+
+// UTM WGS84下的UTM投影坐标
+type UTM struct {
+	Zone     int     // 投影带号，1~60
+	Band     byte    // 纬度带字母
+	Easting  float64 // 东向坐标（米），含500km东向偏移
+	Northing float64 // 北向坐标（米），南半球含10000km北向偏移
+}
+
+// North 判断是否为北半球坐标
+func (u UTM) North() bool {
+	return u.Band >= 'N'
+}
+
+// String 返回形如 48R 400360 3401192 的UTM坐标
+func (u UTM) String() string {
+	return fmt.Sprintf("%d%c %.0f %.0f", u.Zone, u.Band, math.Floor(u.Easting), math.Floor(u.Northing))
+}
+
+// utmZone 计算经纬度所在的投影带号，包含挪威和斯瓦尔巴群岛的特殊分带
+func utmZone(pos LatLon) int {
+	lon := pos.Lon
+	if lon >= 180 {
+		lon -= 360
+	}
+	zone := int(math.Floor((lon+180)/6)) + 1
+
+	switch {
+	case pos.Lat >= 56 && pos.Lat < 64 && lon >= 3 && lon < 12:
+		zone = 32
+	case pos.Lat >= 72 && pos.Lat <= 84 && lon >= 0 && lon < 42:
+		switch {
+		case lon < 9:
+			zone = 31
+		case lon < 21:
+			zone = 33
+		case lon < 33:
+			zone = 35
+		default:
+			zone = 37
+		}
+	}
+	return zone
+}
+
+// toUTM 将WGS84经纬度投影到所在带的UTM坐标，纬度须在-80°~84°之间
+func toUTM(pos LatLon) (UTM, error) {
+	return toUTMZone(pos, utmZone(pos))
+}
+
+// toUTMZone 将WGS84经纬度投影到指定的UTM带，用于跨带作业时统一到同一投影带
+func toUTMZone(pos LatLon, zone int) (UTM, error) {
+	if pos.Lat < -80 || pos.Lat > 84 || math.IsNaN(pos.Lat) {
+		return UTM{}, fmt.Errorf("纬度%.6f超出UTM范围(-80~84)", pos.Lat)
+	}
+	if pos.Lon < -180 || pos.Lon > 180 || math.IsNaN(pos.Lon) {
+		return UTM{}, fmt.Errorf("无效的经度: %.6f", pos.Lon)
+	}
+	if zone < 1 || zone > 60 {
+		return UTM{}, fmt.Errorf("无效的UTM投影带: %d", zone)
+	}
+
+	band := min(int(math.Floor((pos.Lat+80)/8)), len(utmBands)-1)
+	easting, northing := transverseMercator(pos, float64((zone-1)*6-180+3))
+	if pos.Lat < 0 {
+		northing += utmFalseNorthing
+	}
+
+	return UTM{Zone: zone, Band: utmBands[band], Easting: easting, Northing: northing}, nil
+}
+
+// transverseMercator 以lon0为中央经线做横轴墨卡托投影（USGS级数展开，带内精度毫米级）
+func transverseMercator(pos LatLon, lon0 float64) (float64, float64) {
+	e2 := wgs84F * (2 - wgs84F)
+	e4, e6 := e2*e2, e2*e2*e2
+	ep2 := e2 / (1 - e2)
+
+	phi := toRadians(pos.Lat)
+	sinPhi, cosPhi, tanPhi := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+
+	n := wgs84A / math.Sqrt(1-e2*sinPhi*sinPhi)
+	t := tanPhi * tanPhi
+	c := ep2 * cosPhi * cosPhi
+	a := cosPhi * toRadians(math.Remainder(pos.Lon-lon0, 360))
+
+	// 赤道到该纬度的子午线弧长
+	m := wgs84A * ((1-e2/4-3*e4/64-5*e6/256)*phi -
+		(3*e2/8+3*e4/32+45*e6/1024)*math.Sin(2*phi) +
+		(15*e4/256+45*e6/1024)*math.Sin(4*phi) -
+		(35*e6/3072)*math.Sin(6*phi))
+
+	a2 := a * a
+	easting := utmScale*n*(a+(1-t+c)*a2*a/6+(5-18*t+t*t+72*c-58*ep2)*a2*a2*a/120) + utmFalseEasting
+	northing := utmScale * (m + n*tanPhi*(a2/2+(5-t+9*c+4*c*c)*a2*a2/24+(61-58*t+t*t+600*c-330*ep2)*a2*a2*a2/720))
+	return easting, northing
+}
+
+// toMGRS 将经纬度转换为1米精度的MGRS坐标，形如 48R VV 00360 01192
+func toMGRS(pos LatLon) (string, error) {
+	u, err := toUTM(pos)
+	if err != nil {
+		return "", err
+	}
+
+	// 列字母每3个投影带循环一次，行字母在偶数带上错开5个
+	column := int(math.Floor(u.Easting / 100000))
+	row := int(math.Floor(u.Northing/100000)) % len(mgrsRowLetters)
+	if column < 1 || column > 8 {
+		return "", fmt.Errorf("东向坐标%.0f超出MGRS方格范围", u.Easting)
+	}
+	set := (u.Zone - 1) % 3
+	if u.Zone%2 == 0 {
+		row = (row + 5) % len(mgrsRowLetters)
+	}
+
+	east := int(math.Floor(math.Mod(u.Easting, 100000)))
+	north := int(math.Floor(math.Mod(u.Northing, 100000)))
+	return fmt.Sprintf("%d%c %c%c %05d %05d", u.Zone, u.Band,
+		mgrsColumnLetters[set*8+column-1], mgrsRowLetters[row], east, north), nil
+}
@@ -0,0 +1,137 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+// ErrNoValidFix 当前定位不满足有效性策略
+var ErrNoValidFix = errors.New("无有效定位")
+
+// 无效定位的处理方式
+const (
+	InvalidFixError = "error" // 略去受约束的读数，请求的资源全部受约束时读取返回错误
+	InvalidFixTag   = "tag"   // 照常返回读数，并通过标签标明定位无效及原因
+)
+
+// 按有效性策略标注读数的标签
+const (
+	TagFixValid  = "fixValid"  // true或false
+	TagFixReason = "fixReason" // 无效原因，仅在fixValid为false时存在
+)
+
+// fixTypeRank 定位类型的高低，用于比较最低定位类型
+var fixTypeRank = map[string]int{"none": 0, "2d": 2, "3d": 3}
+
+// ValidityPolicy 定位有效性策略，各项为零值时不检查
+type ValidityPolicy struct {
+	MaxAge        time.Duration // 距最近一次历元结束的最长时间
+	MinFixType    string        // 最低定位类型：none、2d或3d
+	MaxHDOP       float64
+	MaxPDOP       float64
+	MinSatellites int
+	RequireActive bool   // 要求RMC状态为A
+	OnInvalid     string // error或tag
+}
+
+// Check 按策略检查定位，不满足时返回包装了ErrNoValidFix的错误，说明第一条未满足的规则
+func (p ValidityPolicy) Check(fix Fix, now time.Time) error {
+	switch {
+	case fix.Received.IsZero():
+		return fmt.Errorf("%w: 尚未收到定位数据", ErrNoValidFix)
+	case p.MaxAge > 0 && now.Sub(fix.Received) > p.MaxAge:
+		return fmt.Errorf("%w: 数据已过期%v", ErrNoValidFix, now.Sub(fix.Received).Round(time.Millisecond))
+	case p.RequireActive && fix.Status != "A":
+		return fmt.Errorf("%w: RMC状态为%q", ErrNoValidFix, fix.Status)
+	case !fix.HasPosition:
+		return fmt.Errorf("%w: 没有位置", ErrNoValidFix)
+	case fixTypeRank[fixType(fix)] < fixTypeRank[p.MinFixType]:
+		return fmt.Errorf("%w: 定位类型%s低于%s", ErrNoValidFix, fixType(fix), p.MinFixType)
+	case p.MaxHDOP > 0 && (!fix.HasHDOP || fix.HDOP > p.MaxHDOP):
+		return fmt.Errorf("%w: HDOP超过%v", ErrNoValidFix, p.MaxHDOP)
+	case p.MaxPDOP > 0 && (!fix.HasPDOP || fix.PDOP > p.MaxPDOP):
+		return fmt.Errorf("%w: PDOP超过%v", ErrNoValidFix, p.MaxPDOP)
+	case p.MinSatellites > 0 && (!fix.HasSatellites || fix.Satellites < p.MinSatellites):
+		return fmt.Errorf("%w: 卫星数少于%d", ErrNoValidFix, p.MinSatellites)
+	}
+	return nil
+}
+
+// gatedResources 受有效性策略约束的位置和速度资源
+var gatedResources = map[string]bool{
+	"latitude":      true,
+	"longitude":     true,
+	"position":      true,
+	"altitude":      true,
+	"speed":         true,
+	"course":        true,
+	"latitude_deg":  true,
+	"longitude_deg": true,
+	"altitude_m":    true,
+	"speed_mps":     true,
+	"course_deg":    true,
+
+	"position_crs":         true,
+	"position_gcj02":       true,
+	"position_bd09":        true,
+	"ellipsoidal_height_m": true,
+	"utm_easting":          true,
+	"utm_northing":         true,
+	"utm_zone":             true,
+
+	"filtered_latitude":  true,
+	"filtered_longitude": true,
+	"filtered_speed":     true,
+	"filtered_course":    true,
+}
+
+// fixValidity 请求中包含受约束的资源时按有效性策略检查当前定位，否则返回nil
+func (s *Driver) fixValidity(rcv *receiver, reqs []dsModels.CommandRequest) error {
+	for _, req := range reqs {
+		if gatedResources[req.DeviceResourceName] {
+			return s.config.Validity.Check(rcv.gps.Fix(), time.Now())
+		}
+	}
+	return nil
+}
+
+// allGated 请求的资源是否全部受有效性策略约束
+func allGated(reqs []dsModels.CommandRequest) bool {
+	for _, req := range reqs {
+		if !gatedResources[req.DeviceResourceName] {
+			return false
+		}
+	}
+	return len(reqs) > 0
+}
+
+// withheld 无效定位按error方式处理时，受约束的资源不返回读数
+func (s *Driver) withheld(req dsModels.CommandRequest, validity error) bool {
+	return validity != nil && s.config.Validity.OnInvalid == InvalidFixError && gatedResources[req.DeviceResourceName]
+}
+
+// tagFixValidity 无效定位按tag方式处理时，在受约束的读数上标注定位是否有效
+func (s *Driver) tagFixValidity(cv *dsModels.CommandValue, validity error) {
+	if s.config.Validity.OnInvalid != InvalidFixTag || !gatedResources[cv.DeviceResourceName] {
+		return
+	}
+	cv.Tags[TagFixValid] = strconv.FormatBool(validity == nil)
+	if validity != nil {
+		cv.Tags[TagFixReason] = validity.Error()
+	}
+}
+
+// getFixAge 获取距最近一次历元结束的毫秒数
+func (s *Driver) getFixAge(gps *LCX6XZ, req dsModels.CommandRequest) *dsModels.CommandValue {
+	fix := gps.Fix()
+	if fix.Received.IsZero() {
+		return nil
+	}
+	cv, _ := dsModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt64, time.Since(fix.Received).Milliseconds())
+	return cv
+}
@@ -0,0 +1,99 @@
+package driver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+)
+
+func TestValidityPolicy(t *testing.T) {
+	now := time.Date(2025, 6, 10, 0, 0, 10, 0, time.UTC)
+	good := trackFix(0, 0)
+	good.Status, good.FixMode, good.Received = "A", 3, now.Add(-time.Second)
+	good.HDOP, good.PDOP, good.Satellites = 0.9, 1.8, 10
+	good.HasHDOP, good.HasPDOP, good.HasSatellites = true, true, true
+
+	policy := ValidityPolicy{MaxAge: 5 * time.Second, MinFixType: "3d", MaxHDOP: 2, MaxPDOP: 3, MinSatellites: 6, RequireActive: true}
+	tests := []struct {
+		name   string
+		modify func(fix *Fix)
+		reason string // 为空表示有效
+	}{
+		{"good", func(fix *Fix) {}, ""},
+		{"no data", func(fix *Fix) { fix.Received = time.Time{} }, "尚未收到"},
+		{"stale", func(fix *Fix) { fix.Received = now.Add(-6 * time.Second) }, "过期"},
+		{"status V", func(fix *Fix) { fix.Status = "V" }, "RMC状态"},
+		{"2d", func(fix *Fix) { fix.FixMode = 2 }, "定位类型2d"},
+		{"hdop", func(fix *Fix) { fix.HDOP = 2.5 }, "HDOP"},
+		{"no pdop", func(fix *Fix) { fix.HasPDOP = false }, "PDOP"},
+		{"satellites", func(fix *Fix) { fix.Satellites = 5 }, "卫星数"},
+	}
+	for _, test := range tests {
+		fix := good
+		test.modify(&fix)
+		err := policy.Check(fix, now)
+		if test.reason == "" {
+			if err != nil {
+				t.Errorf("%s: Check() = %v, expected nil", test.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrNoValidFix) || !strings.Contains(err.Error(), test.reason) {
+			t.Errorf("%s: Check() = %v, expected %q", test.name, err, test.reason)
+		}
+	}
+
+	// 零值策略只要求收到过带位置的定位
+	stale := good
+	stale.Status, stale.FixMode, stale.Received = "V", 1, now.Add(-time.Hour)
+	if err := (ValidityPolicy{}).Check(stale, now); err != nil {
+		t.Errorf("zero policy: Check() = %v, expected nil", err)
+	}
+}
+
+func TestDriverValidity(t *testing.T) {
+	read := func(driver *Driver, resources ...string) ([]*dsModels.CommandValue, error) {
+		reqs := make([]dsModels.CommandRequest, len(resources))
+		for i, resource := range resources {
+			reqs[i].DeviceResourceName = resource
+		}
+		return driver.HandleReadCommands("GPS-A", nil, reqs)
+	}
+	device := models.Device{Name: "GPS-A", Protocols: simProtocols("30.0")}
+
+	// error方式：位置资源返回错误，其他资源不受影响
+	driver, _ := newTestDriverWithConfig(t, map[string]string{configFixMinSatellites: "99"}, device)
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if age, _ := readResource(t, driver, "GPS-A", "fix_age_ms").Int64Value(); age < 0 || age > 1000 {
+		t.Errorf("fix_age_ms = %d", age)
+	}
+	if _, err := read(driver, "latitude_deg", "longitude_deg"); !errors.Is(err, ErrNoValidFix) {
+		t.Errorf("read latitude_deg = %v, expected ErrNoValidFix", err)
+	}
+	// 同时请求其他资源时只略去位置读数
+	if res, err := read(driver, "latitude_deg", "fix_age_ms", "longitude_deg"); err != nil || len(res) != 1 || res[0].DeviceResourceName != "fix_age_ms" {
+		t.Errorf("read latitude_deg, fix_age_ms = %v, %v", res, err)
+	}
+	if res, err := read(driver, "satellites_used"); err != nil || len(res) != 1 {
+		t.Errorf("read satellites_used = %v, %v", res, err)
+	}
+
+	// tag方式：照常返回读数并标注原因
+	driver, _ = newTestDriverWithConfig(t, map[string]string{configFixMinSatellites: "99", configFixOnInvalid: "tag"}, device)
+	if err := driver.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	cv := readResource(t, driver, "GPS-A", "latitude_deg")
+	if cv.Tags[TagFixValid] != "false" || !strings.Contains(cv.Tags[TagFixReason], "卫星数") {
+		t.Errorf("latitude_deg tags = %v", cv.Tags)
+	}
+	if cv := readResource(t, driver, "GPS-A", "fix_age_ms"); len(cv.Tags) != 0 {
+		t.Errorf("fix_age_ms tags = %v, expected none", cv.Tags)
+	}
+}